@@ -3,18 +3,26 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 
 	stderrors "errors"
 
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/redis/go-redis/v9"
 )
 
-// Cache wraps a function with Redis caching
-
+// Cache wraps a function with Redis caching. The key is scoped to the
+// organization of the context so cached data never crosses tenants.
 func Cache[T any](ctx context.Context, redisClient redis.UniversalClient, key string, f func() (T, error)) (T, error) {
+	return Global(ctx, redisClient, Key(ctx, key), f)
+}
+
+// Global is like Cache but the key is shared by every organization. It is
+// meant for platform-wide data, such as roles.
+func Global[T any](ctx context.Context, redisClient redis.UniversalClient, key string, f func() (T, error)) (T, error) {
 	var model T
 	val, err := redisClient.Get(ctx, key).Result()
 
@@ -47,3 +55,45 @@ func Cache[T any](ctx context.Context, redisClient redis.UniversalClient, key st
 	}
 	return model, nil
 }
+
+// Key returns the key Cache stores the given key under.
+func Key(ctx context.Context, key string) string {
+	return fmt.Sprintf("org:%d:%s", tenant.ID(ctx), key)
+}
+
+// Forget drops the cached values of the given keys in the organization of
+// the context.
+func Forget(ctx context.Context, redisClient redis.UniversalClient, keys ...string) error {
+	scoped := make([]string, 0, len(keys))
+	for _, key := range keys {
+		scoped = append(scoped, Key(ctx, key))
+	}
+	return redisClient.Del(ctx, scoped...).Err()
+}
+
+// Versioned is like Cache for values of a namespace too many to be forgotten
+// one by one, such as the pages of a list. Invalidate forgets all of them at
+// once by bumping the version of the namespace the keys are stored under.
+func Versioned[T any](ctx context.Context, redisClient redis.UniversalClient, namespace string, key string, f func() (T, error)) (T, error) {
+	version, err := redisClient.Get(ctx, Key(ctx, namespace+":version")).Int64()
+	if err != nil && err != redis.Nil {
+		var model T
+		return model, err
+	}
+	return Cache(ctx, redisClient, fmt.Sprintf("%s:%d:%s", namespace, version, key), f)
+}
+
+// Invalidate drops the values cached by Versioned in the given namespaces of
+// the organization of the context.
+func Invalidate(ctx context.Context, redisClient redis.UniversalClient, namespaces ...string) error {
+	pipe := redisClient.TxPipeline()
+	for _, namespace := range namespaces {
+		key := Key(ctx, namespace+":version")
+		pipe.Incr(ctx, key)
+		// Outlives the values cached under the previous version, so it cannot
+		// start over while they are still there
+		pipe.Expire(ctx, key, time.Hour)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
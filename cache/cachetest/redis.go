@@ -0,0 +1,127 @@
+// Package cachetest provides an in-memory stand-in for Redis, to test code
+// that keeps short-lived state in Redis without running a server.
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis implements the string commands of redis.UniversalClient in memory.
+// Expirations are ignored. Other commands panic.
+type Redis struct {
+	redis.UniversalClient
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func NewRedis() *Redis {
+	return &Redis{values: make(map[string]string)}
+}
+
+// Value returns the value stored under key, and whether there is one.
+func (r *Redis) Value(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	return value, ok
+}
+
+// Len returns the number of keys stored.
+func (r *Redis) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.values)
+}
+
+// Put stores the value under key.
+func (r *Redis) Put(key string, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = value
+}
+
+func (r *Redis) Get(ctx context.Context, key string) *redis.StringCmd {
+	value, ok := r.Value(key)
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (r *Redis) GetDel(ctx context.Context, key string) *redis.StringCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	delete(r.values, key)
+	return redis.NewStringResult(value, nil)
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd {
+	r.Put(key, format(value))
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *Redis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	r.values[key] = format(value)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (r *Redis) Incr(ctx context.Context, key string) *redis.IntCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, _ := strconv.ParseInt(r.values[key], 10, 64)
+	n++
+	r.values[key] = strconv.FormatInt(n, 10)
+	return redis.NewIntResult(n, nil)
+}
+
+func (r *Redis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := r.values[key]; ok {
+			delete(r.values, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (r *Redis) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found int64
+	for _, key := range keys {
+		if _, ok := r.values[key]; ok {
+			found++
+		}
+	}
+	return redis.NewIntResult(found, nil)
+}
+
+func format(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
+	"github.com/dapthehuman/learning-management-system/service"
+	adminService "github.com/dapthehuman/learning-management-system/service/admin-service"
+	organizationService "github.com/dapthehuman/learning-management-system/service/organization-service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"goyave.dev/goyave/v5"
+)
+
+// organizationContext returns a context scoped to the organization having the
+// given slug, so the commands only see and create its records.
+func organizationContext(server *goyave.Server, slug string) (context.Context, error) {
+	organizations := server.Service(service.Organization).(*organizationService.Service)
+	organization, err := organizations.Resolve(context.Background(), slug)
+	if err != nil {
+		return nil, err
+	}
+	return tenant.WithOrganization(context.Background(), organization.ID), nil
+}
+
+// runUserImport imports users from a CSV file on behalf of the user actorID,
+// whose role limits the roles of the imported users, and prints the report as
+// JSON. It returns the process exit code.
+func runUserImport(server *goyave.Server, organization string, actorID uint64, path string, options *adminDto.ImportOptions) int {
+	ctx, err := organizationContext(server, organization)
+	if err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+	defer file.Close()
+
+	admin := server.Service(service.Admin).(*adminService.Service)
+	report, err := admin.ImportUsers(ctx, actorID, file, options)
+	if err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runUserExport writes all users as CSV to a file, or to stdout if path is "-".
+// It returns the process exit code.
+func runUserExport(server *goyave.Server, organization string, path string) int {
+	ctx, err := organizationContext(server, organization)
+	if err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			server.Logger.Error(err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	admin := server.Service(service.Admin).(*adminService.Service)
+	if err := admin.ExportUsers(ctx, w); err != nil {
+		server.Logger.Error(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	ID           uint64          `json:"id"`
+	ActorID      *uint64         `json:"actor_id"`
+	Action       string          `json:"action"` // e.g., "impersonation.start", "impersonation.stop"
+	TargetUserID *uint64         `json:"target_user_id"`
+	Metadata     json.RawMessage `json:"metadata"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
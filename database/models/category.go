@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Category groups courses in the catalog. Categories are nested under a
+// parent, or at the top level when ParentID is nil.
+type Category struct {
+	ID        uint64    `json:"id"`
+	ParentID  *uint64   `json:"parent_id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
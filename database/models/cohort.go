@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Cohort is a group of students following a course together, on its own
+// schedule and with its own staff. Students join a cohort through their
+// enrollment in its course.
+type Cohort struct {
+	ID         uint64           `json:"id"`
+	CourseID   uint64           `json:"course_id"`
+	Name       string           `json:"name"`
+	ExternalID *string          `json:"external_id"`
+	StartsAt   *time.Time       `json:"starts_at"`
+	EndsAt     *time.Time       `json:"ends_at"`
+	Members    []*CohortMember  `json:"members"`
+	Students   int              `json:"students"` // Number of members, when they are not loaded
+	Staff      []*CohortStaff   `json:"staff"`
+	DueDates   []*CohortDueDate `json:"due_dates"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+type CohortMember struct {
+	UserID uint64 `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// CohortStaff is a member of the course staff assigned to a cohort.
+type CohortStaff struct {
+	UserID uint64 `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"` // Role in the course staff
+}
+
+// CohortDueDate replaces the due date of an assessment for a cohort.
+type CohortDueDate struct {
+	AssessmentID uint64    `json:"assessment_id"`
+	DueAt        time.Time `json:"due_at"`
+}
@@ -4,10 +4,111 @@ import (
 	"time"
 )
 
+// Course levels. Courses without a level have an empty one.
+const (
+	LevelBeginner     = "beginner"
+	LevelIntermediate = "intermediate"
+	LevelAdvanced     = "advanced"
+)
+
+// Course statuses. A course is only listed in the catalog once published and
+// its PublishedAt is reached.
+const (
+	CourseDraft     = "draft"
+	CourseInReview  = "in_review"
+	CoursePublished = "published"
+	CourseArchived  = "archived"
+)
+
+// Course staff roles, the only ones allowed in course_staff.
+const (
+	StaffOwner             = "owner"
+	StaffInstructor        = "instructor"
+	StaffTeachingAssistant = "teaching_assistant"
+	StaffGrader            = "grader"
+)
+
+// IsStaffRole reports whether role is a course staff role.
+func IsStaffRole(role string) bool {
+	switch role {
+	case StaffOwner, StaffInstructor, StaffTeachingAssistant, StaffGrader:
+		return true
+	}
+	return false
+}
+
 type Course struct {
-	ID          uint64    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                  uint64     `json:"id"`
+	OrganizationID      uint64     `json:"organization_id"`
+	Title               string     `json:"title"`
+	Description         string     `json:"description"`
+	Status              string     `json:"status"`
+	PublishedAt         *time.Time `json:"published_at"` // May be in the future for scheduled publications
+	ArchivedAt          *time.Time `json:"archived_at"`
+	ReviewedBy          *uint64    `json:"reviewed_by"`
+	ReviewNote          string     `json:"review_note"`           // Reason given when the review was rejected
+	PublishedSnapshotID *uint64    `json:"published_snapshot_id"` // Snapshot students read, nil to read the live content
+	IsTemplate          bool       `json:"is_template"`
+	ClonedFromID        *uint64    `json:"cloned_from_id"`
+	CategoryID          *uint64    `json:"category_id"`
+	Level               string     `json:"level"`
+	Language            string     `json:"language"`          // BCP 47 tag, e.g. "en" or "pt-BR"
+	EstimatedMinutes    int        `json:"estimated_minutes"` // 0 when unknown
+	Tags                []string   `json:"tags" gorm:"-"`     // Stored in course_tags
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Listed reports whether the course is in the catalog at the given time.
+func (c *Course) Listed(at time.Time) bool {
+	return c.Status == CoursePublished && c.PublishedAt != nil && !c.PublishedAt.After(at)
+}
+
+// CourseClone describes the copy of a course made by Clone.
+type CourseClone struct {
+	Title      string        // Title of the copy, the one of the course if empty
+	IsTemplate bool          // Whether the copy is a template
+	Shift      time.Duration // Added to every date of the copied content
+}
+
+// CourseStaff is the membership of a user in the teaching staff of a course.
+type CourseStaff struct {
+	CourseID  uint64    `json:"course_id"`
+	UserID    uint64    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"` // e.g., "owner", "instructor", "teaching_assistant", "grader"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CourseRequirements are the conditions students must meet to enroll in a
+// course, and its number of seats. Empty lists and nil values do not restrict
+// anything.
+type CourseRequirements struct {
+	CourseID      uint64          `json:"course_id"`
+	Prerequisites []*Prerequisite `json:"prerequisites"`
+	Roles         []string        `json:"roles"`         // Platform roles allowed to enroll
+	EmailDomains  []string        `json:"email_domains"` // Email domains allowed to enroll, lowercased
+	OpensAt       *time.Time      `json:"opens_at"`
+	ClosesAt      *time.Time      `json:"closes_at"`
+	Capacity      *int            `json:"capacity"` // Students enrolling once it is reached join the waitlist
+}
+
+// Prerequisite is a course students must have completed, with at least
+// MinGrade if it is not nil.
+type Prerequisite struct {
+	CourseID uint64 `json:"course_id"`
+	Title    string `json:"title"`
+	MinGrade *int   `json:"min_grade"`
+}
+
+// CourseResult is how a student did in a course. A course is completed once
+// every one of its materials is, and its grade is the average of the best
+// grade of the student in each of its assessments, counting the ones without
+// a graded submission as 0. Courses without assessments have no grade.
+type CourseResult struct {
+	CourseID  uint64   `json:"course_id"`
+	Completed bool     `json:"completed"`
+	Grade     *float64 `json:"grade"`
 }
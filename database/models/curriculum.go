@@ -5,10 +5,13 @@ import (
 )
 
 type Curriculum struct {
-	ID           uint64    `json:"id" db:"id"`
-	CourseID     uint64    `json:"course_id" db:"course_id"`
-	SectionName  string    `json:"section_name" db:"section_name"`
-	SectionOrder int       `json:"section_order" db:"section_order"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID             uint64    `json:"id" db:"id"`
+	OrganizationID uint64    `json:"organization_id" db:"organization_id"`
+	CourseID       uint64    `json:"course_id" db:"course_id"`
+	ParentID       *uint64   `json:"parent_id" db:"parent_id"` // Nil for top-level sections
+	SectionName    string    `json:"section_name" db:"section_name"`
+	SectionOrder   int       `json:"section_order" db:"section_order"`
+	IsPreview      bool      `json:"is_preview" db:"is_preview"` // Readable without enrolling
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
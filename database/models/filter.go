@@ -0,0 +1,10 @@
+package models
+
+// FilterCondition compares a field of a resource to a value, e.g.
+// {Field: "userName", Operator: "eq", Value: "jane@example.com"}. Repositories
+// map the fields they support to columns.
+type FilterCondition struct {
+	Field    string
+	Operator string // "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le" or "pr"
+	Value    any
+}
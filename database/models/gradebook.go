@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// GradebookEntry is the grades of a student enrolled in a course.
+type GradebookEntry struct {
+	UserID   uint64             `json:"user_id"`
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	CohortID *uint64            `json:"cohort_id"`
+	Grade    float64            `json:"grade"` // Average of the best grade in each assessment, missing ones counting as 0
+	Grades   []*AssessmentGrade `json:"grades"`
+}
+
+// AssessmentGrade is the best grade of a student in an assessment, and its
+// due date for their cohort.
+type AssessmentGrade struct {
+	AssessmentID uint64     `json:"assessment_id"`
+	Grade        *int       `json:"grade"` // Nil until the student submits
+	DueAt        *time.Time `json:"due_at"`
+	SubmittedAt  *time.Time `json:"submitted_at"` // Last submission
+}
+
+// StudentProgress is how far a student enrolled in a course is through its
+// materials.
+type StudentProgress struct {
+	UserID    uint64  `json:"user_id"`
+	Name      string  `json:"name"`
+	CohortID  *uint64 `json:"cohort_id"`
+	Completed int     `json:"completed"`
+	Total     int     `json:"total"`
+}
+
+// CourseSubmission is a submission to an assessment of a course, with the
+// cohort of the student and the due date that applied to them.
+type CourseSubmission struct {
+	ID           uint64     `json:"id"`
+	AssessmentID uint64     `json:"assessment_id"`
+	UserID       uint64     `json:"user_id"`
+	Name         string     `json:"name"`
+	CohortID     *uint64    `json:"cohort_id"`
+	Grade        *int       `json:"grade"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+	DueAt        *time.Time `json:"due_at"`
+	Late         bool       `json:"late"`
+}
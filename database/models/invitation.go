@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Invitation lets someone create an account with a pre-assigned role, and
+// optionally join the staff of a course. Only the hash of the token sent in
+// the invitation link is stored.
+type Invitation struct {
+	ID         uint64     `json:"id"`
+	Email      string     `json:"email"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	CourseID   *uint64    `json:"course_id"`
+	CourseRole *string    `json:"course_role"`
+	TokenHash  string     `json:"-"`
+	InvitedBy  *uint64    `json:"invited_by"`
+	UserID     *uint64    `json:"user_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Status returns "accepted", "revoked", "expired" or "pending".
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return "accepted"
+	case i.RevokedAt != nil:
+		return "revoked"
+	case !i.ExpiresAt.After(time.Now()):
+		return "expired"
+	}
+	return "pending"
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Organization is a school using the platform. It owns its users, courses
+// and everything attached to them.
+type Organization struct {
+	ID        uint64          `json:"id"`
+	Slug      string          `json:"slug"` // Subdomain and X-Organization header value
+	Name      string          `json:"name"`
+	Settings  json.RawMessage `json:"settings"` // JSON object
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DataExport is a request of a user for a copy of their personal data.
+type DataExport struct {
+	ID          uint64     `json:"id"`
+	UserID      uint64     `json:"user_id"`
+	Status      string     `json:"status"` // "pending", "completed" or "failed"
+	Archive     []byte     `json:"-"`
+	Error       *string    `json:"error"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// UserData is the personal data of a user, as JSON documents keyed by
+// section (e.g. "profile", "submissions").
+type UserData map[string]json.RawMessage
+
+// ErasureRequest is a request of a user to have their personal data erased,
+// which an administrator has to approve.
+type ErasureRequest struct {
+	ID         uint64     `json:"id"`
+	UserID     uint64     `json:"user_id"`
+	Status     string     `json:"status"` // "pending", "approved" or "rejected"
+	Reason     string     `json:"reason"`
+	ReviewedBy *uint64    `json:"reviewed_by"`
+	ReviewNote string     `json:"review_note"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
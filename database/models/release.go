@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReleaseRule holds back a curriculum section or a material until a date,
+// some days after the student enrolled, or until they complete another
+// section. Exactly one of the targets and one of the conditions is set.
+type ReleaseRule struct {
+	ID                  uint64     `json:"id"`
+	CourseID            uint64     `json:"course_id"`
+	CurriculumID        *uint64    `json:"curriculum_id"`
+	MaterialID          *uint64    `json:"material_id"`
+	ReleaseAt           *time.Time `json:"release_at"`
+	DaysAfterEnrollment *int       `json:"days_after_enrollment"`
+	AfterCurriculumID   *uint64    `json:"after_curriculum_id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
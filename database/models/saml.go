@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SAMLProvider is the SAML identity provider configured for an organization.
+type SAMLProvider struct {
+	ID             uint64    `json:"id"`
+	OrganizationID uint64    `json:"organization_id"`
+	EntityID       string    `json:"entity_id"`
+	SSOURL         string    `json:"sso_url"`
+	Certificate    string    `json:"certificate"`
+	DefaultRole    string    `json:"default_role"`
+	EmailAttribute string    `json:"email_attribute"`
+	NameAttribute  string    `json:"name_attribute"`
+	RoleAttribute  string    `json:"role_attribute"`
+	AllowedRoles   []string  `json:"allowed_roles"` // Roles RoleAttribute may grant
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// Types of search results.
+const (
+	SearchCourse     = "course"
+	SearchCurriculum = "curriculum"
+	SearchMaterial   = "material"
+)
+
+// SearchQuery is a full-text search made by a user, who only finds what they
+// are allowed to see.
+type SearchQuery struct {
+	Text      string // Web search syntax: quoted phrases, "or", "-" to exclude
+	Type      string // Only returns results of this type if not empty
+	Limit     int
+	UserID    uint64
+	ManageAny bool // Sees every course, as "course.manage_any" grants
+	CanReview bool // Sees the courses in review, as "course.review" grants
+}
+
+type SearchResult struct {
+	Type         string  `json:"type"`
+	ID           uint64  `json:"id"`
+	CourseID     uint64  `json:"course_id"`
+	CurriculumID *uint64 `json:"curriculum_id"`
+	Title        string  `json:"title"`   // Course title, section name, or name of the section of a material
+	Snippet      string  `json:"snippet"` // Matches are delimited by SnippetStart and SnippetStop
+	Rank         float64 `json:"rank"`
+}
+
+// Delimiters of the matches in snippets, private use characters that are
+// not expected in content.
+const (
+	SnippetStart = "\uE000"
+	SnippetStop  = "\uE001"
+)
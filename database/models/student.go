@@ -18,6 +18,16 @@ type Enrollment struct {
 	EnrolledAt time.Time `json:"enrolled_at"`
 }
 
+// WaitlistEntry is a student waiting for a seat in a full course. Position
+// starts at 1 for the next student to be enrolled.
+type WaitlistEntry struct {
+	ID        uint64    `json:"id"`
+	CourseID  uint64    `json:"course_id"`
+	UserID    uint64    `json:"user_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type ProgressTracking struct {
 	ID                 int       `json:"id"`
 	UserID             int       `json:"user_id"`
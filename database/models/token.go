@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// APIToken is a personal access token or service-account key. Only the hash
+// of the token is stored.
+type APIToken struct {
+	ID          uint64     `json:"id"`
+	UserID      uint64     `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"-"`
+	Scopes      string     `json:"scopes"`
+	CreatedBy   *uint64    `json:"created_by"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -3,11 +3,37 @@ package models
 import "time"
 
 type User struct {
-	ID           uint64    `json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"password_hash"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                    uint64     `json:"id"`
+	OrganizationID        uint64     `json:"organization_id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	PasswordHash          string     `json:"password_hash"`
+	Role                  string     `json:"role"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	ExternalID            *string    `json:"external_id"` // Set for users provisioned through SCIM
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// UserFilter narrows down a user search.
+type UserFilter struct {
+	Search string // Matched against name and email
+	Role   string
+	Status string // "active" or "suspended"
+}
+
+// UserImport is a user created by a bulk import, with the courses to enroll
+// them in and the token letting them choose their password.
+type UserImport struct {
+	User           User
+	CourseIDs      []uint64
+	ResetTokenHash string
+	ResetExpiresAt time.Time
+}
+
+// UserExport is a row of the user export.
+type UserExport struct {
+	User      User
+	CourseIDs []uint64
 }
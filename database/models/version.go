@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Versioned entity types.
+const (
+	VersionCourse     = "course"
+	VersionCurriculum = "curriculum"
+	VersionMaterial   = "material"
+)
+
+// ContentVersion is a change to a course, a curriculum section or a material.
+type ContentVersion struct {
+	ID         uint64          `json:"id"`
+	CourseID   uint64          `json:"course_id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint64          `json:"entity_id"`
+	Version    int             `json:"version"`
+	Action     string          `json:"action"` // "create", "update", "delete" or "rollback"
+	Data       json.RawMessage `json:"data"`   // Versioned fields after the change, null once deleted
+	Diff       json.RawMessage `json:"diff"`   // {"field": {"from": .., "to": ..}}
+	AuthorID   *uint64         `json:"author_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// CourseSnapshot is the content of a course frozen when it was published.
+type CourseSnapshot struct {
+	ID        uint64         `json:"id"`
+	CourseID  uint64         `json:"course_id"`
+	Version   int            `json:"version"`
+	Content   *CourseContent `json:"content"` // Not loaded when listing snapshots
+	CreatedBy *uint64        `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CourseContent is everything students read in a course.
+type CourseContent struct {
+	Course      *Course       `json:"course"`
+	Curriculums []*Curriculum `json:"curriculums"`
+	Materials   []*Material   `json:"materials"`
+}
+
+// VersionData returns the versioned fields of the course.
+func (c *Course) VersionData() map[string]any {
+	return map[string]any{"title": c.Title, "description": c.Description}
+}
+
+// VersionData returns the versioned fields of the curriculum section.
+func (c *Curriculum) VersionData() map[string]any {
+	return map[string]any{"parent_id": c.ParentID, "section_name": c.SectionName, "section_order": c.SectionOrder}
+}
+
+// VersionData returns the versioned fields of the material.
+func (m *Material) VersionData() map[string]any {
+	return map[string]any{"curriculum_id": m.CurriculumID, "material_type": m.MaterialType, "content": m.Content, "order": m.Order}
+}
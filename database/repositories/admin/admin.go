@@ -3,14 +3,20 @@ package admin
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/dapthehuman/learning-management-system/cache"
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/redis/go-redis/v9"
 )
 
+const userColumns = `id, name, email, role, suspended_at, password_reset_token_hash IS NOT NULL, created_at, updated_at`
+
 type Admin struct {
 	DB    *gorm.DB
 	redis redis.UniversalClient
@@ -23,51 +29,111 @@ func NewAdmin(db *gorm.DB, redis redis.UniversalClient) *Admin {
 	}
 }
 
-func (r *Admin) GetAllUsers(ctx context.Context) ([]*model.User, error) {
-	key := "all-users"
-	query := `SELECT id, name, email, role, created_at, updated_at FROM users`
-
-	return cache.Cache(ctx, r.redis, key, func() ([]*model.User, error) {
-		rows, err := r.DB.Raw(query).Rows()
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
-
-		var users []*model.User
-		for rows.Next() {
-			var user model.User
-			err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
-			if err != nil {
-				return nil, err
-			}
-			users = append(users, &user)
-		}
+// SearchUsers returns the page of the users matching the filter.
+func (r *Admin) SearchUsers(ctx context.Context, filter *model.UserFilter, q *listing.Query) (*listing.Page[*model.User], error) {
+	where := []string{`organization_id = ?`}
+	args := []any{tenant.ID(ctx)}
+	if filter.Search != "" {
+		where = append(where, `(name ILIKE ? OR email ILIKE ?)`)
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if filter.Role != "" {
+		where = append(where, `role = ?`)
+		args = append(args, filter.Role)
+	}
+	switch filter.Status {
+	case "active":
+		where = append(where, `suspended_at IS NULL`)
+	case "suspended":
+		where = append(where, `suspended_at IS NOT NULL`)
+	}
 
-		return users, nil
+	base := `SELECT id, name, email, role, suspended_at, password_reset_token_hash IS NOT NULL AS password_reset_required, created_at, updated_at FROM users WHERE ` + strings.Join(where, " AND ")
+	return listing.Fetch(r.DB, userSchema, q, base, args, func(row listing.Scanner) (*model.User, error) {
+		var user model.User
+		err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired, &user.CreatedAt, &user.UpdatedAt)
+		return &user, err
 	})
 }
 
+var userSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":           {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":         {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"email":        {Column: "email", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"role":         {Column: "role", Type: listing.Text, Operators: []string{listing.Eq, listing.Ne, listing.In}},
+		"suspended_at": {Column: "suspended_at", Type: listing.Time, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at":   {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at":   {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
+}
+
+func (r *Admin) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	query := `INSERT INTO users (organization_id, name, email, password_hash, role) VALUES (?, ?, ?, ?, ?) RETURNING id, created_at, updated_at`
+	err := r.DB.Raw(query, tenant.ID(ctx), user.Name, user.Email, user.PasswordHash, user.Role).Row().Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (r *Admin) UpdateUser(ctx context.Context, userID uint64, user *model.User) (*model.User, error) {
-	query := `UPDATE users SET name = ?, email = ?, role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING updated_at`
-	err := r.DB.Raw(query, user.Name, user.Email, user.Role, userID).
+	query := `UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? RETURNING updated_at`
+	err := r.DB.Raw(query, user.Name, user.Email, userID, tenant.ID(ctx)).
 		Scan(&user.UpdatedAt)
 
 	if err.Error != nil {
 		return nil, err.Error
 	}
 
-	return user, nil
+	return user, r.forget(ctx, userID)
+}
+
+func (r *Admin) UpdateRole(ctx context.Context, userID uint64, role string) error {
+	query := `UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, role, userID, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+
+	return r.forget(ctx, userID)
+}
+
+// SetSuspended suspends the user or lifts their suspension.
+func (r *Admin) SetSuspended(ctx context.Context, userID uint64, suspended bool) error {
+	// Erased accounts stay locked
+	query := `UPDATE users SET suspended_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? AND erased_at IS NULL`
+	if suspended {
+		query = `UPDATE users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	}
+	if err := r.DB.Exec(query, userID, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+
+	return r.forget(ctx, userID)
+}
+
+// RequirePasswordReset locks the user out until they choose a new password
+// with the reset token of the given hash.
+func (r *Admin) RequirePasswordReset(ctx context.Context, userID uint64, tokenHash string, expiresAt time.Time) error {
+	query := `UPDATE users SET password_reset_token_hash = ?, password_reset_expires_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, tokenHash, expiresAt, userID, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+
+	return r.forget(ctx, userID)
 }
 
 func (r *Admin) GetUserByID(ctx context.Context, id uint64) (*model.User, error) {
 	key := fmt.Sprintf("user:%d", id)
-	query := `SELECT id, name, email, role, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND organization_id = ?`
 	return cache.Cache(ctx, r.redis, key, func() (*model.User, error) {
-		row := r.DB.Raw(query, id).Row()
+		row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
 
 		var user model.User
-		err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -77,7 +143,19 @@ func (r *Admin) GetUserByID(ctx context.Context, id uint64) (*model.User, error)
 }
 
 func (r *Admin) DeleteUser(ctx context.Context, id uint64) error {
-	query := `DELETE FROM users WHERE id = ?`
-	err := r.DB.Exec(query, id).Error
-	return err
+	query := `DELETE FROM users WHERE id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, id, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+
+	return r.forget(ctx, id)
+}
+
+// forget drops the cached copy of the user.
+func (r *Admin) forget(ctx context.Context, userID uint64) error {
+	return cache.Forget(ctx, r.redis, fmt.Sprintf("user:%d", userID))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
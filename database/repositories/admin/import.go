@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// errDryRun rolls back the import transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// ExistingEmails returns which of the given emails already belong to a user,
+// compared case-insensitively.
+func (r *Admin) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(emails) == 0 {
+		return existing, nil
+	}
+
+	lower := make([]string, 0, len(emails))
+	for _, email := range emails {
+		lower = append(lower, strings.ToLower(email))
+	}
+
+	rows, err := r.DB.Raw(`SELECT LOWER(email) FROM users WHERE LOWER(email) IN ? AND organization_id = ?`, lower, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		existing[email] = true
+	}
+	return existing, nil
+}
+
+// ExistingCourses returns which of the given course IDs exist.
+func (r *Admin) ExistingCourses(ctx context.Context, ids []uint64) (map[uint64]bool, error) {
+	existing := make(map[uint64]bool)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.DB.Raw(`SELECT id FROM courses WHERE id IN ? AND organization_id = ?`, ids, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// ImportUsers creates the users and their enrollments in a single
+// transaction. Nothing is persisted when dryRun is set, but every statement
+// still runs so database constraints are checked.
+func (r *Admin) ImportUsers(ctx context.Context, users []*model.UserImport, dryRun bool) error {
+	organizationID := tenant.ID(ctx)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, u := range users {
+			query := `INSERT INTO users (organization_id, name, email, password_hash, role, password_reset_token_hash, password_reset_expires_at)
+			          VALUES (?, ?, ?, '', ?, ?, ?) RETURNING id, created_at, updated_at`
+			err := tx.Raw(query, organizationID, u.User.Name, u.User.Email, u.User.Role, u.ResetTokenHash, u.ResetExpiresAt).
+				Row().Scan(&u.User.ID, &u.User.CreatedAt, &u.User.UpdatedAt)
+			if err != nil {
+				return err
+			}
+
+			for _, courseID := range u.CourseIDs {
+				query := `INSERT INTO enrollments (organization_id, user_id, course_id) VALUES (?, ?, ?)`
+				if err := tx.Exec(query, organizationID, u.User.ID, courseID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
+
+// ExportUsers calls f for every user, ordered by ID, along with the IDs of
+// the courses they are enrolled in.
+func (r *Admin) ExportUsers(ctx context.Context, f func(*model.UserExport) error) error {
+	query := `SELECT u.id, u.name, u.email, u.role, u.suspended_at, u.created_at, u.updated_at,
+	          COALESCE(STRING_AGG(e.course_id::text, ';' ORDER BY e.course_id), '')
+	          FROM users u LEFT JOIN enrollments e ON e.user_id = u.id
+	          WHERE u.organization_id = ?
+	          GROUP BY u.id ORDER BY u.id`
+	rows, err := r.DB.Raw(query, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var export model.UserExport
+		var courses string
+		err := rows.Scan(&export.User.ID, &export.User.Name, &export.User.Email, &export.User.Role,
+			&export.User.SuspendedAt, &export.User.CreatedAt, &export.User.UpdatedAt, &courses)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range strings.Split(courses, ";") {
+			if courseID, err := strconv.ParseUint(id, 10, 64); err == nil {
+				export.CourseIDs = append(export.CourseIDs, courseID)
+			}
+		}
+
+		if err := f(&export); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
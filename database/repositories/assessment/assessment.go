@@ -8,6 +8,8 @@ import (
 
 	"github.com/dapthehuman/learning-management-system/database/models"
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
 type Assessment struct {
@@ -21,9 +23,9 @@ func NewAssessment(db *gorm.DB) *Assessment {
 }
 
 func (r *Assessment) Create(ctx context.Context, assessment *model.Assessment) (*model.Assessment, error) {
-	query := `INSERT INTO assessments (course_id, type, question, created_at) 
-	VALUES ($1, $2, $3, $4) RETURNING id, created_at`
-	rows, err := r.DB.Raw(query, assessment.CourseID, assessment.Type, assessment.Question, time.Now()).Rows()
+	query := `INSERT INTO assessments (organization_id, course_id, type, question, due_at, created_at) 
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	rows, err := r.DB.Raw(query, tenant.ID(ctx), assessment.CourseID, assessment.Type, assessment.Question, assessment.DueAt, time.Now()).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -43,33 +45,37 @@ func (r *Assessment) Create(ctx context.Context, assessment *model.Assessment) (
 	return assessment, nil
 }
 
-func (r *Assessment) GetAllByCourseID(ctx context.Context, courseID uint64) ([]*model.Assessment, error) {
-	query := `SELECT id, course_id, type, question, created_at FROM assessments WHERE course_id = $1`
-	rows, err := r.DB.Raw(query, courseID).Rows()
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	assessments := make([]*model.Assessment, 0)
-	for rows.Next() {
+// ListByCourse returns a page of the assessments of the course, the earliest
+// created first by default. Their due dates are those of the cohort of the
+// user, if they are enrolled in one.
+func (r *Assessment) ListByCourse(ctx context.Context, courseID uint64, userID uint64, q *listing.Query) (*listing.Page[*model.Assessment], error) {
+	base := `SELECT a.id, a.course_id, a.type, a.question, COALESCE(d.due_at, a.due_at) AS due_at, a.created_at FROM assessments a
+		LEFT JOIN enrollments e ON e.course_id = a.course_id AND e.user_id = ?
+		LEFT JOIN cohort_due_dates d ON d.cohort_id = e.cohort_id AND d.assessment_id = a.id
+		WHERE a.course_id = ? AND a.organization_id = ?`
+	return listing.Fetch(r.DB, assessmentSchema, q, base, []any{userID, courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.Assessment, error) {
 		var assessment model.Assessment
-		err := rows.Scan(&assessment.ID, &assessment.CourseID, &assessment.Type, &assessment.Question, &assessment.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		assessments = append(assessments, &assessment)
-	}
+		err := row.Scan(&assessment.ID, &assessment.CourseID, &assessment.Type, &assessment.Question, &assessment.DueAt, &assessment.CreatedAt)
+		return &assessment, err
+	})
+}
 
-	return assessments, nil
+var assessmentSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"type":       {Column: "type", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"due_at":     {Column: "due_at", Type: listing.Time, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
 }
 
 func (r *Assessment) GetByID(ctx context.Context, assessmentID uint64) (*model.Assessment, error) {
-	query := `SELECT id, course_id, type, question, created_at FROM assessments WHERE id = $1`
-	row := r.DB.Raw(query, assessmentID).Row()
+	query := `SELECT id, course_id, type, question, due_at, created_at FROM assessments WHERE id = $1 AND organization_id = $2`
+	row := r.DB.Raw(query, assessmentID, tenant.ID(ctx)).Row()
 
 	var assessment model.Assessment
-	err := row.Scan(&assessment.ID, &assessment.CourseID, &assessment.Type, &assessment.Question, &assessment.CreatedAt)
+	err := row.Scan(&assessment.ID, &assessment.CourseID, &assessment.Type, &assessment.Question, &assessment.DueAt, &assessment.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -80,9 +86,9 @@ func (r *Assessment) GetByID(ctx context.Context, assessmentID uint64) (*model.A
 func (r *Assessment) SubmitAnswer(ctx context.Context, submission *models.Submission) (*model.Submission, error) {
 	grade := autoGrade(submission.AssessmentID, submission.Answer)
 
-	query := `INSERT INTO submissions (user_id, assessment_id, answer, grade, submitted_at)
-	          VALUES ($1, $2, $3, $4, $5) RETURNING id, submitted_at`
-	rows := r.DB.Raw(query, submission.UserID, submission.AssessmentID, submission.Answer, grade, time.Now()).Row()
+	query := `INSERT INTO submissions (organization_id, user_id, assessment_id, answer, grade, submitted_at)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, submitted_at`
+	rows := r.DB.Raw(query, tenant.ID(ctx), submission.UserID, submission.AssessmentID, submission.Answer, grade, time.Now()).Row()
 	err := rows.Scan(&submission.ID, &submission.SubmittedAt)
 	if err != nil {
 		return nil, err
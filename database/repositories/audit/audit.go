@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+type Audit struct {
+	DB *gorm.DB
+}
+
+func NewAudit(db *gorm.DB) *Audit {
+	return &Audit{
+		DB: db,
+	}
+}
+
+func (r *Audit) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	metadata := entry.Metadata
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+
+	query := `INSERT INTO audit_logs (organization_id, actor_id, action, target_user_id, metadata) VALUES (?, ?, ?, ?, ?) RETURNING id, created_at`
+	err := r.DB.Raw(query, tenant.ID(ctx), entry.ActorID, entry.Action, entry.TargetUserID, string(metadata)).Row().Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// List returns the page of the entries selected by q, the most recent first
+// unless sorted otherwise.
+func (r *Audit) List(ctx context.Context, q *listing.Query) (*listing.Page[*model.AuditLog], error) {
+	base := `SELECT id, actor_id, action, target_user_id, metadata::text AS metadata, created_at FROM audit_logs WHERE organization_id = ?`
+	return listing.Fetch(r.DB, auditSchema, q, base, []any{tenant.ID(ctx)}, func(row listing.Scanner) (*model.AuditLog, error) {
+		var entry model.AuditLog
+		var metadata string
+		err := row.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetUserID, &metadata, &entry.CreatedAt)
+		entry.Metadata = []byte(metadata)
+		return &entry, err
+	})
+}
+
+var auditSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":             {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.Lt, listing.Gt}},
+		"actor_id":       {Column: "actor_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"action":         {Column: "action", Type: listing.Text, Operators: []string{listing.Eq, listing.In, listing.Contains}},
+		"target_user_id": {Column: "target_user_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"created_at":     {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id", Desc: true}},
+}
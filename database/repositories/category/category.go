@@ -0,0 +1,122 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+)
+
+const columns = `id, parent_id, name, slug, created_at, updated_at`
+
+type Category struct {
+	DB    *gorm.DB
+	Redis redis.UniversalClient
+}
+
+func NewCategory(db *gorm.DB, redis redis.UniversalClient) *Category {
+	return &Category{
+		DB:    db,
+		Redis: redis,
+	}
+}
+
+// GetAll returns every category of the organization, parents first. The
+// whole tree is cached as it is read by every catalog request.
+func (r *Category) GetAll(ctx context.Context) ([]*model.Category, error) {
+	query := `WITH RECURSIVE tree AS (
+			SELECT ` + columns + `, 0 AS depth FROM categories WHERE parent_id IS NULL AND organization_id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, c.name, c.slug, c.created_at, c.updated_at, tree.depth + 1
+			FROM categories c JOIN tree ON c.parent_id = tree.id
+		)
+		SELECT ` + columns + ` FROM tree ORDER BY depth, name`
+
+	return cache.Cache(ctx, r.Redis, "categories", func() ([]*model.Category, error) {
+		rows, err := r.DB.Raw(query, tenant.ID(ctx)).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		categories := make([]*model.Category, 0)
+		for rows.Next() {
+			category, err := scan(rows)
+			if err != nil {
+				return nil, err
+			}
+			categories = append(categories, category)
+		}
+		return categories, rows.Err()
+	})
+}
+
+// GetByID returns the category, or nil if it does not exist.
+func (r *Category) GetByID(ctx context.Context, id uint64) (*model.Category, error) {
+	query := `SELECT ` + columns + ` FROM categories WHERE id = ? AND organization_id = ?`
+	category, err := scan(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return category, err
+}
+
+func (r *Category) Create(ctx context.Context, category *model.Category) (*model.Category, error) {
+	query := `INSERT INTO categories (organization_id, parent_id, name, slug) VALUES (?, ?, ?, ?) RETURNING ` + columns
+	created, err := scan(r.DB.Raw(query, tenant.ID(ctx), category.ParentID, category.Name, category.Slug).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	return created, r.forget(ctx)
+}
+
+// Update renames or moves the category.
+func (r *Category) Update(ctx context.Context, category *model.Category) (*model.Category, error) {
+	query := `UPDATE categories SET parent_id = ?, name = ?, slug = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND organization_id = ? RETURNING ` + columns
+	updated, err := scan(r.DB.Raw(query, category.ParentID, category.Name, category.Slug, category.ID, tenant.ID(ctx)).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, r.forget(ctx)
+}
+
+// Delete deletes the category and its subcategories. Their courses are left
+// without a category.
+func (r *Category) Delete(ctx context.Context, id uint64) error {
+	query := `DELETE FROM categories WHERE id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, id, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+	return r.forget(ctx)
+}
+
+// forget drops the cached categories and the cached course lists, which hold
+// the category of each course.
+func (r *Category) forget(ctx context.Context) error {
+	if err := cache.Forget(ctx, r.Redis, "categories", "courses:published"); err != nil {
+		return err
+	}
+	return cache.Invalidate(ctx, r.Redis, "courses")
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (*model.Category, error) {
+	var category model.Category
+	err := row.Scan(&category.ID, &category.ParentID, &category.Name, &category.Slug, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
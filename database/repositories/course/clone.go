@@ -0,0 +1,260 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// cloneVersions starts the history of a cloned course with the first version
+// of the course, its sections and its materials.
+const cloneVersions = `INSERT INTO content_versions (organization_id, course_id, entity_type, entity_id, version, action, data, author_id)
+	SELECT organization_id, id, 'course', id, 1, 'create', jsonb_build_object('title', title, 'description', description), ?
+	FROM courses WHERE id = ?
+	UNION ALL
+	SELECT organization_id, course_id, 'curriculum', id, 1, 'create',
+		jsonb_build_object('parent_id', parent_id, 'section_name', section_name, 'section_order', section_order), ?
+	FROM curriculums WHERE course_id = ?
+	UNION ALL
+	SELECT m.organization_id, c.course_id, 'material', m.id, 1, 'create',
+		jsonb_build_object('curriculum_id', m.curriculum_id, 'material_type', m.material_type, 'content', m.content, 'order', m."order"), ?
+	FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE c.course_id = ?`
+
+// Clone deep-copies the course, its curriculum sections, their materials and
+// release rules, its assessments, its enrollment requirements and its
+// cohorts, without their students and staff, into a new draft owned by
+// ownerID, in a single transaction.
+// It returns nil if the course does not exist.
+func (r *Course) Clone(ctx context.Context, id uint64, clone *model.CourseClone, ownerID uint64) (*model.Course, error) {
+	var course *model.Course
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `INSERT INTO courses (organization_id, title, description, is_template, cloned_from_id, category_id, level, language, estimated_minutes)
+			SELECT organization_id, COALESCE(NULLIF(?, ''), title), description, ?, id, category_id, level, language, estimated_minutes
+			FROM courses WHERE id = ? AND organization_id = ?
+			RETURNING id`
+		var cloneID uint64
+		err := tx.Raw(query, clone.Title, clone.IsTemplate, id, tenant.ID(ctx)).Row().Scan(&cloneID)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_staff (organization_id, course_id, user_id, role) VALUES (?, ?, ?, 'owner')`
+		if err := tx.Exec(query, tenant.ID(ctx), cloneID, ownerID).Error; err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_tags (organization_id, course_id, tag) SELECT organization_id, ?, tag FROM course_tags WHERE course_id = ?`
+		if err := tx.Exec(query, cloneID, id).Error; err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_prerequisites (organization_id, course_id, prerequisite_id, min_grade)
+			SELECT organization_id, ?, prerequisite_id, min_grade FROM course_prerequisites WHERE course_id = ?`
+		if err := tx.Exec(query, cloneID, id).Error; err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_requirements (organization_id, course_id, roles, email_domains, opens_at, closes_at, capacity)
+			SELECT organization_id, ?, roles, email_domains, opens_at + make_interval(secs => ?), closes_at + make_interval(secs => ?), capacity
+			FROM course_requirements WHERE course_id = ?`
+		if err := tx.Exec(query, cloneID, clone.Shift.Seconds(), clone.Shift.Seconds(), id).Error; err != nil {
+			return err
+		}
+
+		sections, err := cloneSections(tx, id, cloneID)
+		if err != nil {
+			return err
+		}
+
+		materials, err := cloneMaterials(tx, id, sections)
+		if err != nil {
+			return err
+		}
+
+		if err := cloneReleaseRules(tx, id, cloneID, sections, materials, clone.Shift.Seconds()); err != nil {
+			return err
+		}
+
+		assessments, err := cloneAssessments(tx, id, cloneID, clone.Shift.Seconds())
+		if err != nil {
+			return err
+		}
+
+		if err := cloneCohorts(tx, id, cloneID, assessments, clone.Shift.Seconds()); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(cloneVersions, ownerID, cloneID, ownerID, cloneID, ownerID, cloneID).Error; err != nil {
+			return err
+		}
+
+		query = `SELECT ` + columns + ` FROM courses WHERE id = ?`
+		course, err = scan(tx.Raw(query, cloneID).Row())
+		return err
+	})
+	if err != nil || course == nil {
+		return nil, err
+	}
+
+	return course, r.forget(ctx, course.ID)
+}
+
+// cloneSections copies the curriculum sections of the course from into the
+// course to, nested the same way, and returns the ID of the copy of each
+// section.
+func cloneSections(tx *gorm.DB, from uint64, to uint64) (map[uint64]uint64, error) {
+	query := `INSERT INTO curriculums (organization_id, course_id, section_name, section_order, is_preview)
+		SELECT organization_id, ?, section_name, section_order, is_preview FROM curriculums WHERE id = ? RETURNING id`
+	sections, err := cloneRows(tx, `SELECT id FROM curriculums WHERE course_id = ? ORDER BY id`, from, query, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parents are set once all the copies exist, mapped in JSON
+	mapping, err := json.Marshal(sections)
+	if err != nil {
+		return nil, err
+	}
+	query = `UPDATE curriculums c SET parent_id = (?::jsonb->>source.parent_id::text)::int
+		FROM curriculums source JOIN jsonb_each_text(?::jsonb) m ON m.key::int = source.id
+		WHERE c.id = m.value::int AND source.parent_id IS NOT NULL`
+	if err := tx.Exec(query, string(mapping), string(mapping)).Error; err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// cloneMaterials copies the materials of the course from into the copies of
+// their sections, and returns the ID of the copy of each material.
+func cloneMaterials(tx *gorm.DB, from uint64, sections map[uint64]uint64) (map[uint64]uint64, error) {
+	mapping, err := json.Marshal(sections)
+	if err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO materials (organization_id, curriculum_id, material_type, content, "order")
+		SELECT m.organization_id, s.value::int, m.material_type, m.content, m."order"
+		FROM materials m JOIN jsonb_each_text(?::jsonb) s ON s.key::int = m.curriculum_id WHERE m.id = ?
+		RETURNING id`
+	selectQuery := `SELECT m.id FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE c.course_id = ? ORDER BY m.id`
+	return cloneRows(tx, selectQuery, from, query, string(mapping))
+}
+
+// cloneReleaseRules copies the release rules of the course from into the
+// course to, attached to the copies of their sections and materials, with
+// their release dates shifted.
+func cloneReleaseRules(tx *gorm.DB, from uint64, to uint64, sections map[uint64]uint64, materials map[uint64]uint64, shift float64) error {
+	sectionMapping, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	materialMapping, err := json.Marshal(materials)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO release_rules (organization_id, course_id, curriculum_id, material_id, release_at, days_after_enrollment, after_curriculum_id)
+		SELECT r.organization_id, ?, (s.mapping->>r.curriculum_id::text)::int, (m.mapping->>r.material_id::text)::int,
+			r.release_at + make_interval(secs => ?), r.days_after_enrollment, (s.mapping->>r.after_curriculum_id::text)::int
+		FROM release_rules r CROSS JOIN (SELECT ?::jsonb AS mapping) s CROSS JOIN (SELECT ?::jsonb AS mapping) m
+		WHERE r.course_id = ?`
+	return tx.Exec(query, to, shift, string(sectionMapping), string(materialMapping), from).Error
+}
+
+// cloneAssessments copies the assessments of the course from into the course
+// to, shifting their due dates, and returns the ID of the copy of each
+// assessment.
+func cloneAssessments(tx *gorm.DB, from uint64, to uint64, shift float64) (map[uint64]uint64, error) {
+	query := `INSERT INTO assessments (organization_id, course_id, type, question, due_at)
+		SELECT organization_id, ?, type, question, due_at + make_interval(secs => ?) FROM assessments WHERE id = ? RETURNING id`
+	return cloneRows(tx, `SELECT id FROM assessments WHERE course_id = ? ORDER BY id`, from, query, to, shift)
+}
+
+// cloneCohorts copies the cohorts of the course from into the course to, with
+// their schedule and due dates shifted. Directory-managed cohorts become
+// regular ones.
+func cloneCohorts(tx *gorm.DB, from uint64, to uint64, assessments map[uint64]uint64, shift float64) error {
+	query := `INSERT INTO cohorts (organization_id, course_id, name, starts_at, ends_at)
+		SELECT organization_id, ?, name, starts_at + make_interval(secs => ?), ends_at + make_interval(secs => ?) FROM cohorts WHERE id = ?
+		RETURNING id`
+	cohorts, err := cloneRows(tx, `SELECT id FROM cohorts WHERE course_id = ? ORDER BY id`, from, query, to, shift, shift)
+	if err != nil {
+		return err
+	}
+
+	// The due dates refer to the copies of the assessments, mapped in JSON
+	mapping, err := json.Marshal(assessments)
+	if err != nil {
+		return err
+	}
+	query = `INSERT INTO cohort_due_dates (organization_id, cohort_id, course_id, assessment_id, due_at)
+		SELECT d.organization_id, ?, ?, m.value::int, d.due_at + make_interval(secs => ?)
+		FROM cohort_due_dates d JOIN jsonb_each_text(?::jsonb) m ON m.key::int = d.assessment_id
+		WHERE d.cohort_id = ?`
+	for source, cohort := range cohorts {
+		if err := tx.Exec(query, cohort, to, shift, string(mapping), source).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneRows runs the insert query for each row returned by the select query,
+// and returns the ID of the copy of each row. The insert query takes the
+// arguments followed by the ID of the row to copy.
+func cloneRows(tx *gorm.DB, selectQuery string, from uint64, insertQuery string, args ...any) (map[uint64]uint64, error) {
+	rows, err := tx.Raw(selectQuery, from).Rows()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	copies := make(map[uint64]uint64, len(ids))
+	for _, id := range ids {
+		var created uint64
+		if err := tx.Raw(insertQuery, append(args, id)...).Row().Scan(&created); err != nil {
+			return nil, err
+		}
+		copies[id] = created
+	}
+	return copies, nil
+}
+
+// SetTemplate marks the course as a template, or not.
+func (r *Course) SetTemplate(ctx context.Context, id uint64, isTemplate bool) (*model.Course, error) {
+	query := `UPDATE courses SET is_template = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? RETURNING ` + columns
+	course, err := scan(r.DB.Raw(query, isTemplate, id, tenant.ID(ctx)).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	return course, r.forget(ctx, course.ID)
+}
+
+// ListTemplates returns a page of the templates of the organization, sorted
+// by title by default.
+func (r *Course) ListTemplates(ctx context.Context, q *listing.Query) (*listing.Page[*model.Course], error) {
+	base := `SELECT ` + columns + ` FROM courses WHERE organization_id = ? AND is_template`
+	return r.page(ctx, titleSchema, "templates:"+q.Key(), q, base, tenant.ID(ctx))
+}
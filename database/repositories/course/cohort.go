@@ -0,0 +1,285 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// cohortColumns of a cohort, with its number of students.
+const cohortColumns = `id, course_id, name, external_id, starts_at, ends_at,
+	(SELECT COUNT(*) FROM enrollments e WHERE e.cohort_id = cohorts.id) AS students, created_at, updated_at`
+
+var (
+	errCohortNameTaken   = stderrors.New("Another cohort of this course already has this name")
+	errNotStaff          = stderrors.New("Cohort staff must be part of the course staff")
+	errUnknownAssessment = stderrors.New("Due dates can only be set for the assessments of the course")
+	errNotEnrolled       = stderrors.New("Only students enrolled in the course can join its cohorts")
+)
+
+// ListCohorts returns a page of the cohorts of the course with their staff
+// and due dates, sorted by start date by default.
+func (r *Course) ListCohorts(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.Cohort], error) {
+	base := `SELECT ` + cohortColumns + `, COALESCE(starts_at, created_at) AS starts FROM cohorts WHERE course_id = ? AND organization_id = ?`
+	page, err := listing.Fetch(r.DB, cohortSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.Cohort, error) {
+		return scanCohort(row, new(time.Time))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, loadCohortDetails(r.DB, page.Data)
+}
+
+var cohortSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":        {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":      {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"starts_at": {Column: "starts", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"students":  {Column: "students", Type: listing.Int, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "starts_at"}},
+}
+
+// GetCohort returns the cohort of the course with its staff and due dates, or
+// nil if it does not exist.
+func (r *Course) GetCohort(ctx context.Context, courseID uint64, cohortID uint64) (*model.Cohort, error) {
+	return getCohort(r.DB, courseID, cohortID, tenant.ID(ctx))
+}
+
+// CreateCohort inserts the cohort with its staff and due dates.
+func (r *Course) CreateCohort(ctx context.Context, cohort *model.Cohort) (*model.Cohort, error) {
+	var created *model.Cohort
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCohort(tx, cohort, 0); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO cohorts (organization_id, course_id, name, starts_at, ends_at) VALUES (?, ?, ?, ?, ?) RETURNING id`
+		if err := tx.Raw(query, tenant.ID(ctx), cohort.CourseID, cohort.Name, cohort.StartsAt, cohort.EndsAt).Row().Scan(&cohort.ID); err != nil {
+			return err
+		}
+		if err := saveCohortDetails(ctx, tx, cohort); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = getCohort(tx, cohort.CourseID, cohort.ID, tenant.ID(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateCohort saves the name and schedule of the cohort and replaces its
+// staff and due dates. It returns nil if the cohort does not exist.
+func (r *Course) UpdateCohort(ctx context.Context, cohort *model.Cohort) (*model.Cohort, error) {
+	var updated *model.Cohort
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCohort(tx, cohort, cohort.ID); err != nil {
+			return err
+		}
+
+		query := `UPDATE cohorts SET name = ?, starts_at = ?, ends_at = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND course_id = ? AND organization_id = ?`
+		result := tx.Exec(query, cohort.Name, cohort.StartsAt, cohort.EndsAt, cohort.ID, cohort.CourseID, tenant.ID(ctx))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		for _, table := range []string{"cohort_staff", "cohort_due_dates"} {
+			if err := tx.Exec(`DELETE FROM `+table+` WHERE cohort_id = ?`, cohort.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := saveCohortDetails(ctx, tx, cohort); err != nil {
+			return err
+		}
+
+		var err error
+		updated, err = getCohort(tx, cohort.CourseID, cohort.ID, tenant.ID(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteCohort deletes the cohort. Its students stay enrolled in the course,
+// outside of any cohort. It returns false if the cohort does not exist.
+func (r *Course) DeleteCohort(ctx context.Context, courseID uint64, cohortID uint64) (bool, error) {
+	result := r.DB.Exec(`DELETE FROM cohorts WHERE id = ? AND course_id = ? AND organization_id = ?`, cohortID, courseID, tenant.ID(ctx))
+	return result.RowsAffected > 0, result.Error
+}
+
+// AddCohortMembers moves the enrollments of the students in the course to the
+// cohort. It returns false if the cohort does not exist.
+func (r *Course) AddCohortMembers(ctx context.Context, courseID uint64, cohortID uint64, userIDs []uint64) (bool, error) {
+	var found bool
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `UPDATE enrollments e SET cohort_id = c.id FROM cohorts c
+			WHERE c.id = ? AND c.course_id = ? AND c.organization_id = ? AND e.course_id = c.course_id AND e.user_id IN ?`
+		result := tx.Exec(query, cohortID, courseID, tenant.ID(ctx), userIDs)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == int64(len(userIDs)) {
+			found = true
+			return nil
+		}
+
+		err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM cohorts WHERE id = ? AND course_id = ? AND organization_id = ?)`,
+			cohortID, courseID, tenant.ID(ctx)).Row().Scan(&found)
+		if err != nil || !found {
+			return err
+		}
+		return errNotEnrolled
+	})
+	return found, err
+}
+
+// RemoveCohortMember takes the student out of the cohort. They stay enrolled
+// in the course. It returns false if they were not part of the cohort.
+func (r *Course) RemoveCohortMember(ctx context.Context, courseID uint64, cohortID uint64, userID uint64) (bool, error) {
+	query := `UPDATE enrollments SET cohort_id = NULL WHERE course_id = ? AND cohort_id = ? AND user_id = ? AND organization_id = ?`
+	result := r.DB.Exec(query, courseID, cohortID, userID, tenant.ID(ctx))
+	return result.RowsAffected > 0, result.Error
+}
+
+// checkCohort returns an error if the name of the cohort is taken by another
+// cohort of the course, or if its staff or due dates do not belong to the
+// course.
+func checkCohort(tx *gorm.DB, cohort *model.Cohort, id uint64) error {
+	staffIDs := make([]uint64, 0, len(cohort.Staff))
+	for _, member := range cohort.Staff {
+		staffIDs = append(staffIDs, member.UserID)
+	}
+	assessmentIDs := make([]uint64, 0, len(cohort.DueDates))
+	for _, dueDate := range cohort.DueDates {
+		assessmentIDs = append(assessmentIDs, dueDate.AssessmentID)
+	}
+
+	query := `SELECT
+			EXISTS (SELECT 1 FROM cohorts WHERE course_id = ? AND name = ? AND id <> ?),
+			(SELECT COUNT(*) FROM course_staff WHERE course_id = ? AND user_id IN ?),
+			(SELECT COUNT(*) FROM assessments WHERE course_id = ? AND id IN ?)`
+	var taken bool
+	var staff, assessments int
+	err := tx.Raw(query, cohort.CourseID, cohort.Name, id, cohort.CourseID, staffIDs, cohort.CourseID, assessmentIDs).Row().Scan(&taken, &staff, &assessments)
+	switch {
+	case err != nil:
+		return err
+	case taken:
+		return errCohortNameTaken
+	case staff != len(staffIDs):
+		return errNotStaff
+	case assessments != len(assessmentIDs):
+		return errUnknownAssessment
+	}
+	return nil
+}
+
+func saveCohortDetails(ctx context.Context, tx *gorm.DB, cohort *model.Cohort) error {
+	query := `INSERT INTO cohort_staff (organization_id, cohort_id, course_id, user_id) VALUES (?, ?, ?, ?)`
+	for _, member := range cohort.Staff {
+		if err := tx.Exec(query, tenant.ID(ctx), cohort.ID, cohort.CourseID, member.UserID).Error; err != nil {
+			return err
+		}
+	}
+
+	query = `INSERT INTO cohort_due_dates (organization_id, cohort_id, course_id, assessment_id, due_at) VALUES (?, ?, ?, ?, ?)`
+	for _, dueDate := range cohort.DueDates {
+		if err := tx.Exec(query, tenant.ID(ctx), cohort.ID, cohort.CourseID, dueDate.AssessmentID, dueDate.DueAt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getCohort(db *gorm.DB, courseID uint64, cohortID uint64, organizationID uint64) (*model.Cohort, error) {
+	query := `SELECT ` + cohortColumns + ` FROM cohorts WHERE id = ? AND course_id = ? AND organization_id = ?`
+	cohort, err := scanCohort(db.Raw(query, cohortID, courseID, organizationID).Row())
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cohort, loadCohortDetails(db, []*model.Cohort{cohort})
+}
+
+func scanCohort(row listing.Scanner, extra ...any) (*model.Cohort, error) {
+	var cohort model.Cohort
+	dest := []any{&cohort.ID, &cohort.CourseID, &cohort.Name, &cohort.ExternalID, &cohort.StartsAt, &cohort.EndsAt,
+		&cohort.Students, &cohort.CreatedAt, &cohort.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+// loadCohortDetails loads the staff and due dates of the cohorts.
+func loadCohortDetails(db *gorm.DB, cohorts []*model.Cohort) error {
+	if len(cohorts) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint64]*model.Cohort, len(cohorts))
+	ids := make([]uint64, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		cohort.Staff = make([]*model.CohortStaff, 0)
+		cohort.DueDates = make([]*model.CohortDueDate, 0)
+		byID[cohort.ID] = cohort
+		ids = append(ids, cohort.ID)
+	}
+
+	query := `SELECT s.cohort_id, u.id, u.name, u.email, cs.role FROM cohort_staff s
+		JOIN users u ON u.id = s.user_id JOIN course_staff cs ON cs.course_id = s.course_id AND cs.user_id = s.user_id
+		WHERE s.cohort_id IN ? ORDER BY u.name, u.id`
+	rows, err := db.Raw(query, ids).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cohortID uint64
+		var member model.CohortStaff
+		if err := rows.Scan(&cohortID, &member.UserID, &member.Name, &member.Email, &member.Role); err != nil {
+			return err
+		}
+		byID[cohortID].Staff = append(byID[cohortID].Staff, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dueDates, err := db.Raw(`SELECT cohort_id, assessment_id, due_at FROM cohort_due_dates WHERE cohort_id IN ? ORDER BY due_at, assessment_id`, ids).Rows()
+	if err != nil {
+		return err
+	}
+	defer dueDates.Close()
+
+	for dueDates.Next() {
+		var cohortID uint64
+		var dueDate model.CohortDueDate
+		if err := dueDates.Scan(&cohortID, &dueDate.AssessmentID, &dueDate.DueAt); err != nil {
+			return err
+		}
+		byID[cohortID].DueDates = append(byID[cohortID].DueDates, &dueDate)
+	}
+	return dueDates.Err()
+}
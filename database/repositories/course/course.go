@@ -2,6 +2,9 @@ package course
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"time"
 
@@ -9,9 +12,17 @@ import (
 
 	"github.com/dapthehuman/learning-management-system/cache"
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/redis/go-redis/v9"
 )
 
+// columns of a course, its tags aggregated as a JSON array.
+const columns = `id, title, description, status, published_at, archived_at, reviewed_by, review_note, published_snapshot_id, is_template, cloned_from_id,
+	category_id, level, language, estimated_minutes,
+	COALESCE((SELECT json_agg(tag ORDER BY tag) FROM course_tags WHERE course_tags.course_id = courses.id), '[]'),
+	created_at, updated_at`
+
 type Course struct {
 	DB    *gorm.DB
 	Redis redis.UniversalClient
@@ -26,127 +37,310 @@ func NewCourse(db *gorm.DB, redis redis.UniversalClient) *Course {
 
 func (r *Course) First(ctx context.Context, id uint64) (*model.Course, error) {
 	key := fmt.Sprintf("course:%d", id)
-	query := `SELECT id, title, description, created_at, updated_at FROM courses WHERE id = ?`
+	query := `SELECT ` + columns + ` FROM courses WHERE id = ? AND organization_id = ?`
 
 	return cache.Cache(ctx, r.Redis, key, func() (*model.Course, error) {
-		var course model.Course
-		err := r.DB.Raw(query, id).Scan(&course).Error
-		if err != nil {
-			return nil, err
+		course, err := scan(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return &model.Course{}, nil
 		}
-		return &course, nil
+		return course, err
 	})
 }
 
-func (r *Course) Create(ctx context.Context, course *model.Course) (*model.Course, error) {
-	query := `INSERT INTO courses (title, description) VALUES (?, ?) RETURNING id`
-	rows, err := r.DB.Raw(query, course.Title, course.Description).Rows()
+// Create inserts the course as a draft with its tags and makes ownerID its
+// owner.
+func (r *Course) Create(ctx context.Context, course *model.Course, ownerID uint64) (*model.Course, error) {
+	var created *model.Course
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `INSERT INTO courses (organization_id, title, description, category_id, level, language, estimated_minutes)
+			VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`
+		err := tx.Raw(query, tenant.ID(ctx), course.Title, course.Description, course.CategoryID, course.Level, course.Language,
+			course.EstimatedMinutes).Row().Scan(&course.ID)
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_staff (organization_id, course_id, user_id, role) VALUES (?, ?, ?, 'owner')`
+		if err := tx.Exec(query, tenant.ID(ctx), course.ID, ownerID).Error; err != nil {
+			return err
+		}
+
+		created, err = saveTags(ctx, tx, course.ID, course.Tags)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	// Get the ID of the newly created course
-	if rows.Next() {
-		var id uint64
-		err := rows.Scan(&id)
-		if err != nil {
-			return nil, err
-		}
-		course.ID = id
-	}
+	return created, r.forget(ctx, created.ID)
+}
 
-	return course, nil
+// List returns a page of the courses the user can see: the listed ones and
+// the ones the user is part of the staff of, or every course if all is true.
+func (r *Course) List(ctx context.Context, userID uint64, all bool, q *listing.Query) (*listing.Page[*model.Course], error) {
+	key := fmt.Sprintf("user:%d:%s", userID, q.Key())
+	if all {
+		key = "all:" + q.Key()
+	}
+	base := `SELECT ` + columns + ` FROM courses WHERE organization_id = ? AND (?
+		OR (status = 'published' AND published_at <= CURRENT_TIMESTAMP)
+		OR id IN (SELECT course_id FROM course_staff WHERE user_id = ? AND organization_id = courses.organization_id))`
+	return r.page(ctx, courseSchema, key, q, base, tenant.ID(ctx), all, userID)
 }
 
-func (r *Course) GetAll(ctx context.Context) ([]*model.Course, error) {
-	key := "courses:all"
-	query := `SELECT id, title, description, created_at, updated_at FROM courses`
+// GetPublished returns the published courses, including the ones scheduled
+// for later so the cached list does not depend on the time it was built at.
+func (r *Course) GetPublished(ctx context.Context) ([]*model.Course, error) {
+	key := "courses:published"
+	query := `SELECT ` + columns + ` FROM courses WHERE organization_id = ? AND status = 'published' ORDER BY id`
 
 	return cache.Cache(ctx, r.Redis, key, func() ([]*model.Course, error) {
-		rows, err := r.DB.Raw(query).Rows()
-		if err != nil {
-			return nil, err
+		return r.list(query, tenant.ID(ctx))
+	})
+}
+
+// ListByStatus returns a page of the courses having the given status, the
+// least recently updated first by default.
+func (r *Course) ListByStatus(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.Course], error) {
+	base := `SELECT ` + columns + ` FROM courses WHERE organization_id = ? AND status = ?`
+	return r.page(ctx, oldestSchema, "status:"+status+":"+q.Key(), q, base, tenant.ID(ctx), status)
+}
+
+// ChangeStatus saves the status, publication and review fields of the course
+// if its current status is one of from. It returns nil if it is not, so two
+// concurrent transitions of the same course cannot both succeed.
+func (r *Course) ChangeStatus(ctx context.Context, course *model.Course, from ...string) (*model.Course, error) {
+	query := `UPDATE courses SET status = ?, published_at = ?, archived_at = ?, reviewed_by = ?, review_note = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND organization_id = ? AND status IN ? RETURNING ` + columns
+	row := r.DB.Raw(query, course.Status, course.PublishedAt, course.ArchivedAt, course.ReviewedBy, course.ReviewNote,
+		course.ID, tenant.ID(ctx), from).Row()
+
+	updated, err := scan(row)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, r.forget(ctx, updated.ID)
+}
+
+// ReviewRequired reports whether the organization requires courses to be
+// approved by a reviewer before they are published, through its
+// "course_review_required" setting.
+func (r *Course) ReviewRequired(ctx context.Context) (bool, error) {
+	var required bool
+	query := `SELECT COALESCE(settings->'course_review_required' = 'true'::jsonb, false) FROM organizations WHERE id = ?`
+	err := r.DB.Raw(query, tenant.ID(ctx)).Row().Scan(&required)
+	return required, err
+}
+
+// IsEnrolled reports whether the user is enrolled in the course.
+func (r *Course) IsEnrolled(ctx context.Context, courseID uint64, userID uint64) (bool, error) {
+	var enrolled bool
+	query := `SELECT EXISTS (SELECT 1 FROM enrollments WHERE course_id = ? AND user_id = ? AND organization_id = ?)`
+	err := r.DB.Raw(query, courseID, userID, tenant.ID(ctx)).Row().Scan(&enrolled)
+	return enrolled, err
+}
+
+// PreviewSections returns the sections of the course readable without
+// enrolling.
+func (r *Course) PreviewSections(ctx context.Context, courseID uint64) ([]uint64, error) {
+	ids := make([]uint64, 0)
+	query := `SELECT id FROM curriculums WHERE course_id = ? AND organization_id = ? AND is_preview ORDER BY id`
+	err := r.DB.Raw(query, courseID, tenant.ID(ctx)).Scan(&ids).Error
+	return ids, err
+}
+
+// Update saves the content and catalog fields of the course, replacing its
+// tags.
+func (r *Course) Update(ctx context.Context, course *model.Course) (*model.Course, error) {
+	var updated *model.Course
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `UPDATE courses SET title = ?, description = ?, category_id = ?, level = ?, language = ?, estimated_minutes = ?, updated_at = ?
+			WHERE id = ? AND organization_id = ?`
+		result := tx.Exec(query, course.Title, course.Description, course.CategoryID, course.Level, course.Language, course.EstimatedMinutes,
+			time.Now(), course.ID, tenant.ID(ctx))
+		if result.Error != nil {
+			return result.Error
 		}
-		defer rows.Close()
-
-		courses := make([]*model.Course, 0)
-		for rows.Next() {
-			var course model.Course
-			err := rows.Scan(&course.ID, &course.Title, &course.Description, &course.CreatedAt, &course.UpdatedAt)
-			if err != nil {
-				return nil, err
-			}
-			courses = append(courses, &course)
+		if result.RowsAffected == 0 {
+			return sql.ErrNoRows
 		}
 
-		return courses, nil
+		var err error
+		updated, err = saveTags(ctx, tx, course.ID, course.Tags)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, r.forget(ctx, updated.ID)
 }
 
-func (r *Course) Update(ctx context.Context, course *model.Course) (*model.Course, error) {
-	query := `UPDATE courses SET title = $1, description = $2, updated_at = $3 WHERE id = $4 
-	          RETURNING updated_at`
-	err := r.DB.Raw(query, course.Title, course.Description, time.Now(), course.ID).
-		Scan(&course.UpdatedAt)
+// saveTags replaces the tags of the course and returns it.
+func saveTags(ctx context.Context, tx *gorm.DB, courseID uint64, tags []string) (*model.Course, error) {
+	query := `DELETE FROM course_tags WHERE course_id = ? AND organization_id = ?`
+	if err := tx.Exec(query, courseID, tenant.ID(ctx)).Error; err != nil {
+		return nil, err
+	}
 
-	if err.Error != nil {
-		return nil, err.Error
+	query = `INSERT INTO course_tags (organization_id, course_id, tag) VALUES (?, ?, ?)`
+	for _, tag := range tags {
+		if err := tx.Exec(query, tenant.ID(ctx), courseID, tag).Error; err != nil {
+			return nil, err
+		}
 	}
 
-	return course, nil
+	query = `SELECT ` + columns + ` FROM courses WHERE id = ? AND organization_id = ?`
+	return scan(tx.Raw(query, courseID, tenant.ID(ctx)).Row())
 }
 
 func (r *Course) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM courses WHERE id = ?`
-	err := r.DB.Exec(query, id).Error
-	return err
+	query := `DELETE FROM courses WHERE id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, id, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+	return r.forget(ctx, id)
 }
 
-func (r *Course) CreateCurriculum(ctx context.Context, courseID uint64, createDTO *model.Curriculum) (*model.Curriculum, error) {
-	query := `INSERT INTO curriculums (course_id, 
-	section_name, section_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?) returning id`
-	err := r.DB.Raw(query, courseID, createDTO.SectionName, createDTO.SectionOrder, createDTO.CreatedAt, createDTO.UpdatedAt).Scan(&createDTO.ID)
-
-	if err.Error != nil {
-		return nil, err.Error
+// forget drops the cached course and the cached course lists.
+func (r *Course) forget(ctx context.Context, id uint64) error {
+	if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("course:%d", id), "courses:published"); err != nil {
+		return err
 	}
+	return cache.Invalidate(ctx, r.Redis, "courses")
+}
+
+// courseSchema is the schema of the course lists.
+var courseSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":                {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"title":             {Column: "title", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"status":            {Column: "status", Type: listing.Text, Operators: []string{listing.Eq, listing.Ne, listing.In}},
+		"category_id":       {Column: "category_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"level":             {Column: "level", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"language":          {Column: "language", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"is_template":       {Column: "is_template", Type: listing.Bool, Operators: []string{listing.Eq}},
+		"estimated_minutes": {Column: "estimated_minutes", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.Gte, listing.Lte}},
+		"published_at":      {Column: "published_at", Type: listing.Time, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at":        {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at":        {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
+}
+
+// oldestSchema lists the least recently updated courses first, and
+// titleSchema sorts them by title.
+var (
+	oldestSchema = &listing.Schema{Fields: courseSchema.Fields, Sort: []listing.Sort{{Field: "updated_at"}}}
+	titleSchema  = &listing.Schema{Fields: courseSchema.Fields, Sort: []listing.Sort{{Field: "title"}}}
+)
 
-	return createDTO, nil
+// page returns the page of the courses of the base query, cached under the
+// given key until a course changes.
+func (r *Course) page(ctx context.Context, schema *listing.Schema, key string, q *listing.Query, base string, args ...any) (*listing.Page[*model.Course], error) {
+	return cache.Versioned(ctx, r.Redis, "courses", key, func() (*listing.Page[*model.Course], error) {
+		return listing.Fetch(r.DB, schema, q, base, args, scan)
+	})
 }
 
-func (r *Course) GetCurriculum(ctx context.Context, courseID uint64) ([]*model.Curriculum, error) {
-	key := fmt.Sprintf("curriculum:course:%d", courseID)
-	query := `SELECT id, course_id, section_name, section_order, created_at, updated_at FROM curriculums WHERE course_id = ?`
+func (r *Course) list(query string, args ...any) ([]*model.Course, error) {
+	rows, err := r.DB.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return cache.Cache(ctx, r.Redis, key, func() ([]*model.Curriculum, error) {
-		rows, err := r.DB.Raw(query, courseID).Rows()
+	courses := make([]*model.Course, 0)
+	for rows.Next() {
+		course, err := scan(rows)
 		if err != nil {
 			return nil, err
 		}
-		defer rows.Close()
+		courses = append(courses, course)
+	}
 
-		curriculums := make([]*model.Curriculum, 0)
-		for rows.Next() {
-			var curriculum model.Curriculum
-			err := rows.Scan(&curriculum.ID, &curriculum.CourseID, &curriculum.SectionName, &curriculum.SectionOrder, &curriculum.CreatedAt, &curriculum.UpdatedAt)
-			if err != nil {
-				return nil, err
-			}
-			curriculums = append(curriculums, &curriculum)
+	return courses, rows.Err()
+}
+
+func scan(row listing.Scanner) (*model.Course, error) {
+	var course model.Course
+	var tags []byte
+	err := row.Scan(&course.ID, &course.Title, &course.Description, &course.Status, &course.PublishedAt, &course.ArchivedAt,
+		&course.ReviewedBy, &course.ReviewNote, &course.PublishedSnapshotID, &course.IsTemplate, &course.ClonedFromID,
+		&course.CategoryID, &course.Level, &course.Language, &course.EstimatedMinutes, &tags, &course.CreatedAt, &course.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(tags, &course.Tags); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// CreateCurriculum adds the section after the last one of its parent, or
+// of the top level.
+func (r *Course) CreateCurriculum(ctx context.Context, courseID uint64, createDTO *model.Curriculum) (*model.Curriculum, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Sections are numbered one change at a time for each course
+		if err := tx.Exec(`SELECT 1 FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`, courseID, tenant.ID(ctx)).Error; err != nil {
+			return err
 		}
 
-		return curriculums, nil
+		query := `INSERT INTO curriculums (organization_id, course_id, parent_id, section_name, section_order, is_preview, created_at, updated_at)
+			VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(section_order), 0) + 1 FROM curriculums WHERE course_id = ? AND parent_id IS NOT DISTINCT FROM ?), ?, ?, ?)
+			RETURNING id, section_order`
+		return tx.Raw(query, tenant.ID(ctx), courseID, createDTO.ParentID, createDTO.SectionName, courseID, createDTO.ParentID,
+			createDTO.IsPreview, createDTO.CreatedAt, createDTO.UpdatedAt).Row().Scan(&createDTO.ID, &createDTO.SectionOrder)
+	})
+	if err != nil {
+		return nil, err
+	}
+	createDTO.CourseID = courseID
+
+	return createDTO, cache.Invalidate(ctx, r.Redis, fmt.Sprintf("curriculum:course:%d", courseID))
+}
+
+// ListCurriculum returns a page of the sections of the course, in the order
+// of the course by default.
+func (r *Course) ListCurriculum(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.Curriculum], error) {
+	namespace := fmt.Sprintf("curriculum:course:%d", courseID)
+	base := `SELECT id, course_id, parent_id, section_name, section_order, is_preview, created_at, updated_at FROM curriculums WHERE course_id = ? AND organization_id = ?`
+
+	return cache.Versioned(ctx, r.Redis, namespace, q.Key(), func() (*listing.Page[*model.Curriculum], error) {
+		return listing.Fetch(r.DB, curriculumSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.Curriculum, error) {
+			var curriculum model.Curriculum
+			err := row.Scan(&curriculum.ID, &curriculum.CourseID, &curriculum.ParentID, &curriculum.SectionName, &curriculum.SectionOrder, &curriculum.IsPreview,
+				&curriculum.CreatedAt, &curriculum.UpdatedAt)
+			return &curriculum, err
+		})
 	})
 }
 
+var curriculumSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":            {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"parent_id":     {Column: "parent_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"section_name":  {Column: "section_name", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"section_order": {Column: "section_order", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.Gte, listing.Lte}},
+		"is_preview":    {Column: "is_preview", Type: listing.Bool, Operators: []string{listing.Eq}},
+		"created_at":    {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at":    {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "section_order"}},
+}
+
 func (r *Course) GetCurriculumByID(ctx context.Context, id uint64) (*model.Curriculum, error) {
 	key := fmt.Sprintf("curriculum:%d", id)
-	query := `SELECT id, course_id, section_name, section_order, created_at, updated_at FROM curriculums WHERE id = ?`
+	query := `SELECT id, course_id, parent_id, section_name, section_order, is_preview, created_at, updated_at FROM curriculums WHERE id = ? AND organization_id = ?`
 
 	return cache.Cache(ctx, r.Redis, key, func() (*model.Curriculum, error) {
 		var curriculum model.Curriculum
-		err := r.DB.Raw(query, id).Scan(&curriculum).Error
+		err := r.DB.Raw(query, id, tenant.ID(ctx)).Scan(&curriculum).Error
 		if err != nil {
 			return nil, err
 		}
@@ -154,20 +348,49 @@ func (r *Course) GetCurriculumByID(ctx context.Context, id uint64) (*model.Curri
 	})
 }
 
+// UpdateCurriculum updates the section. Its place is changed by moving it.
 func (r *Course) UpdateCurriculum(ctx context.Context, curriculum *model.Curriculum) (*model.Curriculum, error) {
-	query := `UPDATE curriculums SET section_name = $1, section_order = $2, updated_at = $3 WHERE id = $4 
-	          RETURNING updated_at`
-	err := r.DB.Raw(query, curriculum.SectionName, curriculum.SectionOrder, time.Now(), curriculum.ID).Scan(&curriculum.UpdatedAt)
+	query := `UPDATE curriculums SET section_name = $1, is_preview = $2, updated_at = $3 WHERE id = $4 AND organization_id = $5
+	          RETURNING course_id, parent_id, section_order, updated_at`
+	err := r.DB.Raw(query, curriculum.SectionName, curriculum.IsPreview, time.Now(), curriculum.ID, tenant.ID(ctx)).
+		Row().Scan(&curriculum.CourseID, &curriculum.ParentID, &curriculum.SectionOrder, &curriculum.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return curriculum, r.forgetCurriculum(ctx, curriculum.ID, curriculum.CourseID)
+}
 
-	if err.Error != nil {
-		return nil, err.Error
+// DeleteCurriculum deletes the section and the sections nested in it, and
+// returns their IDs.
+func (r *Course) DeleteCurriculum(ctx context.Context, id uint64) ([]uint64, error) {
+	query := `DELETE FROM curriculums WHERE course_id = (SELECT course_id FROM curriculums WHERE id = ? AND organization_id = ?)
+		AND ? = ANY(curriculum_path(id)) RETURNING id, course_id`
+	rows, err := r.DB.Raw(query, id, tenant.ID(ctx), id).Rows()
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return curriculum, nil
+	deleted := make([]uint64, 0)
+	for rows.Next() {
+		var sectionID, courseID uint64
+		if err := rows.Scan(&sectionID, &courseID); err != nil {
+			return nil, err
+		}
+		if err := r.forgetCurriculum(ctx, sectionID, courseID); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, sectionID)
+	}
+	return deleted, rows.Err()
 }
 
-func (r *Course) DeleteCurriculum(ctx context.Context, id uint64) error {
-	query := `DELETE FROM curriculums WHERE id = ?`
-	err := r.DB.Exec(query, id).Error
-	return err
+// forgetCurriculum drops the cached curriculum and the cached curriculum list
+// of its course.
+func (r *Course) forgetCurriculum(ctx context.Context, id uint64, courseID uint64) error {
+	if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("curriculum:%d", id)); err != nil {
+		return err
+	}
+	return cache.Invalidate(ctx, r.Redis, fmt.Sprintf("curriculum:course:%d", courseID))
 }
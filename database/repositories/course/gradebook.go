@@ -0,0 +1,127 @@
+package course
+
+import (
+	"context"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// Gradebook returns a page of the grades of the students enrolled in the
+// course, sorted by name by default.
+func (r *Course) Gradebook(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.GradebookEntry], error) {
+	base := `SELECT e.user_id, u.name, u.email, e.cohort_id,
+			COALESCE((SELECT AVG(COALESCE((SELECT MAX(s.grade) FROM submissions s WHERE s.assessment_id = a.id AND s.user_id = e.user_id), 0))::float8
+				FROM assessments a WHERE a.course_id = e.course_id), 0) AS grade
+		FROM enrollments e JOIN users u ON u.id = e.user_id
+		WHERE e.course_id = ? AND e.organization_id = ?`
+	page, err := listing.Fetch(r.DB, gradebookSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.GradebookEntry, error) {
+		entry := &model.GradebookEntry{Grades: make([]*model.AssessmentGrade, 0)}
+		err := row.Scan(&entry.UserID, &entry.Name, &entry.Email, &entry.CohortID, &entry.Grade)
+		return entry, err
+	})
+	if err != nil || len(page.Data) == 0 {
+		return page, err
+	}
+
+	byUser := make(map[uint64]*model.GradebookEntry, len(page.Data))
+	userIDs := make([]uint64, 0, len(page.Data))
+	for _, entry := range page.Data {
+		byUser[entry.UserID] = entry
+		userIDs = append(userIDs, entry.UserID)
+	}
+
+	query := `SELECT e.user_id, a.id, COALESCE(d.due_at, a.due_at),
+			(SELECT MAX(s.grade) FROM submissions s WHERE s.assessment_id = a.id AND s.user_id = e.user_id),
+			(SELECT MAX(s.submitted_at) FROM submissions s WHERE s.assessment_id = a.id AND s.user_id = e.user_id)
+		FROM enrollments e JOIN assessments a ON a.course_id = e.course_id
+		LEFT JOIN cohort_due_dates d ON d.cohort_id = e.cohort_id AND d.assessment_id = a.id
+		WHERE e.course_id = ? AND e.user_id IN ? AND e.organization_id = ?
+		ORDER BY a.id`
+	rows, err := r.DB.Raw(query, courseID, userIDs, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID uint64
+		var grade model.AssessmentGrade
+		if err := rows.Scan(&userID, &grade.AssessmentID, &grade.DueAt, &grade.Grade, &grade.SubmittedAt); err != nil {
+			return nil, err
+		}
+		byUser[userID].Grades = append(byUser[userID].Grades, &grade)
+	}
+
+	return page, rows.Err()
+}
+
+var gradebookSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"user_id":   {Column: "user_id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":      {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Contains}},
+		"cohort_id": {Column: "cohort_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"grade":     {Column: "grade", Type: listing.Float, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "name"}},
+	Key:  "user_id",
+}
+
+// ListProgress returns a page of how far each student enrolled in the course
+// is through its materials, sorted by name by default.
+func (r *Course) ListProgress(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.StudentProgress], error) {
+	base := `SELECT e.user_id, u.name, e.cohort_id,
+			(SELECT COUNT(*) FROM progress_tracking p JOIN materials m ON m.id = p.material_id JOIN curriculums c ON c.id = m.curriculum_id
+				WHERE c.course_id = e.course_id AND p.user_id = e.user_id AND p.status = 'completed') AS completed,
+			(SELECT COUNT(*) FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE c.course_id = e.course_id) AS total
+		FROM enrollments e JOIN users u ON u.id = e.user_id
+		WHERE e.course_id = ? AND e.organization_id = ?`
+	return listing.Fetch(r.DB, courseProgressSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.StudentProgress, error) {
+		var progress model.StudentProgress
+		err := row.Scan(&progress.UserID, &progress.Name, &progress.CohortID, &progress.Completed, &progress.Total)
+		return &progress, err
+	})
+}
+
+var courseProgressSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"user_id":   {Column: "user_id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":      {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Contains}},
+		"cohort_id": {Column: "cohort_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"completed": {Column: "completed", Type: listing.Int, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "name"}},
+	Key:  "user_id",
+}
+
+// ListSubmissions returns a page of the submissions to the assessments of the
+// course, the latest first by default. A submission is late if it was made
+// after the due date of the assessment for the cohort of the student.
+func (r *Course) ListSubmissions(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseSubmission], error) {
+	base := `SELECT s.id, s.assessment_id, s.user_id, u.name, e.cohort_id, s.grade, s.submitted_at, due.at AS due_at,
+			COALESCE(s.submitted_at > due.at, false) AS late
+		FROM submissions s JOIN assessments a ON a.id = s.assessment_id JOIN users u ON u.id = s.user_id
+		LEFT JOIN enrollments e ON e.course_id = a.course_id AND e.user_id = s.user_id
+		LEFT JOIN cohort_due_dates d ON d.cohort_id = e.cohort_id AND d.assessment_id = a.id
+		CROSS JOIN LATERAL (SELECT COALESCE(d.due_at, a.due_at) AS at) due
+		WHERE a.course_id = ? AND s.organization_id = ? AND s.submitted_at IS NOT NULL`
+	return listing.Fetch(r.DB, submissionSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.CourseSubmission, error) {
+		var submission model.CourseSubmission
+		err := row.Scan(&submission.ID, &submission.AssessmentID, &submission.UserID, &submission.Name, &submission.CohortID,
+			&submission.Grade, &submission.SubmittedAt, &submission.DueAt, &submission.Late)
+		return &submission, err
+	})
+}
+
+var submissionSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":            {Column: "id", Type: listing.Int, Sortable: true},
+		"assessment_id": {Column: "assessment_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"user_id":       {Column: "user_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"cohort_id":     {Column: "cohort_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"late":          {Column: "late", Type: listing.Bool, Operators: []string{listing.Eq}},
+		"submitted_at":  {Column: "submitted_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "submitted_at", Desc: true}},
+}
@@ -0,0 +1,404 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+var (
+	errUnknownParent = stderrors.New("The parent section must be a section of the same course")
+	errMoveIntoSelf  = stderrors.New("A section cannot be moved into itself or one of its sections")
+	errOutlineItems  = stderrors.New("The outline must list every section and every material of the course once")
+)
+
+// Outline returns all the sections of the course and all their materials,
+// each in their order.
+func (r *Course) Outline(ctx context.Context, courseID uint64) ([]*model.Curriculum, []*model.Material, error) {
+	query := `SELECT id, course_id, parent_id, section_name, section_order, is_preview, created_at, updated_at FROM curriculums
+		WHERE course_id = ? AND organization_id = ? ORDER BY section_order, id`
+	rows, err := r.DB.Raw(query, courseID, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	curriculums := make([]*model.Curriculum, 0)
+	for rows.Next() {
+		var curriculum model.Curriculum
+		err := rows.Scan(&curriculum.ID, &curriculum.CourseID, &curriculum.ParentID, &curriculum.SectionName, &curriculum.SectionOrder,
+			&curriculum.IsPreview, &curriculum.CreatedAt, &curriculum.UpdatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		curriculums = append(curriculums, &curriculum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	query = `SELECT m.id, m.curriculum_id, m.material_type, m.content, m."order", m.created_at, m.updated_at
+		FROM materials m JOIN curriculums c ON c.id = m.curriculum_id
+		WHERE c.course_id = ? AND m.organization_id = ? ORDER BY m."order", m.id`
+	rows, err = r.DB.Raw(query, courseID, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	materials := make([]*model.Material, 0)
+	for rows.Next() {
+		var material model.Material
+		err := rows.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order,
+			&material.CreatedAt, &material.UpdatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		materials = append(materials, &material)
+	}
+	return curriculums, materials, rows.Err()
+}
+
+// SectionParents returns the parent of each section of the course, 0 for
+// the top-level ones.
+func (r *Course) SectionParents(ctx context.Context, courseID uint64) (map[uint64]uint64, error) {
+	return sectionParents(r.DB, courseID, tenant.ID(ctx))
+}
+
+func sectionParents(db *gorm.DB, courseID uint64, orgID uint64) (map[uint64]uint64, error) {
+	query := `SELECT id, COALESCE(parent_id, 0) FROM curriculums WHERE course_id = ? AND organization_id = ?`
+	rows, err := db.Raw(query, courseID, orgID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[uint64]uint64)
+	for rows.Next() {
+		var id, parentID uint64
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		parents[id] = parentID
+	}
+	return parents, rows.Err()
+}
+
+// MoveCurriculum moves the section of the course, with the sections nested
+// in it, under the parent section, or to the top level if parentID is nil,
+// at the position among its new siblings, starting at 1. Its former and new
+// siblings are renumbered from 1 in the same transaction.
+// It returns the sections whose parent or order changed, nil if the section
+// does not exist.
+func (r *Course) MoveCurriculum(ctx context.Context, courseID uint64, id uint64, parentID *uint64, position int) ([]*model.Curriculum, error) {
+	var moved []*model.Curriculum
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Moves within a course are applied one at a time so that they
+		// renumber siblings from a consistent order
+		var found int
+		err := tx.Raw(`SELECT 1 FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`, courseID, tenant.ID(ctx)).Row().Scan(&found)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		parents, err := sectionParents(tx, courseID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		from, ok := parents[id]
+		if !ok {
+			return nil
+		}
+		var to uint64
+		if parentID != nil {
+			to = *parentID
+			if _, ok := parents[to]; !ok {
+				return errUnknownParent
+			}
+			for ancestor := to; ancestor != 0; ancestor = parents[ancestor] {
+				if ancestor == id {
+					return errMoveIntoSelf
+				}
+			}
+		}
+
+		query := `UPDATE curriculums SET parent_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND COALESCE(parent_id, 0) <> ?`
+		result := tx.Exec(query, parentID, id, to)
+		if result.Error != nil {
+			return result.Error
+		}
+		changed := make([]uint64, 0)
+		if result.RowsAffected > 0 {
+			changed = append(changed, id)
+		}
+
+		siblings, err := childSections(tx, courseID, to, id)
+		if err != nil {
+			return err
+		}
+		if position < 1 || position > len(siblings) {
+			position = len(siblings) + 1
+		}
+		siblings = append(siblings[:position-1], append([]uint64{id}, siblings[position-1:]...)...)
+		renumbered, err := renumberSections(tx, siblings)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, renumbered...)
+
+		if from != to {
+			siblings, err := childSections(tx, courseID, from, id)
+			if err != nil {
+				return err
+			}
+			renumbered, err := renumberSections(tx, siblings)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, renumbered...)
+
+			// The section now waits for the release rules of its new
+			// ancestors
+			if err := checkReleaseCycle(tx, courseID, tenant.ID(ctx)); err != nil {
+				return err
+			}
+		}
+
+		moved, err = sectionsByID(tx, changed)
+		return err
+	})
+	if err != nil || moved == nil {
+		return nil, err
+	}
+
+	for _, curriculum := range moved {
+		if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("curriculum:%d", curriculum.ID)); err != nil {
+			return nil, err
+		}
+	}
+	return moved, cache.Invalidate(ctx, r.Redis, fmt.Sprintf("curriculum:course:%d", courseID))
+}
+
+// ReorderOutline places every section of the course under its ParentID at
+// its SectionOrder, and every material of the course in its CurriculumID at
+// its Order, in one transaction. The sections and materials must be all the
+// ones of the course.
+// It returns the sections and the materials whose place changed, nil if the
+// course does not exist.
+func (r *Course) ReorderOutline(ctx context.Context, courseID uint64, sections []*model.Curriculum, materials []*model.Material) ([]*model.Curriculum, []*model.Material, error) {
+	var changedSections []*model.Curriculum
+	var changedMaterials []*model.Material
+	previous := make(map[uint64]uint64)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var found int
+		err := tx.Raw(`SELECT 1 FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`, courseID, tenant.ID(ctx)).Row().Scan(&found)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		parents, err := sectionParents(tx, courseID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		if len(sections) != len(parents) {
+			return errOutlineItems
+		}
+		for _, section := range sections {
+			if _, ok := parents[section.ID]; !ok {
+				return errOutlineItems
+			}
+		}
+
+		query := `SELECT m.id, m.curriculum_id FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE c.course_id = ?`
+		rows, err := tx.Raw(query, courseID).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, curriculumID uint64
+			if err := rows.Scan(&id, &curriculumID); err != nil {
+				return err
+			}
+			previous[id] = curriculumID
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(materials) != len(previous) {
+			return errOutlineItems
+		}
+		for _, material := range materials {
+			if _, ok := previous[uint64(material.ID)]; !ok {
+				return errOutlineItems
+			}
+		}
+
+		sectionIDs := make([]uint64, 0)
+		query = `UPDATE curriculums SET parent_id = ?, section_order = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND (parent_id IS DISTINCT FROM ? OR section_order IS DISTINCT FROM ?)`
+		for _, section := range sections {
+			result := tx.Exec(query, section.ParentID, section.SectionOrder, section.ID, section.ParentID, section.SectionOrder)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				sectionIDs = append(sectionIDs, section.ID)
+			}
+		}
+
+		materialIDs := make([]uint64, 0)
+		query = `UPDATE materials SET curriculum_id = ?, "order" = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND (curriculum_id <> ? OR "order" IS DISTINCT FROM ?)`
+		for _, material := range materials {
+			result := tx.Exec(query, material.CurriculumID, material.Order, material.ID, material.CurriculumID, material.Order)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				materialIDs = append(materialIDs, uint64(material.ID))
+			}
+		}
+
+		// Sections wait for the release rules of their new ancestors, and
+		// for the completion of the materials moved into them
+		if len(sectionIDs) > 0 || len(materialIDs) > 0 {
+			if err := checkReleaseCycle(tx, courseID, tenant.ID(ctx)); err != nil {
+				return err
+			}
+		}
+
+		if changedSections, err = sectionsByID(tx, sectionIDs); err != nil {
+			return err
+		}
+		changedMaterials, err = materialsByID(tx, materialIDs)
+		return err
+	})
+	if err != nil || changedSections == nil {
+		return nil, nil, err
+	}
+
+	for _, curriculum := range changedSections {
+		if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("curriculum:%d", curriculum.ID)); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, material := range changedMaterials {
+		err := cache.Forget(ctx, r.Redis, fmt.Sprintf("material:%d", material.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		err = cache.Invalidate(ctx, r.Redis,
+			fmt.Sprintf("materials:curriculum:%d", material.CurriculumID),
+			fmt.Sprintf("materials:curriculum:%d", previous[uint64(material.ID)]))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return changedSections, changedMaterials, cache.Invalidate(ctx, r.Redis, fmt.Sprintf("curriculum:course:%d", courseID))
+}
+
+// sectionsByID returns the sections, in their order.
+func sectionsByID(tx *gorm.DB, ids []uint64) ([]*model.Curriculum, error) {
+	curriculums := make([]*model.Curriculum, 0, len(ids))
+	if len(ids) == 0 {
+		return curriculums, nil
+	}
+
+	query := `SELECT id, course_id, parent_id, section_name, section_order, is_preview, created_at, updated_at FROM curriculums
+		WHERE id IN ? ORDER BY section_order, id`
+	rows, err := tx.Raw(query, ids).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var curriculum model.Curriculum
+		err := rows.Scan(&curriculum.ID, &curriculum.CourseID, &curriculum.ParentID, &curriculum.SectionName, &curriculum.SectionOrder,
+			&curriculum.IsPreview, &curriculum.CreatedAt, &curriculum.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		curriculums = append(curriculums, &curriculum)
+	}
+	return curriculums, rows.Err()
+}
+
+// materialsByID returns the materials, by section and in their order.
+func materialsByID(tx *gorm.DB, ids []uint64) ([]*model.Material, error) {
+	materials := make([]*model.Material, 0, len(ids))
+	if len(ids) == 0 {
+		return materials, nil
+	}
+
+	query := `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials
+		WHERE id IN ? ORDER BY curriculum_id, "order", id`
+	rows, err := tx.Raw(query, ids).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var material model.Material
+		err := rows.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order,
+			&material.CreatedAt, &material.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		materials = append(materials, &material)
+	}
+	return materials, rows.Err()
+}
+
+// childSections returns the sections of the course under the parent, 0 for
+// the top-level ones, in their order, without the excluded one.
+func childSections(tx *gorm.DB, courseID uint64, parentID uint64, excluded uint64) ([]uint64, error) {
+	query := `SELECT id FROM curriculums WHERE course_id = ? AND COALESCE(parent_id, 0) = ? AND id <> ? ORDER BY section_order, id`
+	rows, err := tx.Raw(query, courseID, parentID, excluded).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// renumberSections orders the sections as listed, from 1, and returns the
+// ones whose order changed.
+func renumberSections(tx *gorm.DB, ids []uint64) ([]uint64, error) {
+	changed := make([]uint64, 0)
+	query := `UPDATE curriculums SET section_order = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND section_order IS DISTINCT FROM ?`
+	for i, id := range ids {
+		result := tx.Exec(query, i+1, id, i+1)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			changed = append(changed, id)
+		}
+	}
+	return changed, nil
+}
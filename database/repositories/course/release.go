@@ -0,0 +1,255 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+const releaseColumns = `id, course_id, curriculum_id, material_id, release_at, days_after_enrollment, after_curriculum_id, created_at, updated_at`
+
+var (
+	errUnknownContent = stderrors.New("Release rules can only refer to the sections and materials of the course")
+	errReleaseCycle   = stderrors.New("Sections cannot wait for each other to be completed")
+)
+
+// ListReleaseRules returns the release rules of the content of the course.
+func (r *Course) ListReleaseRules(ctx context.Context, courseID uint64) ([]*model.ReleaseRule, error) {
+	return listReleaseRules(r.DB, courseID, tenant.ID(ctx))
+}
+
+func listReleaseRules(db *gorm.DB, courseID uint64, orgID uint64) ([]*model.ReleaseRule, error) {
+	query := `SELECT ` + releaseColumns + ` FROM release_rules WHERE course_id = ? AND organization_id = ? ORDER BY id`
+	rows, err := db.Raw(query, courseID, orgID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]*model.ReleaseRule, 0)
+	for rows.Next() {
+		rule, err := scanReleaseRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SaveReleaseRule sets the release rule of its section or material, replacing
+// the previous one.
+func (r *Course) SaveReleaseRule(ctx context.Context, rule *model.ReleaseRule) (*model.ReleaseRule, error) {
+	var saved *model.ReleaseRule
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Rules of a course are saved one at a time so concurrent saves
+		// cannot both pass the cycle check
+		var found int
+		err := tx.Raw(`SELECT 1 FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`, rule.CourseID, tenant.ID(ctx)).Row().Scan(&found)
+		if stderrors.Is(err, sql.ErrNoRows) {
+			return errUnknownContent
+		}
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT
+				(CAST(? AS int) IS NULL OR EXISTS (SELECT 1 FROM curriculums WHERE id = ? AND course_id = ?))
+				AND (CAST(? AS int) IS NULL OR EXISTS (SELECT 1 FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE m.id = ? AND c.course_id = ?))
+				AND (CAST(? AS int) IS NULL OR EXISTS (SELECT 1 FROM curriculums WHERE id = ? AND course_id = ?))`
+		var valid bool
+		err = tx.Raw(query, rule.CurriculumID, rule.CurriculumID, rule.CourseID, rule.MaterialID, rule.MaterialID, rule.CourseID,
+			rule.AfterCurriculumID, rule.AfterCurriculumID, rule.CourseID).Row().Scan(&valid)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errUnknownContent
+		}
+
+		target := "curriculum_id"
+		if rule.MaterialID != nil {
+			target = "material_id"
+		}
+		query = `INSERT INTO release_rules (organization_id, course_id, curriculum_id, material_id, release_at, days_after_enrollment, after_curriculum_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (` + target + `) DO UPDATE SET release_at = EXCLUDED.release_at, days_after_enrollment = EXCLUDED.days_after_enrollment,
+				after_curriculum_id = EXCLUDED.after_curriculum_id, updated_at = CURRENT_TIMESTAMP
+			WHERE release_rules.course_id = EXCLUDED.course_id
+			RETURNING ` + releaseColumns
+		saved, err = scanReleaseRule(tx.Raw(query, tenant.ID(ctx), rule.CourseID, rule.CurriculumID, rule.MaterialID, rule.ReleaseAt,
+			rule.DaysAfterEnrollment, rule.AfterCurriculumID).Row())
+		if err != nil {
+			return err
+		}
+		if saved.AfterCurriculumID == nil {
+			return nil
+		}
+
+		return checkReleaseCycle(tx, rule.CourseID, tenant.ID(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// DeleteReleaseRule releases the content held back by the rule. It returns
+// false if the rule does not exist.
+func (r *Course) DeleteReleaseRule(ctx context.Context, courseID uint64, id uint64) (bool, error) {
+	result := r.DB.Exec(`DELETE FROM release_rules WHERE id = ? AND course_id = ? AND organization_id = ?`, id, courseID, tenant.ID(ctx))
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseState returns when the student enrolled in the course, nil if they
+// are not enrolled, and the sections of the course whose own materials they
+// all completed, regardless of the sections nested in them.
+func (r *Course) ReleaseState(ctx context.Context, courseID uint64, userID uint64) (*time.Time, []uint64, error) {
+	var enrolledAt *time.Time
+	query := `SELECT enrolled_at FROM enrollments WHERE course_id = ? AND user_id = ? AND organization_id = ?`
+	err := r.DB.Raw(query, courseID, userID, tenant.ID(ctx)).Row().Scan(&enrolledAt)
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	query = `SELECT cu.id FROM curriculums cu WHERE cu.course_id = ? AND cu.organization_id = ? AND NOT EXISTS (
+			SELECT 1 FROM materials m WHERE m.curriculum_id = cu.id AND NOT EXISTS (
+				SELECT 1 FROM progress_tracking p WHERE p.material_id = m.id AND p.user_id = ? AND p.status = 'completed'
+			)
+		)`
+	rows, err := r.DB.Raw(query, courseID, tenant.ID(ctx), userID).Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	completed := make([]uint64, 0)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		completed = append(completed, id)
+	}
+	return enrolledAt, completed, rows.Err()
+}
+
+// checkReleaseCycle returns errReleaseCycle if some sections of the course
+// cannot be completed because of its release rules.
+func checkReleaseCycle(tx *gorm.DB, courseID uint64, orgID uint64) error {
+	rules, err := listReleaseRules(tx, courseID, orgID)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	parents, err := sectionParents(tx, courseID, orgID)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT m.id, m.curriculum_id FROM release_rules r JOIN materials m ON m.id = r.material_id WHERE r.course_id = ?`
+	rows, err := tx.Raw(query, courseID).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	materials := make(map[uint64]uint64)
+	for rows.Next() {
+		var id, curriculumID uint64
+		if err := rows.Scan(&id, &curriculumID); err != nil {
+			return err
+		}
+		materials[id] = curriculumID
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if releaseCycle(rules, parents, materials) {
+		return errReleaseCycle
+	}
+	return nil
+}
+
+// releaseCycle reports whether some sections cannot be completed because of
+// the release rules: reading a section waits for the sections named by the
+// rules of the section, of its ancestors and of its materials, and
+// completing a section means reading it and the sections nested in it.
+func releaseCycle(rules []*model.ReleaseRule, parents map[uint64]uint64, materials map[uint64]uint64) bool {
+	waits := make(map[uint64][]uint64)
+	for _, rule := range rules {
+		if rule.AfterCurriculumID == nil {
+			continue
+		}
+		target := materials[ptrValue(rule.MaterialID)]
+		if rule.CurriculumID != nil {
+			target = *rule.CurriculumID
+		}
+		waits[target] = append(waits[target], *rule.AfterCurriculumID)
+	}
+
+	// requires[a] are the sections to complete before completing a
+	requires := make(map[uint64][]uint64)
+	for section := range parents {
+		var after []uint64
+		for ancestor := section; ancestor != 0; ancestor = parents[ancestor] {
+			after = append(after, waits[ancestor]...)
+		}
+		for ancestor := section; ancestor != 0; ancestor = parents[ancestor] {
+			requires[ancestor] = append(requires[ancestor], after...)
+		}
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[uint64]int)
+	var visit func(section uint64) bool
+	visit = func(section uint64) bool {
+		switch state[section] {
+		case visiting:
+			return true
+		case done:
+			return false
+		}
+		state[section] = visiting
+		for _, next := range requires[section] {
+			if visit(next) {
+				return true
+			}
+		}
+		state[section] = done
+		return false
+	}
+	for section := range parents {
+		if visit(section) {
+			return true
+		}
+	}
+	return false
+}
+
+func ptrValue(id *uint64) uint64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func scanReleaseRule(row listing.Scanner) (*model.ReleaseRule, error) {
+	var rule model.ReleaseRule
+	err := row.Scan(&rule.ID, &rule.CourseID, &rule.CurriculumID, &rule.MaterialID, &rule.ReleaseAt, &rule.DaysAfterEnrollment,
+		&rule.AfterCurriculumID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
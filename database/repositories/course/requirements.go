@@ -0,0 +1,137 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// errPrerequisiteCycle is returned when saving prerequisites that would make
+// a course require itself.
+var errPrerequisiteCycle = stderrors.New("A course cannot require itself, even through its prerequisites")
+
+// GetRequirements returns the enrollment requirements of the course, with
+// the title of each prerequisite.
+func (r *Course) GetRequirements(ctx context.Context, courseID uint64) (*model.CourseRequirements, error) {
+	return loadRequirements(r.DB, courseID, tenant.ID(ctx))
+}
+
+// SaveRequirements replaces the enrollment requirements of the course. The
+// students on the waitlist are enrolled in the seats it has left.
+func (r *Course) SaveRequirements(ctx context.Context, requirements *model.CourseRequirements) (*model.CourseRequirements, error) {
+	var saved *model.CourseRequirements
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Prerequisites are saved one course at a time so concurrent saves
+		// cannot both pass the cycle check
+		if err := tx.Exec(`LOCK TABLE course_prerequisites IN SHARE ROW EXCLUSIVE MODE`).Error; err != nil {
+			return err
+		}
+
+		query := `DELETE FROM course_prerequisites WHERE course_id = ? AND organization_id = ?`
+		if err := tx.Exec(query, requirements.CourseID, tenant.ID(ctx)).Error; err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_prerequisites (organization_id, course_id, prerequisite_id, min_grade) VALUES (?, ?, ?, ?)`
+		for _, prerequisite := range requirements.Prerequisites {
+			if err := tx.Exec(query, tenant.ID(ctx), requirements.CourseID, prerequisite.CourseID, prerequisite.MinGrade).Error; err != nil {
+				return err
+			}
+		}
+
+		query = `WITH RECURSIVE required (id) AS (
+				SELECT prerequisite_id FROM course_prerequisites WHERE course_id = ?
+				UNION
+				SELECT p.prerequisite_id FROM course_prerequisites p JOIN required r ON p.course_id = r.id
+			)
+			SELECT EXISTS (SELECT 1 FROM required WHERE id = ?)`
+		var cycle bool
+		if err := tx.Raw(query, requirements.CourseID, requirements.CourseID).Row().Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return errPrerequisiteCycle
+		}
+
+		query = `INSERT INTO course_requirements (organization_id, course_id, roles, email_domains, opens_at, closes_at, capacity)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (course_id) DO UPDATE SET roles = EXCLUDED.roles, email_domains = EXCLUDED.email_domains,
+				opens_at = EXCLUDED.opens_at, closes_at = EXCLUDED.closes_at, capacity = EXCLUDED.capacity, updated_at = CURRENT_TIMESTAMP
+			WHERE course_requirements.organization_id = EXCLUDED.organization_id`
+		err := tx.Exec(query, tenant.ID(ctx), requirements.CourseID, jsonList(requirements.Roles), jsonList(requirements.EmailDomains),
+			requirements.OpensAt, requirements.ClosesAt, requirements.Capacity).Error
+		if err != nil {
+			return err
+		}
+
+		// Seats added to the course go to the waitlist
+		if err := tx.Exec(`SELECT promote_waitlist(?)`, requirements.CourseID).Error; err != nil {
+			return err
+		}
+
+		saved, err = loadRequirements(tx, requirements.CourseID, tenant.ID(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+func loadRequirements(db *gorm.DB, courseID uint64, organizationID uint64) (*model.CourseRequirements, error) {
+	result := &model.CourseRequirements{
+		CourseID:      courseID,
+		Prerequisites: make([]*model.Prerequisite, 0),
+		Roles:         make([]string, 0),
+		EmailDomains:  make([]string, 0),
+	}
+
+	query := `SELECT roles, email_domains, opens_at, closes_at, capacity FROM course_requirements WHERE course_id = ? AND organization_id = ?`
+	var roles, domains string
+	err := db.Raw(query, courseID, organizationID).Row().Scan(&roles, &domains, &result.OpensAt, &result.ClosesAt, &result.Capacity)
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(roles), &result.Roles); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(domains), &result.EmailDomains); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `SELECT p.prerequisite_id, c.title, p.min_grade FROM course_prerequisites p JOIN courses c ON c.id = p.prerequisite_id
+		WHERE p.course_id = ? AND p.organization_id = ? ORDER BY p.prerequisite_id`
+	rows, err := db.Raw(query, courseID, organizationID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var prerequisite model.Prerequisite
+		if err := rows.Scan(&prerequisite.CourseID, &prerequisite.Title, &prerequisite.MinGrade); err != nil {
+			return nil, err
+		}
+		result.Prerequisites = append(result.Prerequisites, &prerequisite)
+	}
+
+	return result, rows.Err()
+}
+
+// jsonList encodes the list as a JSON array, empty if it is nil.
+func jsonList(list []string) string {
+	if list == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
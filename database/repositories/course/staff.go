@@ -0,0 +1,79 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// ListStaff returns a page of the staff of the course, the earliest members
+// first by default.
+func (r *Course) ListStaff(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseStaff], error) {
+	base := `SELECT cs.course_id, cs.user_id, u.name, u.email, cs.role, cs.created_at, cs.updated_at
+	         FROM course_staff cs JOIN users u ON u.id = cs.user_id
+	         WHERE cs.course_id = ? AND cs.organization_id = ?`
+	return listing.Fetch(r.DB, staffSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.CourseStaff, error) {
+		var member model.CourseStaff
+		err := row.Scan(&member.CourseID, &member.UserID, &member.Name, &member.Email, &member.Role, &member.CreatedAt, &member.UpdatedAt)
+		return &member, err
+	})
+}
+
+var staffSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"user_id":    {Column: "user_id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":       {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Contains}},
+		"email":      {Column: "email", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"role":       {Column: "role", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "created_at"}},
+	Key:  "user_id",
+}
+
+// GetStaffRole returns the role of the user in the staff of the course, or
+// an empty string if the user is not part of it.
+func (r *Course) GetStaffRole(ctx context.Context, courseID uint64, userID uint64) (string, error) {
+	query := `SELECT role FROM course_staff WHERE course_id = ? AND user_id = ? AND organization_id = ?`
+
+	var role string
+	err := r.DB.Raw(query, courseID, userID, tenant.ID(ctx)).Row().Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// SaveStaff adds the user to the staff of the course or changes their role.
+func (r *Course) SaveStaff(ctx context.Context, member *model.CourseStaff) (*model.CourseStaff, error) {
+	query := `INSERT INTO course_staff (organization_id, course_id, user_id, role) VALUES (?, ?, ?, ?)
+	          ON CONFLICT (course_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = CURRENT_TIMESTAMP
+	          WHERE course_staff.organization_id = EXCLUDED.organization_id
+	          RETURNING created_at, updated_at`
+	err := r.DB.Raw(query, tenant.ID(ctx), member.CourseID, member.UserID, member.Role).Row().Scan(&member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The staff see the courses they are part of in their course list
+	return member, cache.Invalidate(ctx, r.Redis, "courses")
+}
+
+func (r *Course) DeleteStaff(ctx context.Context, courseID uint64, userID uint64) error {
+	query := `DELETE FROM course_staff WHERE course_id = ? AND user_id = ? AND organization_id = ?`
+	if err := r.DB.Exec(query, courseID, userID, tenant.ID(ctx)).Error; err != nil {
+		return err
+	}
+	return cache.Invalidate(ctx, r.Redis, "courses")
+}
+
+func (r *Course) CountOwners(ctx context.Context, courseID uint64) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM course_staff WHERE course_id = ? AND role = 'owner' AND organization_id = ?`
+	err := r.DB.Raw(query, courseID, tenant.ID(ctx)).Scan(&count).Error
+	return count, err
+}
@@ -6,6 +6,7 @@ import (
 	"gorm.io/gorm"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
 type Curriculum struct {
@@ -19,15 +20,15 @@ func NewCurriculum(db *gorm.DB) *Curriculum {
 }
 
 func (r *Curriculum) CreateCurriculum(ctx context.Context, courseID uint64, createDTO *model.Curriculum) error {
-	query := `INSERT INTO curriculums (course_id, 
-	section_name, 'order', created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
-	err := r.DB.Exec(query, courseID, createDTO.SectionName, createDTO.SectionOrder, createDTO.CreatedAt, createDTO.UpdatedAt).Error
+	query := `INSERT INTO curriculums (organization_id, course_id, 
+	section_name, 'order', created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`
+	err := r.DB.Exec(query, tenant.ID(ctx), courseID, createDTO.SectionName, createDTO.SectionOrder, createDTO.CreatedAt, createDTO.UpdatedAt).Error
 	return err
 }
 
 func (r *Curriculum) GetCurriculum(ctx context.Context, id uint64) ([]*model.Curriculum, error) {
-	query := `SELECT id, course_id, section_name, 'order', created_at, updated_at FROM curriculums WHERE course_id = ?`
-	rows, err := r.DB.Raw(query, id).Rows()
+	query := `SELECT id, course_id, section_name, 'order', created_at, updated_at FROM curriculums WHERE course_id = ? AND organization_id = ?`
+	rows, err := r.DB.Raw(query, id, tenant.ID(ctx)).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -47,13 +48,13 @@ func (r *Curriculum) GetCurriculum(ctx context.Context, id uint64) ([]*model.Cur
 }
 
 func (r *Curriculum) UpdateCurriculum(ctx context.Context, curriculum *model.Curriculum) error {
-	query := `UPDATE curriculums SET section_name = ?, 'order' = ?, updated_at = ? WHERE id = ?`
-	err := r.DB.Exec(query, curriculum.SectionName, curriculum.SectionOrder, curriculum.UpdatedAt, curriculum.ID).Error
+	query := `UPDATE curriculums SET section_name = ?, 'order' = ?, updated_at = ? WHERE id = ? AND organization_id = ?`
+	err := r.DB.Exec(query, curriculum.SectionName, curriculum.SectionOrder, curriculum.UpdatedAt, curriculum.ID, tenant.ID(ctx)).Error
 	return err
 }
 
 func (r *Curriculum) DeleteCurriculum(ctx context.Context, id uint64) error {
-	query := `DELETE FROM curriculums WHERE id = ?`
-	err := r.DB.Exec(query, id).Error
+	query := `DELETE FROM curriculums WHERE id = ? AND organization_id = ?`
+	err := r.DB.Exec(query, id, tenant.ID(ctx)).Error
 	return err
 }
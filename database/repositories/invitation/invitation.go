@@ -0,0 +1,183 @@
+package invitation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+const columns = `id, email, name, role, course_id, course_role, invited_by, user_id, expires_at, accepted_at, revoked_at, created_at, updated_at`
+
+// open matches invitations that were neither accepted nor revoked.
+const open = `accepted_at IS NULL AND revoked_at IS NULL`
+
+// errNotOpen is returned when acting on an invitation that was already
+// accepted or revoked, or that does not exist.
+var errNotOpen = errors.New("invitation not found or no longer open")
+
+type Invitation struct {
+	DB *gorm.DB
+}
+
+func NewInvitation(db *gorm.DB) *Invitation {
+	return &Invitation{
+		DB: db,
+	}
+}
+
+func (r *Invitation) Create(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error) {
+	query := `INSERT INTO invitations (organization_id, email, name, role, course_id, course_role, token_hash, invited_by, expires_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at, updated_at`
+	err := r.DB.Raw(query, tenant.ID(ctx), invitation.Email, invitation.Name, invitation.Role, invitation.CourseID, invitation.CourseRole,
+		invitation.TokenHash, invitation.InvitedBy, invitation.ExpiresAt).
+		Row().Scan(&invitation.ID, &invitation.CreatedAt, &invitation.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (r *Invitation) GetByID(ctx context.Context, id uint64) (*model.Invitation, error) {
+	return scan(r.DB.Raw(`SELECT `+columns+` FROM invitations WHERE id = ? AND organization_id = ?`, id, tenant.ID(ctx)).Row())
+}
+
+// GetOpenByEmail returns the invitation of the email that was neither
+// accepted nor revoked, or nil if there is none.
+func (r *Invitation) GetOpenByEmail(ctx context.Context, email string) (*model.Invitation, error) {
+	query := `SELECT ` + columns + ` FROM invitations WHERE LOWER(email) = LOWER(?) AND organization_id = ? AND ` + open
+	invitation, err := scan(r.DB.Raw(query, email, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return invitation, err
+}
+
+// GetPendingByTokenHash returns the open, unexpired invitation holding the
+// token of the given hash.
+func (r *Invitation) GetPendingByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	query := `SELECT ` + columns + ` FROM invitations WHERE token_hash = ? AND organization_id = ? AND ` + open + ` AND expires_at > CURRENT_TIMESTAMP`
+	return scan(r.DB.Raw(query, tokenHash, tenant.ID(ctx)).Row())
+}
+
+// List returns the page of the invitations having the given status, most
+// recent first unless sorted otherwise. An empty status matches every
+// invitation.
+func (r *Invitation) List(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.Invitation], error) {
+	base := `SELECT ` + columns + ` FROM invitations WHERE organization_id = ?`
+	switch status {
+	case "pending":
+		base += ` AND ` + open + ` AND expires_at > CURRENT_TIMESTAMP`
+	case "expired":
+		base += ` AND ` + open + ` AND expires_at <= CURRENT_TIMESTAMP`
+	case "accepted":
+		base += ` AND accepted_at IS NOT NULL`
+	case "revoked":
+		base += ` AND revoked_at IS NOT NULL`
+	}
+
+	return listing.Fetch(r.DB, invitationSchema, q, base, []any{tenant.ID(ctx)}, scan)
+}
+
+var invitationSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"email":      {Column: "email", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"role":       {Column: "role", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"course_id":  {Column: "course_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"invited_by": {Column: "invited_by", Type: listing.Int, Operators: []string{listing.Eq}},
+		"expires_at": {Column: "expires_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id", Desc: true}},
+}
+
+// Renew replaces the token of an open invitation and pushes back its expiry,
+// invalidating the links sent before.
+func (r *Invitation) Renew(ctx context.Context, id uint64, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	query := `UPDATE invitations SET token_hash = ?, expires_at = ?, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ? AND organization_id = ? AND ` + open + ` RETURNING ` + columns
+	invitation, err := scan(r.DB.Raw(query, tokenHash, expiresAt, id, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, errNotOpen
+	}
+	return invitation, err
+}
+
+func (r *Invitation) Revoke(ctx context.Context, id uint64) (*model.Invitation, error) {
+	query := `UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ? AND organization_id = ? AND ` + open + ` RETURNING ` + columns
+	invitation, err := scan(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, errNotOpen
+	}
+	return invitation, err
+}
+
+// Accept creates the invited user with the pre-assigned role, adds them to
+// the staff of the course if any, and closes the invitation, in a single
+// transaction. The invitation row is locked so a token cannot be redeemed
+// twice.
+func (r *Invitation) Accept(ctx context.Context, tokenHash string, user *model.User) (*model.Invitation, error) {
+	organizationID := tenant.ID(ctx)
+	var invitation *model.Invitation
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `SELECT ` + columns + ` FROM invitations
+		          WHERE token_hash = ? AND organization_id = ? AND ` + open + ` AND expires_at > CURRENT_TIMESTAMP FOR UPDATE`
+		var err error
+		invitation, err = scan(tx.Raw(query, tokenHash, organizationID).Row())
+		if err == sql.ErrNoRows {
+			return errNotOpen
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.Name == "" {
+			user.Name = invitation.Name
+		}
+		user.Email = invitation.Email
+		user.Role = invitation.Role
+
+		query = `INSERT INTO users (organization_id, name, email, password_hash, role) VALUES (?, ?, ?, ?, ?) RETURNING id, created_at, updated_at`
+		err = tx.Raw(query, organizationID, user.Name, user.Email, user.PasswordHash, user.Role).Row().Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if invitation.CourseID != nil {
+			query = `INSERT INTO course_staff (organization_id, course_id, user_id, role) VALUES (?, ?, ?, ?)`
+			if err := tx.Exec(query, organizationID, *invitation.CourseID, user.ID, *invitation.CourseRole).Error; err != nil {
+				return err
+			}
+		}
+
+		query = `UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP, user_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+		         RETURNING accepted_at, updated_at`
+		invitation.UserID = &user.ID
+		return tx.Raw(query, user.ID, invitation.ID).Row().Scan(&invitation.AcceptedAt, &invitation.UpdatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func scan(row listing.Scanner) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := row.Scan(&invitation.ID, &invitation.Email, &invitation.Name, &invitation.Role, &invitation.CourseID, &invitation.CourseRole,
+		&invitation.InvitedBy, &invitation.UserID, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.RevokedAt,
+		&invitation.CreatedAt, &invitation.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
@@ -2,12 +2,15 @@ package material
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"gorm.io/gorm"
 
 	"github.com/dapthehuman/learning-management-system/cache"
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -23,25 +26,34 @@ func NewMaterial(db *gorm.DB, redis redis.UniversalClient) *Material {
 	}
 }
 
+// Create adds the material after the last one of its curriculum.
 func (r *Material) Create(ctx context.Context, material *model.Material) (*model.Material, error) {
-	query := `INSERT INTO materials (curriculum_id, material_type, content, "order", created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Content is reordered one change at a time for each course
+		query := `SELECT 1 FROM courses WHERE id = (SELECT course_id FROM curriculums WHERE id = ? AND organization_id = ?) FOR UPDATE`
+		if err := tx.Exec(query, material.CurriculumID, tenant.ID(ctx)).Error; err != nil {
+			return err
+		}
 
-	row := r.DB.Raw(query, material.CurriculumID, material.MaterialType, material.Content, material.Order, material.CreatedAt, material.UpdatedAt).Row()
-	err := row.Scan(&material.ID, &material.CreatedAt, &material.UpdatedAt)
+		query = `INSERT INTO materials (organization_id, curriculum_id, material_type, content, "order", created_at, updated_at)
+			VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX("order"), 0) + 1 FROM materials WHERE curriculum_id = ?), ?, ?)
+			RETURNING id, "order", created_at, updated_at`
+		row := tx.Raw(query, tenant.ID(ctx), material.CurriculumID, material.MaterialType, material.Content, material.CurriculumID, material.CreatedAt, material.UpdatedAt).Row()
+		return row.Scan(&material.ID, &material.Order, &material.CreatedAt, &material.UpdatedAt)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return material, nil
+	return material, cache.Invalidate(ctx, r.Redis, fmt.Sprintf("materials:curriculum:%d", material.CurriculumID))
 }
 
 func (r *Material) GetByID(ctx context.Context, id uint64) (*model.Material, error) {
 	cacheKey := fmt.Sprintf("material:%d", id)
 
 	return cache.Cache(ctx, r.Redis, cacheKey, func() (*model.Material, error) {
-		query := `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials WHERE id = $1`
-		row := r.DB.Raw(query, id).Row()
+		query := `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials WHERE id = $1 AND organization_id = $2`
+		row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
 
 		material := &model.Material{}
 		err := row.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order, &material.CreatedAt, &material.UpdatedAt)
@@ -53,48 +65,192 @@ func (r *Material) GetByID(ctx context.Context, id uint64) (*model.Material, err
 	})
 }
 
-func (r *Material) GetByCurriculumID(ctx context.Context, curriculumID uint64) ([]*model.Material, error) {
-	cacheKey := fmt.Sprintf("materials:curriculum:%d", curriculumID)
+// ListByCurriculum returns a page of the materials of the curriculum, in
+// their order by default.
+func (r *Material) ListByCurriculum(ctx context.Context, curriculumID uint64, q *listing.Query) (*listing.Page[*model.Material], error) {
+	namespace := fmt.Sprintf("materials:curriculum:%d", curriculumID)
+	base := `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials WHERE curriculum_id = ? AND organization_id = ?`
+
+	return cache.Versioned(ctx, r.Redis, namespace, q.Key(), func() (*listing.Page[*model.Material], error) {
+		return listing.Fetch(r.DB, materialSchema, q, base, []any{curriculumID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.Material, error) {
+			material := &model.Material{}
+			err := row.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order, &material.CreatedAt, &material.UpdatedAt)
+			return material, err
+		})
+	})
+}
+
+var materialSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":            {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"material_type": {Column: "material_type", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"order":         {Column: `"order"`, Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.Gte, listing.Lte}},
+		"created_at":    {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at":    {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "order"}},
+}
+
+// Update updates the material. Its place is changed by moving it.
+func (r *Material) Update(ctx context.Context, material *model.Material) (*model.Material, error) {
+	query := `UPDATE materials SET curriculum_id = $1, material_type = $2, content = $3, updated_at = $4 WHERE id = $5 AND organization_id = $6 RETURNING id, "order", created_at, updated_at`
+	row := r.DB.Raw(query, material.CurriculumID, material.MaterialType, material.Content, material.UpdatedAt, material.ID, tenant.ID(ctx)).Row()
+	err := row.Scan(&material.ID, &material.Order, &material.CreatedAt, &material.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return material, r.forget(ctx, uint64(material.ID), material.CurriculumID)
+}
+
+func (r *Material) Delete(ctx context.Context, id uint64) error {
+	var curriculumID int
+	query := `DELETE FROM materials WHERE id = $1 AND organization_id = $2 RETURNING curriculum_id`
+	err := r.DB.Raw(query, id, tenant.ID(ctx)).Row().Scan(&curriculumID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.forget(ctx, id, curriculumID)
+}
 
-	return cache.Cache(ctx, r.Redis, cacheKey, func() ([]*model.Material, error) {
-		query := `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials WHERE curriculum_id = $1`
-		rows, err := r.DB.Raw(query, curriculumID).Rows()
+// Move moves the material to the curriculum, at the position among its
+// materials, starting at 1. The materials of its former and new curriculum
+// are renumbered from 1 in the same transaction.
+// It returns the materials whose curriculum or order changed, nil if the
+// material does not exist.
+func (r *Material) Move(ctx context.Context, id uint64, curriculumID uint64, position int) ([]*model.Material, error) {
+	var from uint64
+	var moved []*model.Material
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Content is reordered one change at a time for each course
+		var courseID uint64
+		query := `SELECT c.course_id, m.curriculum_id FROM materials m JOIN curriculums c ON c.id = m.curriculum_id
+			WHERE m.id = ? AND m.organization_id = ?`
+		err := tx.Raw(query, id, tenant.ID(ctx)).Row().Scan(&courseID, &from)
+		if err == sql.ErrNoRows {
+			return nil
+		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := tx.Exec(`SELECT 1 FROM courses WHERE id = ? FOR UPDATE`, courseID).Error; err != nil {
+			return err
+		}
+
+		changed := make([]uint64, 0)
+		query = `UPDATE materials SET curriculum_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND curriculum_id <> ?`
+		result := tx.Exec(query, curriculumID, id, curriculumID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			changed = append(changed, id)
+		}
+
+		siblings, err := curriculumMaterials(tx, curriculumID, id)
+		if err != nil {
+			return err
+		}
+		if position < 1 || position > len(siblings) {
+			position = len(siblings) + 1
+		}
+		siblings = append(siblings[:position-1], append([]uint64{id}, siblings[position-1:]...)...)
+		renumbered, err := renumberMaterials(tx, siblings)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, renumbered...)
+
+		if from != curriculumID {
+			siblings, err := curriculumMaterials(tx, from, id)
+			if err != nil {
+				return err
+			}
+			renumbered, err := renumberMaterials(tx, siblings)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, renumbered...)
+		}
+
+		query = `SELECT id, curriculum_id, material_type, content, "order", created_at, updated_at FROM materials
+			WHERE id IN ? ORDER BY curriculum_id, "order", id`
+		rows, err := tx.Raw(query, changed).Rows()
+		if err != nil {
+			return err
 		}
 		defer rows.Close()
 
-		materials := []*model.Material{}
+		moved = make([]*model.Material, 0, len(changed))
 		for rows.Next() {
 			material := &model.Material{}
 			err := rows.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order, &material.CreatedAt, &material.UpdatedAt)
 			if err != nil {
-				return nil, err
+				return err
 			}
-			materials = append(materials, material)
+			moved = append(moved, material)
 		}
-
-		return materials, nil
+		return rows.Err()
 	})
+	if err != nil || moved == nil {
+		return nil, err
+	}
+
+	for _, material := range moved {
+		if err := r.forget(ctx, uint64(material.ID), material.CurriculumID); err != nil {
+			return nil, err
+		}
+	}
+	return moved, cache.Invalidate(ctx, r.Redis, fmt.Sprintf("materials:curriculum:%d", from))
 }
 
-func (r *Material) Update(ctx context.Context, material *model.Material) (*model.Material, error) {
-	query := `UPDATE materials SET curriculum_id = $1, material_type = $2, content = $3, "order" = $4, updated_at = $5 WHERE id = $6 RETURNING id, created_at, updated_at`
-	row := r.DB.Raw(query, material.CurriculumID, material.MaterialType, material.Content, material.Order, material.UpdatedAt, material.ID).Row()
-	err := row.Scan(&material.ID, &material.CreatedAt, &material.UpdatedAt)
+// curriculumMaterials returns the materials of the curriculum in their order,
+// without the excluded one.
+func curriculumMaterials(tx *gorm.DB, curriculumID uint64, excluded uint64) ([]uint64, error) {
+	query := `SELECT id FROM materials WHERE curriculum_id = ? AND id <> ? ORDER BY "order", id`
+	rows, err := tx.Raw(query, curriculumID, excluded).Rows()
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return material, nil
+	ids := make([]uint64, 0)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
-func (r *Material) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM materials WHERE id = $1`
-	result := r.DB.Exec(query, id)
-	if result.Error != nil {
-		return result.Error
+// renumberMaterials orders the materials as listed, from 1, and returns the
+// ones whose order changed.
+func renumberMaterials(tx *gorm.DB, ids []uint64) ([]uint64, error) {
+	changed := make([]uint64, 0)
+	query := `UPDATE materials SET "order" = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND "order" IS DISTINCT FROM ?`
+	for i, id := range ids {
+		result := tx.Exec(query, i+1, id, i+1)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			changed = append(changed, id)
+		}
 	}
+	return changed, nil
+}
 
-	return nil
+// forget drops the cached material and the cached material list of its
+// curriculum.
+func (r *Material) forget(ctx context.Context, id uint64, curriculumID int) error {
+	if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("material:%d", id)); err != nil {
+		return err
+	}
+	return cache.Invalidate(ctx, r.Redis, fmt.Sprintf("materials:curriculum:%d", curriculumID))
 }
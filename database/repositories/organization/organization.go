@@ -0,0 +1,76 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const columns = `id, slug, name, settings, created_at, updated_at`
+
+// Organization stores the organizations themselves. Unlike the other
+// repositories it is not scoped to the organization of the context: it is
+// what tenants are resolved with.
+type Organization struct {
+	DB    *gorm.DB
+	Redis redis.UniversalClient
+}
+
+func NewOrganization(db *gorm.DB, redis redis.UniversalClient) *Organization {
+	return &Organization{
+		DB:    db,
+		Redis: redis,
+	}
+}
+
+// GetBySlug is called on every request to resolve the tenant, so the result
+// is cached.
+func (r *Organization) GetBySlug(ctx context.Context, slug string) (*model.Organization, error) {
+	key := fmt.Sprintf("organization:slug:%s", slug)
+	query := `SELECT ` + columns + ` FROM organizations WHERE slug = ?`
+
+	return cache.Global(ctx, r.Redis, key, func() (*model.Organization, error) {
+		return scan(r.DB.Raw(query, slug).Row())
+	})
+}
+
+func (r *Organization) GetByID(ctx context.Context, id uint64) (*model.Organization, error) {
+	return scan(r.DB.Raw(`SELECT `+columns+` FROM organizations WHERE id = ?`, id).Row())
+}
+
+func (r *Organization) Create(ctx context.Context, organization *model.Organization) (*model.Organization, error) {
+	query := `INSERT INTO organizations (slug, name, settings) VALUES (?, ?, ?) RETURNING ` + columns
+	return scan(r.DB.Raw(query, organization.Slug, organization.Name, string(organization.Settings)).Row())
+}
+
+// Update saves the name and settings of the organization. The slug cannot
+// change: it is part of the URLs given to users and identity providers.
+func (r *Organization) Update(ctx context.Context, organization *model.Organization) (*model.Organization, error) {
+	query := `UPDATE organizations SET name = ?, settings = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING ` + columns
+	updated, err := scan(r.DB.Raw(query, organization.Name, string(organization.Settings), organization.ID).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, r.Redis.Del(ctx, fmt.Sprintf("organization:slug:%s", updated.Slug)).Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scan(row scanner) (*model.Organization, error) {
+	var organization model.Organization
+	var settings string
+	err := row.Scan(&organization.ID, &organization.Slug, &organization.Name, &settings, &organization.CreatedAt, &organization.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	organization.Settings = []byte(settings)
+	return &organization, nil
+}
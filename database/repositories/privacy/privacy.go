@@ -0,0 +1,324 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	exportColumns  = `id, user_id, status, error, expires_at, created_at, completed_at`
+	erasureColumns = `id, user_id, status, reason, reviewed_by, review_note, reviewed_at, created_at`
+)
+
+// errNotPending is returned when reviewing an erasure request that was
+// already reviewed, or that does not exist.
+var errNotPending = errors.New("erasure request not found or already reviewed")
+
+// errLastOwner is returned when erasing the only owner of a course.
+var errLastOwner = errors.New("the user is the only owner of some courses, transfer their ownership first")
+
+// userDataQueries select each section of the personal data of a user as a
+// JSON document. They take the user ID and the organization ID.
+var userDataQueries = map[string]string{
+	"profile": `SELECT row_to_json(t) FROM (
+		SELECT id, name, email, role, created_at, updated_at FROM users WHERE id = ? AND organization_id = ?
+	) t`,
+	"enrollments": `SELECT COALESCE(json_agg(t ORDER BY t.enrolled_at), '[]') FROM (
+		SELECT e.id, e.course_id, c.title AS course_title, e.enrolled_at
+		FROM enrollments e JOIN courses c ON c.id = e.course_id WHERE e.user_id = ? AND e.organization_id = ?
+	) t`,
+	"progress": `SELECT COALESCE(json_agg(t ORDER BY t.updated_at), '[]') FROM (
+		SELECT id, curriculum_id, material_id, status, progress_percentage, updated_at
+		FROM progress_tracking WHERE user_id = ? AND organization_id = ?
+	) t`,
+	"learning_paths": `SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+		SELECT id, curriculum_id, status, progress_percentage, created_at, updated_at
+		FROM student_paths WHERE user_id = ? AND organization_id = ?
+	) t`,
+	"submissions": `SELECT COALESCE(json_agg(t ORDER BY t.submitted_at), '[]') FROM (
+		SELECT s.id, s.assessment_id, a.course_id, a.question, s.answer, s.grade, s.submitted_at
+		FROM submissions s JOIN assessments a ON a.id = s.assessment_id WHERE s.user_id = ? AND s.organization_id = ?
+	) t`,
+	"achievements": `SELECT COALESCE(json_agg(t ORDER BY t.awarded_at), '[]') FROM (
+		SELECT id, course_id, achievement_type, description, awarded_at
+		FROM achievements WHERE user_id = ? AND organization_id = ?
+	) t`,
+}
+
+type Privacy struct {
+	DB    *gorm.DB
+	redis redis.UniversalClient
+}
+
+func NewPrivacy(db *gorm.DB, redis redis.UniversalClient) *Privacy {
+	return &Privacy{
+		DB:    db,
+		redis: redis,
+	}
+}
+
+func (r *Privacy) CreateExport(ctx context.Context, userID uint64) (*model.DataExport, error) {
+	query := `INSERT INTO data_exports (organization_id, user_id) VALUES (?, ?) RETURNING ` + exportColumns
+	return scanExport(r.DB.Raw(query, tenant.ID(ctx), userID).Row())
+}
+
+// GetLatestExport returns the most recent export of the user, or nil if
+// they never requested one.
+func (r *Privacy) GetLatestExport(ctx context.Context, userID uint64) (*model.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE user_id = ? AND organization_id = ? ORDER BY id DESC LIMIT 1`
+	export, err := scanExport(r.DB.Raw(query, userID, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return export, err
+}
+
+// GetExportArchive returns the export of the user with its archive.
+func (r *Privacy) GetExportArchive(ctx context.Context, userID uint64, id uint64) (*model.DataExport, error) {
+	query := `SELECT ` + exportColumns + `, archive FROM data_exports WHERE id = ? AND user_id = ? AND organization_id = ?`
+
+	var export model.DataExport
+	err := r.DB.Raw(query, id, userID, tenant.ID(ctx)).Row().Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.ExpiresAt,
+		&export.CreatedAt, &export.CompletedAt, &export.Archive)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *Privacy) CompleteExport(ctx context.Context, id uint64, archive []byte, expiresAt time.Time) error {
+	query := `UPDATE data_exports SET status = 'completed', archive = ?, expires_at = ?, completed_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	return r.DB.Exec(query, archive, expiresAt, id, tenant.ID(ctx)).Error
+}
+
+func (r *Privacy) FailExport(ctx context.Context, id uint64, message string) error {
+	query := `UPDATE data_exports SET status = 'failed', error = ?, completed_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	return r.DB.Exec(query, message, id, tenant.ID(ctx)).Error
+}
+
+// GetUserData collects every section of the personal data of the user.
+func (r *Privacy) GetUserData(ctx context.Context, userID uint64) (model.UserData, error) {
+	data := make(model.UserData, len(userDataQueries))
+	for section, query := range userDataQueries {
+		var document []byte
+		if err := r.DB.Raw(query, userID, tenant.ID(ctx)).Row().Scan(&document); err != nil {
+			return nil, fmt.Errorf("could not export %s: %w", section, err)
+		}
+		data[section] = json.RawMessage(document)
+	}
+	return data, nil
+}
+
+func (r *Privacy) CreateErasureRequest(ctx context.Context, userID uint64, reason string) (*model.ErasureRequest, error) {
+	query := `INSERT INTO erasure_requests (organization_id, user_id, reason) VALUES (?, ?, ?) RETURNING ` + erasureColumns
+	return scanErasure(r.DB.Raw(query, tenant.ID(ctx), userID, reason).Row())
+}
+
+// GetLatestErasureRequest returns the most recent erasure request of the
+// user, or nil if they never made one.
+func (r *Privacy) GetLatestErasureRequest(ctx context.Context, userID uint64) (*model.ErasureRequest, error) {
+	query := `SELECT ` + erasureColumns + ` FROM erasure_requests WHERE user_id = ? AND organization_id = ? ORDER BY id DESC LIMIT 1`
+	request, err := scanErasure(r.DB.Raw(query, userID, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return request, err
+}
+
+func (r *Privacy) GetErasureRequest(ctx context.Context, id uint64) (*model.ErasureRequest, error) {
+	query := `SELECT ` + erasureColumns + ` FROM erasure_requests WHERE id = ? AND organization_id = ?`
+	return scanErasure(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+}
+
+// ListErasureRequests returns the page of the erasure requests having the
+// given status, oldest first unless sorted otherwise. An empty status
+// matches every request.
+func (r *Privacy) ListErasureRequests(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.ErasureRequest], error) {
+	base := `SELECT ` + erasureColumns + ` FROM erasure_requests WHERE organization_id = ?`
+	args := []any{tenant.ID(ctx)}
+	if status != "" {
+		base += ` AND status = ?`
+		args = append(args, status)
+	}
+
+	return listing.Fetch(r.DB, erasureSchema, q, base, args, scanErasure)
+}
+
+var erasureSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":          {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"user_id":     {Column: "user_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"reviewed_by": {Column: "reviewed_by", Type: listing.Int, Operators: []string{listing.Eq}},
+		"reviewed_at": {Column: "reviewed_at", Type: listing.Time, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at":  {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
+}
+
+func (r *Privacy) RejectErasureRequest(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error) {
+	query := `UPDATE erasure_requests SET status = 'rejected', reviewed_by = ?, review_note = ?, reviewed_at = CURRENT_TIMESTAMP
+	          WHERE id = ? AND organization_id = ? AND status = 'pending' RETURNING ` + erasureColumns
+	request, err := scanErasure(r.DB.Raw(query, reviewerID, note, id, tenant.ID(ctx)).Row())
+	if err == sql.ErrNoRows {
+		return nil, errNotPending
+	}
+	return request, err
+}
+
+// Erase approves the erasure request and anonymizes the personal data of
+// its user, in a single transaction.
+//
+// The user row is kept so enrollments, progress and grades still count
+// towards course statistics, but everything identifying the user is
+// replaced or deleted: name, email, credentials, linked identities, API
+// tokens, submission answers, achievement descriptions, data exports and
+// emails recorded in invitations and audit logs. The user leaves the staff
+// of courses and cohorts, their cohorts and the waitlists. The account can
+// no longer be used to sign in.
+//
+// Erasing the only owner of a course is refused, as removing them from the
+// staff would leave the course without an owner.
+func (r *Privacy) Erase(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error) {
+	organizationID := tenant.ID(ctx)
+	var request *model.ErasureRequest
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		query := `SELECT ` + erasureColumns + ` FROM erasure_requests WHERE id = ? AND organization_id = ? AND status = 'pending' FOR UPDATE`
+		request, err = scanErasure(tx.Raw(query, id, organizationID).Row())
+		if err == sql.ErrNoRows {
+			return errNotPending
+		}
+		if err != nil {
+			return err
+		}
+
+		var email string
+		if err := tx.Raw(`SELECT email FROM users WHERE id = ? FOR UPDATE`, request.UserID).Row().Scan(&email); err != nil {
+			return err
+		}
+
+		if err := checkOwnership(tx, request.UserID); err != nil {
+			return err
+		}
+		anonymousEmail := fmt.Sprintf("erased-%d@erased.invalid", request.UserID)
+
+		statements := []struct {
+			query string
+			args  []any
+		}{
+			{`UPDATE users SET name = 'Erased user', email = ?, password_hash = '', password_reset_token_hash = NULL, password_reset_expires_at = NULL, external_id = NULL,
+			  suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP), erased_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+				[]any{anonymousEmail, request.UserID}},
+			{`DELETE FROM user_identities WHERE user_id = ?`, []any{request.UserID}},
+			{`DELETE FROM api_tokens WHERE user_id = ?`, []any{request.UserID}},
+			{`DELETE FROM cohort_staff WHERE user_id = ?`, []any{request.UserID}},
+			{`DELETE FROM course_staff WHERE user_id = ?`, []any{request.UserID}},
+			{`DELETE FROM course_waitlist WHERE user_id = ?`, []any{request.UserID}},
+			// Enrollments are kept for course statistics, without the cohort
+			{`UPDATE enrollments SET cohort_id = NULL WHERE user_id = ?`, []any{request.UserID}},
+			{`DELETE FROM data_exports WHERE user_id = ?`, []any{request.UserID}},
+			// Grades are kept for course statistics
+			{`UPDATE submissions SET answer = '' WHERE user_id = ?`, []any{request.UserID}},
+			{`UPDATE achievements SET description = '' WHERE user_id = ?`, []any{request.UserID}},
+			// Other organizations may have a user with the same email
+			{`UPDATE invitations SET email = ?, name = '' WHERE organization_id = ? AND (user_id = ? OR LOWER(email) = LOWER(?))`,
+				[]any{anonymousEmail, organizationID, request.UserID, email}},
+			{`UPDATE audit_logs SET metadata = metadata - 'email' WHERE organization_id = ? AND LOWER(metadata->>'email') = LOWER(?)`,
+				[]any{organizationID, email}},
+			{`UPDATE erasure_requests SET reason = '' WHERE user_id = ?`, []any{request.UserID}},
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement.query, statement.args...).Error; err != nil {
+				return err
+			}
+		}
+
+		query = `UPDATE erasure_requests SET status = 'approved', reviewed_by = ?, review_note = ?, reviewed_at = CURRENT_TIMESTAMP
+		         WHERE id = ? RETURNING ` + erasureColumns
+		request, err = scanErasure(tx.Raw(query, reviewerID, note, id).Row())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.Forget(ctx, r.redis, fmt.Sprintf("user:%d", request.UserID)); err != nil {
+		return nil, err
+	}
+
+	// The staff see the courses they are part of in their course list
+	return request, cache.Invalidate(ctx, r.redis, "courses")
+}
+
+// checkOwnership returns errLastOwner if the user is the only owner of a
+// course. The owners of the courses of the user are locked until the end of
+// the transaction, so none of them can leave in the meantime.
+func checkOwnership(tx *gorm.DB, userID uint64) error {
+	query := `SELECT course_id, user_id FROM course_staff
+	          WHERE role = 'owner' AND course_id IN (SELECT course_id FROM course_staff WHERE user_id = ? AND role = 'owner')
+	          ORDER BY course_id, user_id FOR UPDATE`
+	rows, err := tx.Raw(query, userID).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	owners := make(map[uint64]int)
+	courseIDs := make([]uint64, 0)
+	for rows.Next() {
+		var courseID, ownerID uint64
+		if err := rows.Scan(&courseID, &ownerID); err != nil {
+			return err
+		}
+		if _, ok := owners[courseID]; !ok {
+			courseIDs = append(courseIDs, courseID)
+		}
+		owners[courseID]++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	orphaned := make([]string, 0)
+	for _, courseID := range courseIDs {
+		if owners[courseID] == 1 {
+			orphaned = append(orphaned, fmt.Sprint(courseID))
+		}
+	}
+	if len(orphaned) > 0 {
+		return fmt.Errorf("%w: %s", errLastOwner, strings.Join(orphaned, ", "))
+	}
+	return nil
+}
+
+func scanExport(row listing.Scanner) (*model.DataExport, error) {
+	var export model.DataExport
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.ExpiresAt, &export.CreatedAt, &export.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func scanErasure(row listing.Scanner) (*model.ErasureRequest, error) {
+	var request model.ErasureRequest
+	err := row.Scan(&request.ID, &request.UserID, &request.Status, &request.Reason, &request.ReviewedBy, &request.ReviewNote,
+		&request.ReviewedAt, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
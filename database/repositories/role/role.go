@@ -0,0 +1,200 @@
+package role
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Role stores roles and permissions, which are shared by every organization.
+type Role struct {
+	DB    *gorm.DB
+	Redis redis.UniversalClient
+}
+
+func NewRole(db *gorm.DB, redis redis.UniversalClient) *Role {
+	return &Role{
+		DB:    db,
+		Redis: redis,
+	}
+}
+
+func (r *Role) GetAll(ctx context.Context) ([]*model.Role, error) {
+	query := `SELECT name, COALESCE(description, ''), is_system, created_at, updated_at FROM roles ORDER BY name`
+	rows, err := r.DB.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*model.Role, 0)
+	for rows.Next() {
+		var role model.Role
+		err := rows.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	for _, role := range roles {
+		if role.Permissions, err = r.GetPermissions(ctx, role.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+func (r *Role) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	query := `SELECT name, COALESCE(description, ''), is_system, created_at, updated_at FROM roles WHERE name = ?`
+	row := r.DB.Raw(query, name).Row()
+
+	var role model.Role
+	err := row.Scan(&role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Permissions, err = r.GetPermissions(ctx, name); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (r *Role) Exists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.DB.Raw(`SELECT COUNT(*) FROM roles WHERE name = ?`, name).Scan(&count).Error
+	return count > 0, err
+}
+
+// GetPermissions returns the permission names granted to a role.
+func (r *Role) GetPermissions(ctx context.Context, name string) ([]string, error) {
+	key := fmt.Sprintf("role:%s:permissions", name)
+	query := `SELECT permission FROM role_permissions WHERE role = ? ORDER BY permission`
+
+	return cache.Global(ctx, r.Redis, key, func() ([]string, error) {
+		rows, err := r.DB.Raw(query, name).Rows()
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		permissions := make([]string, 0)
+		for rows.Next() {
+			var permission string
+			if err := rows.Scan(&permission); err != nil {
+				return nil, err
+			}
+			permissions = append(permissions, permission)
+		}
+
+		return permissions, nil
+	})
+}
+
+// Covers reports whether the holder role has every permission of the role.
+// Users may only grant roles their own role covers, so that they cannot give
+// anyone, themselves included, more than they have.
+func (r *Role) Covers(ctx context.Context, holder string, role string) (bool, error) {
+	held, err := r.GetPermissions(ctx, holder)
+	if err != nil {
+		return false, err
+	}
+	granted, err := r.GetPermissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+
+	for _, permission := range granted {
+		if !slices.Contains(held, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *Role) ListPermissions(ctx context.Context) ([]*model.Permission, error) {
+	query := `SELECT name, COALESCE(description, '') FROM permissions ORDER BY name`
+	rows, err := r.DB.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]*model.Permission, 0)
+	for rows.Next() {
+		var permission model.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, &permission)
+	}
+
+	return permissions, nil
+}
+
+// Create stores a new role with its permissions. The permissions of a role
+// are cached even while it does not exist, so the cache is cleared as well.
+func (r *Role) Create(ctx context.Context, role *model.Role) (*model.Role, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `INSERT INTO roles (name, description) VALUES (?, ?) RETURNING created_at, updated_at`
+		if err := tx.Raw(query, role.Name, role.Description).Row().Scan(&role.CreatedAt, &role.UpdatedAt); err != nil {
+			return err
+		}
+
+		return insertPermissions(tx, role.Name, role.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, r.Redis.Del(ctx, fmt.Sprintf("role:%s:permissions", role.Name)).Err()
+}
+
+// Update changes the description of a role and replaces its permission set.
+func (r *Role) Update(ctx context.Context, role *model.Role) (*model.Role, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `UPDATE roles SET description = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ? RETURNING updated_at`
+		if err := tx.Raw(query, role.Description, role.Name).Row().Scan(&role.UpdatedAt); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`DELETE FROM role_permissions WHERE role = ?`, role.Name).Error; err != nil {
+			return err
+		}
+
+		return insertPermissions(tx, role.Name, role.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, r.Redis.Del(ctx, fmt.Sprintf("role:%s:permissions", role.Name)).Err()
+}
+
+func (r *Role) Delete(ctx context.Context, name string) error {
+	query := `DELETE FROM roles WHERE name = ? AND is_system = FALSE`
+	if err := r.DB.Exec(query, name).Error; err != nil {
+		return err
+	}
+
+	return r.Redis.Del(ctx, fmt.Sprintf("role:%s:permissions", name)).Err()
+}
+
+func insertPermissions(tx *gorm.DB, role string, permissions []string) error {
+	query := `INSERT INTO role_permissions (role, permission) VALUES (?, ?) ON CONFLICT DO NOTHING`
+	for _, permission := range permissions {
+		if err := tx.Exec(query, role, permission).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
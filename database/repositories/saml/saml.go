@@ -0,0 +1,70 @@
+package saml
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+type Provider struct {
+	DB *gorm.DB
+}
+
+func NewProvider(db *gorm.DB) *Provider {
+	return &Provider{
+		DB: db,
+	}
+}
+
+// Get returns the identity provider of the organization.
+func (r *Provider) Get(ctx context.Context) (*model.SAMLProvider, error) {
+	query := `SELECT id, organization_id, entity_id, sso_url, certificate, default_role, email_attribute, name_attribute, role_attribute, allowed_roles, created_at, updated_at
+	          FROM saml_providers WHERE organization_id = ?`
+	row := r.DB.Raw(query, tenant.ID(ctx)).Row()
+
+	var provider model.SAMLProvider
+	var allowedRoles []byte
+	err := row.Scan(&provider.ID, &provider.OrganizationID, &provider.EntityID, &provider.SSOURL, &provider.Certificate, &provider.DefaultRole,
+		&provider.EmailAttribute, &provider.NameAttribute, &provider.RoleAttribute, &allowedRoles, &provider.CreatedAt, &provider.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider, json.Unmarshal(allowedRoles, &provider.AllowedRoles)
+}
+
+// Save creates or replaces the identity provider of an organization.
+func (r *Provider) Save(ctx context.Context, provider *model.SAMLProvider) (*model.SAMLProvider, error) {
+	allowedRoles, err := json.Marshal(provider.AllowedRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO saml_providers (organization_id, entity_id, sso_url, certificate, default_role, email_attribute, name_attribute, role_attribute, allowed_roles)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	          ON CONFLICT (organization_id) DO UPDATE SET entity_id = EXCLUDED.entity_id, sso_url = EXCLUDED.sso_url,
+	          certificate = EXCLUDED.certificate, default_role = EXCLUDED.default_role, email_attribute = EXCLUDED.email_attribute,
+	          name_attribute = EXCLUDED.name_attribute, role_attribute = EXCLUDED.role_attribute, allowed_roles = EXCLUDED.allowed_roles,
+	          updated_at = CURRENT_TIMESTAMP
+	          RETURNING id, created_at, updated_at`
+	provider.OrganizationID = tenant.ID(ctx)
+	row := r.DB.Raw(query, provider.OrganizationID, provider.EntityID, provider.SSOURL, provider.Certificate, provider.DefaultRole,
+		provider.EmailAttribute, provider.NameAttribute, provider.RoleAttribute, string(allowedRoles)).Row()
+
+	err = row.Scan(&provider.ID, &provider.CreatedAt, &provider.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (r *Provider) Delete(ctx context.Context) error {
+	query := `DELETE FROM saml_providers WHERE organization_id = ?`
+	err := r.DB.Exec(query, tenant.ID(ctx)).Error
+	return err
+}
@@ -0,0 +1,149 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+)
+
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindID
+	kindTime
+	kindActive // Derived from suspended_at
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+var userColumns = map[string]column{
+	"id":                {"id", kindID},
+	"userName":          {"email", kindText},
+	"emails.value":      {"email", kindText},
+	"displayName":       {"name", kindText},
+	"name.formatted":    {"name", kindText},
+	"externalId":        {"external_id", kindText},
+	"active":            {"suspended_at", kindActive},
+	"meta.created":      {"created_at", kindTime},
+	"meta.lastModified": {"updated_at", kindTime},
+}
+
+var groupColumns = map[string]column{
+	"id":                {"id", kindID},
+	"displayName":       {"name", kindText},
+	"externalId":        {"external_id", kindText},
+	"meta.created":      {"created_at", kindTime},
+	"meta.lastModified": {"updated_at", kindTime},
+}
+
+// where translates filter conditions into SQL conditions joined by AND,
+// appended to the base conditions taking the given arguments.
+func where(base []string, baseArgs []any, filters []model.FilterCondition, columns map[string]column) (string, []any, error) {
+	conditions := append(make([]string, 0, len(base)+len(filters)), base...)
+	args := append(make([]any, 0, len(baseArgs)+len(filters)), baseArgs...)
+	for _, filter := range filters {
+		column, ok := columns[filter.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported filter attribute %q", filter.Field)
+		}
+
+		condition, arg, err := compare(column, filter.Operator, filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		if arg != nil {
+			args = append(args, arg)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+func compare(column column, operator string, value any) (string, any, error) {
+	if operator == "pr" {
+		if column.kind == kindActive {
+			return "TRUE", nil, nil
+		}
+		return column.name + " IS NOT NULL", nil, nil
+	}
+
+	switch column.kind {
+	case kindActive:
+		active, ok := value.(bool)
+		if !ok || (operator != "eq" && operator != "ne") {
+			return "", nil, fmt.Errorf("active only supports eq and ne with a boolean")
+		}
+		if active == (operator == "eq") {
+			return column.name + " IS NULL", nil, nil
+		}
+		return column.name + " IS NOT NULL", nil, nil
+
+	case kindID:
+		var id uint64
+		switch v := value.(type) {
+		case string:
+			parsed, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				// Identifiers are numeric, nothing can match
+				return "FALSE", nil, nil
+			}
+			id = parsed
+		case float64:
+			id = uint64(v)
+		default:
+			return "", nil, fmt.Errorf("invalid id value")
+		}
+		switch operator {
+		case "eq":
+			return column.name + " = ?", id, nil
+		case "ne":
+			return column.name + " <> ?", id, nil
+		}
+		return "", nil, fmt.Errorf("id only supports eq and ne")
+
+	case kindTime:
+		comparisons := map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+		s, ok := value.(string)
+		if !ok || comparisons[operator] == "" {
+			return "", nil, fmt.Errorf("invalid date comparison")
+		}
+		return column.name + " " + comparisons[operator] + " ?", s, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		if value == nil && operator == "eq" {
+			return column.name + " IS NULL", nil, nil
+		}
+		return "", nil, fmt.Errorf("expected a string value")
+	}
+
+	// String attributes are compared case-insensitively
+	switch operator {
+	case "eq":
+		return "LOWER(" + column.name + ") = LOWER(?)", s, nil
+	case "ne":
+		return "(" + column.name + " IS NULL OR LOWER(" + column.name + ") <> LOWER(?))", s, nil
+	case "co":
+		return column.name + " ILIKE ?", "%" + escapeLike(s) + "%", nil
+	case "sw":
+		return column.name + " ILIKE ?", escapeLike(s) + "%", nil
+	case "ew":
+		return column.name + " ILIKE ?", "%" + escapeLike(s), nil
+	}
+	return "", nil, fmt.Errorf("operator %q is not supported for strings", operator)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,317 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userSelect  = `id, name, email, role, suspended_at, external_id, created_at, updated_at`
+	groupSelect = `id, course_id, name, external_id, created_at, updated_at`
+)
+
+// SCIM stores the users and cohorts managed by the provisioning directory.
+// Erased users are invisible to it.
+type SCIM struct {
+	DB    *gorm.DB
+	redis redis.UniversalClient
+}
+
+func NewSCIM(db *gorm.DB, redis redis.UniversalClient) *SCIM {
+	return &SCIM{
+		DB:    db,
+		redis: redis,
+	}
+}
+
+// ListUsers returns a page of the users matching every filter condition and
+// the total number of matching users.
+func (r *SCIM) ListUsers(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.User, int64, error) {
+	conditions, args, err := where([]string{"organization_id = ?", "erased_at IS NULL"}, []any{tenant.ID(ctx)}, filters, userColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.DB.Raw(`SELECT COUNT(*) FROM users`+conditions, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + userSelect + ` FROM users` + conditions + ` ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := r.DB.Raw(query, append(args, limit, offset)...).Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, nil
+}
+
+func (r *SCIM) GetUser(ctx context.Context, id uint64) (*model.User, error) {
+	query := `SELECT ` + userSelect + ` FROM users WHERE id = ? AND organization_id = ? AND erased_at IS NULL`
+	return scanUser(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+}
+
+// CreateUser inserts a user without a password: provisioned users sign in
+// through single sign-on, or choose a password after a reset.
+func (r *SCIM) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	query := `INSERT INTO users (organization_id, name, email, password_hash, role, external_id, suspended_at) VALUES (?, ?, ?, '', ?, ?, ?)
+	          RETURNING id, created_at, updated_at`
+	err := r.DB.Raw(query, tenant.ID(ctx), user.Name, user.Email, user.Role, user.ExternalID, user.SuspendedAt).
+		Row().Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser saves the attributes managed by the directory. The user is
+// suspended when SuspendedAt is set, keeping the date of an existing
+// suspension.
+func (r *SCIM) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	query := `UPDATE users SET name = ?, email = ?, external_id = ?,
+	          suspended_at = CASE WHEN ? THEN COALESCE(suspended_at, CURRENT_TIMESTAMP) END, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ? AND organization_id = ? AND erased_at IS NULL RETURNING ` + userSelect
+	updated, err := scanUser(r.DB.Raw(query, user.Name, user.Email, user.ExternalID, user.SuspendedAt != nil, user.ID, tenant.ID(ctx)).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, cache.Forget(ctx, r.redis, fmt.Sprintf("user:%d", user.ID))
+}
+
+// ExistingUsers returns which of the given user IDs exist.
+func (r *SCIM) ExistingUsers(ctx context.Context, ids []uint64) (map[uint64]bool, error) {
+	existing := make(map[uint64]bool)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.DB.Raw(`SELECT id FROM users WHERE id IN ? AND organization_id = ? AND erased_at IS NULL`, ids, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// ListGroups returns a page of the cohorts matching every filter condition,
+// with their members, and the total number of matching cohorts.
+func (r *SCIM) ListGroups(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.Cohort, int64, error) {
+	conditions, args, err := where([]string{"organization_id = ?"}, []any{tenant.ID(ctx)}, filters, groupColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.DB.Raw(`SELECT COUNT(*) FROM cohorts`+conditions, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + groupSelect + ` FROM cohorts` + conditions + ` ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := r.DB.Raw(query, append(args, limit, offset)...).Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cohorts := make([]*model.Cohort, 0, limit)
+	for rows.Next() {
+		cohort, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		cohorts = append(cohorts, cohort)
+	}
+
+	if err := r.loadMembers(ctx, r.DB, cohorts); err != nil {
+		return nil, 0, err
+	}
+	return cohorts, total, nil
+}
+
+func (r *SCIM) GetGroup(ctx context.Context, id uint64) (*model.Cohort, error) {
+	return r.getGroup(ctx, r.DB, id)
+}
+
+// CreateGroup inserts the cohort and enrolls its members in the course, or
+// moves their existing enrollment to the cohort.
+func (r *SCIM) CreateGroup(ctx context.Context, cohort *model.Cohort, memberIDs []uint64) (*model.Cohort, error) {
+	var created *model.Cohort
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `INSERT INTO cohorts (organization_id, course_id, name, external_id) VALUES (?, ?, ?, ?) RETURNING ` + groupSelect
+		var err error
+		created, err = scanGroup(tx.Raw(query, tenant.ID(ctx), cohort.CourseID, cohort.Name, cohort.ExternalID).Row())
+		if err != nil {
+			return err
+		}
+
+		if err := addMembers(ctx, tx, created, memberIDs); err != nil {
+			return err
+		}
+
+		created, err = r.getGroup(ctx, tx, created.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateGroup saves the attributes of the cohort, then adds and removes
+// members, in a single transaction. Removed members stay enrolled in the
+// course so their progress is kept.
+func (r *SCIM) UpdateGroup(ctx context.Context, cohort *model.Cohort, add []uint64, remove []uint64) (*model.Cohort, error) {
+	var updated *model.Cohort
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `UPDATE cohorts SET name = ?, external_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? RETURNING ` + groupSelect
+		var err error
+		updated, err = scanGroup(tx.Raw(query, cohort.Name, cohort.ExternalID, cohort.ID, tenant.ID(ctx)).Row())
+		if err != nil {
+			return err
+		}
+
+		if len(remove) > 0 {
+			query = `UPDATE enrollments SET cohort_id = NULL WHERE cohort_id = ? AND user_id IN ? AND organization_id = ?`
+			if err := tx.Exec(query, cohort.ID, remove, tenant.ID(ctx)).Error; err != nil {
+				return err
+			}
+		}
+		if err := addMembers(ctx, tx, updated, add); err != nil {
+			return err
+		}
+
+		updated, err = r.getGroup(ctx, tx, cohort.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *SCIM) DeleteGroup(ctx context.Context, id uint64) error {
+	result := r.DB.Exec(`DELETE FROM cohorts WHERE id = ? AND organization_id = ?`, id, tenant.ID(ctx))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *SCIM) getGroup(ctx context.Context, db *gorm.DB, id uint64) (*model.Cohort, error) {
+	cohort, err := scanGroup(db.Raw(`SELECT `+groupSelect+` FROM cohorts WHERE id = ? AND organization_id = ?`, id, tenant.ID(ctx)).Row())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadMembers(ctx, db, []*model.Cohort{cohort}); err != nil {
+		return nil, err
+	}
+	return cohort, nil
+}
+
+func (r *SCIM) loadMembers(ctx context.Context, db *gorm.DB, cohorts []*model.Cohort) error {
+	if len(cohorts) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint64]*model.Cohort, len(cohorts))
+	ids := make([]uint64, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		cohort.Members = make([]*model.CohortMember, 0)
+		byID[cohort.ID] = cohort
+		ids = append(ids, cohort.ID)
+	}
+
+	query := `SELECT e.cohort_id, u.id, u.name FROM enrollments e JOIN users u ON u.id = e.user_id
+	          WHERE e.cohort_id IN ? AND e.organization_id = ? AND u.erased_at IS NULL ORDER BY u.id`
+	rows, err := db.Raw(query, ids, tenant.ID(ctx)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cohortID uint64
+		var member model.CohortMember
+		if err := rows.Scan(&cohortID, &member.UserID, &member.Name); err != nil {
+			return err
+		}
+		byID[cohortID].Members = append(byID[cohortID].Members, &member)
+	}
+	return nil
+}
+
+// addMembers assigns the enrollment of each user in the course of the cohort
+// to the cohort, enrolling them first if needed.
+func addMembers(ctx context.Context, tx *gorm.DB, cohort *model.Cohort, userIDs []uint64) error {
+	for _, userID := range userIDs {
+		query := `UPDATE enrollments SET cohort_id = ? WHERE user_id = ? AND course_id = ? AND organization_id = ?`
+		result := tx.Exec(query, cohort.ID, userID, cohort.CourseID, tenant.ID(ctx))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+
+		query = `INSERT INTO enrollments (organization_id, user_id, course_id, cohort_id) VALUES (?, ?, ?, ?)`
+		if err := tx.Exec(query, tenant.ID(ctx), userID, cohort.CourseID, cohort.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.SuspendedAt, &user.ExternalID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func scanGroup(row scanner) (*model.Cohort, error) {
+	var cohort model.Cohort
+	err := row.Scan(&cohort.ID, &cohort.CourseID, &cohort.Name, &cohort.ExternalID, &cohort.CreatedAt, &cohort.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
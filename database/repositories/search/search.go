@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// searchQuery searches the courses the user can see, their sections and
+// their text materials.
+//
+// Users who are not part of the staff of a course read its published
+// snapshot when it has one (see the version repository). They search the
+// snapshot's course title and description, and the sections and materials
+// that did not change since the snapshot was taken, as their live content
+// is not published yet. They only search the sections and materials of the
+// courses they are enrolled in and of preview sections, and materials that are not
+// released to them yet (see the release rules of the course and of the
+// sections they are nested in) are left out.
+const searchQuery = `WITH visible AS (
+		SELECT c.id, search_config(c.language) AS config, s.created_at AS snapshot_at,
+			CASE WHEN s.id IS NULL THEN c.title ELSE s.content->'course'->>'title' END AS title,
+			CASE WHEN s.id IS NULL THEN c.description ELSE s.content->'course'->>'description' END AS description,
+			s.id IS NULL AS live, c.staff, c.enrolled
+		FROM (
+			SELECT c.*, @manage_any OR EXISTS (SELECT 1 FROM course_staff cs WHERE cs.course_id = c.id AND cs.user_id = @user_id) AS staff,
+				EXISTS (SELECT 1 FROM enrollments e WHERE e.course_id = c.id AND e.user_id = @user_id) AS enrolled
+			FROM courses c WHERE c.organization_id = @organization_id
+		) c
+		LEFT JOIN course_snapshots s ON s.id = c.published_snapshot_id AND NOT c.staff
+		WHERE c.staff
+			OR (c.status = 'published' AND c.published_at <= CURRENT_TIMESTAMP)
+			OR (@can_review AND c.status = 'in_review')
+			OR (c.status = 'archived' AND c.enrolled)
+	),
+	results AS (
+		SELECT 'course' AS type, v.id, v.id AS course_id, NULL::int AS curriculum_id, v.title,
+			ts_headline(v.config, COALESCE(v.description, ''), websearch_to_tsquery(v.config, @text), @headline) AS snippet,
+			ts_rank_cd(d.vector, websearch_to_tsquery(v.config, @text)) AS rank
+		FROM visible v JOIN courses c ON c.id = v.id
+		CROSS JOIN LATERAL (SELECT CASE WHEN v.live THEN c.search_vector ELSE
+			setweight(to_tsvector(v.config, COALESCE(v.title, '')), 'A') || setweight(to_tsvector(v.config, COALESCE(v.description, '')), 'B')
+		END AS vector) d
+		WHERE @type IN ('', 'course') AND d.vector @@ websearch_to_tsquery(v.config, @text)
+
+		UNION ALL
+
+		SELECT 'curriculum', s.id, s.course_id, s.id, s.section_name,
+			ts_headline(v.config, s.section_name, websearch_to_tsquery(v.config, @text), @headline),
+			ts_rank_cd(s.search_vector, websearch_to_tsquery(v.config, @text))
+		FROM curriculums s JOIN visible v ON v.id = s.course_id
+		WHERE @type IN ('', 'curriculum') AND (v.live OR s.updated_at <= v.snapshot_at)
+			AND (v.staff OR v.enrolled OR s.is_preview)
+			AND s.search_vector @@ websearch_to_tsquery(v.config, @text)
+
+		UNION ALL
+
+		SELECT 'material', m.id, s.course_id, s.id, s.section_name,
+			ts_headline(v.config, m.content, websearch_to_tsquery(v.config, @text), @headline),
+			ts_rank_cd(m.search_vector, websearch_to_tsquery(v.config, @text))
+		FROM materials m JOIN curriculums s ON s.id = m.curriculum_id JOIN visible v ON v.id = s.course_id
+		WHERE @type IN ('', 'material') AND (v.live OR m.updated_at <= v.snapshot_at)
+			AND m.search_vector @@ websearch_to_tsquery(v.config, @text)
+			AND (v.staff OR (v.enrolled OR s.is_preview) AND NOT EXISTS (
+				SELECT 1 FROM release_rules r
+				WHERE (r.curriculum_id = ANY(curriculum_path(s.id)) OR r.material_id = m.id) AND (
+					r.release_at > CURRENT_TIMESTAMP
+					OR (r.days_after_enrollment IS NOT NULL AND NOT EXISTS (
+						SELECT 1 FROM enrollments e WHERE e.course_id = r.course_id AND e.user_id = @user_id
+							AND e.enrolled_at + make_interval(days => r.days_after_enrollment) <= CURRENT_TIMESTAMP
+					))
+					OR (r.after_curriculum_id IS NOT NULL AND EXISTS (
+						SELECT 1 FROM materials am JOIN curriculums ac ON ac.id = am.curriculum_id
+						WHERE ac.course_id = r.course_id AND r.after_curriculum_id = ANY(curriculum_path(ac.id)) AND NOT EXISTS (
+							SELECT 1 FROM progress_tracking p WHERE p.material_id = am.id AND p.user_id = @user_id AND p.status = 'completed'
+						)
+					))
+				)
+			))
+	)
+	SELECT type, id, course_id, curriculum_id, title, snippet, rank FROM results
+	ORDER BY rank DESC, type, id
+	LIMIT @limit`
+
+// headline are the options of the snippets of the results.
+const headline = `StartSel=` + model.SnippetStart + `, StopSel=` + model.SnippetStop + `, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+
+type Search struct {
+	DB *gorm.DB
+}
+
+func NewSearch(db *gorm.DB) *Search {
+	return &Search{
+		DB: db,
+	}
+}
+
+// Search returns the results matching the query, best first.
+func (r *Search) Search(ctx context.Context, query *model.SearchQuery) ([]*model.SearchResult, error) {
+	rows, err := r.DB.Raw(searchQuery,
+		sql.Named("organization_id", tenant.ID(ctx)),
+		sql.Named("user_id", query.UserID),
+		sql.Named("manage_any", query.ManageAny),
+		sql.Named("can_review", query.CanReview),
+		sql.Named("text", query.Text),
+		sql.Named("type", query.Type),
+		sql.Named("headline", headline),
+		sql.Named("limit", query.Limit),
+	).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]*model.SearchResult, 0)
+	for rows.Next() {
+		var result model.SearchResult
+		err := rows.Scan(&result.Type, &result.ID, &result.CourseID, &result.CurriculumID, &result.Title, &result.Snippet, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
@@ -4,35 +4,34 @@ import (
 	"context"
 
 	"github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
-func (r *Student) ListAchievementByUserID(ctx context.Context, userID uint64) ([]*models.Achievement, error) {
-	query := `SELECT id, user_id, course_id, achievement_type, description, awarded_at FROM achievements WHERE user_id = ?`
-	rows, err := r.DB.Raw(query, userID).Rows()
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var achievements []*models.Achievement
-	for rows.Next() {
+// ListAchievementByUserID returns a page of the achievements of the user,
+// the most recent first by default.
+func (r *Student) ListAchievementByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*models.Achievement], error) {
+	base := `SELECT id, user_id, course_id, achievement_type, description, awarded_at FROM achievements WHERE user_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, achievementSchema, q, base, []any{userID, tenant.ID(ctx)}, func(row listing.Scanner) (*models.Achievement, error) {
 		var achievement models.Achievement
-		if err := rows.Scan(&achievement.ID, &achievement.UserID, &achievement.CourseID, &achievement.AchievementType, &achievement.Description, &achievement.AwardedAt); err != nil {
-			return nil, err
-		}
-		achievements = append(achievements, &achievement)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+		err := row.Scan(&achievement.ID, &achievement.UserID, &achievement.CourseID, &achievement.AchievementType, &achievement.Description, &achievement.AwardedAt)
+		return &achievement, err
+	})
+}
 
-	return achievements, nil
+var achievementSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":               {Column: "id", Type: listing.Int, Sortable: true},
+		"course_id":        {Column: "course_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"achievement_type": {Column: "achievement_type", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"awarded_at":       {Column: "awarded_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "awarded_at", Desc: true}},
 }
 
 func (r *Student) GetAchievementByID(ctx context.Context, id uint64) (*models.Achievement, error) {
-	query := `SELECT id, user_id, course_id, achievement_type, description, awarded_at FROM achievements WHERE id = ?`
-	row := r.DB.Raw(query, id).Row()
+	query := `SELECT id, user_id, course_id, achievement_type, description, awarded_at FROM achievements WHERE id = ? AND organization_id = ?`
+	row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
 
 	var achievement models.Achievement
 	err := row.Scan(&achievement.ID, &achievement.UserID, &achievement.CourseID, &achievement.AchievementType, &achievement.Description, &achievement.AwardedAt)
@@ -44,8 +43,8 @@ func (r *Student) GetAchievementByID(ctx context.Context, id uint64) (*models.Ac
 }
 
 func (r *Student) CreateAchievement(ctx context.Context, achievement *models.Achievement) (*models.Achievement, error) {
-	query := `INSERT INTO achievements (user_id, course_id, achievement_type, description, awarded_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, user_id, course_id, achievement_type, description, awarded_at`
-	row := r.DB.Raw(query, achievement.UserID, achievement.CourseID, achievement.AchievementType, achievement.Description, achievement.AwardedAt).Row()
+	query := `INSERT INTO achievements (organization_id, user_id, course_id, achievement_type, description, awarded_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, user_id, course_id, achievement_type, description, awarded_at`
+	row := r.DB.Raw(query, tenant.ID(ctx), achievement.UserID, achievement.CourseID, achievement.AchievementType, achievement.Description, achievement.AwardedAt).Row()
 
 	var newAchievement models.Achievement
 	err := row.Scan(&newAchievement.ID, &newAchievement.UserID, &newAchievement.CourseID, &newAchievement.AchievementType, &newAchievement.Description, &newAchievement.AwardedAt)
@@ -57,8 +56,8 @@ func (r *Student) CreateAchievement(ctx context.Context, achievement *models.Ach
 }
 
 func (r *Student) UpdateAchievement(ctx context.Context, achievement *models.Achievement) (*models.Achievement, error) {
-	query := `UPDATE achievements SET course_id = $1, achievement_type = $2, description = $3, awarded_at = $4 WHERE id = $5 RETURNING id, user_id, course_id, achievement_type, description, awarded_at`
-	row := r.DB.Raw(query, achievement.CourseID, achievement.AchievementType, achievement.Description, achievement.AwardedAt, achievement.ID).Row()
+	query := `UPDATE achievements SET course_id = $1, achievement_type = $2, description = $3, awarded_at = $4 WHERE id = $5 AND organization_id = $6 RETURNING id, user_id, course_id, achievement_type, description, awarded_at`
+	row := r.DB.Raw(query, achievement.CourseID, achievement.AchievementType, achievement.Description, achievement.AwardedAt, achievement.ID, tenant.ID(ctx)).Row()
 
 	var updatedAchievement models.Achievement
 	err := row.Scan(&updatedAchievement.ID, &updatedAchievement.UserID, &updatedAchievement.CourseID, &updatedAchievement.AchievementType, &updatedAchievement.Description, &updatedAchievement.AwardedAt)
@@ -70,8 +69,8 @@ func (r *Student) UpdateAchievement(ctx context.Context, achievement *models.Ach
 }
 
 func (r *Student) DeleteAchievement(ctx context.Context, id uint64) error {
-	query := `DELETE FROM achievements WHERE id = $1`
-	result := r.DB.Exec(query, id)
+	query := `DELETE FROM achievements WHERE id = $1 AND organization_id = $2`
+	result := r.DB.Exec(query, id, tenant.ID(ctx))
 	if result.Error != nil {
 		return result.Error
 	}
@@ -3,60 +3,212 @@ package student
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
-func (r *Student) EnrollCourse(ctx context.Context, studentID uint64, courseID uint64) (*models.Enrollment, error) {
-	query := `INSERT INTO enrollments (user_id, course_id, enrolled_at) VALUES ($1, $2, $3) RETURNING id, course_id, user_id, enrolled_at`
-	row := r.DB.Raw(query, studentID, courseID, time.Now()).Row()
+var (
+	errAlreadyEnrolled   = errors.New("You are already enrolled in this course")
+	errAlreadyWaitlisted = errors.New("You are already on the waitlist of this course")
+)
 
-	var enrollment models.Enrollment
-	err := row.Scan(&enrollment.ID, &enrollment.CourseID, &enrollment.UserID, &enrollment.EnrolledAt)
+// EnrollCourse enrolls the student in the course if it is listed in the
+// catalog and its enrollment window is open. When the course is full, the
+// student joins its waitlist instead and the waitlist entry is returned. It
+// returns nil for both if the course is not open for enrollment.
+//
+// Enrollments in a course are serialized by locking its row, so concurrent
+// requests cannot take more seats than the capacity.
+func (r *Student) EnrollCourse(ctx context.Context, studentID uint64, courseID uint64) (*models.Enrollment, *models.WaitlistEntry, error) {
+	var enrollment *models.Enrollment
+	var entry *models.WaitlistEntry
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := `SELECT r.capacity FROM courses c LEFT JOIN course_requirements r ON r.course_id = c.id
+			WHERE c.id = ? AND c.organization_id = ? AND c.status = 'published' AND c.published_at <= ?
+			AND (r.opens_at IS NULL OR r.opens_at <= ?) AND (r.closes_at IS NULL OR r.closes_at > ?)
+			FOR UPDATE OF c`
+		var capacity *int
+		err := tx.Raw(query, courseID, tenant.ID(ctx), now, now, now).Row().Scan(&capacity)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `SELECT
+				EXISTS (SELECT 1 FROM enrollments WHERE course_id = ? AND user_id = ?),
+				EXISTS (SELECT 1 FROM course_waitlist WHERE course_id = ? AND user_id = ?),
+				(SELECT COUNT(*) FROM enrollments WHERE course_id = ?),
+				(SELECT COUNT(*) FROM course_waitlist WHERE course_id = ?)`
+		var enrolled, waitlisted bool
+		var taken, waiting int
+		err = tx.Raw(query, courseID, studentID, courseID, studentID, courseID, courseID).Row().Scan(&enrolled, &waitlisted, &taken, &waiting)
+		if err != nil {
+			return err
+		}
+		if enrolled {
+			return errAlreadyEnrolled
+		}
+		if waitlisted {
+			return errAlreadyWaitlisted
+		}
+
+		// Students already waiting keep their turn
+		if capacity == nil || (taken < *capacity && waiting == 0) {
+			enrollment = &models.Enrollment{}
+			query = `INSERT INTO enrollments (organization_id, user_id, course_id, enrolled_at) VALUES (?, ?, ?, ?)
+				RETURNING id, course_id, user_id, enrolled_at`
+			row := tx.Raw(query, tenant.ID(ctx), studentID, courseID, now).Row()
+			return row.Scan(&enrollment.ID, &enrollment.CourseID, &enrollment.UserID, &enrollment.EnrolledAt)
+		}
+
+		entry = &models.WaitlistEntry{Position: waiting + 1}
+		query = `INSERT INTO course_waitlist (organization_id, course_id, user_id, created_at) VALUES (?, ?, ?, ?)
+			RETURNING id, course_id, user_id, created_at`
+		row := tx.Raw(query, tenant.ID(ctx), courseID, studentID, now).Row()
+		return row.Scan(&entry.ID, &entry.CourseID, &entry.UserID, &entry.CreatedAt)
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &enrollment, nil
+	return enrollment, entry, nil
+}
+
+// Unenroll removes the student from the course, or from its waitlist, and
+// enrolls the next students waiting in the seats left. It returns false if
+// the student was neither enrolled nor waiting.
+func (r *Student) Unenroll(ctx context.Context, studentID uint64, courseID uint64) (bool, error) {
+	var removed bool
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := `SELECT 1 FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`
+		var found int
+		err := tx.Raw(query, courseID, tenant.ID(ctx)).Row().Scan(&found)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := tx.Exec(`DELETE FROM enrollments WHERE course_id = ? AND user_id = ? AND organization_id = ?`, courseID, studentID, tenant.ID(ctx))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			removed = true
+			return tx.Exec(`SELECT promote_waitlist(?)`, courseID).Error
+		}
+
+		result = tx.Exec(`DELETE FROM course_waitlist WHERE course_id = ? AND user_id = ? AND organization_id = ?`, courseID, studentID, tenant.ID(ctx))
+		removed = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// ListWaitlist returns a page of the waitlists the student is on, with their
+// position in each.
+func (r *Student) ListWaitlist(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*models.WaitlistEntry], error) {
+	base := `SELECT w.id, w.course_id, w.user_id,
+			(SELECT COUNT(*) FROM course_waitlist o WHERE o.course_id = w.course_id AND o.id <= w.id) AS position, w.created_at
+		FROM course_waitlist w WHERE w.user_id = ? AND w.organization_id = ?`
+	return listing.Fetch(r.DB, waitlistSchema, q, base, []any{studentID, tenant.ID(ctx)}, func(row listing.Scanner) (*models.WaitlistEntry, error) {
+		var entry models.WaitlistEntry
+		err := row.Scan(&entry.ID, &entry.CourseID, &entry.UserID, &entry.Position, &entry.CreatedAt)
+		return &entry, err
+	})
+}
+
+var waitlistSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int, Sortable: true},
+		"course_id":  {Column: "course_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "created_at"}},
 }
 
-func (r *Student) GetEnrollmentsByUserID(ctx context.Context, studentID uint64) ([]*models.Enrollment, error) {
-	query := `SELECT id, user_id, course_id, enrolled_at FROM enrollments WHERE user_id = $1`
-	rows, err := r.DB.Raw(query, studentID).Rows()
+// CourseResults returns the results of the student in each of the courses,
+// by course ID. Courses that do not exist are left out.
+func (r *Student) CourseResults(ctx context.Context, studentID uint64, courseIDs []uint64) (map[uint64]*models.CourseResult, error) {
+	results := make(map[uint64]*models.CourseResult, len(courseIDs))
+	if len(courseIDs) == 0 {
+		return results, nil
+	}
+
+	query := `SELECT c.id,
+			EXISTS (SELECT 1 FROM materials m JOIN curriculums cu ON cu.id = m.curriculum_id WHERE cu.course_id = c.id)
+			AND NOT EXISTS (
+				SELECT 1 FROM materials m JOIN curriculums cu ON cu.id = m.curriculum_id
+				WHERE cu.course_id = c.id AND NOT EXISTS (
+					SELECT 1 FROM progress_tracking p WHERE p.material_id = m.id AND p.user_id = ? AND p.status = 'completed'
+				)
+			),
+			(SELECT AVG(COALESCE((SELECT MAX(s.grade) FROM submissions s WHERE s.assessment_id = a.id AND s.user_id = ?), 0))::float8
+			 FROM assessments a WHERE a.course_id = c.id)
+		FROM courses c WHERE c.id IN ? AND c.organization_id = ?`
+	rows, err := r.DB.Raw(query, studentID, studentID, courseIDs, tenant.ID(ctx)).Rows()
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var enrollments []*models.Enrollment
 	for rows.Next() {
-		var enrollment models.Enrollment
-		if err := rows.Scan(&enrollment.ID, &enrollment.UserID, &enrollment.CourseID, &enrollment.EnrolledAt); err != nil {
+		var result models.CourseResult
+		if err := rows.Scan(&result.CourseID, &result.Completed, &result.Grade); err != nil {
 			return nil, err
 		}
-		enrollments = append(enrollments, &enrollment)
+		results[result.CourseID] = &result
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+	return results, rows.Err()
+}
+
+// ListEnrollmentsByUserID returns a page of the enrollments of the student,
+// the earliest first by default.
+func (r *Student) ListEnrollmentsByUserID(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*models.Enrollment], error) {
+	base := `SELECT id, user_id, course_id, enrolled_at FROM enrollments WHERE user_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, enrollmentSchema, q, base, []any{studentID, tenant.ID(ctx)}, func(row listing.Scanner) (*models.Enrollment, error) {
+		var enrollment models.Enrollment
+		err := row.Scan(&enrollment.ID, &enrollment.UserID, &enrollment.CourseID, &enrollment.EnrolledAt)
+		return &enrollment, err
+	})
+}
 
-	return enrollments, nil
+var enrollmentSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":          {Column: "id", Type: listing.Int, Sortable: true},
+		"course_id":   {Column: "course_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"enrolled_at": {Column: "enrolled_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "enrolled_at"}},
 }
 
 func (r *Student) TrackProgress(ctx context.Context, progress *models.ProgressTracking) (*models.ProgressTracking, error) {
 	var existingID int
-	query := `SELECT id FROM progress_tracking WHERE user_id = $1 AND curriculum_id = $2 AND material_id = $3`
-	row := r.DB.Raw(query, progress.UserID, progress.CurriculumID, progress.MaterialID).Row()
+	query := `SELECT id FROM progress_tracking WHERE user_id = $1 AND curriculum_id = $2 AND material_id = $3 AND organization_id = $4`
+	row := r.DB.Raw(query, progress.UserID, progress.CurriculumID, progress.MaterialID, tenant.ID(ctx)).Row()
 	err := row.Scan(&existingID)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Create new progress record
-			query := `INSERT INTO progress_tracking (user_id, curriculum_id, material_id, status, progress_percentage, updated_at) 
-					  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, updated_at`
-			row := r.DB.Raw(query, progress.UserID, progress.CurriculumID, progress.MaterialID, progress.Status, progress.ProgressPercentage, time.Now()).Row()
+			query := `INSERT INTO progress_tracking (organization_id, user_id, curriculum_id, material_id, status, progress_percentage, updated_at) 
+					  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, updated_at`
+			row := r.DB.Raw(query, tenant.ID(ctx), progress.UserID, progress.CurriculumID, progress.MaterialID, progress.Status, progress.ProgressPercentage, time.Now()).Row()
 			err = row.Scan(&progress.ID, &progress.UpdatedAt)
 			if err != nil {
 				return nil, err
@@ -66,8 +218,8 @@ func (r *Student) TrackProgress(ctx context.Context, progress *models.ProgressTr
 		}
 	}
 
-	query = `UPDATE progress_tracking SET status = $1, progress_percentage = $2, updated_at = $3 WHERE id = $4 RETURNING id, updated_at`
-	row = r.DB.Raw(query, progress.Status, progress.ProgressPercentage, time.Now(), existingID).Row()
+	query = `UPDATE progress_tracking SET status = $1, progress_percentage = $2, updated_at = $3 WHERE id = $4 AND organization_id = $5 RETURNING id, updated_at`
+	row = r.DB.Raw(query, progress.Status, progress.ProgressPercentage, time.Now(), existingID, tenant.ID(ctx)).Row()
 	err = row.Scan(&progress.ID, &progress.UpdatedAt)
 	if err != nil {
 		return nil, err
@@ -75,27 +227,25 @@ func (r *Student) TrackProgress(ctx context.Context, progress *models.ProgressTr
 	return progress, nil
 }
 
-func (r *Student) GetProgressByStudentAndCurriculum(ctx context.Context, studentID uint64, curriculumID uint64) ([]*models.ProgressTracking, error) {
-	query := `SELECT id, user_id, curriculum_id, material_id, status, progress_percentage, updated_at FROM
-	 progress_tracking WHERE user_id = $1 AND curriculum_id = $2`
-	rows, err := r.DB.Raw(query, studentID, curriculumID).Rows()
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var progress []*models.ProgressTracking
-	for rows.Next() {
+// ListProgress returns a page of the progress of the student in the
+// materials of the curriculum.
+func (r *Student) ListProgress(ctx context.Context, studentID uint64, curriculumID uint64, q *listing.Query) (*listing.Page[*models.ProgressTracking], error) {
+	base := `SELECT id, user_id, curriculum_id, material_id, status, progress_percentage, updated_at FROM progress_tracking
+		WHERE user_id = ? AND curriculum_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, progressSchema, q, base, []any{studentID, curriculumID, tenant.ID(ctx)}, func(row listing.Scanner) (*models.ProgressTracking, error) {
 		var p models.ProgressTracking
-		if err := rows.Scan(&p.ID, &p.UserID, &p.CurriculumID, &p.MaterialID, &p.Status, &p.ProgressPercentage, &p.UpdatedAt); err != nil {
-			return nil, err
-		}
-		progress = append(progress, &p)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+		err := row.Scan(&p.ID, &p.UserID, &p.CurriculumID, &p.MaterialID, &p.Status, &p.ProgressPercentage, &p.UpdatedAt)
+		return &p, err
+	})
+}
 
-	return progress, nil
+var progressSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":                  {Column: "id", Type: listing.Int, Sortable: true},
+		"material_id":         {Column: "material_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"status":              {Column: "status", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"progress_percentage": {Column: "progress_percentage", Type: listing.Int, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at":          {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
 }
@@ -7,6 +7,8 @@ import (
 	"gorm.io/gorm"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
 type Student struct {
@@ -21,7 +23,7 @@ func NewStudent(db *gorm.DB) *Student {
 
 func (r *Student) GetByID(ctx context.Context, id uint64) (*model.User, error) {
 	var student model.User
-	result := r.DB.First(&student, id)
+	result := r.DB.Where("organization_id = ?", tenant.ID(ctx)).First(&student, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -32,8 +34,8 @@ func (r *Student) GetByID(ctx context.Context, id uint64) (*model.User, error) {
 }
 
 func (r *Student) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE email = ?`
-	row := r.DB.Raw(query, email).Row()
+	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE email = ? AND organization_id = ?`
+	row := r.DB.Raw(query, email, tenant.ID(ctx)).Row()
 
 	var student model.User
 	err := row.Scan(&student.ID, &student.Name, &student.Email, &student.CreatedAt, &student.UpdatedAt)
@@ -48,8 +50,8 @@ func (r *Student) GetByEmail(ctx context.Context, email string) (*model.User, er
 }
 
 func (r *Student) Get(ctx context.Context, id uint64) (*model.User, error) {
-	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id = ?`
-	row := r.DB.Raw(query, id).Row()
+	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id = ? AND organization_id = ?`
+	row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
 
 	var student model.User
 	err := row.Scan(&student.ID, &student.Name, &student.Email, &student.CreatedAt, &student.UpdatedAt)
@@ -63,30 +65,31 @@ func (r *Student) Get(ctx context.Context, id uint64) (*model.User, error) {
 	return &student, nil
 }
 
-func (r *Student) GetAll(ctx context.Context) ([]*model.User, error) {
-	query := `SELECT id, name, email, created_at, updated_at FROM users`
-	rows, err := r.DB.Raw(query).Rows()
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	courses := make([]*model.User, 0)
-	for rows.Next() {
+// List returns a page of the users of the organization.
+func (r *Student) List(ctx context.Context, q *listing.Query) (*listing.Page[*model.User], error) {
+	base := `SELECT id, name, email, created_at, updated_at, role FROM users WHERE organization_id = ?`
+	return listing.Fetch(r.DB, studentSchema, q, base, []any{tenant.ID(ctx)}, func(row listing.Scanner) (*model.User, error) {
 		var student model.User
-		err := rows.Scan(&student.ID, &student.Name, &student.Email, &student.CreatedAt, &student.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		courses = append(courses, &student)
-	}
+		err := row.Scan(&student.ID, &student.Name, &student.Email, &student.CreatedAt, &student.UpdatedAt, &student.Role)
+		return &student, err
+	})
+}
 
-	return courses, nil
+var studentSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"name":       {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"email":      {Column: "email", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"role":       {Column: "role", Type: listing.Text, Operators: []string{listing.Eq, listing.Ne, listing.In}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+		"updated_at": {Column: "updated_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id"}},
 }
 
 func (r *Student) Update(ctx context.Context, student *model.User) (*model.User, error) {
-	query := `UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING updated_at`
-	rows := r.DB.Raw(query, student.Name, student.Email, student.ID).Row()
+	query := `UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? RETURNING updated_at`
+	rows := r.DB.Raw(query, student.Name, student.Email, student.ID, tenant.ID(ctx)).Row()
 	err := rows.Scan(&student.UpdatedAt)
 	if err != nil {
 		return nil, err
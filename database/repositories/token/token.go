@@ -0,0 +1,114 @@
+package token
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+const columns = `id, user_id, name, token_prefix, token_hash, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+type Token struct {
+	DB *gorm.DB
+}
+
+func NewToken(db *gorm.DB) *Token {
+	return &Token{
+		DB: db,
+	}
+}
+
+func (r *Token) Create(ctx context.Context, token *model.APIToken) (*model.APIToken, error) {
+	query := `INSERT INTO api_tokens (organization_id, user_id, name, token_prefix, token_hash, scopes, created_by, expires_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+	row := r.DB.Raw(query, tenant.ID(ctx), token.UserID, token.Name, token.TokenPrefix, token.TokenHash, token.Scopes, token.CreatedBy, token.ExpiresAt).Row()
+
+	err := row.Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r *Token) GetByID(ctx context.Context, id uint64) (*model.APIToken, error) {
+	query := `SELECT ` + columns + ` FROM api_tokens WHERE id = ? AND organization_id = ?`
+	row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
+
+	var token model.APIToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.TokenHash, &token.Scopes, &token.CreatedBy,
+		&token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetActiveByHash returns the unrevoked, unexpired token with the given hash
+// along with its owner.
+func (r *Token) GetActiveByHash(ctx context.Context, hash string) (*model.APIToken, *model.User, error) {
+	query := `SELECT t.id, t.user_id, t.name, t.token_prefix, t.scopes, t.expires_at, t.created_at, u.id, u.name, u.email, u.role
+	          FROM api_tokens t JOIN users u ON u.id = t.user_id
+	          WHERE t.token_hash = ? AND t.revoked_at IS NULL AND t.expires_at > CURRENT_TIMESTAMP AND t.organization_id = ?`
+	row := r.DB.Raw(query, hash, tenant.ID(ctx)).Row()
+
+	var token model.APIToken
+	var user model.User
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.Scopes, &token.ExpiresAt, &token.CreatedAt,
+		&user.ID, &user.Name, &user.Email, &user.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &token, &user, nil
+}
+
+// ListByUserID returns the page of the tokens of the user, most recent first
+// unless sorted otherwise.
+func (r *Token) ListByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*model.APIToken], error) {
+	base := `SELECT ` + columns + ` FROM api_tokens WHERE user_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, tokenSchema, q, base, []any{userID, tenant.ID(ctx)}, scan)
+}
+
+// ListAll returns the page of the tokens of the organization, most recent
+// first unless sorted otherwise.
+func (r *Token) ListAll(ctx context.Context, q *listing.Query) (*listing.Page[*model.APIToken], error) {
+	base := `SELECT ` + columns + ` FROM api_tokens WHERE organization_id = ?`
+	return listing.Fetch(r.DB, tokenSchema, q, base, []any{tenant.ID(ctx)}, scan)
+}
+
+var tokenSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":           {Column: "id", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.In}},
+		"user_id":      {Column: "user_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"name":         {Column: "name", Type: listing.Text, Sortable: true, Operators: []string{listing.Eq, listing.Contains}},
+		"expires_at":   {Column: "expires_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gt, listing.Lte}},
+		"last_used_at": {Column: "last_used_at", Type: listing.Time, Operators: []string{listing.Gte, listing.Lte}},
+		"created_at":   {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "created_at", Desc: true}},
+}
+
+func scan(row listing.Scanner) (*model.APIToken, error) {
+	var token model.APIToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.TokenHash, &token.Scopes, &token.CreatedBy,
+		&token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt)
+	return &token, err
+}
+
+func (r *Token) TouchLastUsed(ctx context.Context, id uint64) error {
+	query := `UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	err := r.DB.Exec(query, id, tenant.ID(ctx)).Error
+	return err
+}
+
+func (r *Token) Revoke(ctx context.Context, id uint64) error {
+	query := `UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL AND organization_id = ?`
+	err := r.DB.Exec(query, id, tenant.ID(ctx)).Error
+	return err
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"context"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+func (r *User) GetIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE provider = ? AND subject = ? AND organization_id = ?`
+	row := r.DB.Raw(query, provider, subject, tenant.ID(ctx)).Row()
+
+	var identity model.UserIdentity
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *User) CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	query := `INSERT INTO user_identities (organization_id, user_id, provider, subject, email) VALUES (?, ?, ?, ?, ?) RETURNING id, created_at`
+	row := r.DB.Raw(query, tenant.ID(ctx), identity.UserID, identity.Provider, identity.Subject, identity.Email).Row()
+
+	err := row.Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
@@ -6,6 +6,7 @@ import (
 	"gorm.io/gorm"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 )
 
 type User struct {
@@ -19,8 +20,8 @@ func NewUser(db *gorm.DB) *User {
 }
 
 func (r *User) Create(ctx context.Context, user *model.User) (*model.User, error) {
-	query := `INSERT INTO users (name, email, password_hash, role) VALUES (?, ?, ?, ?) returning id`
-	row := r.DB.Raw(query, user.Name, user.Email, user.PasswordHash, user.Role).Row()
+	query := `INSERT INTO users (organization_id, name, email, password_hash, role) VALUES (?, ?, ?, ?, ?) returning id`
+	row := r.DB.Raw(query, tenant.ID(ctx), user.Name, user.Email, user.PasswordHash, user.Role).Row()
 
 	err := row.Scan(&user.ID)
 	if err != nil {
@@ -31,8 +32,21 @@ func (r *User) Create(ctx context.Context, user *model.User) (*model.User, error
 }
 
 func (r *User) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, name, email, role, password_hash, created_at, updated_at FROM users WHERE email = ?`
-	row := r.DB.Raw(query, email).Row()
+	query := `SELECT id, name, email, role, password_hash, suspended_at, password_reset_token_hash IS NOT NULL, created_at, updated_at FROM users WHERE email = ? AND organization_id = ?`
+	row := r.DB.Raw(query, email, tenant.ID(ctx)).Row()
+
+	var user model.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.PasswordHash, &user.SuspendedAt, &user.PasswordResetRequired, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *User) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	query := `SELECT id, name, email, role, password_hash, created_at, updated_at FROM users WHERE id = ? AND organization_id = ?`
+	row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
 
 	var user model.User
 	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
@@ -43,9 +57,43 @@ func (r *User) GetByEmail(ctx context.Context, email string) (*model.User, error
 	return &user, nil
 }
 
+// GetStatus returns the current role and account state of the user.
+func (r *User) GetStatus(ctx context.Context, id uint64) (*model.User, error) {
+	query := `SELECT id, role, suspended_at, password_reset_token_hash IS NOT NULL FROM users WHERE id = ? AND organization_id = ?`
+	row := r.DB.Raw(query, id, tenant.ID(ctx)).Row()
+
+	user := model.User{}
+	err := row.Scan(&user.ID, &user.Role, &user.SuspendedAt, &user.PasswordResetRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ResetPassword replaces the password of the user holding the unexpired
+// reset token of the given hash, and consumes the token.
+func (r *User) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (*model.User, error) {
+	query := `UPDATE users SET password_hash = ?, password_reset_token_hash = NULL, password_reset_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+	          WHERE password_reset_token_hash = ? AND password_reset_expires_at > CURRENT_TIMESTAMP AND organization_id = ? RETURNING id, email`
+	row := r.DB.Raw(query, passwordHash, tokenHash, tenant.ID(ctx)).Row()
+
+	user := model.User{}
+	if err := row.Scan(&user.ID, &user.Email); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *User) UpdatePassword(ctx context.Context, id uint64, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+	return r.DB.Exec(query, passwordHash, id, tenant.ID(ctx)).Error
+}
+
 func (r *User) Update(ctx context.Context, user *model.User) (*model.User, error) {
-	query := `UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? returning updated_at`
-	err := r.DB.Raw(query, user.Name, user.Email, user.ID).
+	query := `UPDATE users SET name = ?, email = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ? returning updated_at`
+	err := r.DB.Raw(query, user.Name, user.Email, user.ID, tenant.ID(ctx)).
 		Scan(&user.UpdatedAt)
 
 	if err.Error != nil {
@@ -56,7 +104,7 @@ func (r *User) Update(ctx context.Context, user *model.User) (*model.User, error
 }
 
 func (r *User) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM users WHERE id = ?`
-	err := r.DB.Exec(query, id).Error
+	query := `DELETE FROM users WHERE id = ? AND organization_id = ?`
+	err := r.DB.Exec(query, id, tenant.ID(ctx)).Error
 	return err
 }
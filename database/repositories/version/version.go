@@ -0,0 +1,341 @@
+package version
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/dapthehuman/learning-management-system/cache"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+)
+
+const columns = `id, course_id, entity_type, entity_id, version, action, data, diff, author_id, created_at`
+
+// Version stores the change history of course content and the snapshots of
+// published courses.
+type Version struct {
+	DB    *gorm.DB
+	Redis redis.UniversalClient
+}
+
+func NewVersion(db *gorm.DB, redis redis.UniversalClient) *Version {
+	return &Version{
+		DB:    db,
+		Redis: redis,
+	}
+}
+
+// Record saves a new version of the entity. Its number and its diff with the
+// previous version are computed by the database.
+func (r *Version) Record(ctx context.Context, version *model.ContentVersion) (*model.ContentVersion, error) {
+	return record(ctx, r.DB, version)
+}
+
+func record(ctx context.Context, db *gorm.DB, version *model.ContentVersion) (*model.ContentVersion, error) {
+	var data any
+	if len(version.Data) > 0 {
+		data = string(version.Data)
+	}
+
+	query := `WITH previous AS (
+			SELECT version, data FROM content_versions
+			WHERE organization_id = ? AND entity_type = ? AND entity_id = ?
+			ORDER BY version DESC LIMIT 1
+		)
+		INSERT INTO content_versions (organization_id, course_id, entity_type, entity_id, version, action, data, diff, author_id)
+		SELECT ?, ?, ?, ?, COALESCE((SELECT version FROM previous), 0) + 1, ?, CAST(? AS JSONB),
+			(SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object('from', o.value, 'to', n.value)), '{}')
+				FROM jsonb_each(CAST(? AS JSONB)) n FULL JOIN jsonb_each((SELECT data FROM previous)) o USING (key)
+				WHERE n.value IS DISTINCT FROM o.value),
+			?
+		RETURNING ` + columns
+	orgID := tenant.ID(ctx)
+	return scan(db.Raw(query, orgID, version.EntityType, version.EntityID,
+		orgID, version.CourseID, version.EntityType, version.EntityID, version.Action, data, data, version.AuthorID).Row())
+}
+
+// ListByCourse returns a page of the changes to the course and its content,
+// newest first by default.
+func (r *Version) ListByCourse(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.ContentVersion], error) {
+	base := `SELECT ` + columns + ` FROM content_versions WHERE course_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, versionSchema, q, base, []any{courseID, tenant.ID(ctx)}, scan)
+}
+
+var versionSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":          {Column: "id", Type: listing.Int, Sortable: true},
+		"entity_type": {Column: "entity_type", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"entity_id":   {Column: "entity_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"action":      {Column: "action", Type: listing.Text, Operators: []string{listing.Eq, listing.In}},
+		"author_id":   {Column: "author_id", Type: listing.Int, Operators: []string{listing.Eq, listing.In}},
+		"created_at":  {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "id", Desc: true}},
+}
+
+// GetByID returns the version, or nil if it does not exist.
+func (r *Version) GetByID(ctx context.Context, id uint64) (*model.ContentVersion, error) {
+	query := `SELECT ` + columns + ` FROM content_versions WHERE id = ? AND organization_id = ?`
+	version, err := scan(r.DB.Raw(query, id, tenant.ID(ctx)).Row())
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return version, err
+}
+
+// Restore puts the entity of the version back in the state it describes,
+// recreating it if it was deleted, and records the rollback as a new version
+// authored by authorID.
+func (r *Version) Restore(ctx context.Context, version *model.ContentVersion, authorID uint64) (*model.ContentVersion, error) {
+	var restored *model.ContentVersion
+	var keys, lists []string
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if keys, lists, err = restore(ctx, tx, version); err != nil {
+			return err
+		}
+
+		restored, err = record(ctx, tx, &model.ContentVersion{
+			CourseID:   version.CourseID,
+			EntityType: version.EntityType,
+			EntityID:   version.EntityID,
+			Action:     "rollback",
+			Data:       version.Data,
+			AuthorID:   &authorID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.Forget(ctx, r.Redis, keys...); err != nil {
+		return nil, err
+	}
+	return restored, cache.Invalidate(ctx, r.Redis, lists...)
+}
+
+// restore writes the data of the version to its entity and returns the cache
+// keys holding it and the namespaces of the cached lists holding it.
+func restore(ctx context.Context, tx *gorm.DB, version *model.ContentVersion) ([]string, []string, error) {
+	orgID := tenant.ID(ctx)
+	switch version.EntityType {
+	case model.VersionCourse:
+		var course model.Course
+		if err := json.Unmarshal(version.Data, &course); err != nil {
+			return nil, nil, err
+		}
+		query := `UPDATE courses SET title = ?, description = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND organization_id = ?`
+		if err := tx.Exec(query, course.Title, course.Description, version.EntityID, orgID).Error; err != nil {
+			return nil, nil, err
+		}
+		return []string{fmt.Sprintf("course:%d", version.EntityID), "courses:published"}, []string{"courses"}, nil
+
+	case model.VersionCurriculum:
+		var curriculum model.Curriculum
+		if err := json.Unmarshal(version.Data, &curriculum); err != nil {
+			return nil, nil, err
+		}
+		// The section goes back to its parent if it still exists, to the top
+		// level otherwise
+		query := `INSERT INTO curriculums (id, organization_id, course_id, parent_id, section_name, section_order)
+			VALUES (?, ?, ?, (SELECT id FROM curriculums WHERE id = ? AND course_id = ? AND NOT ? = ANY(curriculum_path(id))), ?, ?)
+			ON CONFLICT (id) DO UPDATE SET parent_id = EXCLUDED.parent_id, section_name = EXCLUDED.section_name,
+				section_order = EXCLUDED.section_order, updated_at = CURRENT_TIMESTAMP
+			WHERE curriculums.organization_id = EXCLUDED.organization_id`
+		err := tx.Exec(query, version.EntityID, orgID, version.CourseID, curriculum.ParentID, version.CourseID, version.EntityID,
+			curriculum.SectionName, curriculum.SectionOrder).Error
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The section takes back its place among its current siblings,
+		// which are renumbered around it
+		query = `UPDATE curriculums c SET section_order = numbered.position, updated_at = CURRENT_TIMESTAMP
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY section_order, id <> ?, id) AS position FROM curriculums
+				WHERE course_id = ? AND parent_id IS NOT DISTINCT FROM (SELECT parent_id FROM curriculums WHERE id = ?)
+			) numbered
+			WHERE c.id = numbered.id AND c.section_order <> numbered.position`
+		if err := tx.Exec(query, version.EntityID, version.CourseID, version.EntityID).Error; err != nil {
+			return nil, nil, err
+		}
+		return []string{fmt.Sprintf("curriculum:%d", version.EntityID)}, []string{fmt.Sprintf("curriculum:course:%d", version.CourseID)}, nil
+
+	case model.VersionMaterial:
+		var material model.Material
+		if err := json.Unmarshal(version.Data, &material); err != nil {
+			return nil, nil, err
+		}
+		query := `INSERT INTO materials (id, organization_id, curriculum_id, material_type, content, "order") VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET curriculum_id = EXCLUDED.curriculum_id, material_type = EXCLUDED.material_type,
+				content = EXCLUDED.content, "order" = EXCLUDED."order", updated_at = CURRENT_TIMESTAMP
+			WHERE materials.organization_id = EXCLUDED.organization_id`
+		err := tx.Exec(query, version.EntityID, orgID, material.CurriculumID, material.MaterialType, material.Content, material.Order).Error
+		if err != nil {
+			return nil, nil, err
+		}
+
+		query = `UPDATE materials m SET "order" = numbered.position, updated_at = CURRENT_TIMESTAMP
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY "order", id <> ?, id) AS position FROM materials WHERE curriculum_id = ?
+			) numbered
+			WHERE m.id = numbered.id AND m."order" <> numbered.position`
+		if err := tx.Exec(query, version.EntityID, material.CurriculumID).Error; err != nil {
+			return nil, nil, err
+		}
+		return []string{fmt.Sprintf("material:%d", version.EntityID)}, []string{fmt.Sprintf("materials:curriculum:%d", material.CurriculumID)}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown entity type %q", version.EntityType)
+}
+
+// CreateSnapshot freezes the current content of the course and makes it the
+// one students read.
+func (r *Version) CreateSnapshot(ctx context.Context, courseID uint64, authorID uint64) (*model.CourseSnapshot, error) {
+	orgID := tenant.ID(ctx)
+	snapshot := &model.CourseSnapshot{CourseID: courseID, CreatedBy: &authorID}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// The lock gives concurrent snapshots of the course distinct versions
+		course := &model.Course{}
+		query := `SELECT id, title, description FROM courses WHERE id = ? AND organization_id = ? FOR UPDATE`
+		if err := tx.Raw(query, courseID, orgID).Row().Scan(&course.ID, &course.Title, &course.Description); err != nil {
+			return err
+		}
+
+		content, err := loadContent(tx, course, orgID)
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return err
+		}
+
+		query = `INSERT INTO course_snapshots (organization_id, course_id, version, content, created_by)
+			SELECT ?, ?, COALESCE(MAX(version), 0) + 1, CAST(? AS JSONB), ? FROM course_snapshots WHERE course_id = ?
+			RETURNING id, version, created_at`
+		err = tx.Raw(query, orgID, courseID, string(raw), authorID, courseID).Row().Scan(&snapshot.ID, &snapshot.Version, &snapshot.CreatedAt)
+		if err != nil {
+			return err
+		}
+		snapshot.Content = content
+
+		query = `UPDATE courses SET published_snapshot_id = ? WHERE id = ? AND organization_id = ?`
+		return tx.Exec(query, snapshot.ID, courseID, orgID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.Forget(ctx, r.Redis, fmt.Sprintf("course:%d", courseID), "courses:published"); err != nil {
+		return nil, err
+	}
+	return snapshot, cache.Invalidate(ctx, r.Redis, "courses")
+}
+
+func loadContent(tx *gorm.DB, course *model.Course, orgID uint64) (*model.CourseContent, error) {
+	content := &model.CourseContent{
+		Course:      course,
+		Curriculums: make([]*model.Curriculum, 0),
+		Materials:   make([]*model.Material, 0),
+	}
+
+	query := `SELECT id, course_id, parent_id, section_name, section_order FROM curriculums
+		WHERE course_id = ? AND organization_id = ? ORDER BY section_order, id`
+	rows, err := tx.Raw(query, course.ID, orgID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		curriculum := &model.Curriculum{}
+		if err := rows.Scan(&curriculum.ID, &curriculum.CourseID, &curriculum.ParentID, &curriculum.SectionName, &curriculum.SectionOrder); err != nil {
+			return nil, err
+		}
+		content.Curriculums = append(content.Curriculums, curriculum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT m.id, m.curriculum_id, m.material_type, m.content, m."order" FROM materials m
+		JOIN curriculums c ON c.id = m.curriculum_id
+		WHERE c.course_id = ? AND m.organization_id = ? ORDER BY m."order", m.id`
+	materialRows, err := tx.Raw(query, course.ID, orgID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer materialRows.Close()
+	for materialRows.Next() {
+		material := &model.Material{}
+		if err := materialRows.Scan(&material.ID, &material.CurriculumID, &material.MaterialType, &material.Content, &material.Order); err != nil {
+			return nil, err
+		}
+		content.Materials = append(content.Materials, material)
+	}
+
+	return content, materialRows.Err()
+}
+
+// GetSnapshot returns the snapshot with its content. Snapshots never change,
+// so they are cached.
+func (r *Version) GetSnapshot(ctx context.Context, id uint64) (*model.CourseSnapshot, error) {
+	key := fmt.Sprintf("snapshot:%d", id)
+	query := `SELECT id, course_id, version, content, created_by, created_at FROM course_snapshots WHERE id = ? AND organization_id = ?`
+
+	return cache.Cache(ctx, r.Redis, key, func() (*model.CourseSnapshot, error) {
+		snapshot := &model.CourseSnapshot{}
+		var content string
+		err := r.DB.Raw(query, id, tenant.ID(ctx)).Row().
+			Scan(&snapshot.ID, &snapshot.CourseID, &snapshot.Version, &content, &snapshot.CreatedBy, &snapshot.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		return snapshot, json.Unmarshal([]byte(content), &snapshot.Content)
+	})
+}
+
+// ListSnapshots returns a page of the snapshots of the course, newest first
+// by default, without their content.
+func (r *Version) ListSnapshots(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseSnapshot], error) {
+	base := `SELECT id, course_id, version, created_by, created_at FROM course_snapshots WHERE course_id = ? AND organization_id = ?`
+	return listing.Fetch(r.DB, snapshotSchema, q, base, []any{courseID, tenant.ID(ctx)}, func(row listing.Scanner) (*model.CourseSnapshot, error) {
+		snapshot := &model.CourseSnapshot{}
+		err := row.Scan(&snapshot.ID, &snapshot.CourseID, &snapshot.Version, &snapshot.CreatedBy, &snapshot.CreatedAt)
+		return snapshot, err
+	})
+}
+
+var snapshotSchema = &listing.Schema{
+	Fields: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int, Sortable: true},
+		"version":    {Column: "version", Type: listing.Int, Sortable: true, Operators: []string{listing.Eq, listing.Gte, listing.Lte}},
+		"created_by": {Column: "created_by", Type: listing.Int, Operators: []string{listing.Eq}},
+		"created_at": {Column: "created_at", Type: listing.Time, Sortable: true, Operators: []string{listing.Gte, listing.Lte}},
+	},
+	Sort: []listing.Sort{{Field: "version", Desc: true}},
+}
+
+func scan(row listing.Scanner) (*model.ContentVersion, error) {
+	var version model.ContentVersion
+	var data sql.NullString
+	var diff string
+	err := row.Scan(&version.ID, &version.CourseID, &version.EntityType, &version.EntityID, &version.Version,
+		&version.Action, &data, &diff, &version.AuthorID, &version.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if data.Valid {
+		version.Data = []byte(data.String)
+	}
+	version.Diff = []byte(diff)
+	return &version, nil
+}
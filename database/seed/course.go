@@ -1,13 +1,20 @@
 package seed
 
 import (
+	"time"
+
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/go-faker/faker/v4"
 )
 
 func CourseGenerator() *model.Course {
+	now := time.Now()
 	a := &model.Course{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.Title = faker.Sentence()
 	a.Description = faker.Paragraph()
+	a.Status = model.CoursePublished
+	a.PublishedAt = &now
 	return a
 }
@@ -2,13 +2,19 @@ package seed
 
 import (
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/go-faker/faker/v4"
 )
 
+// sectionOrder numbers the generated sections, which share a course.
+var sectionOrder int
+
 func CurriculumGenerator() *model.Curriculum {
+	sectionOrder++
 	a := &model.Curriculum{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.CourseID = uint64(1)
 	a.SectionName = faker.Sentence()
-	a.SectionOrder = 0
+	a.SectionOrder = sectionOrder
 	return a
 }
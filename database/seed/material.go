@@ -2,15 +2,21 @@ package seed
 
 import (
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/go-faker/faker/v4"
 )
 
+// materialOrder numbers the generated materials, which share a section.
+var materialOrder int
+
 func MaterialGenerator() *model.Material {
+	materialOrder++
 	a := &model.Material{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.CurriculumID = 1
 	a.MaterialType = "video"
 	a.Content = faker.URL()
-	a.Order = 0
+	a.Order = materialOrder
 
 	return a
 }
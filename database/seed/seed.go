@@ -9,7 +9,8 @@ import (
 //
 // Learn more here: https://goyave.dev/advanced/testing.html#factories
 
-// Seed migrate all the models and populates the database.
+// Seed migrate all the models and populates the database. Records are
+// created in the default organization.
 func Seed(db *gorm.DB) {
 	// TODO implement seeds
 
@@ -2,12 +2,14 @@ package seed
 
 import (
 	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/go-faker/faker/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func StudentGenerator() *model.User {
 	a := &model.User{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.Name = faker.Name()
 	a.Email = faker.Email()
 	a.Role = "student"
@@ -18,6 +20,7 @@ func StudentGenerator() *model.User {
 
 func InstructorGenerator() *model.User {
 	a := &model.User{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.Name = faker.Name()
 	a.Email = faker.Email()
 	a.Role = "instructor"
@@ -28,6 +31,7 @@ func InstructorGenerator() *model.User {
 
 func AdminGenerator() *model.User {
 	a := &model.User{}
+	a.OrganizationID = tenant.DefaultOrganizationID
 	a.Name = faker.Name()
 	a.Email = "admin@admin.com"
 	a.Role = "admin"
@@ -0,0 +1,13 @@
+-- migrate:up
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(255) NOT NULL, -- Issuer URL of the identity provider
+    subject VARCHAR(255) NOT NULL, -- Stable user identifier at the provider ("sub" claim)
+    email VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+
+-- migrate:down
+DROP TABLE user_identities;
@@ -0,0 +1,17 @@
+-- migrate:up
+CREATE TABLE saml_providers (
+    id SERIAL PRIMARY KEY,
+    organization VARCHAR(255) UNIQUE NOT NULL, -- Slug used in the SP URLs, e.g. /auth/saml/{organization}/acs
+    entity_id VARCHAR(255) NOT NULL, -- EntityID of the identity provider
+    sso_url VARCHAR(255) NOT NULL, -- HTTP-Redirect SingleSignOnService location
+    certificate TEXT NOT NULL, -- PEM encoded signing certificate of the identity provider
+    default_role user_role NOT NULL DEFAULT 'student',
+    email_attribute VARCHAR(255) NOT NULL DEFAULT 'email',
+    name_attribute VARCHAR(255) NOT NULL DEFAULT 'name',
+    role_attribute VARCHAR(255) NOT NULL DEFAULT '', -- Empty to always use default_role
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- migrate:down
+DROP TABLE saml_providers;
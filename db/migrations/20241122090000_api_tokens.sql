@@ -0,0 +1,19 @@
+-- migrate:up
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL, -- First characters of the token, to recognize it in listings
+    token_hash VARCHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token, the token itself is never stored
+    scopes VARCHAR(255) NOT NULL DEFAULT 'read', -- Comma separated, e.g. "read,write"
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+-- migrate:down
+DROP TABLE api_tokens;
@@ -0,0 +1,97 @@
+-- migrate:up
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE, -- System roles can be edited but not deleted
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+
+CREATE TABLE permissions (
+    name VARCHAR(100) PRIMARY KEY, -- e.g. "course.create"
+    description TEXT
+);
+
+
+CREATE TABLE role_permissions (
+    role VARCHAR(50) REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    permission VARCHAR(100) REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('student', 'Learner enrolled in courses', TRUE),
+    ('instructor', 'Creates and teaches courses', TRUE),
+    ('admin', 'Full access to the platform', TRUE),
+    ('teaching_assistant', 'Helps instructors with materials and grading', FALSE),
+    ('observer', 'Read-only access to student records', FALSE);
+
+INSERT INTO permissions (name, description) VALUES
+    ('course.create', 'Create courses'),
+    ('course.update', 'Edit courses'),
+    ('course.delete', 'Delete courses'),
+    ('curriculum.write', 'Create, edit and delete curriculum sections'),
+    ('material.write', 'Create, edit and delete materials'),
+    ('assessment.create', 'Create assessments'),
+    ('grade.write', 'Grade submissions'),
+    ('student.read', 'View students, their enrollments and progress'),
+    ('student.update', 'Edit student profiles'),
+    ('progress.write', 'Record progress on behalf of students'),
+    ('user.read', 'View user accounts'),
+    ('user.update', 'Edit user accounts and their roles'),
+    ('user.delete', 'Delete user accounts'),
+    ('token.manage', 'Mint and revoke API tokens for any user'),
+    ('sso.manage', 'Configure single sign-on identity providers'),
+    ('role.manage', 'Create and edit roles and their permissions');
+
+INSERT INTO role_permissions (role, permission) SELECT 'admin', name FROM permissions;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('instructor', 'course.create'),
+    ('instructor', 'course.update'),
+    ('instructor', 'course.delete'),
+    ('instructor', 'curriculum.write'),
+    ('instructor', 'material.write'),
+    ('instructor', 'assessment.create'),
+    ('instructor', 'grade.write'),
+    ('instructor', 'student.read'),
+    ('instructor', 'student.update'),
+    ('instructor', 'progress.write'),
+    ('teaching_assistant', 'material.write'),
+    ('teaching_assistant', 'grade.write'),
+    ('teaching_assistant', 'student.read'),
+    ('teaching_assistant', 'progress.write'),
+    ('observer', 'student.read');
+
+
+-- Roles are now rows instead of a fixed enum
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50) USING role::text;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'student';
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+
+ALTER TABLE saml_providers ALTER COLUMN default_role DROP DEFAULT;
+ALTER TABLE saml_providers ALTER COLUMN default_role TYPE VARCHAR(50) USING default_role::text;
+ALTER TABLE saml_providers ALTER COLUMN default_role SET DEFAULT 'student';
+ALTER TABLE saml_providers ADD CONSTRAINT saml_providers_default_role_fkey FOREIGN KEY (default_role) REFERENCES roles(name) ON UPDATE CASCADE;
+
+DROP TYPE user_role;
+
+-- migrate:down
+CREATE TYPE user_role AS ENUM ('student', 'instructor', 'admin');
+
+ALTER TABLE saml_providers DROP CONSTRAINT saml_providers_default_role_fkey;
+ALTER TABLE saml_providers ALTER COLUMN default_role DROP DEFAULT;
+ALTER TABLE saml_providers ALTER COLUMN default_role TYPE user_role USING default_role::user_role;
+ALTER TABLE saml_providers ALTER COLUMN default_role SET DEFAULT 'student';
+
+ALTER TABLE users DROP CONSTRAINT users_role_fkey;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'student';
+
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
@@ -0,0 +1,46 @@
+-- migrate:up
+CREATE TABLE course_staff (
+    course_id INT NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('owner', 'instructor', 'teaching_assistant', 'grader')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (course_id, user_id)
+);
+
+CREATE INDEX course_staff_user_id_idx ON course_staff (user_id);
+
+
+-- Course mutations are now authorized against course_staff. Administrators
+-- keep access to every course through course.manage_any.
+INSERT INTO permissions (name, description) VALUES
+    ('course.manage_any', 'Manage any course regardless of course staff membership');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'course.manage_any');
+
+DELETE FROM permissions WHERE name IN ('course.update', 'course.delete', 'curriculum.write', 'material.write', 'assessment.create');
+
+-- migrate:down
+INSERT INTO permissions (name, description) VALUES
+    ('course.update', 'Edit courses'),
+    ('course.delete', 'Delete courses'),
+    ('curriculum.write', 'Create, edit and delete curriculum sections'),
+    ('material.write', 'Create, edit and delete materials'),
+    ('assessment.create', 'Create assessments');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'course.update'),
+    ('admin', 'course.delete'),
+    ('admin', 'curriculum.write'),
+    ('admin', 'material.write'),
+    ('admin', 'assessment.create'),
+    ('instructor', 'course.update'),
+    ('instructor', 'course.delete'),
+    ('instructor', 'curriculum.write'),
+    ('instructor', 'material.write'),
+    ('instructor', 'assessment.create'),
+    ('teaching_assistant', 'material.write');
+
+DELETE FROM permissions WHERE name = 'course.manage_any';
+DROP TABLE course_staff;
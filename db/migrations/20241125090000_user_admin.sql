@@ -0,0 +1,18 @@
+-- migrate:up
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN password_reset_token_hash VARCHAR(64) UNIQUE; -- SHA-256 hex, set while a reset is pending
+ALTER TABLE users ADD COLUMN password_reset_expires_at TIMESTAMP;
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('user.create', 'Create user accounts with any role');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'user.create');
+
+-- migrate:down
+DELETE FROM permissions WHERE name = 'user.create';
+
+ALTER TABLE users DROP COLUMN password_reset_expires_at;
+ALTER TABLE users DROP COLUMN password_reset_token_hash;
+ALTER TABLE users DROP COLUMN suspended_at;
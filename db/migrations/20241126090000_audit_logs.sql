@@ -0,0 +1,25 @@
+-- migrate:up
+CREATE TABLE audit_logs (
+    id SERIAL PRIMARY KEY,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL, -- e.g. "impersonation.start"
+    target_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_logs_actor_id_idx ON audit_logs (actor_id);
+CREATE INDEX audit_logs_target_user_id_idx ON audit_logs (target_user_id);
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('user.impersonate', 'Sign in as another user for support purposes'),
+    ('audit.read', 'View the audit log');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'user.impersonate'),
+    ('admin', 'audit.read');
+
+-- migrate:down
+DELETE FROM permissions WHERE name IN ('user.impersonate', 'audit.read');
+DROP TABLE audit_logs;
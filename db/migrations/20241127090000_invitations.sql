@@ -0,0 +1,32 @@
+-- migrate:up
+CREATE TABLE invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE,
+    course_id INT REFERENCES courses(id) ON DELETE CASCADE, -- Optional course staff membership granted on acceptance
+    course_role VARCHAR(50) CHECK (course_role IN ('owner', 'instructor', 'teaching_assistant', 'grader')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 hex of the token sent in the invitation link
+    invited_by INT REFERENCES users(id) ON DELETE SET NULL,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL, -- Account created when the invitation was accepted
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((course_id IS NULL) = (course_role IS NULL))
+);
+
+-- At most one open invitation per email
+CREATE UNIQUE INDEX invitations_open_email_idx ON invitations (LOWER(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('user.invite', 'Invite users with a pre-assigned role and course staff membership');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'user.invite');
+
+-- migrate:down
+DELETE FROM permissions WHERE name = 'user.invite';
+DROP TABLE invitations;
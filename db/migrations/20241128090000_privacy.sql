@@ -0,0 +1,45 @@
+-- migrate:up
+CREATE TABLE data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    archive BYTEA, -- ZIP archive, set once the export is completed
+    error TEXT,
+    expires_at TIMESTAMP, -- The archive cannot be downloaded after this date
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+
+
+CREATE TABLE erasure_requests (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reason TEXT NOT NULL DEFAULT '',
+    reviewed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    review_note TEXT NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one request awaiting review per user
+CREATE UNIQUE INDEX erasure_requests_pending_user_id_idx ON erasure_requests (user_id) WHERE status = 'pending';
+
+
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMP; -- Set when the personal data of the user was anonymized
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('user.erase', 'Review erasure requests and anonymize user accounts');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'user.erase');
+
+-- migrate:down
+DELETE FROM permissions WHERE name = 'user.erase';
+
+ALTER TABLE users DROP COLUMN erased_at;
+DROP TABLE erasure_requests;
+DROP TABLE data_exports;
@@ -0,0 +1,38 @@
+-- migrate:up
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255) UNIQUE; -- Identifier of the user in the provisioning directory
+
+
+-- Groups of students following a course together, synced from the directory as SCIM groups
+CREATE TABLE cohorts (
+    id SERIAL PRIMARY KEY,
+    course_id INT NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (course_id, name)
+);
+
+ALTER TABLE enrollments ADD COLUMN cohort_id INT REFERENCES cohorts(id) ON DELETE SET NULL;
+CREATE INDEX enrollments_cohort_id_idx ON enrollments (cohort_id);
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('scim.provision', 'Provision users and cohorts through the SCIM API');
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('scim_provisioner', 'Service account used by the identity provider to sync users and cohorts', FALSE);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'scim.provision'),
+    ('scim_provisioner', 'scim.provision');
+
+-- migrate:down
+DELETE FROM roles WHERE name = 'scim_provisioner';
+DELETE FROM permissions WHERE name = 'scim.provision';
+
+DROP INDEX enrollments_cohort_id_idx;
+ALTER TABLE enrollments DROP COLUMN cohort_id;
+DROP TABLE cohorts;
+
+ALTER TABLE users DROP COLUMN external_id;
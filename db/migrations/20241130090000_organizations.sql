@@ -0,0 +1,123 @@
+-- migrate:up
+-- Schools sharing the platform. Every user, course and everything attached to
+-- them belongs to exactly one organization.
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) UNIQUE NOT NULL, -- Subdomain and X-Organization header value, e.g. "riverside"
+    name VARCHAR(255) NOT NULL,
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Owns the existing data, and is the platform organization
+INSERT INTO organizations (id, slug, name) VALUES (1, 'default', 'Default organization');
+SELECT setval('organizations_id_seq', 1);
+
+
+-- Existing rows go to the default organization, new rows must name theirs
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'users', 'courses', 'curriculums', 'materials', 'enrollments', 'progress_tracking', 'student_paths',
+        'assessments', 'submissions', 'achievements', 'user_identities', 'saml_providers', 'api_tokens',
+        'course_staff', 'audit_logs', 'invitations', 'data_exports', 'erasure_requests', 'cohorts'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN organization_id INT NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE', t);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN organization_id DROP DEFAULT', t);
+        EXECUTE format('CREATE INDEX %I ON %I (organization_id)', t || '_organization_id_idx', t);
+    END LOOP;
+END $$;
+
+
+-- Unique values are only unique within an organization
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_organization_id_email_key UNIQUE (organization_id, email);
+ALTER TABLE users DROP CONSTRAINT users_external_id_key;
+ALTER TABLE users ADD CONSTRAINT users_organization_id_external_id_key UNIQUE (organization_id, external_id);
+ALTER TABLE cohorts DROP CONSTRAINT cohorts_external_id_key;
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_organization_id_external_id_key UNIQUE (organization_id, external_id);
+ALTER TABLE user_identities DROP CONSTRAINT user_identities_provider_subject_key;
+ALTER TABLE user_identities ADD CONSTRAINT user_identities_organization_id_provider_subject_key UNIQUE (organization_id, provider, subject);
+ALTER TABLE saml_providers DROP CONSTRAINT saml_providers_organization_key;
+ALTER TABLE saml_providers ADD CONSTRAINT saml_providers_organization_id_organization_key UNIQUE (organization_id, organization);
+DROP INDEX invitations_open_email_idx;
+CREATE UNIQUE INDEX invitations_open_email_idx ON invitations (organization_id, LOWER(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+
+-- Rows can only reference rows of their own organization
+ALTER TABLE users ADD CONSTRAINT users_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE courses ADD CONSTRAINT courses_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE materials ADD CONSTRAINT materials_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE assessments ADD CONSTRAINT assessments_id_organization_id_key UNIQUE (id, organization_id);
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_id_organization_id_key UNIQUE (id, organization_id);
+
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE materials ADD CONSTRAINT materials_curriculum_tenant_fkey FOREIGN KEY (curriculum_id, organization_id) REFERENCES curriculums(id, organization_id);
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_cohort_tenant_fkey FOREIGN KEY (cohort_id, organization_id) REFERENCES cohorts(id, organization_id);
+ALTER TABLE progress_tracking ADD CONSTRAINT progress_tracking_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE progress_tracking ADD CONSTRAINT progress_tracking_curriculum_tenant_fkey FOREIGN KEY (curriculum_id, organization_id) REFERENCES curriculums(id, organization_id);
+ALTER TABLE progress_tracking ADD CONSTRAINT progress_tracking_material_tenant_fkey FOREIGN KEY (material_id, organization_id) REFERENCES materials(id, organization_id);
+ALTER TABLE student_paths ADD CONSTRAINT student_paths_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE student_paths ADD CONSTRAINT student_paths_curriculum_tenant_fkey FOREIGN KEY (curriculum_id, organization_id) REFERENCES curriculums(id, organization_id);
+ALTER TABLE assessments ADD CONSTRAINT assessments_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE submissions ADD CONSTRAINT submissions_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE submissions ADD CONSTRAINT submissions_assessment_tenant_fkey FOREIGN KEY (assessment_id, organization_id) REFERENCES assessments(id, organization_id);
+ALTER TABLE achievements ADD CONSTRAINT achievements_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE achievements ADD CONSTRAINT achievements_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE user_identities ADD CONSTRAINT user_identities_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE course_staff ADD CONSTRAINT course_staff_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE course_staff ADD CONSTRAINT course_staff_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE invitations ADD CONSTRAINT invitations_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE erasure_requests ADD CONSTRAINT erasure_requests_user_tenant_fkey FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id);
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_course_tenant_fkey FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id);
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('organization.manage', 'Change the name and settings of the organization'),
+    ('organization.create', 'Create organizations, from the platform organization only');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'organization.manage'),
+    ('admin', 'organization.create');
+
+-- migrate:down
+DELETE FROM permissions WHERE name IN ('organization.manage', 'organization.create');
+
+DELETE FROM organizations WHERE id <> 1;
+
+DROP INDEX invitations_open_email_idx;
+CREATE UNIQUE INDEX invitations_open_email_idx ON invitations (LOWER(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
+ALTER TABLE saml_providers DROP CONSTRAINT saml_providers_organization_id_organization_key;
+ALTER TABLE saml_providers ADD CONSTRAINT saml_providers_organization_key UNIQUE (organization);
+ALTER TABLE user_identities DROP CONSTRAINT user_identities_organization_id_provider_subject_key;
+ALTER TABLE user_identities ADD CONSTRAINT user_identities_provider_subject_key UNIQUE (provider, subject);
+ALTER TABLE cohorts DROP CONSTRAINT cohorts_organization_id_external_id_key;
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_external_id_key UNIQUE (external_id);
+ALTER TABLE users DROP CONSTRAINT users_organization_id_external_id_key;
+ALTER TABLE users ADD CONSTRAINT users_external_id_key UNIQUE (external_id);
+ALTER TABLE users DROP CONSTRAINT users_organization_id_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+-- Dropping the columns drops the composite keys and foreign keys using them
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'users', 'courses', 'curriculums', 'materials', 'enrollments', 'progress_tracking', 'student_paths',
+        'assessments', 'submissions', 'achievements', 'user_identities', 'saml_providers', 'api_tokens',
+        'course_staff', 'audit_logs', 'invitations', 'data_exports', 'erasure_requests', 'cohorts'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I DROP COLUMN organization_id CASCADE', t);
+    END LOOP;
+END $$;
+
+DROP TABLE organizations;
@@ -0,0 +1,34 @@
+-- migrate:up
+-- Courses go through draft -> in_review -> published -> archived. Only
+-- published courses are listed in the catalog, once published_at is reached.
+ALTER TABLE courses
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'in_review', 'published', 'archived')),
+    ADD COLUMN published_at TIMESTAMP, -- May be in the future for scheduled publications
+    ADD COLUMN archived_at TIMESTAMP,
+    ADD COLUMN reviewed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN review_note TEXT NOT NULL DEFAULT '', -- Reason given when the review was rejected
+    ADD CONSTRAINT courses_published_at_check CHECK (status NOT IN ('published', 'archived') OR published_at IS NOT NULL);
+
+-- Existing courses were visible to everyone and stay so
+UPDATE courses SET status = 'published', published_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+
+CREATE INDEX courses_catalog_idx ON courses (organization_id, published_at) WHERE status = 'published';
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('course.review', 'Approve or reject courses submitted for review');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'course.review');
+
+-- migrate:down
+DELETE FROM permissions WHERE name = 'course.review';
+
+DROP INDEX courses_catalog_idx;
+ALTER TABLE courses
+    DROP CONSTRAINT courses_published_at_check,
+    DROP COLUMN review_note,
+    DROP COLUMN reviewed_by,
+    DROP COLUMN archived_at,
+    DROP COLUMN published_at,
+    DROP COLUMN status;
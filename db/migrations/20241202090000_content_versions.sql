@@ -0,0 +1,64 @@
+-- migrate:up
+-- Every change to a course, a curriculum section or a material. data holds
+-- the versioned fields after the change (NULL once deleted), and diff the
+-- fields that changed since the previous version: {"field": {"from": .., "to": ..}}.
+CREATE TABLE content_versions (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('course', 'curriculum', 'material')),
+    entity_id INT NOT NULL, -- Not a foreign key, versions outlive deleted sections and materials
+    version INT NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'rollback')),
+    data JSONB,
+    diff JSONB NOT NULL DEFAULT '{}',
+    author_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, entity_type, entity_id, version),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX content_versions_course_id_idx ON content_versions (course_id, id);
+
+-- The existing content is the first version of everything, so the diff of
+-- the next change is against it
+INSERT INTO content_versions (organization_id, course_id, entity_type, entity_id, version, action, data)
+SELECT organization_id, id, 'course', id, 1, 'create', jsonb_build_object('title', title, 'description', description)
+FROM courses;
+
+INSERT INTO content_versions (organization_id, course_id, entity_type, entity_id, version, action, data)
+SELECT organization_id, course_id, 'curriculum', id, 1, 'create', jsonb_build_object('section_name', section_name, 'section_order', section_order)
+FROM curriculums WHERE course_id IS NOT NULL;
+
+INSERT INTO content_versions (organization_id, course_id, entity_type, entity_id, version, action, data)
+SELECT m.organization_id, c.course_id, 'material', m.id, 1, 'create',
+    jsonb_build_object('curriculum_id', m.curriculum_id, 'material_type', m.material_type, 'content', m.content, 'order', m."order")
+FROM materials m JOIN curriculums c ON c.id = m.curriculum_id WHERE c.course_id IS NOT NULL;
+
+
+-- The content of a course frozen when it is published. Students read the
+-- snapshot the course points to while the staff edit the live content.
+CREATE TABLE course_snapshots (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    version INT NOT NULL,
+    content JSONB NOT NULL,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (course_id, version),
+    UNIQUE (id, organization_id),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+-- Courses published before snapshots existed have none, and show their live
+-- content until they are published again.
+ALTER TABLE courses ADD COLUMN published_snapshot_id INT;
+ALTER TABLE courses ADD CONSTRAINT courses_published_snapshot_tenant_fkey
+    FOREIGN KEY (published_snapshot_id, organization_id) REFERENCES course_snapshots(id, organization_id);
+
+-- migrate:down
+ALTER TABLE courses DROP CONSTRAINT courses_published_snapshot_tenant_fkey;
+ALTER TABLE courses DROP COLUMN published_snapshot_id;
+DROP TABLE course_snapshots;
+DROP TABLE content_versions;
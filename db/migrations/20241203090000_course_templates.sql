@@ -0,0 +1,20 @@
+-- migrate:up
+-- Templates are courses kept as a starting point for new ones. Any user
+-- allowed to create courses can clone them.
+ALTER TABLE courses
+    ADD COLUMN is_template BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN cloned_from_id INT REFERENCES courses(id) ON DELETE SET NULL;
+
+CREATE INDEX courses_templates_idx ON courses (organization_id) WHERE is_template;
+
+-- Assessments can be given a deadline, shifted along when the course is
+-- cloned for a new term
+ALTER TABLE assessments ADD COLUMN due_at TIMESTAMP;
+
+-- migrate:down
+ALTER TABLE assessments DROP COLUMN due_at;
+
+DROP INDEX courses_templates_idx;
+ALTER TABLE courses
+    DROP COLUMN cloned_from_id,
+    DROP COLUMN is_template;
@@ -0,0 +1,56 @@
+-- migrate:up
+-- Course categories, nested under a parent category or at the top level
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    parent_id INT,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, slug),
+    UNIQUE (id, organization_id),
+    CHECK (parent_id <> id),
+    FOREIGN KEY (parent_id, organization_id) REFERENCES categories(id, organization_id) ON DELETE CASCADE
+);
+
+ALTER TABLE courses
+    ADD COLUMN category_id INT,
+    ADD COLUMN level VARCHAR(20) NOT NULL DEFAULT '' CHECK (level IN ('', 'beginner', 'intermediate', 'advanced')),
+    ADD COLUMN language VARCHAR(16) NOT NULL DEFAULT '', -- BCP 47 tag, e.g. "en" or "pt-BR"
+    ADD COLUMN estimated_minutes INT NOT NULL DEFAULT 0 CHECK (estimated_minutes >= 0), -- 0 when unknown
+    ADD CONSTRAINT courses_category_tenant_fkey FOREIGN KEY (category_id, organization_id)
+        REFERENCES categories(id, organization_id) ON DELETE SET NULL (category_id);
+
+CREATE INDEX courses_category_id_idx ON courses (category_id);
+
+-- Free-form tags, stored lowercased
+CREATE TABLE course_tags (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (course_id, tag),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX course_tags_tag_idx ON course_tags (organization_id, tag);
+
+
+INSERT INTO permissions (name, description) VALUES
+    ('category.manage', 'Create, rename, move and delete course categories');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'category.manage');
+
+-- migrate:down
+DELETE FROM permissions WHERE name = 'category.manage';
+
+DROP TABLE course_tags;
+DROP INDEX courses_category_id_idx;
+ALTER TABLE courses
+    DROP CONSTRAINT courses_category_tenant_fkey,
+    DROP COLUMN estimated_minutes,
+    DROP COLUMN language,
+    DROP COLUMN level,
+    DROP COLUMN category_id;
+DROP TABLE categories;
@@ -0,0 +1,98 @@
+-- migrate:up
+-- Text search configuration used to stem the content of a course, from its
+-- BCP 47 language tag. Languages without a configuration are not stemmed.
+CREATE FUNCTION search_config(language TEXT) RETURNS regconfig
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT (CASE lower(split_part(language, '-', 1))
+        WHEN 'ar' THEN 'arabic'
+        WHEN 'da' THEN 'danish'
+        WHEN 'de' THEN 'german'
+        WHEN 'en' THEN 'english'
+        WHEN 'es' THEN 'spanish'
+        WHEN 'fi' THEN 'finnish'
+        WHEN 'fr' THEN 'french'
+        WHEN 'hu' THEN 'hungarian'
+        WHEN 'id' THEN 'indonesian'
+        WHEN 'it' THEN 'italian'
+        WHEN 'nl' THEN 'dutch'
+        WHEN 'no' THEN 'norwegian'
+        WHEN 'nb' THEN 'norwegian'
+        WHEN 'pt' THEN 'portuguese'
+        WHEN 'ro' THEN 'romanian'
+        WHEN 'ru' THEN 'russian'
+        WHEN 'sv' THEN 'swedish'
+        WHEN 'tr' THEN 'turkish'
+        ELSE 'simple'
+    END)::regconfig
+$$;
+
+-- Titles weigh more than bodies: A for course titles and section names, B
+-- for course descriptions and the content of text materials
+ALTER TABLE courses ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(search_config(language), COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector(search_config(language), COALESCE(description, '')), 'B')
+) STORED;
+
+-- Sections and materials are stemmed in the language of their course, which
+-- a generated column cannot read, so they are maintained by triggers
+ALTER TABLE curriculums ADD COLUMN search_vector tsvector;
+ALTER TABLE materials ADD COLUMN search_vector tsvector;
+
+CREATE FUNCTION curriculums_search_vector() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := setweight(to_tsvector(
+        search_config((SELECT language FROM courses WHERE id = NEW.course_id)),
+        COALESCE(NEW.section_name, '')
+    ), 'A');
+    RETURN NEW;
+END $$;
+
+CREATE TRIGGER curriculums_search_vector BEFORE INSERT OR UPDATE OF course_id, section_name ON curriculums
+FOR EACH ROW EXECUTE FUNCTION curriculums_search_vector();
+
+-- Only text materials are indexed, the others hold URLs or quiz definitions
+CREATE FUNCTION materials_search_vector() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := CASE WHEN NEW.material_type = 'text' THEN setweight(to_tsvector(
+        search_config((SELECT c.language FROM curriculums s JOIN courses c ON c.id = s.course_id WHERE s.id = NEW.curriculum_id)),
+        COALESCE(NEW.content, '')
+    ), 'B') END;
+    RETURN NEW;
+END $$;
+
+CREATE TRIGGER materials_search_vector BEFORE INSERT OR UPDATE OF curriculum_id, material_type, content ON materials
+FOR EACH ROW EXECUTE FUNCTION materials_search_vector();
+
+-- Changing the language of a course stems its content again
+CREATE FUNCTION courses_reindex_content() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE curriculums SET section_name = section_name WHERE course_id = NEW.id;
+    UPDATE materials SET content = content WHERE curriculum_id IN (SELECT id FROM curriculums WHERE course_id = NEW.id);
+    RETURN NULL;
+END $$;
+
+CREATE TRIGGER courses_reindex_content AFTER UPDATE OF language ON courses
+FOR EACH ROW WHEN (OLD.language IS DISTINCT FROM NEW.language) EXECUTE FUNCTION courses_reindex_content();
+
+UPDATE curriculums SET section_name = section_name;
+UPDATE materials SET content = content;
+
+CREATE INDEX courses_search_idx ON courses USING GIN (search_vector);
+CREATE INDEX curriculums_search_idx ON curriculums USING GIN (search_vector);
+CREATE INDEX materials_search_idx ON materials USING GIN (search_vector);
+
+-- migrate:down
+DROP TRIGGER courses_reindex_content ON courses;
+DROP TRIGGER materials_search_vector ON materials;
+DROP TRIGGER curriculums_search_vector ON curriculums;
+DROP FUNCTION courses_reindex_content();
+DROP FUNCTION materials_search_vector();
+DROP FUNCTION curriculums_search_vector();
+
+ALTER TABLE materials DROP COLUMN search_vector;
+ALTER TABLE curriculums DROP COLUMN search_vector;
+ALTER TABLE courses DROP COLUMN search_vector;
+DROP FUNCTION search_config(TEXT);
@@ -0,0 +1,34 @@
+-- migrate:up
+-- Courses a student must have completed before enrolling, optionally with a
+-- minimum grade
+CREATE TABLE course_prerequisites (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    prerequisite_id INT NOT NULL,
+    min_grade INT CHECK (min_grade BETWEEN 0 AND 100), -- NULL when completing the course is enough
+    PRIMARY KEY (course_id, prerequisite_id),
+    CHECK (prerequisite_id <> course_id),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE,
+    FOREIGN KEY (prerequisite_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX course_prerequisites_prerequisite_id_idx ON course_prerequisites (prerequisite_id);
+
+-- Who can enroll in a course and when. Courses without a row are open to
+-- everyone, and so are empty lists.
+CREATE TABLE course_requirements (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT PRIMARY KEY,
+    roles JSONB NOT NULL DEFAULT '[]', -- Platform roles allowed to enroll
+    email_domains JSONB NOT NULL DEFAULT '[]', -- Lowercased, e.g. "example.edu"
+    opens_at TIMESTAMP, -- Enrollment window, open-ended when NULL
+    closes_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (closes_at > opens_at),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE course_requirements;
+DROP INDEX course_prerequisites_prerequisite_id_idx;
+DROP TABLE course_prerequisites;
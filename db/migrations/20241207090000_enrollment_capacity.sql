@@ -0,0 +1,53 @@
+-- migrate:up
+-- A student is enrolled in a course at most once
+DELETE FROM enrollments e USING enrollments d
+WHERE e.course_id = d.course_id AND e.user_id = d.user_id AND e.id > d.id;
+
+ALTER TABLE enrollments ADD CONSTRAINT enrollments_course_id_user_id_key UNIQUE (course_id, user_id);
+
+-- Seats of a course, unlimited when NULL
+ALTER TABLE course_requirements ADD COLUMN capacity INT CHECK (capacity > 0);
+
+-- Students waiting for a seat in a full course, first come first served
+CREATE TABLE course_waitlist (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (course_id, user_id),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id, organization_id) REFERENCES users(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX course_waitlist_user_id_idx ON course_waitlist (user_id);
+
+-- Enrolls the students at the head of the waitlist of the course in the
+-- seats left. Enrollments in a course are serialized by locking its row.
+CREATE FUNCTION promote_waitlist(target INT) RETURNS void
+LANGUAGE plpgsql AS $$
+DECLARE
+    seats INT;
+BEGIN
+    PERFORM 1 FROM courses WHERE id = target FOR UPDATE;
+
+    -- NULL, and no limit, when the course has no capacity
+    SELECT r.capacity - (SELECT COUNT(*) FROM enrollments e WHERE e.course_id = target) INTO seats
+    FROM course_requirements r WHERE r.course_id = target;
+
+    WITH promoted AS (
+        DELETE FROM course_waitlist WHERE id IN (
+            SELECT id FROM course_waitlist WHERE course_id = target ORDER BY id LIMIT CASE WHEN seats < 0 THEN 0 ELSE seats END
+        )
+        RETURNING organization_id, user_id, course_id
+    )
+    INSERT INTO enrollments (organization_id, user_id, course_id, enrolled_at)
+    SELECT organization_id, user_id, course_id, CURRENT_TIMESTAMP FROM promoted
+    ON CONFLICT (course_id, user_id) DO NOTHING;
+END $$;
+
+-- migrate:down
+DROP FUNCTION promote_waitlist(INT);
+DROP TABLE course_waitlist;
+ALTER TABLE course_requirements DROP COLUMN capacity;
+ALTER TABLE enrollments DROP CONSTRAINT enrollments_course_id_user_id_key;
@@ -0,0 +1,47 @@
+-- migrate:up
+-- Cohorts run the course on their own schedule, open-ended when NULL
+ALTER TABLE cohorts ADD COLUMN starts_at TIMESTAMP;
+ALTER TABLE cohorts ADD COLUMN ends_at TIMESTAMP;
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_schedule_check CHECK (ends_at > starts_at);
+ALTER TABLE cohorts ADD CONSTRAINT cohorts_id_course_id_key UNIQUE (id, course_id);
+ALTER TABLE assessments ADD CONSTRAINT assessments_id_course_id_key UNIQUE (id, course_id);
+
+-- Members of the course staff assigned to a cohort, e.g. its teaching
+-- assistants. They leave it when they leave the course staff.
+CREATE TABLE cohort_staff (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    cohort_id INT NOT NULL,
+    course_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cohort_id, user_id),
+    FOREIGN KEY (cohort_id, course_id) REFERENCES cohorts(id, course_id) ON DELETE CASCADE,
+    FOREIGN KEY (course_id, user_id) REFERENCES course_staff(course_id, user_id) ON DELETE CASCADE,
+    FOREIGN KEY (cohort_id, organization_id) REFERENCES cohorts(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX cohort_staff_user_id_idx ON cohort_staff (user_id);
+
+-- Due dates of the assessments of the course replaced for a cohort
+CREATE TABLE cohort_due_dates (
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    cohort_id INT NOT NULL,
+    course_id INT NOT NULL,
+    assessment_id INT NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (cohort_id, assessment_id),
+    FOREIGN KEY (cohort_id, course_id) REFERENCES cohorts(id, course_id) ON DELETE CASCADE,
+    FOREIGN KEY (assessment_id, course_id) REFERENCES assessments(id, course_id) ON DELETE CASCADE,
+    FOREIGN KEY (cohort_id, organization_id) REFERENCES cohorts(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX cohort_due_dates_assessment_id_idx ON cohort_due_dates (assessment_id);
+
+-- migrate:down
+DROP TABLE cohort_due_dates;
+DROP TABLE cohort_staff;
+ALTER TABLE assessments DROP CONSTRAINT assessments_id_course_id_key;
+ALTER TABLE cohorts DROP CONSTRAINT cohorts_id_course_id_key;
+ALTER TABLE cohorts DROP CONSTRAINT cohorts_schedule_check;
+ALTER TABLE cohorts DROP COLUMN ends_at;
+ALTER TABLE cohorts DROP COLUMN starts_at;
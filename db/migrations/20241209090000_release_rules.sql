@@ -0,0 +1,24 @@
+-- migrate:up
+-- When a curriculum section or a material becomes available to a student:
+-- at a date, some days after they enrolled, or once they completed another
+-- section. The materials of a section wait for it to be released too.
+CREATE TABLE release_rules (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    course_id INT NOT NULL,
+    curriculum_id INT UNIQUE REFERENCES curriculums(id) ON DELETE CASCADE,
+    material_id INT UNIQUE REFERENCES materials(id) ON DELETE CASCADE,
+    release_at TIMESTAMP,
+    days_after_enrollment INT CHECK (days_after_enrollment >= 0),
+    after_curriculum_id INT REFERENCES curriculums(id) ON DELETE CASCADE, -- Section to complete first
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(curriculum_id, material_id) = 1),
+    CHECK (num_nonnulls(release_at, days_after_enrollment, after_curriculum_id) = 1),
+    FOREIGN KEY (course_id, organization_id) REFERENCES courses(id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX release_rules_course_id_idx ON release_rules (course_id);
+
+-- migrate:down
+DROP TABLE release_rules;
@@ -0,0 +1,11 @@
+-- migrate:up
+-- Preview sections are free samples: users who can see the course read them
+-- without enrolling. The rest of the content is for enrolled students and
+-- the course staff.
+ALTER TABLE curriculums ADD COLUMN is_preview BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX curriculums_preview_idx ON curriculums (course_id) WHERE is_preview;
+
+-- migrate:down
+DROP INDEX curriculums_preview_idx;
+ALTER TABLE curriculums DROP COLUMN is_preview;
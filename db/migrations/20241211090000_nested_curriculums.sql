@@ -0,0 +1,29 @@
+-- migrate:up
+-- Sections nest to any depth, e.g. modules made of lessons. Each one is
+-- ordered among its siblings, and is deleted with its parent.
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_id_course_id_key UNIQUE (id, course_id);
+ALTER TABLE curriculums ADD COLUMN parent_id INT;
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_parent_fkey
+    FOREIGN KEY (parent_id, course_id) REFERENCES curriculums(id, course_id) ON DELETE CASCADE;
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_parent_check CHECK (parent_id <> id);
+
+CREATE INDEX curriculums_parent_id_idx ON curriculums (parent_id);
+
+-- The section followed by its ancestors, up to the top-level one
+CREATE FUNCTION curriculum_path(section INT) RETURNS INT[]
+LANGUAGE sql STABLE AS $$
+    WITH RECURSIVE path (id, parent_id, depth) AS (
+        SELECT id, parent_id, 0 FROM curriculums WHERE id = section
+        UNION ALL
+        SELECT c.id, c.parent_id, path.depth + 1 FROM curriculums c JOIN path ON c.id = path.parent_id
+    )
+    SELECT COALESCE(array_agg(id ORDER BY depth), '{}') FROM path;
+$$;
+
+-- migrate:down
+DROP FUNCTION curriculum_path(INT);
+DROP INDEX curriculums_parent_id_idx;
+ALTER TABLE curriculums DROP CONSTRAINT curriculums_parent_check;
+ALTER TABLE curriculums DROP CONSTRAINT curriculums_parent_fkey;
+ALTER TABLE curriculums DROP COLUMN parent_id;
+ALTER TABLE curriculums DROP CONSTRAINT curriculums_id_course_id_key;
@@ -0,0 +1,7 @@
+-- migrate:up
+-- Roles the role attribute of an identity provider may grant. Any other
+-- value falls back to default_role.
+ALTER TABLE saml_providers ADD COLUMN allowed_roles JSONB NOT NULL DEFAULT '[]';
+
+-- migrate:down
+ALTER TABLE saml_providers DROP COLUMN allowed_roles;
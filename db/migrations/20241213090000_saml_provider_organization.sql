@@ -0,0 +1,24 @@
+-- migrate:up
+-- An organization has one identity provider, and the slug in the SP URLs is
+-- the slug of the organization. Where several providers were configured
+-- under free-text slugs, the one using the organization slug is kept, or
+-- else the last updated; identity providers configured with another slug
+-- must be given the new SP metadata.
+DELETE FROM saml_providers p
+WHERE p.id <> (
+    SELECT keep.id FROM saml_providers keep
+    JOIN organizations o ON o.id = keep.organization_id
+    WHERE keep.organization_id = p.organization_id
+    ORDER BY keep.organization = o.slug DESC, keep.updated_at DESC, keep.id DESC
+    LIMIT 1
+);
+ALTER TABLE saml_providers DROP CONSTRAINT saml_providers_organization_id_organization_key;
+ALTER TABLE saml_providers DROP COLUMN organization;
+ALTER TABLE saml_providers ADD CONSTRAINT saml_providers_organization_id_key UNIQUE (organization_id);
+
+-- migrate:down
+ALTER TABLE saml_providers DROP CONSTRAINT saml_providers_organization_id_key;
+ALTER TABLE saml_providers ADD COLUMN organization VARCHAR(255);
+UPDATE saml_providers p SET organization = o.slug FROM organizations o WHERE o.id = p.organization_id;
+ALTER TABLE saml_providers ALTER COLUMN organization SET NOT NULL;
+ALTER TABLE saml_providers ADD CONSTRAINT saml_providers_organization_id_organization_key UNIQUE (organization_id, organization);
@@ -0,0 +1,24 @@
+-- migrate:up
+-- Sections are numbered from 1 among their siblings, and materials within
+-- their section. Existing duplicates are renumbered in their current order.
+UPDATE curriculums c SET section_order = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY course_id, parent_id ORDER BY section_order, id) AS position FROM curriculums
+) numbered
+WHERE c.id = numbered.id AND c.section_order <> numbered.position;
+
+UPDATE materials m SET "order" = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY curriculum_id ORDER BY "order", id) AS position FROM materials
+) numbered
+WHERE m.id = numbered.id AND m."order" <> numbered.position;
+
+-- Checked at commit, so a transaction can renumber siblings one at a time
+ALTER TABLE curriculums ADD CONSTRAINT curriculums_course_id_parent_id_section_order_key
+    UNIQUE NULLS NOT DISTINCT (course_id, parent_id, section_order) DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE materials ADD CONSTRAINT materials_curriculum_id_order_key
+    UNIQUE (curriculum_id, "order") DEFERRABLE INITIALLY DEFERRED;
+
+-- migrate:down
+ALTER TABLE materials DROP CONSTRAINT materials_curriculum_id_order_key;
+ALTER TABLE curriculums DROP CONSTRAINT curriculums_course_id_parent_id_section_order_key;
@@ -0,0 +1,9 @@
+package dto
+
+// AccountStatus is the current state of an account, checked on every
+// authenticated request.
+type AccountStatus struct {
+	Role                  string `json:"role"`
+	Suspended             bool   `json:"suspended"`
+	PasswordResetRequired bool   `json:"password_reset_required"`
+}
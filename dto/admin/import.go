@@ -0,0 +1,22 @@
+package dto
+
+type ImportOptions struct {
+	DryRun          bool `json:"dry_run"`
+	SendInvitations bool `json:"send_invitations"`
+}
+
+type ImportRowResult struct {
+	Line            int      `json:"line"`
+	Email           string   `json:"email"`
+	UserID          uint64   `json:"user_id,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+	InvitationError string   `json:"invitation_error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
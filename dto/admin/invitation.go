@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+type CreateInvitationRequest struct {
+	Email      string  `json:"email" binding:"required,email"`
+	Name       string  `json:"name"`
+	Role       string  `json:"role"` // Defaults to "instructor"
+	CourseID   *uint64 `json:"course_id"`
+	CourseRole string  `json:"course_role"` // Defaults to "instructor" when course_id is set
+}
+
+type Invitation struct {
+	ID         uint64     `json:"id"`
+	Email      string     `json:"email"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	CourseID   *uint64    `json:"course_id"`
+	CourseRole *string    `json:"course_role"`
+	Status     string     `json:"status"` // "pending", "expired", "accepted" or "revoked"
+	InvitedBy  *uint64    `json:"invited_by"`
+	UserID     *uint64    `json:"user_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// InvitationResponse is returned when an invitation is sent or resent. The
+// token is only included when the invitation email could not be sent, so
+// the link can be delivered by other means.
+type InvitationResponse struct {
+	Invitation *Invitation `json:"invitation"`
+	EmailSent  bool        `json:"email_sent"`
+	Token      string      `json:"token,omitempty"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type User struct {
+	ID                    uint64     `json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+type UserSearchRequest struct {
+	Search string `json:"search"`
+	Role   string `json:"role"`
+	Status string `json:"status"` // "active" or "suspended"
+}
+
+type CreateUserRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role"`
+}
+
+type UpdateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// PasswordResetResponse tells when the token emailed to the user expires.
+type PasswordResetResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+}
+
+type AuditLog struct {
+	ID           uint64          `json:"id"`
+	ActorID      *uint64         `json:"actor_id"`
+	Action       string          `json:"action"`
+	TargetUserID *uint64         `json:"target_user_id"`
+	Metadata     json.RawMessage `json:"metadata"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
@@ -1,17 +1,21 @@
 package dto
 
+import "time"
+
 type Assessment struct {
-	ID        int    `json:"id"`
-	CourseID  int    `json:"course_id"`
-	Type      string `json:"type"` // e.g., "multiple-choice", "essay"
-	Question  string `json:"question"`
-	CreatedAt string `json:"created_at"`
+	ID        int        `json:"id"`
+	CourseID  int        `json:"course_id"`
+	Type      string     `json:"type"` // e.g., "multiple-choice", "essay"
+	Question  string     `json:"question"`
+	DueAt     *time.Time `json:"due_at"`
+	CreatedAt string     `json:"created_at"`
 }
 
 type CreateAssessmentRequest struct {
-	CourseID int    `json:"course_id" binding:"required"`
-	Type     string `json:"type" binding:"required"` // e.g., "multiple-choice", "essay"
-	Question string `json:"question" binding:"required"`
+	CourseID int        `json:"course_id" binding:"required"`
+	Type     string     `json:"type" binding:"required"` // e.g., "multiple-choice", "essay"
+	Question string     `json:"question" binding:"required"`
+	DueAt    *time.Time `json:"due_at"`
 }
 
 type UpdateAssessmentRequest struct {
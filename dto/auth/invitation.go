@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// InvitationDetails is what the invitee sees before accepting.
+type InvitationDetails struct {
+	Email      string    `json:"email"`
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	CourseID   *uint64   `json:"course_id"`
+	CourseRole *string   `json:"course_role"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name"` // Defaults to the name given by the inviter
+	Password string `json:"password" binding:"required,min=6"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type SAMLResponseRequest struct {
+	SAMLResponse string `json:"SAMLResponse" binding:"required"`
+	RelayState   string `json:"RelayState"`
+}
+
+type SAMLProviderRequest struct {
+	Metadata       string `json:"metadata" binding:"required"` // IdP EntityDescriptor XML
+	DefaultRole    string `json:"default_role"`
+	EmailAttribute string `json:"email_attribute"`
+	NameAttribute  string `json:"name_attribute"`
+	RoleAttribute  string `json:"role_attribute"`
+	// AllowedRoles are the roles the role attribute may grant. Other values,
+	// and all values when empty, give the default role.
+	AllowedRoles []string `json:"allowed_roles"`
+}
+
+type SAMLProvider struct {
+	ID             int       `json:"id"`
+	OrganizationID uint64    `json:"organization_id"`
+	EntityID       string    `json:"entity_id"`
+	SSOURL         string    `json:"sso_url"`
+	DefaultRole    string    `json:"default_role"`
+	EmailAttribute string    `json:"email_attribute"`
+	NameAttribute  string    `json:"name_attribute"`
+	RoleAttribute  string    `json:"role_attribute"`
+	AllowedRoles   []string  `json:"allowed_roles"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
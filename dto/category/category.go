@@ -0,0 +1,16 @@
+package dto
+
+type Category struct {
+	ID       uint64  `json:"id"`
+	ParentID *uint64 `json:"parent_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+}
+
+// SaveCategoryRequest creates or updates a category. The slug is derived
+// from the name when it is empty.
+type SaveCategoryRequest struct {
+	ParentID *uint64 `json:"parent_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+}
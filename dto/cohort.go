@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+// Cohort is a group of students following a course on its own schedule, with
+// its own staff and due dates.
+type Cohort struct {
+	ID         uint64           `json:"id"`
+	CourseID   uint64           `json:"course_id"`
+	Name       string           `json:"name"`
+	ExternalID *string          `json:"external_id"` // Set for the cohorts synced from the directory
+	StartsAt   *time.Time       `json:"starts_at"`
+	EndsAt     *time.Time       `json:"ends_at"`
+	Students   int              `json:"students"`
+	Staff      []*CohortStaff   `json:"staff"`
+	DueDates   []*CohortDueDate `json:"due_dates"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+type CohortStaff struct {
+	UserID uint64 `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+type CohortDueDate struct {
+	AssessmentID uint64    `json:"assessment_id"`
+	DueAt        time.Time `json:"due_at"`
+}
+
+// SaveCohortRequest creates a cohort or replaces its schedule, staff and due
+// dates. Staff must be part of the course staff.
+type SaveCohortRequest struct {
+	Name     string           `json:"name"`
+	StartsAt *time.Time       `json:"starts_at"`
+	EndsAt   *time.Time       `json:"ends_at"`
+	StaffIDs []uint64         `json:"staff_ids"`
+	DueDates []*CohortDueDate `json:"due_dates"`
+}
+
+// CohortMembersRequest moves enrolled students to a cohort.
+type CohortMembersRequest struct {
+	UserIDs []uint64 `json:"user_ids"`
+}
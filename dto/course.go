@@ -1,17 +1,242 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+	materialDto "github.com/dapthehuman/learning-management-system/dto/material"
+	"github.com/dapthehuman/learning-management-system/listing"
+)
+
 type Course struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Status       string     `json:"status"`
+	PublishedAt  *time.Time `json:"published_at"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+	ReviewNote   string     `json:"review_note,omitempty"`
+	IsTemplate   bool       `json:"is_template"`
+	ClonedFromID *uint64    `json:"cloned_from_id,omitempty"`
+
+	CategoryID       *uint64  `json:"category_id"`
+	Level            string   `json:"level"`
+	Language         string   `json:"language"`
+	EstimatedMinutes int      `json:"estimated_minutes"`
+	Tags             []string `json:"tags"`
+}
+
+// CourseViewer is the user a course is shown to, used to decide which
+// courses they can see besides the catalog.
+type CourseViewer struct {
+	UserID    uint64
+	ManageAny bool // Granted "course.manage_any"
+	CanReview bool // Granted "course.review"
+}
+
+// PublishCourseRequest publishes a course now, or at PublishAt if it is in
+// the future.
+type PublishCourseRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type RejectCourseRequest struct {
+	Note string `json:"note"`
 }
 
 type CreateCourseRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	CategoryID       *uint64  `json:"category_id"`
+	Level            string   `json:"level"`    // "beginner", "intermediate", "advanced" or empty
+	Language         string   `json:"language"` // BCP 47 tag, e.g. "en" or "pt-BR"
+	EstimatedMinutes int      `json:"estimated_minutes"`
+	Tags             []string `json:"tags"`
 }
 
 type UpdateCourseRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	CategoryID       *uint64  `json:"category_id"`
+	Level            string   `json:"level"`
+	Language         string   `json:"language"`
+	EstimatedMinutes int      `json:"estimated_minutes"`
+	Tags             []string `json:"tags"`
+}
+
+// CatalogFilter narrows the catalog down. Empty fields do not filter.
+type CatalogFilter struct {
+	Category string   // ID or slug, matching the courses of its subcategories too
+	Tags     []string // Courses must have all of them
+	Level    string
+	Language string
+	Duration string // One of the values of the duration facet
+}
+
+// Catalog lists the courses matching a filter with the facets to refine it.
+// Each facet counts the courses matching the other filters, so the value of
+// a dimension can be changed without losing the others; tags, which all have
+// to match, are counted among the courses found.
+type Catalog struct {
+	listing.Page[*Course]
+	Facets CatalogFacets `json:"facets"`
+}
+
+type CatalogFacets struct {
+	Categories []*CategoryFacet `json:"categories"` // Counting the courses of subcategories
+	Tags       []*Facet         `json:"tags"`
+	Levels     []*Facet         `json:"levels"`
+	Languages  []*Facet         `json:"languages"`
+	Durations  []*Facet         `json:"durations"`
+}
+
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type CategoryFacet struct {
+	ID       uint64  `json:"id"`
+	ParentID *uint64 `json:"parent_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	Count    int     `json:"count"`
+}
+
+// CloneCourseRequest copies a course, shifting the dates of its content by
+// ShiftDays, e.g. to reuse it for the next term.
+type CloneCourseRequest struct {
+	Title      string `json:"title"` // The title of the course if empty
+	IsTemplate bool   `json:"is_template"`
+	ShiftDays  int    `json:"shift_days"`
+}
+
+type TemplateCourseRequest struct {
+	IsTemplate bool `json:"is_template"`
+}
+
+type CourseStaff struct {
+	CourseID uint64 `json:"course_id"`
+	UserID   uint64 `json:"user_id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+type SaveCourseStaffRequest struct {
+	UserID uint64 `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// CourseRequirements are the conditions students must meet to enroll in a
+// course, and its number of seats. Empty lists and null values do not
+// restrict anything.
+type CourseRequirements struct {
+	CourseID      uint64          `json:"course_id"`
+	Prerequisites []*Prerequisite `json:"prerequisites"`
+	Roles         []string        `json:"roles"`
+	EmailDomains  []string        `json:"email_domains"`
+	OpensAt       *time.Time      `json:"opens_at"`
+	ClosesAt      *time.Time      `json:"closes_at"`
+	Capacity      *int            `json:"capacity"` // Null for unlimited seats
+}
+
+type Prerequisite struct {
+	CourseID uint64 `json:"course_id"`
+	Title    string `json:"title"`
+	MinGrade *int   `json:"min_grade"` // Out of 100, null when completing the course is enough
+}
+
+// SaveCourseRequirementsRequest replaces the requirements of a course.
+type SaveCourseRequirementsRequest struct {
+	Prerequisites []*PrerequisiteRequest `json:"prerequisites"`
+	Roles         []string               `json:"roles"`
+	EmailDomains  []string               `json:"email_domains"`
+	OpensAt       *time.Time             `json:"opens_at"`
+	ClosesAt      *time.Time             `json:"closes_at"`
+	Capacity      *int                   `json:"capacity"`
+}
+
+type PrerequisiteRequest struct {
+	CourseID uint64 `json:"course_id"`
+	MinGrade *int   `json:"min_grade"`
+}
+
+// CourseContent is the content of a course as students read it.
+type CourseContent struct {
+	Course      *Course                         `json:"course"`
+	Curriculums []*curriculumDto.Curriculum     `json:"curriculums"`
+	Materials   []*materialDto.MaterialResponse `json:"materials"`
+}
+
+// OutlineSection is a section of the outline of a course, with its
+// materials and the sections nested in it, in their order.
+type OutlineSection struct {
+	*curriculumDto.Curriculum
+	Materials []*materialDto.MaterialResponse `json:"materials"`
+	Sections  []*OutlineSection               `json:"sections"`
+}
+
+// ReorderOutlineRequest is the order of all the sections of a course, nested
+// in each other, and of all the materials of each section.
+type ReorderOutlineRequest struct {
+	Sections []*OutlineOrder `json:"sections"`
+}
+
+// OutlineOrder places a section at its position among its siblings, with its
+// materials and the sections nested in it in their order.
+type OutlineOrder struct {
+	ID        uint64          `json:"id"`
+	Materials []uint64        `json:"materials"`
+	Sections  []*OutlineOrder `json:"sections"`
+}
+
+type CourseSnapshot struct {
+	ID        uint64         `json:"id"`
+	CourseID  uint64         `json:"course_id"`
+	Version   int            `json:"version"`
+	Content   *CourseContent `json:"content,omitempty"`
+	CreatedBy *uint64        `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ContentVersion is a change to a course, a curriculum section or a material.
+type ContentVersion struct {
+	ID         uint64          `json:"id"`
+	CourseID   uint64          `json:"course_id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint64          `json:"entity_id"`
+	Version    int             `json:"version"`
+	Action     string          `json:"action"`
+	Data       json.RawMessage `json:"data"`
+	Diff       json.RawMessage `json:"diff"`
+	AuthorID   *uint64         `json:"author_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ReleaseRule holds back a curriculum section or a material until a date,
+// some days after the student enrolled, or until they complete another
+// section.
+type ReleaseRule struct {
+	ID                  uint64     `json:"id"`
+	CourseID            uint64     `json:"course_id"`
+	CurriculumID        *uint64    `json:"curriculum_id"`
+	MaterialID          *uint64    `json:"material_id"`
+	ReleaseAt           *time.Time `json:"release_at"`
+	DaysAfterEnrollment *int       `json:"days_after_enrollment"`
+	AfterCurriculumID   *uint64    `json:"after_curriculum_id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// SaveReleaseRuleRequest sets the release rule of a curriculum section or a
+// material, replacing its previous one. It names exactly one target and one
+// condition.
+type SaveReleaseRuleRequest struct {
+	CurriculumID        *uint64    `json:"curriculum_id"`
+	MaterialID          *uint64    `json:"material_id"`
+	ReleaseAt           *time.Time `json:"release_at"`
+	DaysAfterEnrollment *int       `json:"days_after_enrollment"`
+	AfterCurriculumID   *uint64    `json:"after_curriculum_id"`
 }
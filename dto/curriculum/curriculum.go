@@ -3,28 +3,58 @@ package dto
 import "time"
 
 type Curriculum struct {
-	ID           int    `json:"id"`
-	CourseID     int    `json:"course_id"`
-	SectionName  string `json:"section_name"`
-	SectionOrder int    `json:"section_order"`
+	ID           int     `json:"id"`
+	CourseID     int     `json:"course_id"`
+	ParentID     *uint64 `json:"parent_id"` // Nil for top-level sections
+	SectionName  string  `json:"section_name"`
+	SectionOrder int     `json:"section_order"`
+	IsPreview    bool    `json:"is_preview"`     // Free sample readable without enrolling
+	Lock         *Lock   `json:"lock,omitempty"` // Set while the section is not released to the student
 }
 
+// Reasons content is locked.
+const (
+	LockDate       = "date"       // Released at AvailableAt
+	LockEnrollment = "enrollment" // Released some days after enrolling, at AvailableAt once enrolled
+	LockCompletion = "completion" // Released once the section CurriculumID is completed
+)
+
+// Lock tells a student why content is not available to them yet.
+type Lock struct {
+	Reason       string     `json:"reason"`
+	AvailableAt  *time.Time `json:"available_at,omitempty"`
+	CurriculumID *uint64    `json:"curriculum_id,omitempty"`
+}
+
+// CreateCurriculumRequest adds a section after the last one of its parent.
 type CreateCurriculumRequest struct {
-	CourseID     int    `json:"course_id"`
-	SectionName  string `json:"section_name"`
-	SectionOrder int    `json:"section_order"`
+	CourseID    int     `json:"course_id"`
+	ParentID    *uint64 `json:"parent_id"`
+	SectionName string  `json:"section_name"`
+	IsPreview   bool    `json:"is_preview"`
 }
 
 type CurriculumResponse struct {
 	ID           int       `json:"id"`
 	CourseID     int       `json:"course_id"`
+	ParentID     *uint64   `json:"parent_id"`
 	SectionName  string    `json:"section_name"`
 	SectionOrder int       `json:"section_order"`
+	IsPreview    bool      `json:"is_preview"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// UpdateCurriculumRequest leaves the place of the section unchanged, see
+// MoveCurriculumRequest.
 type UpdateCurriculumRequest struct {
-	SectionName  string `json:"section_name"`
-	SectionOrder int    `json:"section_order"`
+	SectionName string `json:"section_name"`
+	IsPreview   *bool  `json:"is_preview"` // Unchanged if omitted
+}
+
+// MoveCurriculumRequest moves a section under another one, or to the top
+// level when ParentID is nil, at the given position among its new siblings.
+type MoveCurriculumRequest struct {
+	ParentID *uint64 `json:"parent_id"`
+	Position int     `json:"position"` // Starting at 1, last if 0 or past the end
 }
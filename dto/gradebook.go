@@ -0,0 +1,40 @@
+package dto
+
+import "time"
+
+type GradebookEntry struct {
+	UserID   uint64             `json:"user_id"`
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	CohortID *uint64            `json:"cohort_id"`
+	Grade    float64            `json:"grade"`
+	Grades   []*AssessmentGrade `json:"grades"`
+}
+
+type AssessmentGrade struct {
+	AssessmentID uint64     `json:"assessment_id"`
+	Grade        *int       `json:"grade"`
+	DueAt        *time.Time `json:"due_at"` // Due date for the cohort of the student
+	SubmittedAt  *time.Time `json:"submitted_at"`
+}
+
+type StudentProgress struct {
+	UserID     uint64  `json:"user_id"`
+	Name       string  `json:"name"`
+	CohortID   *uint64 `json:"cohort_id"`
+	Completed  int     `json:"completed"`
+	Total      int     `json:"total"`
+	Percentage int     `json:"percentage"`
+}
+
+type CourseSubmission struct {
+	ID           uint64     `json:"id"`
+	AssessmentID uint64     `json:"assessment_id"`
+	UserID       uint64     `json:"user_id"`
+	Name         string     `json:"name"`
+	CohortID     *uint64    `json:"cohort_id"`
+	Grade        *int       `json:"grade"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+	DueAt        *time.Time `json:"due_at"`
+	Late         bool       `json:"late"`
+}
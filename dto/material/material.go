@@ -1,12 +1,16 @@
 package dto
 
-import "time"
+import (
+	"time"
 
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+)
+
+// CreateMaterialRequest adds a material after the last one of its section.
 type CreateMaterialRequest struct {
 	CurriculumID uint64 `json:"curriculum_id" binding:"required"`
 	MaterialType string `json:"material_type" binding:"required,oneof=text video quiz"`
 	Content      string `json:"content" binding:"required"`
-	Order        int    `json:"order" binding:"required"`
 }
 
 type CreateMaterialResponse struct {
@@ -18,18 +22,27 @@ type CreateMaterialResponse struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// UpdateMaterialRequest leaves the place of the material unchanged, see
+// MoveMaterialRequest.
 type UpdateMaterialRequest struct {
 	MaterialType string `json:"material_type" binding:"omitempty,oneof=text video quiz"`
 	Content      string `json:"content" binding:"omitempty"`
-	Order        int    `json:"order" binding:"omitempty"`
 }
 
 type MaterialResponse struct {
-	ID           int       `json:"id"`
-	CurriculumID int       `json:"curriculum_id"`
-	MaterialType string    `json:"material_type"`
-	Content      string    `json:"content"`
-	Order        int       `json:"order"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int                 `json:"id"`
+	CurriculumID int                 `json:"curriculum_id"`
+	MaterialType string              `json:"material_type"`
+	Content      string              `json:"content"` // Empty while the material is locked
+	Order        int                 `json:"order"`
+	Lock         *curriculumDto.Lock `json:"lock,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// MoveMaterialRequest moves a material to a section of the same course, at
+// the given position among its materials.
+type MoveMaterialRequest struct {
+	CurriculumID uint64 `json:"curriculum_id"`
+	Position     int    `json:"position"` // Starting at 1, last if 0 or past the end
 }
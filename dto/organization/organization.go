@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
+)
+
+type Organization struct {
+	ID        uint64         `json:"id"`
+	Slug      string         `json:"slug"`
+	Name      string         `json:"name"`
+	Settings  map[string]any `json:"settings"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// CreateOrganizationRequest creates an organization and invites its first
+// administrator, who can then invite the rest of the school.
+type CreateOrganizationRequest struct {
+	Slug       string         `json:"slug"`
+	Name       string         `json:"name"`
+	Settings   map[string]any `json:"settings"`
+	AdminEmail string         `json:"admin_email"`
+	AdminName  string         `json:"admin_name"`
+}
+
+type CreateOrganizationResponse struct {
+	Organization    *Organization                `json:"organization"`
+	AdminInvitation *adminDto.InvitationResponse `json:"admin_invitation"`
+}
+
+// UpdateOrganizationRequest renames the organization and replaces its
+// settings. Omitted fields are left unchanged.
+type UpdateOrganizationRequest struct {
+	Name     string         `json:"name"`
+	Settings map[string]any `json:"settings"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+type DataExport struct {
+	ID          uint64     `json:"id"`
+	Status      string     `json:"status"` // "pending", "completed" or "failed"
+	Error       *string    `json:"error"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+type ErasureRequest struct {
+	ID         uint64     `json:"id"`
+	UserID     uint64     `json:"user_id"`
+	Status     string     `json:"status"` // "pending", "approved" or "rejected"
+	Reason     string     `json:"reason"`
+	ReviewedBy *uint64    `json:"reviewed_by"`
+	ReviewNote string     `json:"review_note"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateErasureRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReviewErasureRequest struct {
+	Note string `json:"note"`
+}
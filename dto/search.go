@@ -0,0 +1,17 @@
+package dto
+
+type SearchRequest struct {
+	Query string `json:"q"`
+	Type  string `json:"type"` // "course", "curriculum", "material" or empty for all
+	Limit int    `json:"limit"`
+}
+
+type SearchResult struct {
+	Type         string  `json:"type"`
+	ID           uint64  `json:"id"`
+	CourseID     uint64  `json:"course_id"`
+	CurriculumID *uint64 `json:"curriculum_id,omitempty"`
+	Title        string  `json:"title"`
+	Snippet      string  `json:"snippet"` // HTML, matches are wrapped in <mark>
+	Rank         float64 `json:"rank"`
+}
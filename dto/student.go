@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type User struct {
 	ID           int    `json:"id"`
 	Name         string `json:"name"`
@@ -15,6 +17,43 @@ type Enrollment struct {
 	EnrolledAt string `json:"enrolled_at"`
 }
 
+// Eligibility tells whether a student can enroll in a course and, if not,
+// which of its requirements they do not meet.
+type Eligibility struct {
+	CourseID uint64              `json:"course_id"`
+	Eligible bool                `json:"eligible"`
+	Unmet    []*UnmetRequirement `json:"unmet"`
+}
+
+// Types of unmet requirements.
+const (
+	RequirementPrerequisite = "prerequisite" // The course was not completed
+	RequirementGrade        = "min_grade"    // The course was completed with a lower grade
+	RequirementRole         = "role"
+	RequirementEmailDomain  = "email_domain"
+	RequirementNotOpen      = "not_open" // Enrollment has not opened yet
+	RequirementClosed       = "closed"   // Enrollment has closed
+)
+
+type UnmetRequirement struct {
+	Type     string     `json:"type"`
+	Message  string     `json:"message"`
+	CourseID *uint64    `json:"course_id,omitempty"` // The prerequisite course
+	MinGrade *int       `json:"min_grade,omitempty"`
+	Grade    *float64   `json:"grade,omitempty"`   // The grade of the student, if any
+	Allowed  []string   `json:"allowed,omitempty"` // The roles or email domains allowed to enroll
+	At       *time.Time `json:"at,omitempty"`      // When enrollment opens or closed
+}
+
+// WaitlistEntry is returned instead of an enrollment when the course is full.
+type WaitlistEntry struct {
+	ID        uint64    `json:"id"`
+	CourseID  uint64    `json:"course_id"`
+	UserID    uint64    `json:"user_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Achievement struct {
 	ID              int    `json:"id"`
 	UserID          int    `json:"user_id"`
@@ -71,12 +110,6 @@ type UpdateStudentRequest struct {
 	Email string `json:"email"`
 }
 
-type UpdateUserRequest struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
-}
-
 type EnrollStudentRequest struct {
 	UserID   uint64 `json:"user_id"`
 	CourseID uint64 `json:"course_id"`
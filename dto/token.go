@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+type APIToken struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateAPITokenRequest struct {
+	UserID        uint64   `json:"user_id"` // Only used by admins minting service-account keys
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"dive,oneof=read write"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreateAPITokenResponse is the only time the plain token is returned.
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
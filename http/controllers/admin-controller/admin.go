@@ -2,26 +2,55 @@ package admins
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/dapthehuman/learning-management-system/dto"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
 	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
 type Service interface {
-	GetAllUsers(ctx context.Context) ([]*dto.User, error)
+	SearchUsers(ctx context.Context, searchDTO *dto.UserSearchRequest, q *listing.Query) (*listing.Page[*dto.User], error)
 	GetUserByID(ctx context.Context, userID uint64) (*dto.User, error)
-	UpdateUser(ctx context.Context, userID uint64, updateDTO *dto.UpdateUserRequest) (*dto.User, error)
-	DeleteUser(ctx context.Context, id uint64) error
+	CreateUser(ctx context.Context, actorID uint64, createDTO *dto.CreateUserRequest) (*dto.User, error)
+	UpdateUser(ctx context.Context, actorID uint64, userID uint64, updateDTO *dto.UpdateUserRequest) (*dto.User, error)
+	ChangeRole(ctx context.Context, actorID uint64, userID uint64, role string) (*dto.User, error)
+	Suspend(ctx context.Context, actorID uint64, userID uint64) (*dto.User, error)
+	Reactivate(ctx context.Context, actorID uint64, userID uint64) (*dto.User, error)
+	ForcePasswordReset(ctx context.Context, actorID uint64, userID uint64) (*dto.PasswordResetResponse, error)
+	DeleteUser(ctx context.Context, actorID uint64, id uint64) error
+	AuditLogs(ctx context.Context, q *listing.Query) (*listing.Page[*dto.AuditLog], error)
+	ImportUsers(ctx context.Context, actorID uint64, r io.Reader, options *dto.ImportOptions) (*dto.ImportReport, error)
+	ExportUsers(ctx context.Context, w io.Writer) error
+}
+
+// maxImportSize is the largest CSV file accepted by the import endpoint.
+const maxImportSize = 10 << 20
+
+type ImpersonationService interface {
+	Start(ctx context.Context, actorID uint64, userID uint64) (*dto.ImpersonationResponse, error)
+	Stop(ctx context.Context, actorID uint64, userID uint64, session string) error
+}
+
+type InvitationService interface {
+	Create(ctx context.Context, actorID uint64, createDTO *dto.CreateInvitationRequest) (*dto.InvitationResponse, error)
+	List(ctx context.Context, status string, q *listing.Query) (*listing.Page[*dto.Invitation], error)
+	Resend(ctx context.Context, actorID uint64, id uint64) (*dto.InvitationResponse, error)
+	Revoke(ctx context.Context, actorID uint64, id uint64) (*dto.Invitation, error)
 }
 
 type Controller struct {
 	goyave.Component
-	UserService Service
+	AdminService         Service
+	ImpersonationService ImpersonationService
+	InvitationService    InvitationService
 }
 
 func NewController() *Controller {
@@ -29,7 +58,9 @@ func NewController() *Controller {
 }
 
 func (ctrl *Controller) Init(server *goyave.Server) {
-	ctrl.UserService = server.Service(service.User).(Service)
+	ctrl.AdminService = server.Service(service.Admin).(Service)
+	ctrl.ImpersonationService = server.Service(service.Impersonation).(ImpersonationService)
+	ctrl.InvitationService = server.Service(service.Invitation).(InvitationService)
 	ctrl.Component.Init(server)
 }
 
@@ -39,28 +70,110 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 	authMiddleware := middleware.NewUserAuth()
 	subrouter.Middleware(authMiddleware)
 
-	// Enrollment-related routes
-	adminOnly := middleware.NewRoleMiddleware("admin", "instructor")
-	adminSubrouter := subrouter.Group().Middleware(adminOnly)
-	adminSubrouter.Get("/users", ctrl.GetUsers)
-	adminSubrouter.Get("/users/{id}", ctrl.GetUserByID)
-	adminSubrouter.Patch("/users/{id}", ctrl.UpdateUser)
-	adminSubrouter.Delete("/users/{id}", ctrl.DeleteUser)
+	// User management
+	canRead := middleware.RequirePermission("user.read")
+	canUpdate := middleware.RequirePermission("user.update")
+	subrouter.Get("/users", ctrl.GetUsers).Middleware(canRead)
+	subrouter.Post("/users", ctrl.CreateUser).Middleware(middleware.RequirePermission("user.create"))
+	subrouter.Get("/users/export", ctrl.ExportUsers).Middleware(canRead)
+	subrouter.Post("/users/import", ctrl.ImportUsers).Middleware(middleware.RequirePermission("user.create"))
+	subrouter.Get("/users/{id}", ctrl.GetUserByID).Middleware(canRead)
+	subrouter.Patch("/users/{id}", ctrl.UpdateUser).Middleware(canUpdate)
+	subrouter.Delete("/users/{id}", ctrl.DeleteUser).Middleware(middleware.RequirePermission("user.delete"))
 
+	subrouter.Put("/users/{id}/role", ctrl.ChangeRole).Middleware(canUpdate)
+	subrouter.Post("/users/{id}/suspend", ctrl.Suspend).Middleware(canUpdate)
+	subrouter.Post("/users/{id}/reactivate", ctrl.Reactivate).Middleware(canUpdate)
+	subrouter.Post("/users/{id}/password-reset", ctrl.ForcePasswordReset).Middleware(canUpdate)
+
+	// Invitations
+	canInvite := middleware.RequirePermission("user.invite")
+	subrouter.Get("/invitations", ctrl.GetInvitations).Middleware(canInvite)
+	subrouter.Post("/invitations", ctrl.CreateInvitation).Middleware(canInvite)
+	subrouter.Post("/invitations/{id}/resend", ctrl.ResendInvitation).Middleware(canInvite)
+	subrouter.Delete("/invitations/{id}", ctrl.RevokeInvitation).Middleware(canInvite)
+
+	// Impersonation, usable by the impersonated session to end itself
+	subrouter.Post("/users/{id}/impersonate", ctrl.Impersonate).Middleware(middleware.BlockImpersonation(), middleware.RequirePermission("user.impersonate"))
+	subrouter.Post("/impersonation/stop", ctrl.StopImpersonation)
+
+	subrouter.Get("/audit-logs", ctrl.AuditLogs).Middleware(middleware.RequirePermission("audit.read"))
 }
 
 func (ctrl *Controller) GetUsers(response *goyave.Response, request *goyave.Request) {
-	users, err := ctrl.UserService.GetAllUsers(request.Context())
+	query := request.Request().URL.Query()
+	q, err := listing.Parse(query)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	users, err := ctrl.AdminService.SearchUsers(request.Context(), &dto.UserSearchRequest{
+		Search: query.Get("search"),
+		Role:   query.Get("role"),
+		Status: query.Get("status"),
+	}, q)
 	if err != nil {
+		if listing.IsInvalid(err) {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
 		response.Error(err)
 		return
 	}
 	response.JSON(http.StatusOK, users)
 }
 
+// ImportUsers creates users from the CSV file sent as the request body.
+// Nothing is created if any row is invalid, in which case the report lists
+// the errors of each row.
+func (ctrl *Controller) ImportUsers(response *goyave.Response, request *goyave.Request) {
+	query := request.Request().URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+	sendInvitations, _ := strconv.ParseBool(query.Get("send_invitations"))
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	body := io.LimitReader(request.Request().Body, maxImportSize)
+	report, err := ctrl.AdminService.ImportUsers(request.Context(), adminID, body, &dto.ImportOptions{
+		DryRun:          dryRun,
+		SendInvitations: sendInvitations,
+	})
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case report.Failed > 0:
+		response.JSON(http.StatusUnprocessableEntity, report)
+	case dryRun:
+		response.JSON(http.StatusOK, report)
+	default:
+		response.JSON(http.StatusCreated, report)
+	}
+}
+
+func (ctrl *Controller) ExportUsers(response *goyave.Response, request *goyave.Request) {
+	filename := "users-" + time.Now().Format("20060102") + ".csv"
+	response.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	response.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	response.WriteHeader(http.StatusOK)
+
+	if err := ctrl.AdminService.ExportUsers(request.Context(), response); err != nil {
+		ctrl.Logger().Error(err)
+	}
+}
+
 func (ctrl *Controller) GetUserByID(response *goyave.Response, request *goyave.Request) {
 	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
-	user, err := ctrl.UserService.GetUserByID(request.Context(), id)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := ctrl.AdminService.GetUserByID(request.Context(), id)
 	if err != nil {
 		response.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 		return
@@ -68,6 +181,19 @@ func (ctrl *Controller) GetUserByID(response *goyave.Response, request *goyave.R
 	response.JSON(http.StatusOK, user)
 }
 
+func (ctrl *Controller) CreateUser(response *goyave.Response, request *goyave.Request) {
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	createDTO := typeutil.MustConvert[*dto.CreateUserRequest](request.Data)
+	user, err := ctrl.AdminService.CreateUser(request.Context(), adminID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, user)
+}
+
 func (ctrl *Controller) UpdateUser(response *goyave.Response, request *goyave.Request) {
 	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
 	if err != nil {
@@ -75,15 +201,91 @@ func (ctrl *Controller) UpdateUser(response *goyave.Response, request *goyave.Re
 		return
 	}
 
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
 	updateDTO := typeutil.MustConvert[*dto.UpdateUserRequest](request.Data)
-	user, err := ctrl.UserService.UpdateUser(request.Context(), id, updateDTO)
+	user, err := ctrl.AdminService.UpdateUser(request.Context(), adminID, id, updateDTO)
 	if err != nil {
-		response.Error(err)
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, user)
+}
+
+func (ctrl *Controller) ChangeRole(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	roleDTO := typeutil.MustConvert[*dto.ChangeRoleRequest](request.Data)
+	user, err := ctrl.AdminService.ChangeRole(request.Context(), adminID, id, roleDTO.Role)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, user)
+}
+
+func (ctrl *Controller) Suspend(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	user, err := ctrl.AdminService.Suspend(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, user)
+}
+
+func (ctrl *Controller) Reactivate(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	user, err := ctrl.AdminService.Reactivate(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
 		return
 	}
 	response.JSON(http.StatusOK, user)
 }
 
+func (ctrl *Controller) ForcePasswordReset(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	reset, err := ctrl.AdminService.ForcePasswordReset(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, reset)
+}
+
 func (ctrl *Controller) DeleteUser(response *goyave.Response, request *goyave.Request) {
 	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
 	if err != nil {
@@ -91,9 +293,137 @@ func (ctrl *Controller) DeleteUser(response *goyave.Response, request *goyave.Re
 		return
 	}
 
-	if err := ctrl.UserService.DeleteUser(request.Context(), id); err != nil {
-		response.Error(err)
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	if err := ctrl.AdminService.DeleteUser(request.Context(), adminID, id); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
 		return
 	}
 	response.Status(http.StatusNoContent)
 }
+
+func (ctrl *Controller) Impersonate(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	impersonation, err := ctrl.ImpersonationService.Start(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, impersonation)
+}
+
+func (ctrl *Controller) StopImpersonation(response *goyave.Response, request *goyave.Request) {
+	claims := request.Extra["user"].(jwt.MapClaims)
+	act, ok := claims["act"].(map[string]any)
+	if !ok {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Not an impersonation session"})
+		return
+	}
+
+	adminID := uint64(act["sub"].(float64))
+	userID := uint64(claims["user_id"].(float64))
+	session, _ := claims["jti"].(string)
+
+	if err := ctrl.ImpersonationService.Stop(request.Context(), adminID, userID, session); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Impersonation ended"})
+}
+
+func (ctrl *Controller) AuditLogs(response *goyave.Response, request *goyave.Request) {
+	q, err := listing.Parse(request.Request().URL.Query())
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries, err := ctrl.AdminService.AuditLogs(request.Context(), q)
+	if err != nil {
+		if listing.IsInvalid(err) {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, entries)
+}
+
+func (ctrl *Controller) GetInvitations(response *goyave.Response, request *goyave.Request) {
+	query := request.Request().URL.Query()
+	q, err := listing.Parse(query)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	invitations, err := ctrl.InvitationService.List(request.Context(), query.Get("status"), q)
+	if err != nil {
+		if listing.IsInvalid(err) {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, invitations)
+}
+
+func (ctrl *Controller) CreateInvitation(response *goyave.Response, request *goyave.Request) {
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	createDTO := typeutil.MustConvert[*dto.CreateInvitationRequest](request.Data)
+	invitation, err := ctrl.InvitationService.Create(request.Context(), adminID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, invitation)
+}
+
+func (ctrl *Controller) ResendInvitation(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invitation ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	invitation, err := ctrl.InvitationService.Resend(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, invitation)
+}
+
+func (ctrl *Controller) RevokeInvitation(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invitation ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	invitation, err := ctrl.InvitationService.Revoke(request.Context(), adminID, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, invitation)
+}
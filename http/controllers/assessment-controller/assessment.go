@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"strconv"
 
+	courseDto "github.com/dapthehuman/learning-management-system/dto"
 	dto "github.com/dapthehuman/learning-management-system/dto/assesment"
 	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	courseService "github.com/dapthehuman/learning-management-system/service/course-service"
 	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
@@ -15,14 +18,20 @@ import (
 
 type Service interface {
 	CreateAssessment(ctx context.Context, createDTO *dto.CreateAssessmentRequest) (*dto.Assessment, error)
-	GetAssessmentByCourseID(ctx context.Context, courseID uint64) ([]*dto.Assessment, error)
+	GetAssessmentByCourseID(ctx context.Context, courseID uint64, userID uint64, q *listing.Query) (*listing.Page[*dto.Assessment], error)
 	GetAssessmentByID(ctx context.Context, assessmentID uint64) (*dto.Assessment, error)
 	SubmitAnswer(ctx context.Context, submission *dto.SubmissionRequest) (*dto.SubmissionResponse, error)
 }
 
+// CourseService tells who can read the content of a course.
+type CourseService interface {
+	ContentAccess(ctx context.Context, viewer *courseDto.CourseViewer, courseID uint64) (*courseService.ContentAccess, error)
+}
+
 type Controller struct {
 	goyave.Component
 	assessmentService Service
+	courseService     CourseService
 }
 
 func NewController() *Controller {
@@ -31,6 +40,7 @@ func NewController() *Controller {
 
 func (ctrl *Controller) Init(server *goyave.Server) {
 	ctrl.assessmentService = server.Service(service.Assessment).(Service)
+	ctrl.courseService = server.Service(service.Course).(CourseService)
 	ctrl.Component.Init(server)
 }
 
@@ -40,10 +50,9 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 	authMiddleware := middleware.NewUserAuth()
 	subrouter.Middleware(authMiddleware)
 
-	roleMiddleware := middleware.NewRoleMiddleware("admin", "instructor")
-	subrouter.Post("/", ctrl.Create).Middleware(roleMiddleware)
+	subrouter.Post("/", ctrl.Create).Middleware(middleware.RequireBodyCourseRole("owner", "instructor"))
 	subrouter.Get("/course/{courseID}", ctrl.GetByCourseID)
-	subrouter.Post("/submit", ctrl.SubmitAnswer)
+	subrouter.Post("/submit", ctrl.SubmitAnswer).Middleware(middleware.BlockImpersonation())
 }
 
 func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
@@ -64,9 +73,22 @@ func (ctrl *Controller) GetByCourseID(response *goyave.Response, request *goyave
 		return
 	}
 
-	assessments, err := ctrl.assessmentService.GetAssessmentByCourseID(request.Context(), courseID)
+	q, err := listing.Parse(request.Request().URL.Query())
 	if err != nil {
-		response.JSON(500, map[string]string{"error": err.Error()})
+		response.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if !ctrl.canAccess(response, request, courseID) {
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	assessments, err := ctrl.assessmentService.GetAssessmentByCourseID(request.Context(), courseID, userID, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 
@@ -76,6 +98,15 @@ func (ctrl *Controller) GetByCourseID(response *goyave.Response, request *goyave
 func (ctrl *Controller) SubmitAnswer(response *goyave.Response, request *goyave.Request) {
 	submission := typeutil.MustConvert[*dto.SubmissionRequest](request.Data)
 
+	assessment, err := ctrl.assessmentService.GetAssessmentByID(request.Context(), uint64(submission.AssessmentID))
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Assessment not found"})
+		return
+	}
+	if !ctrl.canAccess(response, request, uint64(assessment.CourseID)) {
+		return
+	}
+
 	user := request.Extra["user"].(jwt.MapClaims)
 	userID := uint64(user["user_id"].(float64))
 	submission.UserID = int(userID)
@@ -88,3 +119,24 @@ func (ctrl *Controller) SubmitAnswer(response *goyave.Response, request *goyave.
 
 	response.JSON(http.StatusCreated, submittedAnswer)
 }
+
+// canAccess writes a 403 response and returns false unless the authenticated
+// user is enrolled in the course or part of its staff.
+func (ctrl *Controller) canAccess(response *goyave.Response, request *goyave.Request, courseID uint64) bool {
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return false
+	}
+
+	access, err := ctrl.courseService.ContentAccess(request.Context(), viewer, courseID)
+	if err != nil {
+		response.Error(err)
+		return false
+	}
+	if !access.Full() {
+		response.JSON(http.StatusForbidden, map[string]string{"error": "Enroll in the course to access its assessments"})
+		return false
+	}
+	return true
+}
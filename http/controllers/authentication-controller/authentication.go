@@ -8,7 +8,9 @@ import (
 
 	"github.com/dapthehuman/learning-management-system/dto"
 	authDto "github.com/dapthehuman/learning-management-system/dto/auth"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
 	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
@@ -17,11 +19,33 @@ import (
 type Service interface {
 	Register(ctx context.Context, credsDTO *authDto.RegisterRequest) (*authDto.RegisterResponse, error)
 	Login(ctx context.Context, loginDTO *authDto.LoginRequest) (*dto.User, error)
+	ResetPassword(ctx context.Context, resetDTO *authDto.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, userID uint64, changeDTO *authDto.ChangePasswordRequest) error
+}
+
+type OIDCService interface {
+	BeginLogin(ctx context.Context) (string, error)
+	CompleteLogin(ctx context.Context, code, state string) (*dto.User, uint64, error)
+}
+
+type SAMLService interface {
+	Metadata(ctx context.Context, organization string) ([]byte, error)
+	LoginURL(ctx context.Context, organization string, relayState string) (string, error)
+	ConsumeResponse(ctx context.Context, organization string, samlResponse string) (*dto.User, error)
+	SaveProvider(ctx context.Context, providerDTO *authDto.SAMLProviderRequest) (*authDto.SAMLProvider, error)
+}
+
+type InvitationService interface {
+	Details(ctx context.Context, token string) (*authDto.InvitationDetails, error)
+	Accept(ctx context.Context, acceptDTO *authDto.AcceptInvitationRequest) (*dto.User, error)
 }
 
 type Controller struct {
 	goyave.Component
-	UserService Service
+	UserService       Service
+	OIDCService       OIDCService
+	SAMLService       SAMLService
+	InvitationService InvitationService
 }
 
 func NewController() *Controller {
@@ -30,6 +54,13 @@ func NewController() *Controller {
 
 func (ctrl *Controller) Init(server *goyave.Server) {
 	ctrl.UserService = server.Service(service.User).(Service)
+	ctrl.InvitationService = server.Service(service.Invitation).(InvitationService)
+	if oidc, ok := server.LookupService(service.OIDC); ok {
+		ctrl.OIDCService = oidc.(OIDCService)
+	}
+	if saml, ok := server.LookupService(service.SAML); ok {
+		ctrl.SAMLService = saml.(SAMLService)
+	}
 	ctrl.Component.Init(server)
 }
 
@@ -38,6 +69,31 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 
 	subrouter.Post("/register", ctrl.Register)
 	subrouter.Post("/login", ctrl.Login)
+	subrouter.Post("/password/reset", ctrl.ResetPassword)
+	subrouter.Put("/password", ctrl.ChangePassword).Middleware(middleware.NewUserAuth(), middleware.BlockImpersonation())
+
+	// Invitations sent by administrators
+	subrouter.Get("/invitations/{token}", ctrl.InvitationDetails)
+	subrouter.Post("/invitations/accept", ctrl.AcceptInvitation)
+
+	// Single sign-on, only available when an OIDC provider is configured
+	if ctrl.OIDCService != nil {
+		subrouter.Get("/oidc/login", ctrl.OIDCLogin)
+		subrouter.Get("/oidc/callback", ctrl.OIDCCallback)
+	}
+
+	// SAML 2.0 service provider, one identity provider per organization. The
+	// organization is the one of the URL, so it is also the one the issued
+	// token is valid in.
+	if ctrl.SAMLService != nil {
+		samlRouter := subrouter.Subrouter("/saml/{organization}")
+		samlRouter.Middleware(middleware.RouteTenant("organization"))
+		samlRouter.Get("/metadata", ctrl.SAMLMetadata)
+		samlRouter.Get("/login", ctrl.SAMLLogin)
+		samlRouter.Post("/acs", ctrl.SAMLConsume)
+
+		samlRouter.Put("/provider", ctrl.SAMLSaveProvider).Middleware(middleware.NewUserAuth(), middleware.RequirePermission("sso.manage"))
+	}
 
 }
 
@@ -61,16 +117,143 @@ func (ctrl *Controller) Login(response *goyave.Response, request *goyave.Request
 	}
 
 	// Generate Token
-	token := ctrl.generateToken(user)
+	token := ctrl.generateToken(request.Context(), user)
+	response.JSON(http.StatusOK, map[string]string{"token": token})
+
+}
+
+func (ctrl *Controller) OIDCLogin(response *goyave.Response, request *goyave.Request) {
+	url, err := ctrl.OIDCService.BeginLogin(request.Context())
+	if err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.Header().Set("Location", url)
+	response.Status(http.StatusFound)
+}
+
+func (ctrl *Controller) OIDCCallback(response *goyave.Response, request *goyave.Request) {
+	query := request.Request().URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		response.JSON(http.StatusUnauthorized, map[string]string{"error": errCode})
+		return
+	}
+
+	// The provider redirects without the header or subdomain of the
+	// organization: the token is issued in the one the login was started in
+	user, organizationID, err := ctrl.OIDCService.CompleteLogin(request.Context(), query.Get("code"), query.Get("state"))
+	if err != nil {
+		response.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	token := ctrl.generateToken(tenant.WithOrganization(request.Context(), organizationID), user)
 	response.JSON(http.StatusOK, map[string]string{"token": token})
+}
+
+func (ctrl *Controller) SAMLMetadata(response *goyave.Response, request *goyave.Request) {
+	metadata, err := ctrl.SAMLService.Metadata(request.Context(), request.RouteParams["organization"])
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Organization not found"})
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/samlmetadata+xml")
+	response.WriteHeader(http.StatusOK)
+	_, _ = response.Write(metadata)
+}
+
+func (ctrl *Controller) SAMLLogin(response *goyave.Response, request *goyave.Request) {
+	relayState := request.Request().URL.Query().Get("RelayState")
+	url, err := ctrl.SAMLService.LoginURL(request.Context(), request.RouteParams["organization"], relayState)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Organization not found"})
+		return
+	}
+
+	response.Header().Set("Location", url)
+	response.Status(http.StatusFound)
+}
+
+func (ctrl *Controller) SAMLConsume(response *goyave.Response, request *goyave.Request) {
+	samlDTO := typeutil.MustConvert[*authDto.SAMLResponseRequest](request.Data)
+	user, err := ctrl.SAMLService.ConsumeResponse(request.Context(), request.RouteParams["organization"], samlDTO.SAMLResponse)
+	if err != nil {
+		response.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	token := ctrl.generateToken(request.Context(), user)
+	response.JSON(http.StatusOK, map[string]string{"token": token})
+}
+
+func (ctrl *Controller) SAMLSaveProvider(response *goyave.Response, request *goyave.Request) {
+	providerDTO := typeutil.MustConvert[*authDto.SAMLProviderRequest](request.Data)
+	provider, err := ctrl.SAMLService.SaveProvider(request.Context(), providerDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, provider)
+}
+
+func (ctrl *Controller) ResetPassword(response *goyave.Response, request *goyave.Request) {
+	resetDTO := typeutil.MustConvert[*authDto.ResetPasswordRequest](request.Data)
+	if err := ctrl.UserService.ResetPassword(request.Context(), resetDTO); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Password updated successfully"})
+}
+
+func (ctrl *Controller) ChangePassword(response *goyave.Response, request *goyave.Request) {
+	changeDTO := typeutil.MustConvert[*authDto.ChangePasswordRequest](request.Data)
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	if err := ctrl.UserService.ChangePassword(request.Context(), userID, changeDTO); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Password updated successfully"})
+}
+
+func (ctrl *Controller) InvitationDetails(response *goyave.Response, request *goyave.Request) {
+	invitation, err := ctrl.InvitationService.Details(request.Context(), request.RouteParams["token"])
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, invitation)
+}
+
+// AcceptInvitation creates the account of the invitee and signs them in.
+func (ctrl *Controller) AcceptInvitation(response *goyave.Response, request *goyave.Request) {
+	acceptDTO := typeutil.MustConvert[*authDto.AcceptInvitationRequest](request.Data)
+	user, err := ctrl.InvitationService.Accept(request.Context(), acceptDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
 
+	token := ctrl.generateToken(request.Context(), user)
+	response.JSON(http.StatusCreated, map[string]string{"token": token})
 }
 
-func (ctrl *Controller) generateToken(user *dto.User) string {
+// generateToken signs a session token for the user, only valid in the
+// organization of the request.
+func (ctrl *Controller) generateToken(ctx context.Context, user *dto.User) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
+		"org_id":  tenant.ID(ctx),
 		"exp":     time.Now().Add(time.Hour * 72).Unix(), // 3 days
 	})
 	tokenString, err := token.SignedString([]byte(os.Getenv("APP_SECRET")))
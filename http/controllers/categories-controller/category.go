@@ -0,0 +1,96 @@
+package categories
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/category"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type Service interface {
+	GetAll(ctx context.Context) ([]*dto.Category, error)
+	Create(ctx context.Context, createDTO *dto.SaveCategoryRequest) (*dto.Category, error)
+	Update(ctx context.Context, id uint64, updateDTO *dto.SaveCategoryRequest) (*dto.Category, error)
+	Delete(ctx context.Context, id uint64) error
+}
+
+type Controller struct {
+	goyave.Component
+	CategoryService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.CategoryService = server.Service(service.Category).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	subrouter := router.Subrouter("/categories")
+	subrouter.Middleware(middleware.NewUserAuth())
+
+	subrouter.Get("/", ctrl.Index)
+
+	manageRouter := subrouter.Group()
+	manageRouter.Middleware(middleware.RequirePermission("category.manage"))
+	manageRouter.Post("/", ctrl.Create)
+	manageRouter.Put("/{id}", ctrl.Update)
+	manageRouter.Delete("/{id}", ctrl.Delete)
+}
+
+func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request) {
+	categories, err := ctrl.CategoryService.GetAll(request.Context())
+	if err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, categories)
+}
+
+func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
+	createDTO := typeutil.MustConvert[*dto.SaveCategoryRequest](request.Data)
+	category, err := ctrl.CategoryService.Create(request.Context(), createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, category)
+}
+
+func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid category ID"})
+		return
+	}
+
+	updateDTO := typeutil.MustConvert[*dto.SaveCategoryRequest](request.Data)
+	category, err := ctrl.CategoryService.Update(request.Context(), id, updateDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, category)
+}
+
+func (ctrl *Controller) Delete(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := ctrl.CategoryService.Delete(request.Context(), id); err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Category deleted successfully"})
+}
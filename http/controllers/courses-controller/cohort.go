@@ -0,0 +1,205 @@
+package courses
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+func (ctrl *Controller) ListCohorts(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	cohorts, err := ctrl.CourseService.GetCohorts(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, cohorts)
+}
+
+func (ctrl *Controller) ShowCohort(response *goyave.Response, request *goyave.Request) {
+	id, cohortID, ok := cohortParams(response, request)
+	if !ok {
+		return
+	}
+
+	cohort, err := ctrl.CourseService.GetCohort(request.Context(), id, cohortID)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, cohort)
+}
+
+func (ctrl *Controller) CreateCohort(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	saveDTO := typeutil.MustConvert[*dto.SaveCohortRequest](request.Data)
+	cohort, err := ctrl.CourseService.CreateCohort(request.Context(), id, saveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, cohort)
+}
+
+func (ctrl *Controller) UpdateCohort(response *goyave.Response, request *goyave.Request) {
+	id, cohortID, ok := cohortParams(response, request)
+	if !ok {
+		return
+	}
+
+	saveDTO := typeutil.MustConvert[*dto.SaveCohortRequest](request.Data)
+	cohort, err := ctrl.CourseService.UpdateCohort(request.Context(), id, cohortID, saveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, cohort)
+}
+
+func (ctrl *Controller) DeleteCohort(response *goyave.Response, request *goyave.Request) {
+	id, cohortID, ok := cohortParams(response, request)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.CourseService.DeleteCohort(request.Context(), id, cohortID); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Cohort deleted successfully"})
+}
+
+// AddCohortMembers moves students enrolled in the course to the cohort.
+func (ctrl *Controller) AddCohortMembers(response *goyave.Response, request *goyave.Request) {
+	id, cohortID, ok := cohortParams(response, request)
+	if !ok {
+		return
+	}
+
+	membersDTO := typeutil.MustConvert[*dto.CohortMembersRequest](request.Data)
+	if err := ctrl.CourseService.AddCohortMembers(request.Context(), id, cohortID, membersDTO); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Students added to the cohort successfully"})
+}
+
+func (ctrl *Controller) RemoveCohortMember(response *goyave.Response, request *goyave.Request) {
+	id, cohortID, ok := cohortParams(response, request)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(request.RouteParams["user_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := ctrl.CourseService.RemoveCohortMember(request.Context(), id, cohortID, userID); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Student removed from the cohort successfully"})
+}
+
+// Gradebook lists the grades of the students of the course, e.g. those of a
+// cohort with "filter[cohort_id]=3".
+func (ctrl *Controller) Gradebook(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	gradebook, err := ctrl.CourseService.GetGradebook(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, gradebook)
+}
+
+// Progress lists how far each student of the course is through its
+// materials, filterable by cohort.
+func (ctrl *Controller) Progress(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	progress, err := ctrl.CourseService.GetProgress(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, progress)
+}
+
+// Submissions lists the submissions to the assessments of the course,
+// filterable by cohort, assessment, student and lateness.
+func (ctrl *Controller) Submissions(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	submissions, err := ctrl.CourseService.GetSubmissions(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, submissions)
+}
+
+// cohortParams parses the course and cohort IDs of the route.
+func cohortParams(response *goyave.Response, request *goyave.Request) (uint64, uint64, bool) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return 0, 0, false
+	}
+
+	cohortID, err := strconv.ParseUint(request.RouteParams["cohort_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cohort ID"})
+		return 0, 0, false
+	}
+	return id, cohortID, true
+}
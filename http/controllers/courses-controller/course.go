@@ -8,23 +8,74 @@ import (
 	"github.com/dapthehuman/learning-management-system/dto"
 	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
 	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	courseService "github.com/dapthehuman/learning-management-system/service/course-service"
+	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
 type Service interface {
 	GetByID(ctx context.Context, id uint64) (*dto.Course, error)
-	GetAll(ctx context.Context) ([]*dto.Course, error)
-	Create(ctx context.Context, createDTO *dto.CreateCourseRequest) (*dto.Course, error)
-	Update(ctx context.Context, id uint64, updateDTO *dto.UpdateCourseRequest) (*dto.Course, error)
+	Show(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error)
+	GetAll(ctx context.Context, viewer *dto.CourseViewer, q *listing.Query) (*listing.Page[*dto.Course], error)
+	Catalog(ctx context.Context, filter *dto.CatalogFilter, q *listing.Query) (*dto.Catalog, error)
+	Create(ctx context.Context, ownerID uint64, createDTO *dto.CreateCourseRequest) (*dto.Course, error)
+	Update(ctx context.Context, actorID uint64, id uint64, updateDTO *dto.UpdateCourseRequest) (*dto.Course, error)
 	Delete(ctx context.Context, id uint64) error
-
-	GetCurriculumByCourseID(ctx context.Context, courseID uint64) ([]*curriculumDto.Curriculum, error)
+	Clone(ctx context.Context, viewer *dto.CourseViewer, id uint64, cloneDTO *dto.CloneCourseRequest) (*dto.Course, error)
+	SetTemplate(ctx context.Context, id uint64, templateDTO *dto.TemplateCourseRequest) (*dto.Course, error)
+	Templates(ctx context.Context, q *listing.Query) (*listing.Page[*dto.Course], error)
+
+	ListInReview(ctx context.Context, q *listing.Query) (*listing.Page[*dto.Course], error)
+	Submit(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error)
+	Publish(ctx context.Context, viewer *dto.CourseViewer, id uint64, publishDTO *dto.PublishCourseRequest) (*dto.Course, error)
+	Approve(ctx context.Context, viewer *dto.CourseViewer, id uint64, publishDTO *dto.PublishCourseRequest) (*dto.Course, error)
+	Reject(ctx context.Context, viewer *dto.CourseViewer, id uint64, rejectDTO *dto.RejectCourseRequest) (*dto.Course, error)
+	Withdraw(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error)
+	Archive(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error)
+
+	GetCurriculumByCourseID(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*curriculumDto.Curriculum], error)
 	GetCurriculumByID(ctx context.Context, id uint64) (*curriculumDto.Curriculum, error)
-	CreateCurriculum(ctx context.Context, courseID uint64, createDTO *curriculumDto.CreateCurriculumRequest) (*curriculumDto.Curriculum, error)
-	UpdateCurriculum(ctx context.Context, courseID uint64, updateDTO *curriculumDto.UpdateCurriculumRequest) (*curriculumDto.Curriculum, error)
-	DeleteCurriculum(ctx context.Context, courseID uint64) error
+	CreateCurriculum(ctx context.Context, actorID uint64, courseID uint64, createDTO *curriculumDto.CreateCurriculumRequest) (*curriculumDto.Curriculum, error)
+	UpdateCurriculum(ctx context.Context, actorID uint64, id uint64, updateDTO *curriculumDto.UpdateCurriculumRequest) (*curriculumDto.Curriculum, error)
+	DeleteCurriculum(ctx context.Context, actorID uint64, id uint64) error
+	MoveCurriculum(ctx context.Context, actorID uint64, courseID uint64, id uint64, moveDTO *curriculumDto.MoveCurriculumRequest) ([]*curriculumDto.Curriculum, error)
+	Outline(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) ([]*dto.OutlineSection, error)
+	ReorderOutline(ctx context.Context, viewer *dto.CourseViewer, courseID uint64, reorderDTO *dto.ReorderOutlineRequest) ([]*dto.OutlineSection, error)
+
+	History(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.ContentVersion], error)
+	Rollback(ctx context.Context, actorID uint64, courseID uint64, versionID uint64) (*dto.ContentVersion, error)
+	Snapshots(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseSnapshot], error)
+	Snapshot(ctx context.Context, courseID uint64, id uint64) (*dto.CourseSnapshot, error)
+	PublishChanges(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.CourseSnapshot, error)
+	PublishedContent(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*dto.CourseContent, error)
+	Releases(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*courseService.Releases, error)
+	ContentAccess(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*courseService.ContentAccess, error)
+
+	GetStaff(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseStaff], error)
+	SaveStaff(ctx context.Context, courseID uint64, saveDTO *dto.SaveCourseStaffRequest) (*dto.CourseStaff, error)
+	RemoveStaff(ctx context.Context, courseID uint64, userID uint64) error
+
+	GetRequirements(ctx context.Context, courseID uint64) (*dto.CourseRequirements, error)
+	SaveRequirements(ctx context.Context, courseID uint64, saveDTO *dto.SaveCourseRequirementsRequest) (*dto.CourseRequirements, error)
+
+	GetCohorts(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.Cohort], error)
+	GetCohort(ctx context.Context, courseID uint64, cohortID uint64) (*dto.Cohort, error)
+	CreateCohort(ctx context.Context, courseID uint64, saveDTO *dto.SaveCohortRequest) (*dto.Cohort, error)
+	UpdateCohort(ctx context.Context, courseID uint64, cohortID uint64, saveDTO *dto.SaveCohortRequest) (*dto.Cohort, error)
+	DeleteCohort(ctx context.Context, courseID uint64, cohortID uint64) error
+	AddCohortMembers(ctx context.Context, courseID uint64, cohortID uint64, membersDTO *dto.CohortMembersRequest) error
+	RemoveCohortMember(ctx context.Context, courseID uint64, cohortID uint64, userID uint64) error
+
+	GetGradebook(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.GradebookEntry], error)
+	GetProgress(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.StudentProgress], error)
+	GetSubmissions(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseSubmission], error)
+
+	GetReleaseRules(ctx context.Context, courseID uint64) ([]*dto.ReleaseRule, error)
+	SaveReleaseRule(ctx context.Context, courseID uint64, saveDTO *dto.SaveReleaseRuleRequest) (*dto.ReleaseRule, error)
+	DeleteReleaseRule(ctx context.Context, courseID uint64, id uint64) error
 }
 
 type Controller struct {
@@ -47,42 +98,234 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 	authMiddleware := middleware.NewUserAuth()
 	subrouter.Middleware(authMiddleware)
 	subrouter.Get("/", ctrl.Index)
+	subrouter.Get("/reviews", ctrl.ListInReview).Middleware(middleware.RequirePermission("course.review")) // Before /{id}
+	subrouter.Get("/catalog", ctrl.Catalog)                                                                // Before /{id}
+	subrouter.Get("/templates", ctrl.Templates).Middleware(middleware.RequirePermission("course.create"))  // Before /{id}
 	subrouter.Get("/{id}", ctrl.Show)
 
 	// CRUD routes
-	instructorOnly := middleware.NewRoleMiddleware("admin", "instructor")
-	instructorRouter := subrouter.Group()
-	instructorRouter.Middleware(instructorOnly)
-	instructorRouter.Post("/", ctrl.Create)
-	instructorRouter.Put("/{id}", ctrl.Update)
-	instructorRouter.Delete("/{id}", ctrl.Delete)
+	subrouter.Post("/", ctrl.Create).Middleware(middleware.RequirePermission("course.create"))
+	subrouter.Put("/{id}", ctrl.Update).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Delete("/{id}", ctrl.Delete).Middleware(middleware.RequireCourseRole("owner"))
+
+	// Templates and copies
+	subrouter.Post("/{id}/clone", ctrl.Clone).Middleware(middleware.RequirePermission("course.create"))
+	subrouter.Put("/{id}/template", ctrl.SetTemplate).Middleware(middleware.RequireCourseRole("owner"))
+
+	// Publishing workflow
+	subrouter.Post("/{id}/submit", ctrl.Submit).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Post("/{id}/publish", ctrl.Publish).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Post("/{id}/withdraw", ctrl.Withdraw).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Post("/{id}/archive", ctrl.Archive).Middleware(middleware.RequireCourseRole("owner"))
+	subrouter.Post("/{id}/approve", ctrl.Approve).Middleware(middleware.RequirePermission("course.review"))
+	subrouter.Post("/{id}/reject", ctrl.Reject).Middleware(middleware.RequirePermission("course.review"))
+
+	// Course staff
+	subrouter.Get("/{id}/staff", ctrl.ListStaff).Middleware(middleware.RequireCourseRole("owner", "instructor", "teaching_assistant", "grader"))
+	subrouter.Put("/{id}/staff", ctrl.SaveStaff).Middleware(middleware.RequireCourseRole("owner"))
+	subrouter.Delete("/{id}/staff/{user_id}", ctrl.RemoveStaff).Middleware(middleware.RequireCourseRole("owner"))
+
+	// Prerequisites and eligibility rules checked on enrollment
+	subrouter.Get("/{id}/requirements", ctrl.ShowRequirements)
+	subrouter.Put("/{id}/requirements", ctrl.SaveRequirements).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+
+	// Cohorts, and the views of the students of the course filterable by cohort
+	staffOnly := middleware.RequireCourseRole("owner", "instructor", "teaching_assistant", "grader")
+	subrouter.Get("/{id}/cohorts", ctrl.ListCohorts).Middleware(staffOnly)
+	subrouter.Get("/{id}/cohorts/{cohort_id}", ctrl.ShowCohort).Middleware(staffOnly)
+	cohortRouter := subrouter.Group()
+	cohortRouter.Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	cohortRouter.Post("/{id}/cohorts", ctrl.CreateCohort)
+	cohortRouter.Put("/{id}/cohorts/{cohort_id}", ctrl.UpdateCohort)
+	cohortRouter.Delete("/{id}/cohorts/{cohort_id}", ctrl.DeleteCohort)
+	cohortRouter.Post("/{id}/cohorts/{cohort_id}/members", ctrl.AddCohortMembers)
+	cohortRouter.Delete("/{id}/cohorts/{cohort_id}/members/{user_id}", ctrl.RemoveCohortMember)
+	subrouter.Get("/{id}/gradebook", ctrl.Gradebook).Middleware(staffOnly)
+	subrouter.Get("/{id}/progress", ctrl.Progress).Middleware(staffOnly)
+	subrouter.Get("/{id}/submissions", ctrl.Submissions).Middleware(staffOnly)
+
+	// Drip release of the sections and materials to the students
+	subrouter.Get("/{id}/release-rules", ctrl.ListReleaseRules).Middleware(staffOnly)
+	subrouter.Put("/{id}/release-rules", ctrl.SaveReleaseRule).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Delete("/{id}/release-rules/{rule_id}", ctrl.DeleteReleaseRule).Middleware(middleware.RequireCourseRole("owner", "instructor"))
 
 	// Curriculum-related routes nested under a course
-	subrouter.Get("/{id}/curriculums", ctrl.ListCurriculum)                             // List curriculum for a course
-	subrouter.Get("/{id}/curriculums/{curriculum_id}", ctrl.ShowCurriculum)             // List curriculum for a course
-	instructorRouter.Post("/{id}/curriculums", ctrl.AddCuriculum)                       // Add a curriculum section
-	instructorRouter.Put("/{id}/curriculums/{curriculum_id}", ctrl.UpdateCurriculum)    // Update a curriculum section
-	instructorRouter.Delete("/{id}/curriculums/{curriculum_id}", ctrl.DeleteCurriculum) // Delete a curriculum section
+	subrouter.Get("/{id}/curriculums", ctrl.ListCurriculum)                 // List curriculum for a course
+	subrouter.Get("/{id}/curriculums/{curriculum_id}", ctrl.ShowCurriculum) // List curriculum for a course
+	subrouter.Get("/{id}/outline", ctrl.Outline)                            // Nested sections with their materials
+	curriculumRouter := subrouter.Group()
+	curriculumRouter.Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	curriculumRouter.Post("/{id}/curriculums", ctrl.AddCuriculum)                        // Add a curriculum section
+	curriculumRouter.Put("/{id}/curriculums/{curriculum_id}", ctrl.UpdateCurriculum)     // Update a curriculum section
+	curriculumRouter.Delete("/{id}/curriculums/{curriculum_id}", ctrl.DeleteCurriculum)  // Delete a curriculum section
+	curriculumRouter.Post("/{id}/curriculums/{curriculum_id}/move", ctrl.MoveCurriculum) // Move a section and its nested sections
+	curriculumRouter.Put("/{id}/outline", ctrl.ReorderOutline)                           // Reorder all the sections and materials at once
+
+	// Change history and the snapshots students read
+	subrouter.Get("/{id}/history", ctrl.History).Middleware(middleware.RequireCourseRole("owner", "instructor", "teaching_assistant"))
+	subrouter.Post("/{id}/history/{version_id}/rollback", ctrl.Rollback).Middleware(middleware.RequireCourseRole("owner", "instructor"))
+	subrouter.Get("/{id}/snapshots", ctrl.ListSnapshots).Middleware(middleware.RequireCourseRole("owner", "instructor", "teaching_assistant"))
+	subrouter.Get("/{id}/snapshots/{snapshot_id}", ctrl.ShowSnapshot).Middleware(middleware.RequireCourseRole("owner", "instructor", "teaching_assistant"))
+	subrouter.Post("/{id}/snapshots", ctrl.PublishChanges).Middleware(middleware.RequireCourseRole("owner", "instructor"))
 }
 
 func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request) {
-	courses, err := ctrl.CourseService.GetAll(request.Context())
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.Error(err)
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	courses, err := ctrl.CourseService.GetAll(request.Context(), viewer, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 	response.JSON(http.StatusOK, courses)
 }
 
+// listQuery reads the pagination, sort and filters of a list, writing a 400
+// response if they are malformed.
+func listQuery(response *goyave.Response, request *goyave.Request) (*listing.Query, bool) {
+	q, err := listing.Parse(request.Request().URL.Query())
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return q, true
+}
+
+// Catalog lists the courses of the catalog, filtered by the "category",
+// "tag" (repeatable), "level", "language" and "duration" query parameters,
+// with the facets to refine the results. It is paginated with "page" and
+// "per_page".
+func (ctrl *Controller) Catalog(response *goyave.Response, request *goyave.Request) {
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	query := request.Request().URL.Query()
+	catalog, err := ctrl.CourseService.Catalog(request.Context(), &dto.CatalogFilter{
+		Category: query.Get("category"),
+		Tags:     query["tag"],
+		Level:    query.Get("level"),
+		Language: query.Get("language"),
+		Duration: query.Get("duration"),
+	}, q)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, catalog)
+}
+
 func (ctrl *Controller) Show(response *goyave.Response, request *goyave.Request) {
 	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
 	if err != nil {
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
 		return
 	}
-	course, err := ctrl.CourseService.GetByID(request.Context(), id)
+	_, course, ok := ctrl.visibleCourse(response, request, id)
+	if !ok {
+		return
+	}
+	response.JSON(http.StatusOK, course)
+}
+
+// visibleCourse writes a 404 response and returns false if the course cannot
+// be seen by the authenticated user.
+func (ctrl *Controller) visibleCourse(response *goyave.Response, request *goyave.Request, id uint64) (*dto.CourseViewer, *dto.Course, bool) {
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return nil, nil, false
+	}
+
+	course, err := ctrl.CourseService.Show(request.Context(), viewer, id)
 	if err != nil {
 		response.JSON(http.StatusNotFound, map[string]string{"error": "Course not found"})
+		return nil, nil, false
+	}
+	return viewer, course, true
+}
+
+func (ctrl *Controller) ListInReview(response *goyave.Response, request *goyave.Request) {
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	courses, err := ctrl.CourseService.ListInReview(request.Context(), q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, courses)
+}
+
+func (ctrl *Controller) Submit(response *goyave.Response, request *goyave.Request) {
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Submit(request.Context(), viewer, id)
+	})
+}
+
+func (ctrl *Controller) Publish(response *goyave.Response, request *goyave.Request) {
+	publishDTO := typeutil.MustConvert[*dto.PublishCourseRequest](request.Data)
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Publish(request.Context(), viewer, id, publishDTO)
+	})
+}
+
+func (ctrl *Controller) Approve(response *goyave.Response, request *goyave.Request) {
+	publishDTO := typeutil.MustConvert[*dto.PublishCourseRequest](request.Data)
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Approve(request.Context(), viewer, id, publishDTO)
+	})
+}
+
+func (ctrl *Controller) Reject(response *goyave.Response, request *goyave.Request) {
+	rejectDTO := typeutil.MustConvert[*dto.RejectCourseRequest](request.Data)
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Reject(request.Context(), viewer, id, rejectDTO)
+	})
+}
+
+func (ctrl *Controller) Withdraw(response *goyave.Response, request *goyave.Request) {
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Withdraw(request.Context(), viewer, id)
+	})
+}
+
+func (ctrl *Controller) Archive(response *goyave.Response, request *goyave.Request) {
+	ctrl.changeStatus(response, request, func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+		return ctrl.CourseService.Archive(request.Context(), viewer, id)
+	})
+}
+
+// changeStatus runs a transition of the publishing workflow on the course of
+// the "id" route parameter.
+func (ctrl *Controller) changeStatus(response *goyave.Response, request *goyave.Request, change func(viewer *dto.CourseViewer, id uint64) (*dto.Course, error)) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	course, err := change(viewer, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
 		return
 	}
 	response.JSON(http.StatusOK, course)
@@ -90,7 +333,11 @@ func (ctrl *Controller) Show(response *goyave.Response, request *goyave.Request)
 
 func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
 	createDTO := typeutil.MustConvert[*dto.CreateCourseRequest](request.Data)
-	course, err := ctrl.CourseService.Create(request.Context(), createDTO)
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	course, err := ctrl.CourseService.Create(request.Context(), userID, createDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -99,6 +346,62 @@ func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Reques
 	response.JSON(http.StatusCreated, course)
 }
 
+func (ctrl *Controller) Templates(response *goyave.Response, request *goyave.Request) {
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	courses, err := ctrl.CourseService.Templates(request.Context(), q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, courses)
+}
+
+// Clone copies the course with its sections, materials and assessments into
+// a new draft owned by the authenticated user.
+func (ctrl *Controller) Clone(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	cloneDTO := typeutil.MustConvert[*dto.CloneCourseRequest](request.Data)
+	course, err := ctrl.CourseService.Clone(request.Context(), viewer, id, cloneDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusCreated, course)
+}
+
+func (ctrl *Controller) SetTemplate(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	templateDTO := typeutil.MustConvert[*dto.TemplateCourseRequest](request.Data)
+	course, err := ctrl.CourseService.SetTemplate(request.Context(), id, templateDTO)
+	if err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, course)
+}
+
 func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Request) {
 	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
 	if err != nil {
@@ -108,7 +411,10 @@ func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Reques
 
 	updateDTO := typeutil.MustConvert[*dto.UpdateCourseRequest](request.Data)
 
-	course, err := ctrl.CourseService.Update(request.Context(), id, updateDTO)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	course, err := ctrl.CourseService.Update(request.Context(), userID, id, updateDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -149,7 +455,10 @@ func (ctrl *Controller) AddCuriculum(response *goyave.Response, request *goyave.
 	}
 
 	createDTO := typeutil.MustConvert[*curriculumDto.CreateCurriculumRequest](request.Data)
-	curriculum, err := ctrl.CourseService.CreateCurriculum(request.Context(), id, createDTO)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	curriculum, err := ctrl.CourseService.CreateCurriculum(request.Context(), userID, id, createDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -164,12 +473,60 @@ func (ctrl *Controller) ListCurriculum(response *goyave.Response, request *goyav
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
 		return
 	}
-	curriculum, err := ctrl.CourseService.GetCurriculumByCourseID(request.Context(), id)
+	viewer, _, ok := ctrl.visibleCourse(response, request, id)
+	if !ok {
+		return
+	}
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	content, err := ctrl.CourseService.PublishedContent(request.Context(), viewer, id)
 	if err != nil {
-		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		response.Error(err)
 		return
 	}
-	response.JSON(http.StatusOK, curriculum)
+	access, err := ctrl.CourseService.ContentAccess(request.Context(), viewer, id)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	releases, err := ctrl.CourseService.Releases(request.Context(), viewer, id)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	// Users who did not enroll only list the preview sections
+	var page *listing.Page[*curriculumDto.Curriculum]
+	if content != nil {
+		curriculums := make([]*curriculumDto.Curriculum, 0, len(content.Curriculums))
+		for _, curriculum := range content.Curriculums {
+			curriculum.IsPreview = access.Preview(uint64(curriculum.ID))
+			if access.Section(uint64(curriculum.ID)) {
+				curriculums = append(curriculums, curriculum)
+			}
+		}
+		page, err = listing.Slice(curriculums, q)
+		if err != nil {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	} else {
+		if !access.Full() {
+			q.Filters = append(q.Filters, listing.Filter{Field: "is_preview", Operator: listing.Eq, Value: "true"})
+		}
+		page, err = ctrl.CourseService.GetCurriculumByCourseID(request.Context(), id, q)
+		if err != nil {
+			response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	for _, curriculum := range page.Data {
+		curriculum.Lock = releases.Curriculum(uint64(curriculum.ID))
+	}
+	response.JSON(http.StatusOK, page)
 }
 
 func (ctrl *Controller) ShowCurriculum(response *goyave.Response, request *goyave.Request) {
@@ -178,11 +535,53 @@ func (ctrl *Controller) ShowCurriculum(response *goyave.Response, request *goyav
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid curriculum ID"})
 		return
 	}
-	curriculum, err := ctrl.CourseService.GetCurriculumByID(request.Context(), id)
+	courseID, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
 	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	viewer, _, ok := ctrl.visibleCourse(response, request, courseID)
+	if !ok {
+		return
+	}
+
+	content, err := ctrl.CourseService.PublishedContent(request.Context(), viewer, courseID)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	access, err := ctrl.CourseService.ContentAccess(request.Context(), viewer, courseID)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	releases, err := ctrl.CourseService.Releases(request.Context(), viewer, courseID)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	var curriculum *curriculumDto.Curriculum
+	if content != nil {
+		for _, c := range content.Curriculums {
+			if uint64(c.ID) == id {
+				curriculum = c
+				break
+			}
+		}
+	} else if c, err := ctrl.CourseService.GetCurriculumByID(request.Context(), id); err == nil && uint64(c.CourseID) == courseID {
+		curriculum = c
+	}
+	if curriculum == nil {
 		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
 		return
 	}
+	if !access.Section(id) {
+		response.JSON(http.StatusForbidden, map[string]string{"error": "Enroll in the course to access this section"})
+		return
+	}
+	curriculum.IsPreview = access.Preview(id)
+	curriculum.Lock = releases.Curriculum(id)
 	response.JSON(http.StatusOK, curriculum)
 }
 
@@ -192,9 +591,17 @@ func (ctrl *Controller) UpdateCurriculum(response *goyave.Response, request *goy
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid curriculum ID"})
 		return
 	}
+	if !ctrl.curriculumInCourse(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		return
+	}
+
 	updateDTO := typeutil.MustConvert[*curriculumDto.UpdateCurriculumRequest](request.Data)
 
-	curriculum, err := ctrl.CourseService.UpdateCurriculum(request.Context(), id, updateDTO)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	curriculum, err := ctrl.CourseService.UpdateCurriculum(request.Context(), userID, id, updateDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -209,10 +616,230 @@ func (ctrl *Controller) DeleteCurriculum(response *goyave.Response, request *goy
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid curriculum ID"})
 		return
 	}
-	if err := ctrl.CourseService.DeleteCurriculum(request.Context(), id); err != nil {
+	if !ctrl.curriculumInCourse(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	if err := ctrl.CourseService.DeleteCurriculum(request.Context(), userID, id); err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
 	response.JSON(http.StatusOK, map[string]string{"message": "Curriculum deleted successfully"})
 }
+
+// curriculumInCourse reports whether the curriculum belongs to the course of
+// the "id" route parameter, which is the one staff access was checked against.
+func (ctrl *Controller) curriculumInCourse(request *goyave.Request, curriculumID uint64) bool {
+	courseID, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	curriculum, err := ctrl.CourseService.GetCurriculumByID(request.Context(), curriculumID)
+	return err == nil && uint64(curriculum.CourseID) == courseID
+}
+
+func (ctrl *Controller) ListStaff(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	staff, err := ctrl.CourseService.GetStaff(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, staff)
+}
+
+func (ctrl *Controller) SaveStaff(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	saveDTO := typeutil.MustConvert[*dto.SaveCourseStaffRequest](request.Data)
+	member, err := ctrl.CourseService.SaveStaff(request.Context(), id, saveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, member)
+}
+
+// ShowRequirements returns what students need to enroll in the course, to
+// anyone who can see it.
+func (ctrl *Controller) ShowRequirements(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	if _, _, ok := ctrl.visibleCourse(response, request, id); !ok {
+		return
+	}
+
+	requirements, err := ctrl.CourseService.GetRequirements(request.Context(), id)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, requirements)
+}
+
+func (ctrl *Controller) SaveRequirements(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	saveDTO := typeutil.MustConvert[*dto.SaveCourseRequirementsRequest](request.Data)
+	requirements, err := ctrl.CourseService.SaveRequirements(request.Context(), id, saveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, requirements)
+}
+
+func (ctrl *Controller) RemoveStaff(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(request.RouteParams["user_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := ctrl.CourseService.RemoveStaff(request.Context(), id, userID); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Staff member removed successfully"})
+}
+
+func (ctrl *Controller) History(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	versions, err := ctrl.CourseService.History(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, versions)
+}
+
+func (ctrl *Controller) Rollback(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	versionID, err := strconv.ParseUint(request.RouteParams["version_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid version ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	version, err := ctrl.CourseService.Rollback(request.Context(), userID, id, versionID)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, version)
+}
+
+func (ctrl *Controller) ListSnapshots(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	snapshots, err := ctrl.CourseService.Snapshots(request.Context(), id, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, snapshots)
+}
+
+func (ctrl *Controller) ShowSnapshot(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	snapshotID, err := strconv.ParseUint(request.RouteParams["snapshot_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid snapshot ID"})
+		return
+	}
+
+	snapshot, err := ctrl.CourseService.Snapshot(request.Context(), id, snapshotID)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, snapshot)
+}
+
+// PublishChanges makes the current content of a published course the one
+// students read.
+func (ctrl *Controller) PublishChanges(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	snapshot, err := ctrl.CourseService.PublishChanges(request.Context(), viewer, id)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, snapshot)
+}
@@ -0,0 +1,87 @@
+package courses
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// Outline returns the sections of the course nested in each other, with
+// their materials, as the user reads them.
+func (ctrl *Controller) Outline(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	viewer, _, ok := ctrl.visibleCourse(response, request, id)
+	if !ok {
+		return
+	}
+
+	outline, err := ctrl.CourseService.Outline(request.Context(), viewer, id)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, outline)
+}
+
+// MoveCurriculum moves a section under another section of the course, or to
+// its top level, and returns the sections whose parent or order changed.
+func (ctrl *Controller) MoveCurriculum(response *goyave.Response, request *goyave.Request) {
+	courseID, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	id, err := strconv.ParseUint(request.RouteParams["curriculum_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid curriculum ID"})
+		return
+	}
+	if !ctrl.curriculumInCourse(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		return
+	}
+
+	moveDTO := typeutil.MustConvert[*curriculumDto.MoveCurriculumRequest](request.Data)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	moved, err := ctrl.CourseService.MoveCurriculum(request.Context(), userID, courseID, id, moveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, moved)
+}
+
+// ReorderOutline places all the sections and materials of the course at once,
+// and returns the new outline.
+func (ctrl *Controller) ReorderOutline(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	reorderDTO := typeutil.MustConvert[*dto.ReorderOutlineRequest](request.Data)
+	outline, err := ctrl.CourseService.ReorderOutline(request.Context(), viewer, id, reorderDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, outline)
+}
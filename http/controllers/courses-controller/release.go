@@ -0,0 +1,62 @@
+package courses
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+func (ctrl *Controller) ListReleaseRules(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	rules, err := ctrl.CourseService.GetReleaseRules(request.Context(), id)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, rules)
+}
+
+// SaveReleaseRule sets when a section or a material of the course is released
+// to the students, replacing its previous rule.
+func (ctrl *Controller) SaveReleaseRule(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	saveDTO := typeutil.MustConvert[*dto.SaveReleaseRuleRequest](request.Data)
+	rule, err := ctrl.CourseService.SaveReleaseRule(request.Context(), id, saveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, rule)
+}
+
+func (ctrl *Controller) DeleteReleaseRule(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+	ruleID, err := strconv.ParseUint(request.RouteParams["rule_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid release rule ID"})
+		return
+	}
+
+	if err := ctrl.CourseService.DeleteReleaseRule(request.Context(), id, ruleID); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, map[string]string{"message": "Release rule deleted successfully"})
+}
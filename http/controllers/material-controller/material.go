@@ -5,24 +5,41 @@ import (
 	"net/http"
 	"strconv"
 
+	courseDto "github.com/dapthehuman/learning-management-system/dto"
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
 	dto "github.com/dapthehuman/learning-management-system/dto/material"
 	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	courseService "github.com/dapthehuman/learning-management-system/service/course-service"
+	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
 type Service interface {
-	Create(ctx context.Context, materialDTO *dto.CreateMaterialRequest) (*dto.CreateMaterialResponse, error)
+	Create(ctx context.Context, actorID uint64, materialDTO *dto.CreateMaterialRequest) (*dto.CreateMaterialResponse, error)
 	GetByID(ctx context.Context, id uint64) (*dto.MaterialResponse, error)
-	GetByCurriculumID(ctx context.Context, curriculumID uint64) ([]*dto.MaterialResponse, error)
-	Update(ctx context.Context, id uint64, updateDTO *dto.UpdateMaterialRequest) (*dto.MaterialResponse, error)
-	Delete(ctx context.Context, id uint64) error
+	GetByCurriculumID(ctx context.Context, curriculumID uint64, q *listing.Query) (*listing.Page[*dto.MaterialResponse], error)
+	Update(ctx context.Context, actorID uint64, id uint64, updateDTO *dto.UpdateMaterialRequest) (*dto.MaterialResponse, error)
+	Delete(ctx context.Context, actorID uint64, id uint64) error
+	Move(ctx context.Context, actorID uint64, id uint64, moveDTO *dto.MoveMaterialRequest) ([]*dto.MaterialResponse, error)
+}
+
+// CourseService tells who can read the content of a course, and gives the
+// snapshot of the course students read and the content released to them.
+type CourseService interface {
+	GetCurriculumByID(ctx context.Context, id uint64) (*curriculumDto.Curriculum, error)
+	Show(ctx context.Context, viewer *courseDto.CourseViewer, id uint64) (*courseDto.Course, error)
+	ContentAccess(ctx context.Context, viewer *courseDto.CourseViewer, courseID uint64) (*courseService.ContentAccess, error)
+	PublishedContent(ctx context.Context, viewer *courseDto.CourseViewer, courseID uint64) (*courseDto.CourseContent, error)
+	Releases(ctx context.Context, viewer *courseDto.CourseViewer, courseID uint64) (*courseService.Releases, error)
 }
 
 type Controller struct {
 	goyave.Component
 	MaterialService Service
+	CourseService   CourseService
 }
 
 func NewController() *Controller {
@@ -31,6 +48,7 @@ func NewController() *Controller {
 
 func (ctrl *Controller) Init(server *goyave.Server) {
 	ctrl.MaterialService = server.Service(service.Material).(Service)
+	ctrl.CourseService = server.Service(service.Course).(CourseService)
 	ctrl.Component.Init(server)
 }
 
@@ -43,11 +61,12 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 	subrouter.Get("/", ctrl.Index)    // Get all materials for a curriculum
 	subrouter.Get("/{id}", ctrl.Show) // Get a material by ID
 
-	instructorOnly := middleware.NewRoleMiddleware("admin", "instructor")
+	instructorOnly := middleware.RequireCurriculumRole("owner", "instructor", "teaching_assistant")
 	instructorRouter := subrouter.Group().Middleware(instructorOnly)
-	instructorRouter.Post("/", ctrl.Create)       // Create a material for a curriculum
-	instructorRouter.Put("/{id}", ctrl.Update)    // Update a material by ID
-	instructorRouter.Delete("/{id}", ctrl.Delete) // Delete a material by ID
+	instructorRouter.Post("/", ctrl.Create)        // Create a material for a curriculum
+	instructorRouter.Put("/{id}", ctrl.Update)     // Update a material by ID
+	instructorRouter.Delete("/{id}", ctrl.Delete)  // Delete a material by ID
+	instructorRouter.Post("/{id}/move", ctrl.Move) // Move a material within the course
 }
 
 func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
@@ -61,7 +80,10 @@ func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Reques
 
 	materialDTO.CurriculumID = curriculumID
 
-	createdMaterial, err := ctrl.MaterialService.Create(request.Context(), materialDTO)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	createdMaterial, err := ctrl.MaterialService.Create(request.Context(), userID, materialDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -77,12 +99,39 @@ func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request
 		return
 	}
 
-	materials, err := ctrl.MaterialService.GetByCurriculumID(request.Context(), curriculumID)
+	q, err := listing.Parse(request.Request().URL.Query())
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	published, releases, ok := ctrl.publishedMaterials(response, request, curriculumID)
+	if !ok {
 		return
 	}
 
+	var materials *listing.Page[*dto.MaterialResponse]
+	if published != nil {
+		materials, err = listing.Slice(published, q)
+		if err != nil {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	} else {
+		materials, err = ctrl.MaterialService.GetByCurriculumID(request.Context(), curriculumID, q)
+		if err != nil {
+			response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	// Locked materials are listed without their content
+	for _, material := range materials.Data {
+		material.Lock = releases.Material(uint64(material.ID), uint64(material.CurriculumID))
+		if material.Lock != nil {
+			material.Content = ""
+		}
+	}
 	response.JSON(http.StatusOK, materials)
 }
 
@@ -92,13 +141,37 @@ func (ctrl *Controller) Show(response *goyave.Response, request *goyave.Request)
 		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid material ID"})
 		return
 	}
-
-	material, err := ctrl.MaterialService.GetByID(request.Context(), id)
+	curriculumID, err := strconv.ParseUint(request.RouteParams["curriculum_id"], 10, 64)
 	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid curriculum ID"})
+		return
+	}
+
+	published, releases, ok := ctrl.publishedMaterials(response, request, curriculumID)
+	if !ok {
+		return
+	}
+
+	var material *dto.MaterialResponse
+	if published != nil {
+		for _, m := range published {
+			if uint64(m.ID) == id {
+				material = m
+				break
+			}
+		}
+	} else if m, err := ctrl.MaterialService.GetByID(request.Context(), id); err == nil && uint64(m.CurriculumID) == curriculumID {
+		material = m
+	}
+	if material == nil {
 		response.JSON(http.StatusNotFound, map[string]string{"error": "Material not found"})
 		return
 	}
 
+	if lock := releases.Material(id, uint64(material.CurriculumID)); lock != nil {
+		response.JSON(http.StatusForbidden, map[string]any{"error": "This material is not available yet", "lock": lock})
+		return
+	}
 	response.JSON(http.StatusOK, material)
 }
 
@@ -109,9 +182,17 @@ func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Reques
 		return
 	}
 
+	if !ctrl.materialInCurriculum(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Material not found"})
+		return
+	}
+
 	updateDTO := typeutil.MustConvert[*dto.UpdateMaterialRequest](request.Data)
 
-	material, err := ctrl.MaterialService.Update(request.Context(), id, updateDTO)
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	material, err := ctrl.MaterialService.Update(request.Context(), userID, id, updateDTO)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -127,7 +208,15 @@ func (ctrl *Controller) Delete(response *goyave.Response, request *goyave.Reques
 		return
 	}
 
-	err = ctrl.MaterialService.Delete(request.Context(), id)
+	if !ctrl.materialInCurriculum(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Material not found"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	err = ctrl.MaterialService.Delete(request.Context(), userID, id)
 	if err != nil {
 		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -135,3 +224,101 @@ func (ctrl *Controller) Delete(response *goyave.Response, request *goyave.Reques
 
 	response.JSON(http.StatusOK, map[string]string{"message": "Material deleted successfully"})
 }
+
+// Move moves a material to another position, possibly in another section of
+// the course, and returns the materials whose section or order changed.
+func (ctrl *Controller) Move(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid material ID"})
+		return
+	}
+
+	if !ctrl.materialInCurriculum(request, id) {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Material not found"})
+		return
+	}
+
+	moveDTO := typeutil.MustConvert[*dto.MoveMaterialRequest](request.Data)
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	moved, err := ctrl.MaterialService.Move(request.Context(), userID, id, moveDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, moved)
+}
+
+// materialInCurriculum reports whether the material belongs to the curriculum
+// of the "curriculum_id" route parameter, which is the one staff access was
+// checked against.
+func (ctrl *Controller) materialInCurriculum(request *goyave.Request, materialID uint64) bool {
+	curriculumID, err := strconv.ParseUint(request.RouteParams["curriculum_id"], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	material, err := ctrl.MaterialService.GetByID(request.Context(), materialID)
+	return err == nil && uint64(material.CurriculumID) == curriculumID
+}
+
+// publishedMaterials returns the materials of the curriculum in the snapshot
+// of the course the user reads, or nil if they read the live content, and
+// the content of the course released to them. It writes the response and
+// returns false if the curriculum does not exist or the user cannot read
+// it: they must be enrolled in the course or part of its staff, unless the
+// section is a preview section of a course they can see.
+func (ctrl *Controller) publishedMaterials(response *goyave.Response, request *goyave.Request, curriculumID uint64) ([]*dto.MaterialResponse, *courseService.Releases, bool) {
+	curriculum, err := ctrl.CourseService.GetCurriculumByID(request.Context(), curriculumID)
+	if err != nil || curriculum == nil || curriculum.ID == 0 {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		return nil, nil, false
+	}
+
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return nil, nil, false
+	}
+
+	if _, err := ctrl.CourseService.Show(request.Context(), viewer, uint64(curriculum.CourseID)); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Curriculum not found"})
+		return nil, nil, false
+	}
+	access, err := ctrl.CourseService.ContentAccess(request.Context(), viewer, uint64(curriculum.CourseID))
+	if err != nil {
+		response.Error(err)
+		return nil, nil, false
+	}
+	if !access.Section(curriculumID) {
+		response.JSON(http.StatusForbidden, map[string]string{"error": "Enroll in the course to access this section"})
+		return nil, nil, false
+	}
+
+	releases, err := ctrl.CourseService.Releases(request.Context(), viewer, uint64(curriculum.CourseID))
+	if err != nil {
+		response.Error(err)
+		return nil, nil, false
+	}
+
+	content, err := ctrl.CourseService.PublishedContent(request.Context(), viewer, uint64(curriculum.CourseID))
+	if err != nil {
+		response.Error(err)
+		return nil, nil, false
+	}
+	if content == nil {
+		return nil, releases, true
+	}
+
+	materials := []*dto.MaterialResponse{}
+	for _, material := range content.Materials {
+		if uint64(material.CurriculumID) == curriculumID {
+			materials = append(materials, material)
+		}
+	}
+	return materials, releases, true
+}
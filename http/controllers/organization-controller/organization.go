@@ -0,0 +1,82 @@
+package organization
+
+import (
+	"context"
+	"net/http"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/organization"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type Service interface {
+	Current(ctx context.Context) (*dto.Organization, error)
+	Create(ctx context.Context, actorID uint64, createDTO *dto.CreateOrganizationRequest) (*dto.CreateOrganizationResponse, error)
+	UpdateCurrent(ctx context.Context, updateDTO *dto.UpdateOrganizationRequest) (*dto.Organization, error)
+}
+
+type Controller struct {
+	goyave.Component
+	OrganizationService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.OrganizationService = server.Service(service.Organization).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	authMiddleware := middleware.NewUserAuth()
+
+	// The organization the request is scoped to
+	currentRouter := router.Subrouter("/organization")
+	currentRouter.Middleware(authMiddleware)
+	currentRouter.Get("/", ctrl.Show)
+	currentRouter.Patch("/", ctrl.Update).Middleware(middleware.RequirePermission("organization.manage"))
+
+	// Onboarding of new schools
+	organizationRouter := router.Subrouter("/organizations")
+	organizationRouter.Middleware(authMiddleware)
+	organizationRouter.Middleware(middleware.RequirePermission("organization.create"))
+	organizationRouter.Middleware(middleware.RequirePlatform())
+	organizationRouter.Post("/", ctrl.Create)
+}
+
+func (ctrl *Controller) Show(response *goyave.Response, request *goyave.Request) {
+	organization, err := ctrl.OrganizationService.Current(request.Context())
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, organization)
+}
+
+func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Request) {
+	updateDTO := typeutil.MustConvert[*dto.UpdateOrganizationRequest](request.Data)
+	organization, err := ctrl.OrganizationService.UpdateCurrent(request.Context(), updateDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, organization)
+}
+
+func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	createDTO := typeutil.MustConvert[*dto.CreateOrganizationRequest](request.Data)
+	organization, err := ctrl.OrganizationService.Create(request.Context(), adminID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, organization)
+}
@@ -0,0 +1,195 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/service"
+	privacyService "github.com/dapthehuman/learning-management-system/service/privacy-service"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type Service interface {
+	RequestExport(ctx context.Context, userID uint64) (*dto.DataExport, error)
+	LatestExport(ctx context.Context, userID uint64) (*dto.DataExport, error)
+	DownloadExport(ctx context.Context, userID uint64, id uint64) ([]byte, error)
+
+	RequestErasure(ctx context.Context, userID uint64, createDTO *dto.CreateErasureRequest) (*dto.ErasureRequest, error)
+	LatestErasureRequest(ctx context.Context, userID uint64) (*dto.ErasureRequest, error)
+	ListErasureRequests(ctx context.Context, status string, q *listing.Query) (*listing.Page[*dto.ErasureRequest], error)
+	ApproveErasure(ctx context.Context, reviewerID uint64, id uint64, reviewDTO *dto.ReviewErasureRequest) (*dto.ErasureRequest, error)
+	RejectErasure(ctx context.Context, reviewerID uint64, id uint64, reviewDTO *dto.ReviewErasureRequest) (*dto.ErasureRequest, error)
+}
+
+type Controller struct {
+	goyave.Component
+	PrivacyService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.PrivacyService = server.Service(service.Privacy).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	authMiddleware := middleware.NewUserAuth()
+
+	// Personal data of the current user, out of reach of impersonation sessions
+	exportRouter := router.Subrouter("/me/data-export")
+	exportRouter.Middleware(authMiddleware)
+	exportRouter.Middleware(middleware.BlockImpersonation())
+	exportRouter.Get("/", ctrl.LatestExport)
+	exportRouter.Post("/", ctrl.RequestExport)
+	exportRouter.Get("/{id}/download", ctrl.DownloadExport)
+
+	erasureRouter := router.Subrouter("/me/erasure-request")
+	erasureRouter.Middleware(authMiddleware)
+	erasureRouter.Middleware(middleware.BlockImpersonation())
+	erasureRouter.Get("/", ctrl.LatestErasureRequest)
+	erasureRouter.Post("/", ctrl.RequestErasure)
+
+	// Review of erasure requests
+	adminRouter := router.Subrouter("/admin/erasure-requests")
+	adminRouter.Middleware(authMiddleware)
+	adminRouter.Middleware(middleware.RequirePermission("user.erase"))
+	adminRouter.Get("/", ctrl.ListErasureRequests)
+	adminRouter.Post("/{id}/approve", ctrl.ApproveErasure)
+	adminRouter.Post("/{id}/reject", ctrl.RejectErasure)
+}
+
+func (ctrl *Controller) RequestExport(response *goyave.Response, request *goyave.Request) {
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	export, err := ctrl.PrivacyService.RequestExport(request.Context(), userID)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusAccepted, export)
+}
+
+func (ctrl *Controller) LatestExport(response *goyave.Response, request *goyave.Request) {
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	export, err := ctrl.PrivacyService.LatestExport(request.Context(), userID)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, export)
+}
+
+func (ctrl *Controller) DownloadExport(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid export ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	archive, err := ctrl.PrivacyService.DownloadExport(request.Context(), userID, id)
+	switch {
+	case err == privacyService.ErrExportNotReady:
+		response.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case err == privacyService.ErrExportExpired:
+		response.JSON(http.StatusGone, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Data export not found"})
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/zip")
+	response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, id))
+	response.WriteHeader(http.StatusOK)
+	_, _ = response.Write(archive)
+}
+
+func (ctrl *Controller) RequestErasure(response *goyave.Response, request *goyave.Request) {
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	createDTO := typeutil.MustConvert[*dto.CreateErasureRequest](request.Data)
+	erasure, err := ctrl.PrivacyService.RequestErasure(request.Context(), userID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusCreated, erasure)
+}
+
+func (ctrl *Controller) LatestErasureRequest(response *goyave.Response, request *goyave.Request) {
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	erasure, err := ctrl.PrivacyService.LatestErasureRequest(request.Context(), userID)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, erasure)
+}
+
+func (ctrl *Controller) ListErasureRequests(response *goyave.Response, request *goyave.Request) {
+	query := request.Request().URL.Query()
+	q, err := listing.Parse(query)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	requests, err := ctrl.PrivacyService.ListErasureRequests(request.Context(), query.Get("status"), q)
+	if err != nil {
+		if listing.IsInvalid(err) {
+			response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		response.Error(err)
+		return
+	}
+	response.JSON(http.StatusOK, requests)
+}
+
+func (ctrl *Controller) ApproveErasure(response *goyave.Response, request *goyave.Request) {
+	ctrl.reviewErasure(response, request, ctrl.PrivacyService.ApproveErasure)
+}
+
+func (ctrl *Controller) RejectErasure(response *goyave.Response, request *goyave.Request) {
+	ctrl.reviewErasure(response, request, ctrl.PrivacyService.RejectErasure)
+}
+
+func (ctrl *Controller) reviewErasure(response *goyave.Response, request *goyave.Request,
+	review func(ctx context.Context, reviewerID uint64, id uint64, reviewDTO *dto.ReviewErasureRequest) (*dto.ErasureRequest, error)) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid erasure request ID"})
+		return
+	}
+
+	admin := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(admin["user_id"].(float64))
+
+	reviewDTO := typeutil.MustConvert[*dto.ReviewErasureRequest](request.Data)
+	erasure, err := review(request.Context(), adminID, id, reviewDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, erasure)
+}
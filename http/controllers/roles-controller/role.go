@@ -0,0 +1,113 @@
+package roles
+
+import (
+	"context"
+	"net/http"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/role"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type Service interface {
+	GetAll(ctx context.Context) ([]*dto.Role, error)
+	GetByName(ctx context.Context, name string) (*dto.Role, error)
+	ListPermissions(ctx context.Context) ([]*dto.Permission, error)
+	Create(ctx context.Context, createDTO *dto.CreateRoleRequest) (*dto.Role, error)
+	Update(ctx context.Context, name string, updateDTO *dto.UpdateRoleRequest) (*dto.Role, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type Controller struct {
+	goyave.Component
+	RoleService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.RoleService = server.Service(service.Role).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	authMiddleware := middleware.NewUserAuth()
+	roleManager := middleware.RequirePermission("role.manage")
+
+	// Roles are shared by every organization, only the platform can change them
+	platform := middleware.RequirePlatform()
+
+	subrouter := router.Subrouter("/roles")
+	subrouter.Middleware(authMiddleware, roleManager)
+	subrouter.Get("/", ctrl.Index)
+	subrouter.Post("/", ctrl.Create).Middleware(platform)
+	subrouter.Get("/{name}", ctrl.Show)
+	subrouter.Put("/{name}", ctrl.Update).Middleware(platform)
+	subrouter.Delete("/{name}", ctrl.Delete).Middleware(platform)
+
+	permissionRouter := router.Subrouter("/permissions")
+	permissionRouter.Middleware(authMiddleware, roleManager)
+	permissionRouter.Get("/", ctrl.ListPermissions)
+}
+
+func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request) {
+	roles, err := ctrl.RoleService.GetAll(request.Context())
+	if err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, roles)
+}
+
+func (ctrl *Controller) Show(response *goyave.Response, request *goyave.Request) {
+	role, err := ctrl.RoleService.GetByName(request.Context(), request.RouteParams["name"])
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Role not found"})
+		return
+	}
+	response.JSON(http.StatusOK, role)
+}
+
+func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
+	createDTO := typeutil.MustConvert[*dto.CreateRoleRequest](request.Data)
+	role, err := ctrl.RoleService.Create(request.Context(), createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusCreated, role)
+}
+
+func (ctrl *Controller) Update(response *goyave.Response, request *goyave.Request) {
+	updateDTO := typeutil.MustConvert[*dto.UpdateRoleRequest](request.Data)
+	role, err := ctrl.RoleService.Update(request.Context(), request.RouteParams["name"], updateDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, role)
+}
+
+func (ctrl *Controller) Delete(response *goyave.Response, request *goyave.Request) {
+	if err := ctrl.RoleService.Delete(request.Context(), request.RouteParams["name"]); err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Role deleted successfully"})
+}
+
+func (ctrl *Controller) ListPermissions(response *goyave.Response, request *goyave.Request) {
+	permissions, err := ctrl.RoleService.ListPermissions(request.Context())
+	if err != nil {
+		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, permissions)
+}
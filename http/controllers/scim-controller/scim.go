@@ -0,0 +1,243 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/scim"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/service"
+	scimService "github.com/dapthehuman/learning-management-system/service/scim-service"
+	"goyave.dev/goyave/v5"
+)
+
+// maxBodySize is the largest request body accepted by the SCIM API.
+const maxBodySize = 1 << 20
+
+type Service interface {
+	ListUsers(ctx context.Context, listDTO *dto.ListRequest) (*dto.ListResponse, error)
+	GetUser(ctx context.Context, id string) (*dto.User, error)
+	CreateUser(ctx context.Context, userDTO *dto.User) (*dto.User, error)
+	ReplaceUser(ctx context.Context, id string, userDTO *dto.User) (*dto.User, error)
+	PatchUser(ctx context.Context, id string, patchDTO *dto.PatchRequest) (*dto.User, error)
+	DeprovisionUser(ctx context.Context, id string) error
+
+	ListGroups(ctx context.Context, listDTO *dto.ListRequest) (*dto.ListResponse, error)
+	GetGroup(ctx context.Context, id string) (*dto.Group, error)
+	CreateGroup(ctx context.Context, groupDTO *dto.Group) (*dto.Group, error)
+	ReplaceGroup(ctx context.Context, id string, groupDTO *dto.Group) (*dto.Group, error)
+	PatchGroup(ctx context.Context, id string, patchDTO *dto.PatchRequest) (*dto.Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+
+	ServiceProviderConfig() *dto.ServiceProviderConfig
+}
+
+type Controller struct {
+	goyave.Component
+	SCIMService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.SCIMService = server.Service(service.SCIM).(Service)
+	ctrl.Component.Init(server)
+}
+
+// RegisterRoutes registers the SCIM 2.0 API. The identity provider
+// authenticates with a service-account API key whose user is granted
+// "scim.provision".
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	subrouter := router.Subrouter("/scim/v2")
+	subrouter.Middleware(middleware.NewUserAuth())
+	subrouter.Middleware(middleware.RequirePermission("scim.provision"))
+
+	subrouter.Get("/ServiceProviderConfig", ctrl.ServiceProviderConfig)
+
+	subrouter.Get("/Users", ctrl.ListUsers)
+	subrouter.Post("/Users", ctrl.CreateUser)
+	subrouter.Get("/Users/{id}", ctrl.GetUser)
+	subrouter.Put("/Users/{id}", ctrl.ReplaceUser)
+	subrouter.Patch("/Users/{id}", ctrl.PatchUser)
+	subrouter.Delete("/Users/{id}", ctrl.DeprovisionUser)
+
+	subrouter.Get("/Groups", ctrl.ListGroups)
+	subrouter.Post("/Groups", ctrl.CreateGroup)
+	subrouter.Get("/Groups/{id}", ctrl.GetGroup)
+	subrouter.Put("/Groups/{id}", ctrl.ReplaceGroup)
+	subrouter.Patch("/Groups/{id}", ctrl.PatchGroup)
+	subrouter.Delete("/Groups/{id}", ctrl.DeleteGroup)
+}
+
+func (ctrl *Controller) ServiceProviderConfig(response *goyave.Response, request *goyave.Request) {
+	ctrl.write(response, http.StatusOK, ctrl.SCIMService.ServiceProviderConfig())
+}
+
+func (ctrl *Controller) ListUsers(response *goyave.Response, request *goyave.Request) {
+	users, err := ctrl.SCIMService.ListUsers(request.Context(), listRequest(request))
+	ctrl.respond(response, http.StatusOK, users, err)
+}
+
+func (ctrl *Controller) GetUser(response *goyave.Response, request *goyave.Request) {
+	user, err := ctrl.SCIMService.GetUser(request.Context(), request.RouteParams["id"])
+	ctrl.respond(response, http.StatusOK, user, err)
+}
+
+func (ctrl *Controller) CreateUser(response *goyave.Response, request *goyave.Request) {
+	userDTO := &dto.User{}
+	if !ctrl.decode(response, request, userDTO) {
+		return
+	}
+
+	user, err := ctrl.SCIMService.CreateUser(request.Context(), userDTO)
+	ctrl.respond(response, http.StatusCreated, user, err)
+}
+
+func (ctrl *Controller) ReplaceUser(response *goyave.Response, request *goyave.Request) {
+	userDTO := &dto.User{}
+	if !ctrl.decode(response, request, userDTO) {
+		return
+	}
+
+	user, err := ctrl.SCIMService.ReplaceUser(request.Context(), request.RouteParams["id"], userDTO)
+	ctrl.respond(response, http.StatusOK, user, err)
+}
+
+func (ctrl *Controller) PatchUser(response *goyave.Response, request *goyave.Request) {
+	patchDTO := &dto.PatchRequest{}
+	if !ctrl.decode(response, request, patchDTO) {
+		return
+	}
+
+	user, err := ctrl.SCIMService.PatchUser(request.Context(), request.RouteParams["id"], patchDTO)
+	ctrl.respond(response, http.StatusOK, user, err)
+}
+
+func (ctrl *Controller) DeprovisionUser(response *goyave.Response, request *goyave.Request) {
+	err := ctrl.SCIMService.DeprovisionUser(request.Context(), request.RouteParams["id"])
+	ctrl.respond(response, http.StatusNoContent, nil, err)
+}
+
+func (ctrl *Controller) ListGroups(response *goyave.Response, request *goyave.Request) {
+	groups, err := ctrl.SCIMService.ListGroups(request.Context(), listRequest(request))
+	ctrl.respond(response, http.StatusOK, groups, err)
+}
+
+func (ctrl *Controller) GetGroup(response *goyave.Response, request *goyave.Request) {
+	group, err := ctrl.SCIMService.GetGroup(request.Context(), request.RouteParams["id"])
+	ctrl.respond(response, http.StatusOK, group, err)
+}
+
+func (ctrl *Controller) CreateGroup(response *goyave.Response, request *goyave.Request) {
+	groupDTO := &dto.Group{}
+	if !ctrl.decode(response, request, groupDTO) {
+		return
+	}
+
+	group, err := ctrl.SCIMService.CreateGroup(request.Context(), groupDTO)
+	ctrl.respond(response, http.StatusCreated, group, err)
+}
+
+func (ctrl *Controller) ReplaceGroup(response *goyave.Response, request *goyave.Request) {
+	groupDTO := &dto.Group{}
+	if !ctrl.decode(response, request, groupDTO) {
+		return
+	}
+
+	group, err := ctrl.SCIMService.ReplaceGroup(request.Context(), request.RouteParams["id"], groupDTO)
+	ctrl.respond(response, http.StatusOK, group, err)
+}
+
+func (ctrl *Controller) PatchGroup(response *goyave.Response, request *goyave.Request) {
+	patchDTO := &dto.PatchRequest{}
+	if !ctrl.decode(response, request, patchDTO) {
+		return
+	}
+
+	group, err := ctrl.SCIMService.PatchGroup(request.Context(), request.RouteParams["id"], patchDTO)
+	ctrl.respond(response, http.StatusOK, group, err)
+}
+
+func (ctrl *Controller) DeleteGroup(response *goyave.Response, request *goyave.Request) {
+	err := ctrl.SCIMService.DeleteGroup(request.Context(), request.RouteParams["id"])
+	ctrl.respond(response, http.StatusNoContent, nil, err)
+}
+
+func listRequest(request *goyave.Request) *dto.ListRequest {
+	query := request.Request().URL.Query()
+	startIndex, _ := strconv.Atoi(query.Get("startIndex"))
+	count, _ := strconv.Atoi(query.Get("count"))
+	return &dto.ListRequest{
+		Filter:     query.Get("filter"),
+		StartIndex: startIndex,
+		Count:      count,
+	}
+}
+
+// decode reads the JSON body of the request. Bodies sent as
+// "application/json" are already parsed, "application/scim+json" ones are
+// read from the request.
+func (ctrl *Controller) decode(response *goyave.Response, request *goyave.Request, v any) bool {
+	var err error
+	if request.Data != nil {
+		var raw []byte
+		if raw, err = json.Marshal(request.Data); err == nil {
+			err = json.Unmarshal(raw, v)
+		}
+	} else {
+		err = json.NewDecoder(io.LimitReader(request.Request().Body, maxBodySize)).Decode(v)
+	}
+
+	if err != nil {
+		ctrl.writeError(response, &scimService.Error{Status: http.StatusBadRequest, Type: "invalidSyntax", Detail: "Invalid JSON body"})
+		return false
+	}
+	return true
+}
+
+func (ctrl *Controller) respond(response *goyave.Response, status int, body any, err error) {
+	if err != nil {
+		var scimErr *scimService.Error
+		if errors.As(err, &scimErr) {
+			ctrl.writeError(response, scimErr)
+			return
+		}
+		ctrl.Logger().Error(err)
+		ctrl.writeError(response, &scimService.Error{Status: http.StatusInternalServerError, Detail: "Internal server error"})
+		return
+	}
+
+	if status == http.StatusNoContent {
+		response.Status(status)
+		return
+	}
+	ctrl.write(response, status, body)
+}
+
+func (ctrl *Controller) writeError(response *goyave.Response, err *scimService.Error) {
+	ctrl.write(response, err.Status, &dto.Error{
+		Schemas:  []string{dto.ErrorSchema},
+		Status:   strconv.Itoa(err.Status),
+		ScimType: err.Type,
+		Detail:   err.Detail,
+	})
+}
+
+// write renders the body with the SCIM media type.
+func (ctrl *Controller) write(response *goyave.Response, status int, body any) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/scim+json")
+	response.WriteHeader(status)
+	_, _ = response.Write(raw)
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5"
+)
+
+type Service interface {
+	Search(ctx context.Context, viewer *dto.CourseViewer, request *dto.SearchRequest) ([]*dto.SearchResult, error)
+}
+
+type Controller struct {
+	goyave.Component
+	SearchService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.SearchService = server.Service(service.Search).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	subrouter := router.Subrouter("/search")
+	subrouter.Middleware(middleware.NewUserAuth())
+
+	subrouter.Get("/", ctrl.Search)
+}
+
+// Search searches the courses, sections and text materials the user can see
+// for the "q" query parameter, optionally restricted to one "type".
+func (ctrl *Controller) Search(response *goyave.Response, request *goyave.Request) {
+	viewer, err := middleware.CourseViewer(ctrl.Server(), request)
+	if err != nil {
+		response.Error(err)
+		return
+	}
+
+	query := request.Request().URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	results, err := ctrl.SearchService.Search(request.Context(), viewer, &dto.SearchRequest{
+		Query: query.Get("q"),
+		Type:  query.Get("type"),
+		Limit: limit,
+	})
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	response.JSON(http.StatusOK, results)
+}
@@ -2,13 +2,16 @@ package students
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/dapthehuman/learning-management-system/dto"
 	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	studentService "github.com/dapthehuman/learning-management-system/service/student-service"
 	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/util/typeutil"
@@ -16,15 +19,18 @@ import (
 
 type Service interface {
 	GetByID(ctx context.Context, id uint64) (*dto.User, error)
-	GetAll(ctx context.Context) ([]*dto.User, error)
+	GetAll(ctx context.Context, q *listing.Query) (*listing.Page[*dto.User], error)
 	Update(ctx context.Context, id uint64, updateDTO *dto.UpdateStudentRequest) (*dto.User, error)
 
-	EnrollCourse(ctx context.Context, enrollmentDTO *dto.EnrollStudentRequest) (*dto.Enrollment, error)
-	GetEnrollmentsByStudentID(ctx context.Context, studentID uint64) ([]*dto.Enrollment, error)
+	EnrollCourse(ctx context.Context, enrollmentDTO *dto.EnrollStudentRequest) (*dto.Enrollment, *dto.WaitlistEntry, error)
+	Unenroll(ctx context.Context, studentID uint64, courseID uint64) error
+	GetWaitlist(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.WaitlistEntry], error)
+	Eligibility(ctx context.Context, studentID uint64, courseID uint64) (*dto.Eligibility, error)
+	GetEnrollmentsByStudentID(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.Enrollment], error)
 	TrackProgress(ctx context.Context, progressDTO *dto.TrackProgressRequest) (*dto.ProgressTracking, error)
-	GetProgressByStudentAndCurriculum(ctx context.Context, studentID, curriculumID uint64) ([]*dto.ProgressTracking, error)
+	GetProgressByStudentAndCurriculum(ctx context.Context, studentID, curriculumID uint64, q *listing.Query) (*listing.Page[*dto.ProgressTracking], error)
 
-	GetListAchievements(ctx context.Context, studentID uint64) ([]*dto.Achievement, error)
+	GetListAchievements(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.Achievement], error)
 	CreateAchievement(ctx context.Context, achievementDTO *dto.CreateAchievementRequest) (*dto.Achievement, error)
 }
 
@@ -48,30 +54,35 @@ func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
 	studentRouter := router.Group()
 	studentRouter.Middleware(authMiddleware)
 
+	// Impersonators can read as the user but not act on their behalf: changing
+	// the email alone would be enough to take over the account
+	notImpersonating := middleware.BlockImpersonation()
 	studentSubrouter := studentRouter.Subrouter("/me")
 	studentSubrouter.Get("/", ctrl.ShowCurrentUser)
-	studentSubrouter.Put("/", ctrl.Update)
+	studentSubrouter.Put("/", ctrl.Update).Middleware(notImpersonating)
 
-	studentSubrouter.Post("/enroll", ctrl.EnrollCourse)
-	studentSubrouter.Post("/progress", ctrl.TrackProgressCurrentUser)
+	studentSubrouter.Post("/enroll", ctrl.EnrollCourse).Middleware(notImpersonating)
+	studentSubrouter.Delete("/enrollments/{course_id}", ctrl.Unenroll).Middleware(notImpersonating)
+	studentSubrouter.Get("/waitlist", ctrl.GetWaitlist)
+	studentSubrouter.Get("/eligibility/{course_id}", ctrl.Eligibility)
+	studentSubrouter.Post("/progress", ctrl.TrackProgressCurrentUser).Middleware(notImpersonating)
 	studentSubrouter.Get("/progress/{curriculum_id}", ctrl.GetProgressCurrentUser)
 
 	studentSubrouter.Get("/achievements", ctrl.GetAchievementsByUserID)
-	studentSubrouter.Post("/achievements", ctrl.CreateAchievement)
+	studentSubrouter.Post("/achievements", ctrl.CreateAchievement).Middleware(notImpersonating)
 
 	// Instructor routes
 	instructorSubrouter := studentRouter.Subrouter("/students")
-	instructorOnly := middleware.NewRoleMiddleware("admin", "instructor")
-	instructorSubrouter.Middleware(instructorOnly)
-	instructorSubrouter.Get("/", ctrl.Index)
-	instructorSubrouter.Get("/{studentID}", ctrl.Show)
-	instructorSubrouter.Put("/{studentID}", ctrl.Update)
+	canRead := middleware.RequirePermission("student.read")
+	instructorSubrouter.Get("/", ctrl.Index).Middleware(canRead)
+	instructorSubrouter.Get("/{studentID}", ctrl.Show).Middleware(canRead)
+	instructorSubrouter.Put("/{studentID}", ctrl.Update).Middleware(middleware.RequirePermission("student.update"))
 
-	instructorSubrouter.Get("/{studentID}/enrollments", ctrl.GetEnrollmentsByStudentID)
+	instructorSubrouter.Get("/{studentID}/enrollments", ctrl.GetEnrollmentsByStudentID).Middleware(canRead)
 
 	// Progress tracking
-	instructorSubrouter.Post("/progress", ctrl.TrackProgress)
-	instructorSubrouter.Get("/{studentID}/progress/{curriculumID}", ctrl.GetProgressByStudentAndCurriculum)
+	instructorSubrouter.Post("/progress", ctrl.TrackProgress).Middleware(middleware.RequirePermission("progress.write"))
+	instructorSubrouter.Get("/{studentID}/progress/{curriculumID}", ctrl.GetProgressByStudentAndCurriculum).Middleware(canRead)
 }
 
 func (ctrl *Controller) ShowCurrentUser(response *goyave.Response, request *goyave.Request) {
@@ -87,10 +98,14 @@ func (ctrl *Controller) ShowCurrentUser(response *goyave.Response, request *goya
 }
 
 func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request) {
-	students, err := ctrl.StudentService.GetAll(request.Context())
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
 
+	students, err := ctrl.StudentService.GetAll(request.Context(), q)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 	response.JSON(http.StatusOK, students)
@@ -135,15 +150,86 @@ func (ctrl *Controller) EnrollCourse(response *goyave.Response, request *goyave.
 	userID := uint64(user["user_id"].(float64))
 	enrollmentDTO.UserID = userID
 
-	enrollment, err := ctrl.StudentService.EnrollCourse(request.Context(), enrollmentDTO)
+	enrollment, entry, err := ctrl.StudentService.EnrollCourse(request.Context(), enrollmentDTO)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		var ineligible *studentService.IneligibleError
+		if errors.As(err, &ineligible) {
+			response.JSON(http.StatusForbidden, map[string]any{"error": err.Error(), "unmet": ineligible.Unmet})
+			return
+		}
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// The course is full
+	if entry != nil {
+		response.JSON(http.StatusAccepted, entry)
 		return
 	}
 
 	response.JSON(http.StatusCreated, enrollment)
 }
 
+// Unenroll removes the authenticated user from the course, or from its
+// waitlist.
+func (ctrl *Controller) Unenroll(response *goyave.Response, request *goyave.Request) {
+	courseID, err := strconv.ParseUint(request.RouteParams["course_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	if err := ctrl.StudentService.Unenroll(request.Context(), userID, courseID); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.Status(http.StatusNoContent)
+}
+
+// GetWaitlist lists the waitlists the authenticated user is on.
+func (ctrl *Controller) GetWaitlist(response *goyave.Response, request *goyave.Request) {
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	entries, err := ctrl.StudentService.GetWaitlist(request.Context(), userID, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, entries)
+}
+
+// Eligibility tells the authenticated user whether they can enroll in the
+// course, and which requirements they do not meet otherwise.
+func (ctrl *Controller) Eligibility(response *goyave.Response, request *goyave.Request) {
+	courseID, err := strconv.ParseUint(request.RouteParams["course_id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid course ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	eligibility, err := ctrl.StudentService.Eligibility(request.Context(), userID, courseID)
+	if err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, eligibility)
+}
+
 func (ctrl *Controller) GetEnrollmentsByStudentID(response *goyave.Response, request *goyave.Request) {
 	studentID, err := strconv.ParseUint(request.RouteParams["studentID"], 10, 64)
 	if err != nil {
@@ -151,9 +237,14 @@ func (ctrl *Controller) GetEnrollmentsByStudentID(response *goyave.Response, req
 		return
 	}
 
-	enrollments, err := ctrl.StudentService.GetEnrollmentsByStudentID(request.Context(), studentID)
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	enrollments, err := ctrl.StudentService.GetEnrollmentsByStudentID(request.Context(), studentID, q)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 
@@ -201,9 +292,14 @@ func (ctrl *Controller) GetProgressCurrentUser(response *goyave.Response, reques
 	user := request.Extra["user"].(jwt.MapClaims)
 	userID := uint64(user["user_id"].(float64))
 
-	progress, err := ctrl.StudentService.GetProgressByStudentAndCurriculum(request.Context(), userID, curriculumID)
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	progress, err := ctrl.StudentService.GetProgressByStudentAndCurriculum(request.Context(), userID, curriculumID, q)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 
@@ -223,9 +319,14 @@ func (ctrl *Controller) GetProgressByStudentAndCurriculum(response *goyave.Respo
 		return
 	}
 
-	progress, err := ctrl.StudentService.GetProgressByStudentAndCurriculum(request.Context(), studentID, curriculumID)
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	progress, err := ctrl.StudentService.GetProgressByStudentAndCurriculum(request.Context(), studentID, curriculumID, q)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 
@@ -236,15 +337,31 @@ func (ctrl *Controller) GetAchievementsByUserID(response *goyave.Response, reque
 	user := request.Extra["user"].(jwt.MapClaims)
 	userID := uint64(user["user_id"].(float64))
 
-	achievements, err := ctrl.StudentService.GetListAchievements(request.Context(), userID)
+	q, ok := listQuery(response, request)
+	if !ok {
+		return
+	}
+
+	achievements, err := ctrl.StudentService.GetListAchievements(request.Context(), userID, q)
 	if err != nil {
-		response.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
 		return
 	}
 
 	response.JSON(http.StatusOK, achievements)
 }
 
+// listQuery reads the pagination, sort and filters of a list, writing a 400
+// response if they are malformed.
+func listQuery(response *goyave.Response, request *goyave.Request) (*listing.Query, bool) {
+	q, err := listing.Parse(request.Request().URL.Query())
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	return q, true
+}
+
 func (ctrl *Controller) CreateAchievement(response *goyave.Response, request *goyave.Request) {
 	achievementDTO := typeutil.MustConvert[*dto.CreateAchievementRequest](request.Data)
 
@@ -0,0 +1,161 @@
+package tokens
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/http/middleware"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type Service interface {
+	Create(ctx context.Context, userID uint64, createdBy uint64, createDTO *dto.CreateAPITokenRequest) (*dto.CreateAPITokenResponse, error)
+	ListByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*dto.APIToken], error)
+	ListAll(ctx context.Context, q *listing.Query) (*listing.Page[*dto.APIToken], error)
+	Revoke(ctx context.Context, userID uint64, id uint64) error
+	RevokeAny(ctx context.Context, actorID uint64, id uint64) error
+}
+
+type Controller struct {
+	goyave.Component
+	TokenService Service
+}
+
+func NewController() *Controller {
+	return &Controller{}
+}
+
+func (ctrl *Controller) Init(server *goyave.Server) {
+	ctrl.TokenService = server.Service(service.Token).(Service)
+	ctrl.Component.Init(server)
+}
+
+func (ctrl *Controller) RegisterRoutes(router *goyave.Router) {
+	authMiddleware := middleware.NewUserAuth()
+
+	// Personal access tokens of the current user
+	meRouter := router.Subrouter("/me/tokens")
+	meRouter.Middleware(authMiddleware)
+	meRouter.Get("/", ctrl.Index)
+	meRouter.Post("/", ctrl.Create).Middleware(middleware.BlockImpersonation())
+	meRouter.Delete("/{id}", ctrl.Revoke).Middleware(middleware.BlockImpersonation())
+
+	// Service-account keys and token oversight
+	adminRouter := router.Subrouter("/admin/tokens")
+	adminRouter.Middleware(authMiddleware)
+	adminRouter.Middleware(middleware.RequirePermission("token.manage"))
+	adminRouter.Get("/", ctrl.AdminIndex)
+	adminRouter.Post("/", ctrl.AdminCreate).Middleware(middleware.BlockImpersonation())
+	adminRouter.Delete("/{id}", ctrl.AdminRevoke).Middleware(middleware.BlockImpersonation())
+}
+
+func (ctrl *Controller) Index(response *goyave.Response, request *goyave.Request) {
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	q, err := listing.Parse(request.Request().URL.Query())
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	tokens, err := ctrl.TokenService.ListByUserID(request.Context(), userID, q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, tokens)
+}
+
+func (ctrl *Controller) Create(response *goyave.Response, request *goyave.Request) {
+	createDTO := typeutil.MustConvert[*dto.CreateAPITokenRequest](request.Data)
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	token, err := ctrl.TokenService.Create(request.Context(), userID, userID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusCreated, token)
+}
+
+func (ctrl *Controller) Revoke(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid token ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	userID := uint64(user["user_id"].(float64))
+
+	if err := ctrl.TokenService.Revoke(request.Context(), userID, id); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Token not found"})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Token revoked successfully"})
+}
+
+func (ctrl *Controller) AdminIndex(response *goyave.Response, request *goyave.Request) {
+	q, err := listing.Parse(request.Request().URL.Query())
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	tokens, err := ctrl.TokenService.ListAll(request.Context(), q)
+	if err != nil {
+		response.JSON(listing.Status(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusOK, tokens)
+}
+
+func (ctrl *Controller) AdminCreate(response *goyave.Response, request *goyave.Request) {
+	createDTO := typeutil.MustConvert[*dto.CreateAPITokenRequest](request.Data)
+	if createDTO.UserID == 0 {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "user_id is required"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(user["user_id"].(float64))
+
+	token, err := ctrl.TokenService.Create(request.Context(), createDTO.UserID, adminID, createDTO)
+	if err != nil {
+		response.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	response.JSON(http.StatusCreated, token)
+}
+
+func (ctrl *Controller) AdminRevoke(response *goyave.Response, request *goyave.Request) {
+	id, err := strconv.ParseUint(request.RouteParams["id"], 10, 64)
+	if err != nil {
+		response.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid token ID"})
+		return
+	}
+
+	user := request.Extra["user"].(jwt.MapClaims)
+	adminID := uint64(user["user_id"].(float64))
+
+	if err := ctrl.TokenService.RevokeAny(request.Context(), adminID, id); err != nil {
+		response.JSON(http.StatusNotFound, map[string]string{"error": "Token not found"})
+		return
+	}
+
+	response.JSON(http.StatusOK, map[string]string{"message": "Token revoked successfully"})
+}
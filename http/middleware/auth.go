@@ -1,13 +1,34 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
 	"github.com/golang-jwt/jwt"
 	"goyave.dev/goyave/v5"
 )
 
+// TokenService authenticates personal access tokens and service-account keys.
+type TokenService interface {
+	Authenticate(ctx context.Context, plain string) (*dto.User, *dto.APIToken, error)
+}
+
+// AccountService reports the current state of an account.
+type AccountService interface {
+	AccountStatus(ctx context.Context, id uint64) (*dto.AccountStatus, error)
+}
+
+// ImpersonationService tells whether an impersonation session is still active.
+type ImpersonationService interface {
+	IsActive(ctx context.Context, session string) (bool, error)
+}
+
 type UserAuth struct {
 	goyave.Component
 }
@@ -18,34 +39,139 @@ func NewUserAuth() *UserAuth {
 
 func (m *UserAuth) Handle(next goyave.Handler) goyave.Handler {
 	return func(response *goyave.Response, request *goyave.Request) {
-		authHeader := request.Header().Get("Authorization")
-		if authHeader == "" {
+		token := bearerToken(request)
+		if token == "" {
 			response.Status(401)
 			return
 		}
 
-		bearerToken := strings.Split(authHeader, " ")
-		if len(bearerToken) != 2 {
-			response.Status(401)
+		claims, ok := parseJWT(token)
+		if !ok {
+			m.handleAPIToken(next, response, request, token)
 			return
 		}
 
-		token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("APP_SECRET")), nil
-		})
+		m.authorize(next, response, request, claims)
+	}
+}
 
-		if err != nil || !token.Valid {
+// bearerToken returns the token of the Authorization header, or an empty
+// string if there is none.
+func bearerToken(request *goyave.Request) string {
+	parts := strings.Split(request.Header().Get("Authorization"), " ")
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// parseJWT returns the claims of a JWT signed by the application.
+func parseJWT(raw string) (jwt.MapClaims, bool) {
+	if raw == "" {
+		return nil, false
+	}
+
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("APP_SECRET")), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	return claims, ok
+}
+
+// handleAPIToken authenticates the request with an API token. The claims
+// mirror the ones of a JWT so handlers don't need to tell them apart.
+func (m *UserAuth) handleAPIToken(next goyave.Handler, response *goyave.Response, request *goyave.Request, plain string) {
+	tokenService, ok := m.LookupService(service.Token)
+	if !ok {
+		response.Status(401)
+		return
+	}
+
+	user, apiToken, err := tokenService.(TokenService).Authenticate(request.Context(), plain)
+	if err != nil {
+		response.Status(401)
+		return
+	}
+
+	if !allowsMethod(apiToken.Scopes, request.Request().Method) {
+		response.Status(403)
+		return
+	}
+
+	m.authorize(next, response, request, jwt.MapClaims{
+		"user_id":  float64(user.ID),
+		"email":    user.Email,
+		"role":     user.Role,
+		"org_id":   float64(tenant.ID(request.Context())),
+		"token_id": float64(apiToken.ID),
+		"scopes":   apiToken.Scopes,
+	})
+}
+
+// authorize rejects credentials of another organization, suspended
+// accounts, accounts awaiting a password reset and ended impersonation
+// sessions, then refreshes the role claim so role changes apply immediately.
+func (m *UserAuth) authorize(next goyave.Handler, response *goyave.Response, request *goyave.Request, claims jwt.MapClaims) {
+	userID, ok := claims["user_id"].(float64)
+	if !ok || !sameOrganization(claims, tenant.ID(request.Context())) {
+		response.Status(401)
+		return
+	}
+
+	status, err := m.Server().Service(service.User).(AccountService).AccountStatus(request.Context(), uint64(userID))
+	if err != nil {
+		response.Status(401)
+		return
+	}
+
+	if status.Suspended {
+		response.JSON(http.StatusForbidden, map[string]string{"error": "Account suspended"})
+		return
+	}
+	if status.PasswordResetRequired {
+		response.JSON(http.StatusForbidden, map[string]string{"error": "Password reset required"})
+		return
+	}
+
+	// Impersonation tokens are flagged on every response they are used for
+	if act, ok := claims["act"].(map[string]any); ok {
+		impersonation, ok := m.LookupService(service.Impersonation)
+		if !ok {
 			response.Status(401)
 			return
 		}
 
-		// Convert claims to the correct type
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			request.Extra["user"] = claims
-			next(response, request)
-		} else {
+		session, _ := claims["jti"].(string)
+		active, err := impersonation.(ImpersonationService).IsActive(request.Context(), session)
+		if err != nil || !active {
 			response.Status(401)
 			return
 		}
+
+		response.Header().Set("X-Impersonated-By", fmt.Sprint(act["sub"]))
+	}
+
+	claims["role"] = status.Role
+	request.Extra["user"] = claims
+	next(response, request)
+}
+
+// allowsMethod reports whether a token with the given scopes may perform a
+// request with the given method. Read-only tokens can only use safe methods.
+func allowsMethod(scopes []string, method string) bool {
+	for _, scope := range scopes {
+		if scope == "write" {
+			return true
+		}
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
 	}
+	return false
 }
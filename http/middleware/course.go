@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+)
+
+// CourseStaffService resolves the course-scoped role of a user.
+type CourseStaffService interface {
+	HasCourseRole(ctx context.Context, courseID uint64, userID uint64, roles ...string) (bool, error)
+	CourseIDByCurriculum(ctx context.Context, curriculumID uint64) (uint64, error)
+}
+
+// CourseRoleMiddleware only lets through members of the course staff having
+// one of the given roles, and users granted "course.manage_any". It must run
+// after UserAuth.
+type CourseRoleMiddleware struct {
+	goyave.Component
+	Roles []string
+
+	courseID func(ctx context.Context, staff CourseStaffService, request *goyave.Request) (uint64, error)
+}
+
+// RequireCourseRole checks the staff of the course identified by the "id"
+// route parameter.
+func RequireCourseRole(roles ...string) *CourseRoleMiddleware {
+	return &CourseRoleMiddleware{
+		Roles: roles,
+		courseID: func(_ context.Context, _ CourseStaffService, request *goyave.Request) (uint64, error) {
+			return strconv.ParseUint(request.RouteParams["id"], 10, 64)
+		},
+	}
+}
+
+// RequireCurriculumRole checks the staff of the course owning the curriculum
+// identified by the "curriculum_id" route parameter.
+func RequireCurriculumRole(roles ...string) *CourseRoleMiddleware {
+	return &CourseRoleMiddleware{
+		Roles: roles,
+		courseID: func(ctx context.Context, staff CourseStaffService, request *goyave.Request) (uint64, error) {
+			curriculumID, err := strconv.ParseUint(request.RouteParams["curriculum_id"], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return staff.CourseIDByCurriculum(ctx, curriculumID)
+		},
+	}
+}
+
+// RequireBodyCourseRole checks the staff of the course identified by the
+// "course_id" field of the request body.
+func RequireBodyCourseRole(roles ...string) *CourseRoleMiddleware {
+	return &CourseRoleMiddleware{
+		Roles: roles,
+		courseID: func(_ context.Context, _ CourseStaffService, request *goyave.Request) (uint64, error) {
+			data, _ := request.Data.(map[string]any)
+			switch courseID := data["course_id"].(type) {
+			case float64:
+				return uint64(courseID), nil
+			case string:
+				return strconv.ParseUint(courseID, 10, 64)
+			}
+			return 0, fmt.Errorf("missing course_id")
+		},
+	}
+}
+
+func (cm *CourseRoleMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		claims, ok := request.Extra["user"].(jwt.MapClaims)
+		if !ok {
+			response.Status(401)
+			return
+		}
+
+		role, _ := claims["role"].(string)
+		manageAny, err := cm.Server().Service(service.Role).(PermissionService).HasPermissions(request.Context(), role, "course.manage_any")
+		if err != nil {
+			response.Error(err)
+			return
+		}
+		if manageAny {
+			next(response, request)
+			return
+		}
+
+		staff := cm.Server().Service(service.Course).(CourseStaffService)
+		courseID, err := cm.courseID(request.Context(), staff, request)
+		if err != nil {
+			response.Status(404)
+			return
+		}
+
+		userID := uint64(claims["user_id"].(float64))
+		allowed, err := staff.HasCourseRole(request.Context(), courseID, userID, cm.Roles...)
+		if err != nil {
+			response.Error(err)
+			return
+		}
+
+		if !allowed {
+			response.Status(403)
+			return
+		}
+
+		next(response, request)
+	}
+}
+
+// CourseViewer returns the authenticated user along with the permissions that
+// widen which courses and which content they can see. It must be called
+// after UserAuth.
+func CourseViewer(server *goyave.Server, request *goyave.Request) (*dto.CourseViewer, error) {
+	claims := request.Extra["user"].(jwt.MapClaims)
+	role, _ := claims["role"].(string)
+	permissions := server.Service(service.Role).(PermissionService)
+
+	manageAny, err := permissions.HasPermissions(request.Context(), role, "course.manage_any")
+	if err != nil {
+		return nil, err
+	}
+	canReview, err := permissions.HasPermissions(request.Context(), role, "course.review")
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CourseViewer{
+		UserID:    uint64(claims["user_id"].(float64)),
+		ManageAny: manageAny,
+		CanReview: canReview,
+	}, nil
+}
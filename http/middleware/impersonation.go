@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+)
+
+// BlockImpersonationMiddleware rejects requests made with an impersonation
+// token. It guards destructive or persistent actions, such as changing a
+// password or minting API tokens. It must run after UserAuth.
+type BlockImpersonationMiddleware struct {
+	goyave.Component
+}
+
+func BlockImpersonation() *BlockImpersonationMiddleware {
+	return &BlockImpersonationMiddleware{}
+}
+
+func (bm *BlockImpersonationMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		claims, ok := request.Extra["user"].(jwt.MapClaims)
+		if !ok {
+			response.Status(401)
+			return
+		}
+
+		if _, impersonating := claims["act"]; impersonating {
+			response.JSON(http.StatusForbidden, map[string]string{"error": "This action is not allowed while impersonating a user"})
+			return
+		}
+
+		next(response, request)
+	}
+}
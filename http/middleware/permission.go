@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+)
+
+// PermissionService resolves the permissions granted to a role.
+type PermissionService interface {
+	HasPermissions(ctx context.Context, role string, permissions ...string) (bool, error)
+}
+
+// PermissionMiddleware only lets through users whose role is granted all of
+// the required permissions. It must run after UserAuth.
+type PermissionMiddleware struct {
+	goyave.Component
+	Permissions []string
+}
+
+func RequirePermission(permissions ...string) *PermissionMiddleware {
+	return &PermissionMiddleware{Permissions: permissions}
+}
+
+func (pm *PermissionMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		claims, ok := request.Extra["user"].(jwt.MapClaims)
+		if !ok {
+			response.Status(401)
+			return
+		}
+
+		role, ok := claims["role"].(string)
+		if !ok {
+			response.Status(403)
+			return
+		}
+
+		allowed, err := pm.Server().Service(service.Role).(PermissionService).HasPermissions(request.Context(), role, pm.Permissions...)
+		if err != nil {
+			response.Error(err)
+			return
+		}
+
+		if !allowed {
+			response.Status(403)
+			return
+		}
+
+		next(response, request)
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/organization"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/golang-jwt/jwt"
+	"goyave.dev/goyave/v5"
+)
+
+// OrganizationService resolves organizations by slug.
+type OrganizationService interface {
+	Resolve(ctx context.Context, slug string) (*dto.Organization, error)
+}
+
+// TenantMiddleware scopes the request to an organization, resolved from the
+// first of:
+//   - the X-Organization header, holding the slug of the organization;
+//   - the subdomain of TENANT_DOMAIN the request was sent to;
+//   - the "org_id" claim of the bearer token;
+//   - the DEFAULT_ORGANIZATION slug.
+//
+// Requests that cannot be resolved are rejected. UserAuth then makes sure
+// the credentials belong to the resolved organization.
+type TenantMiddleware struct {
+	goyave.Component
+}
+
+func Tenant() *TenantMiddleware {
+	return &TenantMiddleware{}
+}
+
+func (tm *TenantMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		organizationID, ok := tm.resolve(request)
+		if !ok {
+			response.JSON(http.StatusNotFound, map[string]string{"error": "Unknown organization"})
+			return
+		}
+
+		request.WithContext(tenant.WithOrganization(request.Context(), organizationID))
+		next(response, request)
+	}
+}
+
+func (tm *TenantMiddleware) resolve(request *goyave.Request) (uint64, bool) {
+	slug := request.Header().Get("X-Organization")
+	if slug == "" {
+		slug = subdomain(request.Request().Host, os.Getenv("TENANT_DOMAIN"))
+	}
+	if slug == "" {
+		if claims, ok := parseJWT(bearerToken(request)); ok {
+			if organizationID, ok := claims["org_id"].(float64); ok {
+				return uint64(organizationID), true
+			}
+		}
+		slug = os.Getenv("DEFAULT_ORGANIZATION")
+	}
+	if slug == "" {
+		return 0, false
+	}
+
+	organization, err := tm.Server().Service(service.Organization).(OrganizationService).Resolve(request.Context(), slug)
+	if err != nil {
+		return 0, false
+	}
+	return organization.ID, true
+}
+
+// RouteTenantMiddleware scopes the request to the organization whose slug
+// is the given route parameter, whatever the request was resolved to before.
+// Identity providers post to the SSO endpoints directly, without the header
+// or token of the organization, so its slug is part of their URLs.
+type RouteTenantMiddleware struct {
+	goyave.Component
+	param string
+}
+
+func RouteTenant(param string) *RouteTenantMiddleware {
+	return &RouteTenantMiddleware{param: param}
+}
+
+func (rm *RouteTenantMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		organization, err := rm.Server().Service(service.Organization).(OrganizationService).Resolve(request.Context(), request.RouteParams[rm.param])
+		if err != nil {
+			response.JSON(http.StatusNotFound, map[string]string{"error": "Unknown organization"})
+			return
+		}
+
+		request.WithContext(tenant.WithOrganization(request.Context(), organization.ID))
+		next(response, request)
+	}
+}
+
+// subdomain returns the label preceding domain in host, or an empty string
+// if host is not a direct subdomain of domain.
+func subdomain(host string, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	label, found := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(domain))
+	if !found || label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
+
+// PlatformMiddleware only lets through requests scoped to the platform
+// organization. It guards settings shared by every organization, such as
+// roles, which the administrators of a school must not change for others.
+type PlatformMiddleware struct {
+	goyave.Component
+}
+
+func RequirePlatform() *PlatformMiddleware {
+	return &PlatformMiddleware{}
+}
+
+func (pm *PlatformMiddleware) Handle(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, request *goyave.Request) {
+		if tenant.ID(request.Context()) != tenant.DefaultOrganizationID {
+			response.JSON(http.StatusForbidden, map[string]string{"error": "Only available to the platform organization"})
+			return
+		}
+
+		next(response, request)
+	}
+}
+
+// sameOrganization reports whether the claims were issued by the given
+// organization. Tokens issued before organizations existed carry no claim
+// and belong to the default organization.
+func sameOrganization(claims jwt.MapClaims, organizationID uint64) bool {
+	claim, ok := claims["org_id"].(float64)
+	if !ok {
+		return organizationID == tenant.DefaultOrganizationID
+	}
+	return uint64(claim) == organizationID
+}
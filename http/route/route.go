@@ -1,11 +1,21 @@
 package route
 
 import (
+	adminController "github.com/dapthehuman/learning-management-system/http/controllers/admin-controller"
 	assessController "github.com/dapthehuman/learning-management-system/http/controllers/assessment-controller"
 	authController "github.com/dapthehuman/learning-management-system/http/controllers/authentication-controller"
+	categoryController "github.com/dapthehuman/learning-management-system/http/controllers/categories-controller"
 	courseController "github.com/dapthehuman/learning-management-system/http/controllers/courses-controller"
 	materialController "github.com/dapthehuman/learning-management-system/http/controllers/material-controller"
+	organizationController "github.com/dapthehuman/learning-management-system/http/controllers/organization-controller"
+	privacyController "github.com/dapthehuman/learning-management-system/http/controllers/privacy-controller"
+	roleController "github.com/dapthehuman/learning-management-system/http/controllers/roles-controller"
+	scimController "github.com/dapthehuman/learning-management-system/http/controllers/scim-controller"
+	searchController "github.com/dapthehuman/learning-management-system/http/controllers/search-controller"
 	studentController "github.com/dapthehuman/learning-management-system/http/controllers/students-controller"
+	tokenController "github.com/dapthehuman/learning-management-system/http/controllers/tokens-controller"
+
+	"github.com/dapthehuman/learning-management-system/http/middleware"
 
 	"goyave.dev/goyave/v5"
 	"goyave.dev/goyave/v5/cors"
@@ -23,13 +33,24 @@ import (
 func Register(server *goyave.Server, router *goyave.Router) {
 	router.CORS(cors.Default())
 	router.GlobalMiddleware(&parse.Middleware{})
+	router.GlobalMiddleware(middleware.Tenant())
 
 	// TODO register routes
 	router.Controller(&authController.Controller{})
 	router.Controller(&courseController.Controller{})
 	router.Controller(&materialController.Controller{})
+	router.Controller(&categoryController.Controller{})
+	router.Controller(&searchController.Controller{})
 
+	// Registered before the student and admin routes so /me/tokens,
+	// /me/data-export, /admin/erasure-requests... are not shadowed by /me and /admin
+	router.Controller(&tokenController.Controller{})
+	router.Controller(&privacyController.Controller{})
+	router.Controller(&organizationController.Controller{})
 	router.Controller(&studentController.Controller{})
 	router.Controller(&assessController.Controller{})
+	router.Controller(&roleController.Controller{})
+	router.Controller(&adminController.Controller{})
+	router.Controller(&scimController.Controller{})
 	router.Controller(&authController.Controller{})
 }
@@ -0,0 +1,406 @@
+package listing
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of the fields, the PostgreSQL types filter and cursor values are
+// cast to.
+type Type string
+
+const (
+	Int   Type = "bigint"
+	Float Type = "double precision"
+	Text  Type = "text"
+	Bool  Type = "boolean"
+	Time  Type = "timestamp"
+)
+
+// Field is a field a list can be sorted or filtered by.
+type Field struct {
+	Column    string // Column of the rows of the list
+	Type      Type
+	Sortable  bool     // Sortable fields must not be NULL
+	Operators []string // Filter operators allowed on the field, none if it cannot be filtered
+}
+
+// Schema describes the fields of a list.
+type Schema struct {
+	Fields map[string]Field
+	Sort   []Sort // Default order
+	Key    string // Unique column ending every order so pages never overlap, "id" if empty
+}
+
+// Scanner reads a row of a list.
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
+// Fetch returns the page of the rows of the base query selected by q. The
+// base query is wrapped, so the fields of the schema refer to the columns
+// it returns, and scan reads them in the same order.
+func Fetch[T any](db *gorm.DB, schema *Schema, q *Query, base string, args []any, scan func(Scanner) (T, error)) (*Page[T], error) {
+	sorts, err := schema.sorts(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+	where, whereArgs, err := schema.where(q.Filters)
+	if err != nil {
+		return nil, err
+	}
+	page, perPage := q.normalize()
+
+	from := `FROM (` + base + `) AS list`
+	args = append(slices.Clone(args), whereArgs...)
+	result := &Page[T]{Data: make([]T, 0, perPage), PerPage: perPage}
+
+	if q.Cursor == nil {
+		var total int64
+		if err := db.Raw(`SELECT COUNT(*) `+from+where, args...).Row().Scan(&total); err != nil {
+			return nil, err
+		}
+		result.Total = &total
+		result.Page = page
+		result.HasMore = int64(page*perPage) < total
+
+		query := `SELECT list.* ` + from + where + schema.orderBy(sorts) + ` LIMIT ? OFFSET ?`
+		err := each(db.Raw(query, append(args, perPage, (page-1)*perPage)...), nil, func(row Scanner) error {
+			item, err := scan(row)
+			result.Data = append(result.Data, item)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	after, err := decodeCursor(*q.Cursor, signature(sorts))
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		condition, conditionArgs := schema.after(sorts, after)
+		if where == "" {
+			where = ` WHERE ` + condition
+		} else {
+			where += ` AND ` + condition
+		}
+		args = append(args, conditionArgs...)
+	}
+
+	// The sort values of each row are selected after its columns, to build
+	// the cursor of the next page
+	selected := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		selected = append(selected, `list.`+schema.column(sort.Field)+`::text`)
+	}
+	query := `SELECT list.*, ` + strings.Join(selected, ", ") + ` ` + from + where + schema.orderBy(sorts) + ` LIMIT ?`
+
+	var last []string
+	values := make([]sql.NullString, len(sorts))
+	err = each(db.Raw(query, append(args, perPage+1)...), values, func(row Scanner) error {
+		if len(result.Data) == perPage {
+			result.HasMore = true
+			return nil
+		}
+		item, err := scan(row)
+		result.Data = append(result.Data, item)
+		last = last[:0]
+		for _, value := range values {
+			last = append(last, value.String)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.HasMore {
+		result.NextCursor = encodeCursor(signature(sorts), last)
+	}
+	return result, nil
+}
+
+// Slice returns the page of items selected by q, for lists built in memory.
+// They keep their order and only support page-based pagination.
+func Slice[T any](items []T, q *Query) (*Page[T], error) {
+	if q.Cursor != nil || len(q.Sort) > 0 || len(q.Filters) > 0 {
+		return nil, invalid("this list only supports page-based pagination")
+	}
+	page, perPage := q.normalize()
+	total := int64(len(items))
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	return &Page[T]{
+		Data:    append(make([]T, 0, end-start), items[start:end]...),
+		Total:   &total,
+		Page:    page,
+		PerPage: perPage,
+		HasMore: end < len(items),
+	}, nil
+}
+
+// each runs the query and calls f for each row. The extra destinations are
+// scanned after the columns the row scanner is given.
+func each(db *gorm.DB, extra []sql.NullString, f func(row Scanner) error) error {
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	row := &extraScanner{rows: rows, extra: make([]any, 0, len(extra))}
+	for i := range extra {
+		row.extra = append(row.extra, &extra[i])
+	}
+	for rows.Next() {
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type extraScanner struct {
+	rows  *sql.Rows
+	extra []any
+}
+
+func (s *extraScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.extra...)...)
+}
+
+// sorts returns the order of the query, ending with the key of the schema.
+func (schema *Schema) sorts(requested []Sort) ([]Sort, error) {
+	if len(requested) == 0 {
+		requested = schema.Sort
+	}
+
+	sorts := make([]Sort, 0, len(requested)+1)
+	for _, sort := range requested {
+		field, ok := schema.Fields[sort.Field]
+		if !ok || !field.Sortable {
+			return nil, invalid("cannot sort by %q, sortable fields are: %s", sort.Field, strings.Join(schema.names(true), ", "))
+		}
+		if slices.ContainsFunc(sorts, func(s Sort) bool { return s.Field == sort.Field }) {
+			return nil, invalid("cannot sort by %q twice", sort.Field)
+		}
+		sorts = append(sorts, sort)
+	}
+
+	key := schema.key()
+	if !slices.ContainsFunc(sorts, func(s Sort) bool { return schema.column(s.Field) == key }) {
+		desc := len(sorts) > 0 && sorts[len(sorts)-1].Desc
+		sorts = append(sorts, Sort{Field: key, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// where returns the conditions of the filters.
+func (schema *Schema) where(filters []Filter) (string, []any, error) {
+	conditions := make([]string, 0, len(filters))
+	args := make([]any, 0, len(filters))
+	for _, filter := range filters {
+		field, ok := schema.Fields[filter.Field]
+		if !ok || len(field.Operators) == 0 {
+			return "", nil, invalid("cannot filter by %q, filterable fields are: %s", filter.Field, strings.Join(schema.names(false), ", "))
+		}
+		if !slices.Contains(field.Operators, filter.Operator) {
+			return "", nil, invalid("%q cannot be filtered with %q, use one of: %s", filter.Field, filter.Operator, strings.Join(field.Operators, ", "))
+		}
+
+		column := `list.` + field.Column
+		cast := `CAST(? AS ` + string(field.Type) + `)`
+		switch filter.Operator {
+		case In:
+			values := strings.Split(filter.Value, ",")
+			if len(values) > maxInValues {
+				return "", nil, invalid("%q cannot be filtered by more than %d values", filter.Field, maxInValues)
+			}
+			for _, value := range values {
+				if err := field.Type.check(filter.Field, value); err != nil {
+					return "", nil, err
+				}
+				args = append(args, value)
+			}
+			conditions = append(conditions, column+` IN (`+strings.TrimSuffix(strings.Repeat(cast+`, `, len(values)), `, `)+`)`)
+		case Contains:
+			args = append(args, "%"+escapeLike(filter.Value)+"%")
+			conditions = append(conditions, `CAST(`+column+` AS text) ILIKE ?`)
+		default:
+			if err := field.Type.check(filter.Field, filter.Value); err != nil {
+				return "", nil, err
+			}
+			args = append(args, filter.Value)
+			conditions = append(conditions, column+` `+operators[filter.Operator]+` `+cast)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND "), args, nil
+}
+
+var operators = map[string]string{Eq: "=", Ne: "<>", Gt: ">", Gte: ">=", Lt: "<", Lte: "<="}
+
+// after returns the condition selecting the rows coming after the given sort
+// values: (a > x) OR (a = x AND b < y) OR ...
+func (schema *Schema) after(sorts []Sort, values []string) (string, []any) {
+	alternatives := make([]string, 0, len(sorts))
+	args := make([]any, 0, len(sorts)*(len(sorts)+1)/2)
+	for i, sort := range sorts {
+		terms := make([]string, 0, i+1)
+		for j, previous := range sorts[:i] {
+			terms = append(terms, schema.compare(previous.Field, "="))
+			args = append(args, values[j])
+		}
+		operator := ">"
+		if sort.Desc {
+			operator = "<"
+		}
+		terms = append(terms, schema.compare(sort.Field, operator))
+		args = append(args, values[i])
+		alternatives = append(alternatives, `(`+strings.Join(terms, " AND ")+`)`)
+	}
+	return `(` + strings.Join(alternatives, " OR ") + `)`, args
+}
+
+func (schema *Schema) compare(name string, operator string) string {
+	field := schema.field(name)
+	return `list.` + field.Column + ` ` + operator + ` CAST(? AS ` + string(field.Type) + `)`
+}
+
+func (schema *Schema) orderBy(sorts []Sort) string {
+	terms := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		term := `list.` + schema.column(sort.Field)
+		if sort.Desc {
+			term += ` DESC`
+		}
+		terms = append(terms, term)
+	}
+	return ` ORDER BY ` + strings.Join(terms, ", ")
+}
+
+// field returns the field of the given name, the key being a field of every
+// schema.
+func (schema *Schema) field(name string) Field {
+	if field, ok := schema.Fields[name]; ok {
+		return field
+	}
+	return Field{Column: name, Type: Int}
+}
+
+func (schema *Schema) column(name string) string {
+	return schema.field(name).Column
+}
+
+func (schema *Schema) key() string {
+	if schema.Key == "" {
+		return "id"
+	}
+	return schema.Key
+}
+
+// names returns the sortable or filterable fields, for error messages.
+func (schema *Schema) names(sortable bool) []string {
+	names := make([]string, 0, len(schema.Fields))
+	for name, field := range schema.Fields {
+		if (sortable && field.Sortable) || (!sortable && len(field.Operators) > 0) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// check returns an error if the value cannot be cast to the type.
+func (t Type) check(name string, value string) error {
+	var err error
+	switch t {
+	case Int:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case Float:
+		_, err = strconv.ParseFloat(value, 64)
+	case Bool:
+		_, err = strconv.ParseBool(value)
+	case Time:
+		if _, err = time.Parse(time.RFC3339, value); err != nil {
+			_, err = time.Parse(time.DateOnly, value)
+		}
+	}
+	if err != nil {
+		return invalid("%q must be a %s", name, t.name())
+	}
+	return nil
+}
+
+func (t Type) name() string {
+	switch t {
+	case Int, Float:
+		return "number"
+	case Bool:
+		return "boolean"
+	case Time:
+		return "date such as 2024-01-31 or 2024-01-31T12:00:00Z"
+	}
+	return "string"
+}
+
+// cursor is the position of a row in a list. Its signature identifies the
+// order it is valid for.
+type cursor struct {
+	Signature string   `json:"s"`
+	Values    []string `json:"v"`
+}
+
+func signature(sorts []Sort) string {
+	var b strings.Builder
+	for _, sort := range sorts {
+		if sort.Desc {
+			b.WriteString("-")
+		}
+		b.WriteString(sort.Field)
+		b.WriteString(",")
+	}
+	return b.String()
+}
+
+func encodeCursor(signature string, values []string) string {
+	data, _ := json.Marshal(cursor{Signature: signature, Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort values of the cursor, nil for the first page.
+func decodeCursor(raw string, signature string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return nil, invalid("malformed cursor")
+	}
+	if c.Signature != signature || len(c.Values) != strings.Count(signature, ",") {
+		return nil, invalid("the cursor was made for another sort order")
+	}
+	return c.Values, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
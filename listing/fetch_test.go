@@ -0,0 +1,128 @@
+package listing
+
+import (
+	"slices"
+	"testing"
+)
+
+var schema = &Schema{
+	Fields: map[string]Field{
+		"id":         {Column: "id", Type: Int, Sortable: true, Operators: []string{Eq}},
+		"title":      {Column: "title", Type: Text, Sortable: true, Operators: []string{Eq, Contains}},
+		"created_at": {Column: "created_at", Type: Time, Sortable: true, Operators: []string{Gte, Lte}},
+		"price":      {Column: "price", Type: Float, Operators: []string{Gt, Lt}},
+		"role":       {Column: "role", Type: Text, Operators: []string{In}},
+		"notes":      {Column: "notes", Type: Text},
+	},
+	Sort: []Sort{{Field: "created_at", Desc: true}},
+}
+
+func TestSorts(t *testing.T) {
+	sorts, err := schema.sorts(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key ends every order, in the direction of the last field
+	if !slices.Equal(sorts, []Sort{{Field: "created_at", Desc: true}, {Field: "id", Desc: true}}) {
+		t.Errorf("unexpected default order %v", sorts)
+	}
+
+	sorts, err = schema.sorts([]Sort{{Field: "title"}, {Field: "id", Desc: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sorts, []Sort{{Field: "title"}, {Field: "id", Desc: true}}) {
+		t.Errorf("the key was added twice: %v", sorts)
+	}
+	if orderBy := schema.orderBy(sorts); orderBy != ` ORDER BY list.title, list.id DESC` {
+		t.Errorf("unexpected order %s", orderBy)
+	}
+
+	for _, sort := range [][]Sort{{{Field: "price"}}, {{Field: "password"}}, {{Field: "title"}, {Field: "title", Desc: true}}} {
+		if _, err := schema.sorts(sort); !IsInvalid(err) {
+			t.Errorf("sorted by %v", sort)
+		}
+	}
+}
+
+func TestWhere(t *testing.T) {
+	where, args, err := schema.where([]Filter{
+		{Field: "title", Operator: Contains, Value: "100%_go"},
+		{Field: "created_at", Operator: Gte, Value: "2024-01-01"},
+		{Field: "role", Operator: In, Value: "admin,instructor"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ` WHERE CAST(list.title AS text) ILIKE ?` +
+		` AND list.created_at >= CAST(? AS timestamp)` +
+		` AND list.role IN (CAST(? AS text), CAST(? AS text))`
+	if where != expected {
+		t.Errorf("expected %s, got %s", expected, where)
+	}
+	if !slices.Equal(args, []any{`%100\%\_go%`, "2024-01-01", "admin", "instructor"}) {
+		t.Errorf("unexpected arguments %v", args)
+	}
+
+	if where, args, err := schema.where(nil); where != "" || len(args) != 0 || err != nil {
+		t.Errorf("expected no condition, got %q, %v, %v", where, args, err)
+	}
+}
+
+func TestWhereRejects(t *testing.T) {
+	cases := map[string]Filter{
+		"unknown field":        {Field: "password", Operator: Eq, Value: "x"},
+		"not filterable":       {Field: "notes", Operator: Eq, Value: "x"},
+		"operator not allowed": {Field: "title", Operator: Gt, Value: "x"},
+		"unknown operator":     {Field: "title", Operator: "like", Value: "x"},
+		"invalid time":         {Field: "created_at", Operator: Gte, Value: "yesterday"},
+		"invalid number":       {Field: "price", Operator: Gt, Value: "1; DROP TABLE users"},
+	}
+	for name, filter := range cases {
+		t.Run(name, func(t *testing.T) {
+			if where, _, err := schema.where([]Filter{filter}); !IsInvalid(err) {
+				t.Errorf("filtered with %s", where)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	sorts := []Sort{{Field: "title"}, {Field: "id", Desc: true}}
+	condition, args := schema.after(sorts, []string{"Go", "42"})
+
+	expected := `((list.title > CAST(? AS text)) OR (list.title = CAST(? AS text) AND list.id < CAST(? AS bigint)))`
+	if condition != expected {
+		t.Errorf("expected %s, got %s", expected, condition)
+	}
+	if !slices.Equal(args, []any{"Go", "Go", "42"}) {
+		t.Errorf("unexpected arguments %v", args)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	sorts := []Sort{{Field: "title"}, {Field: "id"}}
+	raw := encodeCursor(signature(sorts), []string{"Go", "42"})
+
+	values, err := decodeCursor(raw, signature(sorts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(values, []string{"Go", "42"}) {
+		t.Errorf("decoded %v", values)
+	}
+
+	if values, err := decodeCursor("", signature(sorts)); values != nil || err != nil {
+		t.Errorf("expected the first page, got %v, %v", values, err)
+	}
+	if _, err := decodeCursor("not a cursor", signature(sorts)); !IsInvalid(err) {
+		t.Errorf("accepted a malformed cursor: %v", err)
+	}
+	if _, err := decodeCursor(raw, signature([]Sort{{Field: "title", Desc: true}, {Field: "id"}})); !IsInvalid(err) {
+		t.Errorf("accepted a cursor of another order: %v", err)
+	}
+	if _, err := decodeCursor(encodeCursor(signature(sorts), []string{"Go"}), signature(sorts)); !IsInvalid(err) {
+		t.Errorf("accepted a cursor missing values: %v", err)
+	}
+}
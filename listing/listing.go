@@ -0,0 +1,229 @@
+// Package listing is the shared layer of the list endpoints: pagination,
+// sorting and filtering.
+//
+// A Query is read from the URL parameters of a request:
+//
+//	page, per_page                 page-based pagination, the default
+//	cursor                         cursor-based pagination, "cursor=" for the first page
+//	sort=-created_at,title         sort fields, "-" for descending order
+//	filter[status]=published       equality filter
+//	filter[created_at][gte]=2024-01-01
+//	filter[role][in]=admin,instructor
+//
+// Repositories describe the fields a list can be sorted and filtered by in a
+// Schema, which rejects the others, and fetch pages with Fetch.
+package listing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Filter operators.
+const (
+	Eq       = "eq"
+	Ne       = "ne"
+	Gt       = "gt"
+	Gte      = "gte"
+	Lt       = "lt"
+	Lte      = "lte"
+	In       = "in"       // Comma-separated values
+	Contains = "contains" // Case-insensitive substring
+)
+
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+	maxFilters     = 20
+	maxInValues    = 100
+)
+
+// Query is a request for a page of a list.
+type Query struct {
+	Page    int     // Starting at 1, used when Cursor is nil
+	PerPage int     // DefaultPerPage if 0
+	Cursor  *string // Position after the previous page, "" for the first page
+	Sort    []Sort
+	Filters []Filter
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// Page is a page of a list and its pagination metadata. Page-based pages
+// have a total, cursor-based pages the cursor of the next one.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	Total      *int64 `json:"total,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Map returns the page with each of its items converted by f.
+func Map[T any, U any](page *Page[T], f func(T) U) *Page[U] {
+	data := make([]U, 0, len(page.Data))
+	for _, item := range page.Data {
+		data = append(data, f(item))
+	}
+	return &Page[U]{
+		Data:       data,
+		Total:      page.Total,
+		Page:       page.Page,
+		PerPage:    page.PerPage,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+}
+
+// Error is returned for queries a list does not support, such as a sort on
+// an unknown field or a malformed cursor.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsInvalid reports whether the error is caused by an unsupported query
+// rather than by the database.
+func IsInvalid(err error) bool {
+	var e *Error
+	return stderrors.As(err, &e)
+}
+
+// Parse reads a query from URL parameters.
+func Parse(values url.Values) (*Query, error) {
+	q := &Query{}
+	if raw := values.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, invalid("page must be a number")
+		}
+		q.Page = page
+	}
+	if raw := values.Get("per_page"); raw != "" {
+		perPage, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, invalid("per_page must be a number")
+		}
+		q.PerPage = perPage
+	}
+	if values.Has("cursor") {
+		cursor := values.Get("cursor")
+		q.Cursor = &cursor
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			desc := strings.HasPrefix(field, "-")
+			q.Sort = append(q.Sort, Sort{Field: strings.TrimPrefix(field, "-"), Desc: desc})
+		}
+	}
+
+	for key, vals := range values {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		field, operator, ok := parseFilterKey(key)
+		if !ok {
+			return nil, invalid("invalid filter %q, expected filter[field] or filter[field][operator]", key)
+		}
+		for _, value := range vals {
+			q.Filters = append(q.Filters, Filter{Field: field, Operator: operator, Value: value})
+		}
+	}
+	if len(q.Filters) > maxFilters {
+		return nil, invalid("a list cannot be filtered by more than %d filters", maxFilters)
+	}
+	// Map iteration is random, the filters are sorted for Key
+	slices.SortFunc(q.Filters, func(a, b Filter) int {
+		return strings.Compare(a.Field+"\x00"+a.Operator+"\x00"+a.Value, b.Field+"\x00"+b.Operator+"\x00"+b.Value)
+	})
+
+	return q, nil
+}
+
+// parseFilterKey splits "filter[field]" and "filter[field][operator]".
+func parseFilterKey(key string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, "filter[")
+	if !ok {
+		return "", "", false
+	}
+	field, rest, ok := strings.Cut(rest, "]")
+	if !ok || field == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return field, Eq, true
+	}
+	operator, ok := strings.CutPrefix(rest, "[")
+	if !ok || !strings.HasSuffix(operator, "]") {
+		return "", "", false
+	}
+	operator = strings.TrimSuffix(operator, "]")
+	return field, operator, operator != ""
+}
+
+// Key returns a string identifying the query, to cache its result under.
+func (q *Query) Key() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d|%d|", q.Page, q.PerPage)
+	if q.Cursor != nil {
+		fmt.Fprintf(&b, "c%s", *q.Cursor)
+	}
+	b.WriteString("|")
+	for _, sort := range q.Sort {
+		fmt.Fprintf(&b, "%s:%t,", sort.Field, sort.Desc)
+	}
+	b.WriteString("|")
+	for _, filter := range q.Filters {
+		fmt.Fprintf(&b, "%s:%s:%s,", filter.Field, filter.Operator, filter.Value)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// normalize returns the page and page size to use.
+func (q *Query) normalize() (int, int) {
+	page, perPage := q.Page, q.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	return page, min(perPage, MaxPerPage)
+}
+
+// Status returns the HTTP status of an error returned while listing: 400 for
+// queries the list does not support, 500 otherwise.
+func Status(err error) int {
+	if IsInvalid(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
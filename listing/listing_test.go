@@ -0,0 +1,141 @@
+package listing
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	values, err := url.ParseQuery("page=2&per_page=50&sort=-created_at,title&filter[status]=published&filter[created_at][gte]=2024-01-01&filter[role][in]=admin,instructor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Page != 2 || q.PerPage != 50 || q.Cursor != nil {
+		t.Errorf("expected page 2 of 50, got %+v", q)
+	}
+	if !slices.Equal(q.Sort, []Sort{{Field: "created_at", Desc: true}, {Field: "title"}}) {
+		t.Errorf("unexpected sort %v", q.Sort)
+	}
+	expected := []Filter{
+		{Field: "created_at", Operator: Gte, Value: "2024-01-01"},
+		{Field: "role", Operator: In, Value: "admin,instructor"},
+		{Field: "status", Operator: Eq, Value: "published"},
+	}
+	if !slices.Equal(q.Filters, expected) {
+		t.Errorf("expected filters %v, got %v", expected, q.Filters)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	q, err := Parse(url.Values{"cursor": {""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Cursor == nil || *q.Cursor != "" {
+		t.Error("expected the first page of a cursor-based list")
+	}
+
+	if q, _ := Parse(url.Values{}); q.Cursor != nil {
+		t.Error("expected a page-based list by default")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	cases := map[string]string{
+		"page":           "page=two",
+		"per_page":       "per_page=all",
+		"filter key":     "filter[status=published",
+		"empty field":    "filter[]=x",
+		"operator":       "filter[status][gte=x",
+		"empty operator": "filter[status][]=x",
+		"too many filters": "filter[a]=1&filter[a]=2&filter[a]=3&filter[a]=4&filter[a]=5&filter[a]=6&filter[a]=7&" +
+			"filter[a]=8&filter[a]=9&filter[a]=10&filter[a]=11&filter[a]=12&filter[a]=13&filter[a]=14&filter[a]=15&" +
+			"filter[a]=16&filter[a]=17&filter[a]=18&filter[a]=19&filter[a]=20&filter[a]=21",
+	}
+	for name, query := range cases {
+		t.Run(name, func(t *testing.T) {
+			values, err := url.ParseQuery(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Parse(values); !IsInvalid(err) || Status(err) != http.StatusBadRequest {
+				t.Errorf("expected an invalid query, got %v", err)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	parse := func(query string) *Query {
+		t.Helper()
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := Parse(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+
+	key := parse("page=2&sort=title&filter[status]=published&filter[title][contains]=go").Key()
+	if other := parse("filter[title][contains]=go&filter[status]=published&sort=title&page=2").Key(); other != key {
+		t.Error("the key depends on the order of the parameters")
+	}
+
+	queries := []string{
+		"page=3&sort=title&filter[status]=published&filter[title][contains]=go",
+		"page=2&per_page=50&sort=title&filter[status]=published&filter[title][contains]=go",
+		"page=2&sort=-title&filter[status]=published&filter[title][contains]=go",
+		"page=2&sort=title&filter[status]=draft&filter[title][contains]=go",
+		"page=2&sort=title&filter[status][ne]=published&filter[title][contains]=go",
+		"page=2&sort=title&filter[status]=published",
+		"page=2&cursor=&sort=title&filter[status]=published&filter[title][contains]=go",
+	}
+	for _, query := range queries {
+		if parse(query).Key() == key {
+			t.Errorf("%s has the same key", query)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	page, err := Slice(items, &Query{Page: 2, PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(page.Data, []int{3, 4}) || *page.Total != 5 || !page.HasMore {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	page, err = Slice(items, &Query{Page: 3, PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(page.Data, []int{5}) || page.HasMore {
+		t.Errorf("unexpected last page %+v", page)
+	}
+
+	page, err = Slice(items, &Query{Page: 10, PerPage: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 0 || page.PerPage != MaxPerPage {
+		t.Errorf("unexpected page past the end %+v", page)
+	}
+
+	cursor := ""
+	if _, err := Slice(items, &Query{Cursor: &cursor}); !IsInvalid(err) {
+		t.Errorf("expected cursors to be refused, got %v", err)
+	}
+}
@@ -6,20 +6,46 @@ import (
 	"fmt"
 	"os"
 
+	adminRepo "github.com/dapthehuman/learning-management-system/database/repositories/admin"
 	assessmentRepo "github.com/dapthehuman/learning-management-system/database/repositories/assessment"
+	auditRepo "github.com/dapthehuman/learning-management-system/database/repositories/audit"
+	categoryRepo "github.com/dapthehuman/learning-management-system/database/repositories/category"
 	courseRepo "github.com/dapthehuman/learning-management-system/database/repositories/course"
+	invitationRepo "github.com/dapthehuman/learning-management-system/database/repositories/invitation"
 	materialRepo "github.com/dapthehuman/learning-management-system/database/repositories/material"
+	organizationRepo "github.com/dapthehuman/learning-management-system/database/repositories/organization"
+	privacyRepo "github.com/dapthehuman/learning-management-system/database/repositories/privacy"
+	roleRepo "github.com/dapthehuman/learning-management-system/database/repositories/role"
+	samlRepo "github.com/dapthehuman/learning-management-system/database/repositories/saml"
+	scimRepo "github.com/dapthehuman/learning-management-system/database/repositories/scim"
+	searchRepo "github.com/dapthehuman/learning-management-system/database/repositories/search"
 	studentRepo "github.com/dapthehuman/learning-management-system/database/repositories/student"
+	tokenRepo "github.com/dapthehuman/learning-management-system/database/repositories/token"
 	userRepo "github.com/dapthehuman/learning-management-system/database/repositories/user"
+	versionRepo "github.com/dapthehuman/learning-management-system/database/repositories/version"
 
+	adminService "github.com/dapthehuman/learning-management-system/service/admin-service"
 	assessmentService "github.com/dapthehuman/learning-management-system/service/assessment-service"
+	categoryService "github.com/dapthehuman/learning-management-system/service/category-service"
 	courseService "github.com/dapthehuman/learning-management-system/service/course-service"
+	impersonationService "github.com/dapthehuman/learning-management-system/service/impersonation-service"
+	invitationService "github.com/dapthehuman/learning-management-system/service/invitation-service"
+	mailService "github.com/dapthehuman/learning-management-system/service/mail-service"
 	materialService "github.com/dapthehuman/learning-management-system/service/material-service"
+	oidcService "github.com/dapthehuman/learning-management-system/service/oidc-service"
+	organizationService "github.com/dapthehuman/learning-management-system/service/organization-service"
+	privacyService "github.com/dapthehuman/learning-management-system/service/privacy-service"
 	"github.com/dapthehuman/learning-management-system/service/redis"
+	roleService "github.com/dapthehuman/learning-management-system/service/role-service"
+	samlService "github.com/dapthehuman/learning-management-system/service/saml-service"
+	scimService "github.com/dapthehuman/learning-management-system/service/scim-service"
+	searchService "github.com/dapthehuman/learning-management-system/service/search-service"
 	studentService "github.com/dapthehuman/learning-management-system/service/student-service"
+	tokenService "github.com/dapthehuman/learning-management-system/service/token-service"
 	userService "github.com/dapthehuman/learning-management-system/service/user-service"
 
 	seeders "github.com/dapthehuman/learning-management-system/database/seed"
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
 	"github.com/dapthehuman/learning-management-system/http/route"
 
 	"goyave.dev/goyave/v5"
@@ -39,7 +65,16 @@ var resources embed.FS
 
 func main() {
 	var seed bool
+	var importUsers, exportUsers, organization string
+	var importAs uint64
+	var importOptions adminDto.ImportOptions
 	flag.BoolVar(&seed, "seed", false, "If true, the database will be seeded with random data.")
+	flag.StringVar(&importUsers, "import-users", "", "Create the users listed in the given CSV file, then exit.")
+	flag.Uint64Var(&importAs, "as", 0, "With -import-users, ID of the user the import is made for, who must be able to give the roles of the imported users.")
+	flag.BoolVar(&importOptions.DryRun, "dry-run", false, "With -import-users, validate the file without creating anything.")
+	flag.BoolVar(&importOptions.SendInvitations, "send-invitations", false, "With -import-users, email imported users a link to choose their password.")
+	flag.StringVar(&exportUsers, "export-users", "", "Write all users to the given CSV file (\"-\" for stdout), then exit.")
+	flag.StringVar(&organization, "organization", "default", "Slug of the organization -import-users and -export-users work on.")
 	flag.Parse()
 	resources := fsutil.NewEmbed(resources)
 	langFS, err := resources.Sub("resources/lang")
@@ -81,6 +116,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if importUsers != "" {
+		os.Exit(runUserImport(server, organization, importAs, importUsers, &importOptions))
+	}
+
+	if exportUsers != "" {
+		os.Exit(runUserExport(server, organization, exportUsers))
+	}
+
 	if err := server.Start(); err != nil {
 		server.Logger.Error(err)
 		os.Exit(2)
@@ -106,14 +149,60 @@ func registerServices(server *goyave.Server) {
 	userRepository := userRepo.NewUser(server.DB())
 	server.RegisterService(userService.NewService(userRepository))
 
-	studentRepository := studentRepo.NewStudent(server.DB())
-	server.RegisterService(studentService.NewService(studentRepository))
+	roleRepository := roleRepo.NewRole(server.DB(), redis)
+	server.RegisterService(roleService.NewService(roleRepository))
+
+	auditRepository := auditRepo.NewAudit(server.DB())
+
+	adminRepository := adminRepo.NewAdmin(server.DB(), redis)
+	var mailer adminService.Mailer
+	if mailConfig := mailService.ConfigFromEnv(); mailConfig.Enabled() {
+		mailServ := mailService.NewService(mailConfig)
+		server.RegisterService(mailServ)
+		mailer = mailServ
+	}
+
+	server.RegisterService(adminService.NewService(adminRepository, roleRepository, auditRepository, mailer))
+	server.RegisterService(impersonationService.NewService(userRepository, roleRepository, auditRepository, redis))
+
+	if oidcConfig := oidcService.ConfigFromEnv(); oidcConfig.Enabled() {
+		server.RegisterService(oidcService.NewService(userRepository, redis, oidcConfig))
+	}
+
+	if samlBaseURL := samlService.BaseURLFromEnv(); samlBaseURL != "" {
+		samlRepository := samlRepo.NewProvider(server.DB())
+		server.RegisterService(samlService.NewService(samlRepository, userRepository, roleRepository, redis, samlBaseURL))
+	}
+
+	tokenRepository := tokenRepo.NewToken(server.DB())
+	server.RegisterService(tokenService.NewService(tokenRepository, userRepository, roleRepository, auditRepository))
 
 	courseRepository := courseRepo.NewCourse(server.DB(), redis)
-	server.RegisterService(courseService.NewService(courseRepository))
+
+	studentRepository := studentRepo.NewStudent(server.DB())
+	server.RegisterService(studentService.NewService(studentRepository, courseRepository))
+
+	versionRepository := versionRepo.NewVersion(server.DB(), redis)
+	categoryRepository := categoryRepo.NewCategory(server.DB(), redis)
+	server.RegisterService(courseService.NewService(courseRepository, auditRepository, versionRepository, categoryRepository))
+	server.RegisterService(categoryService.NewService(categoryRepository))
+	server.RegisterService(searchService.NewService(searchRepo.NewSearch(server.DB())))
+
+	privacyRepository := privacyRepo.NewPrivacy(server.DB(), redis)
+	server.RegisterService(privacyService.NewService(privacyRepository, auditRepository))
+
+	scimRepository := scimRepo.NewSCIM(server.DB(), redis)
+	server.RegisterService(scimService.NewService(scimRepository, courseRepository, roleRepository, os.Getenv("APP_URL")))
+
+	invitationRepository := invitationRepo.NewInvitation(server.DB())
+	invitationServ := invitationService.NewService(invitationRepository, userRepository, roleRepository, courseRepository, auditRepository, mailer)
+	server.RegisterService(invitationServ)
+
+	organizationRepository := organizationRepo.NewOrganization(server.DB(), redis)
+	server.RegisterService(organizationService.NewService(organizationRepository, invitationServ))
 
 	materialRepository := materialRepo.NewMaterial(server.DB(), redis)
-	server.RegisterService(materialService.NewService(materialRepository))
+	server.RegisterService(materialService.NewService(materialRepository, courseRepository, versionRepository))
 
 	assessmentRepository := assessmentRepo.NewAssessment(server.DB())
 	server.RegisterService(assessmentService.NewService(assessmentRepository))
@@ -2,38 +2,77 @@ package adminservice
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
-	"github.com/dapthehuman/learning-management-system/dto"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	"golang.org/x/crypto/bcrypt"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
+// passwordResetLifetime is how long a forced password reset token stays valid.
+const passwordResetLifetime = 24 * time.Hour
+
 type Repository interface {
-	GetAllUsers(ctx context.Context) ([]*model.User, error)
+	SearchUsers(ctx context.Context, filter *model.UserFilter, q *listing.Query) (*listing.Page[*model.User], error)
 	GetUserByID(ctx context.Context, id uint64) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
 	UpdateUser(ctx context.Context, userID uint64, user *model.User) (*model.User, error)
+	UpdateRole(ctx context.Context, userID uint64, role string) error
+	SetSuspended(ctx context.Context, userID uint64, suspended bool) error
+	RequirePasswordReset(ctx context.Context, userID uint64, tokenHash string, expiresAt time.Time) error
 	DeleteUser(ctx context.Context, id uint64) error
+
+	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	ExistingCourses(ctx context.Context, ids []uint64) (map[uint64]bool, error)
+	ImportUsers(ctx context.Context, users []*model.UserImport, dryRun bool) error
+	ExportUsers(ctx context.Context, f func(*model.UserExport) error) error
+}
+
+type RoleRepository interface {
+	Exists(ctx context.Context, name string) (bool, error)
+	Covers(ctx context.Context, holder string, role string) (bool, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
+	List(ctx context.Context, q *listing.Query) (*listing.Page[*model.AuditLog], error)
 }
 
 type Service struct {
-	repository Repository
+	repository      Repository
+	roleRepository  RoleRepository
+	auditRepository AuditRepository
+	mailer          Mailer
 }
 
-func NewService(repository Repository) *Service {
+// NewService creates the admin service. mailer may be nil when sending
+// emails is not configured.
+func NewService(repository Repository, roleRepository RoleRepository, auditRepository AuditRepository, mailer Mailer) *Service {
 	return &Service{
-		repository: repository,
+		repository:      repository,
+		roleRepository:  roleRepository,
+		auditRepository: auditRepository,
+		mailer:          mailer,
 	}
 }
 
-func (s *Service) GetAllUsers(ctx context.Context) ([]*dto.User, error) {
-	users, err := s.repository.GetAllUsers(ctx)
+func (s *Service) SearchUsers(ctx context.Context, searchDTO *dto.UserSearchRequest, q *listing.Query) (*listing.Page[*dto.User], error) {
+	users, err := s.repository.SearchUsers(ctx, &model.UserFilter{
+		Search: searchDTO.Search,
+		Role:   searchDTO.Role,
+		Status: searchDTO.Status,
+	}, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.User](users), nil
+	return typeutil.MustConvert[*listing.Page[*dto.User]](users), nil
 }
 
 func (s *Service) GetUserByID(ctx context.Context, userID uint64) (*dto.User, error) {
@@ -45,27 +84,244 @@ func (s *Service) GetUserByID(ctx context.Context, userID uint64) (*dto.User, er
 	return typeutil.MustConvert[*dto.User](user), nil
 }
 
-func (s *Service) UpdateUser(ctx context.Context, userID uint64, updateDTO *dto.UpdateUserRequest) (*dto.User, error) {
-	user, err := s.repository.GetUserByID(ctx, userID)
+// CreateUser creates a user with a role that has no permissions the actor
+// does not have.
+func (s *Service) CreateUser(ctx context.Context, actorID uint64, createDTO *dto.CreateUserRequest) (*dto.User, error) {
+	if createDTO.Role == "" {
+		createDTO.Role = "student"
+	}
+	if err := s.checkRole(ctx, actorID, createDTO.Role); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(createDTO.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
-	if user == nil {
-		return nil, errors.New("user not found")
+	user, err := s.repository.CreateUser(ctx, &model.User{
+		Name:         createDTO.Name,
+		Email:        createDTO.Email,
+		PasswordHash: string(hashedPassword),
+		Role:         createDTO.Role,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	user = typeutil.MustConvert[*model.User](updateDTO)
+	if err := s.audit(ctx, "user.create", actorID, &user.ID, map[string]any{"role": user.Role}); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.User](user), nil
+}
+
+// UpdateUser changes the name and email of the user. Changing the email of
+// a user whose role has permissions the actor does not have would let them
+// take over the account with a password reset, so they are refused.
+func (s *Service) UpdateUser(ctx context.Context, actorID uint64, userID uint64, updateDTO *dto.UpdateUserRequest) (*dto.User, error) {
+	user, err := s.manageable(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := user.Email
+	if updateDTO.Name != "" {
+		user.Name = updateDTO.Name
+	}
+	if updateDTO.Email != "" {
+		user.Email = updateDTO.Email
+	}
 	updatedUser, err := s.repository.UpdateUser(ctx, userID, user)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.audit(ctx, "user.update", actorID, &userID, map[string]any{"old_email": previous, "new_email": updatedUser.Email}); err != nil {
+		return nil, err
+	}
 
 	return typeutil.MustConvert[*dto.User](updatedUser), nil
 }
 
-func (s *Service) DeleteUser(ctx context.Context, id uint64) error {
-	return s.repository.DeleteUser(ctx, id)
+// ChangeRole gives the user another role. Neither the current nor the new
+// role of the user can have permissions the actor does not have, and
+// administrators cannot change their own role.
+func (s *Service) ChangeRole(ctx context.Context, actorID uint64, userID uint64, role string) (*dto.User, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot change the role of your own account")
+	}
+
+	user, err := s.manageable(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRole(ctx, actorID, role); err != nil {
+		return nil, err
+	}
+
+	previous := user.Role
+	if err := s.repository.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, "user.role_change", actorID, &userID, map[string]any{"old_role": previous, "new_role": role}); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
+// Suspend blocks the user from signing in and from using existing sessions
+// and API tokens. Administrators cannot suspend themselves.
+func (s *Service) Suspend(ctx context.Context, actorID uint64, userID uint64) (*dto.User, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot suspend your own account")
+	}
+	if _, err := s.manageable(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.SetSuspended(ctx, userID, true); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, "user.suspend", actorID, &userID, nil); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
+func (s *Service) Reactivate(ctx context.Context, actorID uint64, userID uint64) (*dto.User, error) {
+	if _, err := s.manageable(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repository.SetSuspended(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, "user.reactivate", actorID, &userID, nil); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
+// ForcePasswordReset locks the user out until they set a new password with
+// the one-time token emailed to them. The token is never shown to the
+// administrator, who could otherwise take over the account.
+func (s *Service) ForcePasswordReset(ctx context.Context, actorID uint64, userID uint64) (*dto.PasswordResetResponse, error) {
+	if s.mailer == nil {
+		return nil, ErrMailDisabled
+	}
+	user, err := s.manageable(ctx, actorID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := newResetToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(passwordResetLifetime)
+
+	if err := s.repository.RequirePasswordReset(ctx, userID, hash, expiresAt); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, "user.password_reset", actorID, &userID, map[string]any{"expires_at": expiresAt}); err != nil {
+		return nil, err
+	}
+
+	link := s.mailer.Link("/reset-password?token=" + token)
+	body := fmt.Sprintf("Hello %s,\n\nAn administrator asked you to choose a new password. Choose it within %d hours using the link below:\n\n%s\n",
+		user.Name, int(passwordResetLifetime.Hours()), link)
+	if err := s.mailer.Send(ctx, user.Email, "Choose a new password", body); err != nil {
+		return nil, fmt.Errorf("could not email the password reset link: %w", err)
+	}
+
+	return &dto.PasswordResetResponse{ExpiresAt: expiresAt}, nil
+}
+
+// DeleteUser deletes the user. The audit entry keeps their email and role,
+// as its target is cleared along with the user.
+func (s *Service) DeleteUser(ctx context.Context, actorID uint64, id uint64) error {
+	user, err := s.manageable(ctx, actorID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, "user.delete", actorID, nil, map[string]any{"user_id": user.ID, "email": user.Email, "role": user.Role})
+}
+
+func (s *Service) AuditLogs(ctx context.Context, q *listing.Query) (*listing.Page[*dto.AuditLog], error) {
+	entries, err := s.auditRepository.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.AuditLog]](entries), nil
+}
+
+// audit records an action of the actor on the user. Metadata may be nil.
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, userID *uint64, metadata map[string]any) error {
+	var raw json.RawMessage
+	if metadata != nil {
+		var err error
+		if raw, err = json.Marshal(metadata); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:      &actorID,
+		Action:       action,
+		TargetUserID: userID,
+		Metadata:     raw,
+	})
+	return err
+}
+
+// manageable returns the user if the actor may manage them, that is if the
+// actor could give them their role.
+func (s *Service) manageable(ctx context.Context, actorID uint64, userID uint64) (*model.User, error) {
+	user, err := s.repository.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+	if err := s.checkGrant(ctx, actorID, user.Role); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkRole makes sure the role exists and can be granted by the actor.
+func (s *Service) checkRole(ctx context.Context, actorID uint64, role string) error {
+	exists, err := s.roleRepository.Exists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("unknown role")
+	}
+	return s.checkGrant(ctx, actorID, role)
+}
+
+// checkGrant prevents the actor from handling users with a role having
+// permissions they do not have, such as an administrator role.
+func (s *Service) checkGrant(ctx context.Context, actorID uint64, role string) error {
+	actor, err := s.repository.GetUserByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	covered, err := s.roleRepository.Covers(ctx, actor.Role, role)
+	if err != nil {
+		return err
+	}
+	if !covered {
+		return fmt.Errorf("the %s role has permissions you do not have", role)
+	}
+	return nil
 }
 
 func (s *Service) Name() string {
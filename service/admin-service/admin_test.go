@@ -0,0 +1,425 @@
+package adminservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type resetRequest struct {
+	tokenHash string
+	expiresAt time.Time
+}
+
+type repository struct {
+	users    map[uint64]*model.User
+	resets   map[uint64]resetRequest
+	courses  []uint64
+	imported []*model.UserImport
+}
+
+func newRepository(users ...*model.User) *repository {
+	r := &repository{users: make(map[uint64]*model.User), resets: make(map[uint64]resetRequest)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *repository) SearchUsers(ctx context.Context, filter *model.UserFilter, q *listing.Query) (*listing.Page[*model.User], error) {
+	page := &listing.Page[*model.User]{Data: make([]*model.User, 0)}
+	for _, user := range r.users {
+		page.Data = append(page.Data, user)
+	}
+	return page, nil
+}
+
+func (r *repository) GetUserByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	user.ID = uint64(len(r.users) + 100)
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *repository) UpdateUser(ctx context.Context, userID uint64, user *model.User) (*model.User, error) {
+	r.users[userID] = user
+	return user, nil
+}
+
+func (r *repository) UpdateRole(ctx context.Context, userID uint64, role string) error {
+	if user, ok := r.users[userID]; ok {
+		user.Role = role
+	}
+	return nil
+}
+
+func (r *repository) SetSuspended(ctx context.Context, userID uint64, suspended bool) error {
+	if user, ok := r.users[userID]; ok {
+		user.SuspendedAt = nil
+		if suspended {
+			now := time.Now()
+			user.SuspendedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *repository) RequirePasswordReset(ctx context.Context, userID uint64, tokenHash string, expiresAt time.Time) error {
+	r.resets[userID] = resetRequest{tokenHash: tokenHash, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *repository) DeleteUser(ctx context.Context, id uint64) error {
+	delete(r.users, id)
+	return nil
+}
+
+func (r *repository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for _, user := range r.users {
+		if slices.Contains(emails, user.Email) {
+			existing[strings.ToLower(user.Email)] = true
+		}
+	}
+	return existing, nil
+}
+
+func (r *repository) ExistingCourses(ctx context.Context, ids []uint64) (map[uint64]bool, error) {
+	existing := make(map[uint64]bool)
+	for _, id := range ids {
+		existing[id] = slices.Contains(r.courses, id)
+	}
+	return existing, nil
+}
+
+func (r *repository) ImportUsers(ctx context.Context, users []*model.UserImport, dryRun bool) error {
+	if !dryRun {
+		r.imported = append(r.imported, users...)
+	}
+	return nil
+}
+
+func (r *repository) ExportUsers(ctx context.Context, f func(*model.UserExport) error) error {
+	for _, user := range r.users {
+		if err := f(&model.UserExport{User: *user}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type roleRepository map[string][]string
+
+func (r roleRepository) Exists(ctx context.Context, name string) (bool, error) {
+	_, ok := r[name]
+	return ok, nil
+}
+
+func (r roleRepository) Covers(ctx context.Context, holder string, role string) (bool, error) {
+	for _, permission := range r[role] {
+		if !slices.Contains(r[holder], permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+var roles = roleRepository{
+	"admin":      {"course.write", "user.create", "user.impersonate", "user.update"},
+	"registrar":  {"course.write", "user.create", "user.update"},
+	"instructor": {"course.write"},
+	"student":    {},
+}
+
+type auditRepository struct {
+	entries []*model.AuditLog
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func (r *auditRepository) List(ctx context.Context, q *listing.Query) (*listing.Page[*model.AuditLog], error) {
+	return &listing.Page[*model.AuditLog]{Data: r.entries}, nil
+}
+
+// The administrator and the registrar acting in the tests
+const (
+	adminID     = 1
+	registrarID = 2
+)
+
+func newService(repository *repository) *Service {
+	repository.users[adminID] = &model.User{ID: adminID, Role: "admin"}
+	repository.users[registrarID] = &model.User{ID: registrarID, Role: "registrar"}
+	return NewService(repository, roles, &auditRepository{}, nil)
+}
+
+func TestCreateUser(t *testing.T) {
+	repository := newRepository()
+	s := newService(repository)
+
+	user, err := s.CreateUser(context.Background(), adminID, &dto.CreateUserRequest{Name: "Student", Email: "student@example.edu", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "student" {
+		t.Errorf("expected the student role by default, got %s", user.Role)
+	}
+	stored := repository.users[user.ID]
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("secret123")); err != nil {
+		t.Errorf("password is not stored as its hash: %v", err)
+	}
+}
+
+func TestCreateUserRejectsRoles(t *testing.T) {
+	cases := map[string]struct {
+		actorID uint64
+		role    string
+	}{
+		"unknown role":          {adminID, "root"},
+		"role with more rights": {registrarID, "admin"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			repository := newRepository()
+			s := newService(repository)
+
+			_, err := s.CreateUser(context.Background(), c.actorID, &dto.CreateUserRequest{Name: "Someone", Email: "someone@example.edu", Password: "secret123", Role: c.role})
+			if err == nil {
+				t.Errorf("created a user with the %s role", c.role)
+			}
+			if len(repository.users) != 2 {
+				t.Errorf("stored a user with the %s role", c.role)
+			}
+		})
+	}
+}
+
+func TestChangeRole(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Role: "student"})
+	s := newService(repository)
+
+	if _, err := s.ChangeRole(context.Background(), adminID, 10, "root"); err == nil {
+		t.Error("changed to an unknown role")
+	}
+	if repository.users[10].Role != "student" {
+		t.Errorf("role changed to %s", repository.users[10].Role)
+	}
+
+	user, err := s.ChangeRole(context.Background(), registrarID, 10, "instructor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "instructor" {
+		t.Errorf("expected the instructor role, got %s", user.Role)
+	}
+}
+
+func TestChangeRoleRejectsEscalation(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Role: "student"})
+	s := newService(repository)
+
+	cases := map[string]struct {
+		actorID uint64
+		userID  uint64
+		role    string
+	}{
+		"role with more rights":  {registrarID, 10, "admin"},
+		"user with more rights":  {registrarID, adminID, "student"},
+		"own role":               {registrarID, registrarID, "admin"},
+		"own role, without gain": {adminID, adminID, "student"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			previous := repository.users[c.userID].Role
+			if _, err := s.ChangeRole(context.Background(), c.actorID, c.userID, c.role); err == nil {
+				t.Errorf("user %d gave the %s role to user %d", c.actorID, c.role, c.userID)
+			}
+			if role := repository.users[c.userID].Role; role != previous {
+				t.Errorf("role changed to %s", role)
+			}
+		})
+	}
+}
+
+func TestSuspend(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Role: "student"})
+	s := newService(repository)
+
+	if _, err := s.Suspend(context.Background(), adminID, adminID); err == nil {
+		t.Error("an administrator suspended their own account")
+	}
+	if repository.users[adminID].SuspendedAt != nil {
+		t.Error("the administrator account is suspended")
+	}
+
+	user, err := s.Suspend(context.Background(), adminID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.SuspendedAt == nil {
+		t.Error("the user is not suspended")
+	}
+
+	user, err = s.Reactivate(context.Background(), adminID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.SuspendedAt != nil {
+		t.Error("the user is still suspended")
+	}
+}
+
+type sentMail struct {
+	to   string
+	body string
+}
+
+type mailer struct {
+	sent []sentMail
+}
+
+func (m *mailer) Send(ctx context.Context, to string, subject string, body string) error {
+	m.sent = append(m.sent, sentMail{to: to, body: body})
+	return nil
+}
+
+func (m *mailer) Link(path string) string {
+	return "https://lms.example.edu" + path
+}
+
+func TestForcePasswordReset(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Email: "student@example.edu", Role: "student"})
+	mailer := &mailer{}
+	s := newService(repository)
+	s.mailer = mailer
+
+	reset, err := s.ForcePasswordReset(context.Background(), adminID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token is only emailed to the user, and only its hash is stored
+	if len(mailer.sent) != 1 || mailer.sent[0].to != "student@example.edu" {
+		t.Fatalf("expected an email to the user, got %v", mailer.sent)
+	}
+	_, token, found := strings.Cut(mailer.sent[0].body, "/reset-password?token=")
+	token = strings.TrimSpace(token)
+	sum := sha256.Sum256([]byte(token))
+	stored := repository.resets[10]
+	if !found || stored.tokenHash != hex.EncodeToString(sum[:]) || stored.tokenHash == token {
+		t.Errorf("stored %q for token %q", stored.tokenHash, token)
+	}
+	if !stored.expiresAt.Equal(reset.ExpiresAt) || time.Until(reset.ExpiresAt) > passwordResetLifetime {
+		t.Errorf("token expires at %s", reset.ExpiresAt)
+	}
+
+	if _, err := s.ForcePasswordReset(context.Background(), adminID, 20); err == nil {
+		t.Error("issued a reset token for an unknown user")
+	}
+	if len(repository.resets) != 1 || len(mailer.sent) != 1 {
+		t.Error("issued a reset token for an unknown user")
+	}
+}
+
+func TestForcePasswordResetRequiresMail(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Role: "student"})
+	s := newService(repository)
+
+	if _, err := s.ForcePasswordReset(context.Background(), adminID, 10); err == nil {
+		t.Error("issued a reset token that cannot be emailed")
+	}
+	if len(repository.resets) != 0 {
+		t.Error("locked the user out without emailing them")
+	}
+}
+
+func TestAudit(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Email: "student@example.edu", Role: "student"})
+	s := newService(repository)
+	s.mailer = &mailer{}
+	audit := s.auditRepository.(*auditRepository)
+	ctx := context.Background()
+
+	if _, err := s.ChangeRole(ctx, registrarID, 10, "instructor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Suspend(ctx, registrarID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reactivate(ctx, registrarID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ForcePasswordReset(ctx, registrarID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteUser(ctx, registrarID, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		action   string
+		target   bool
+		metadata string
+	}{
+		{action: "user.role_change", target: true, metadata: `{"new_role":"instructor","old_role":"student"}`},
+		{action: "user.suspend", target: true},
+		{action: "user.reactivate", target: true},
+		{action: "user.password_reset", target: true},
+		{action: "user.delete", metadata: `{"email":"student@example.edu","role":"instructor","user_id":10}`},
+	}
+	if len(audit.entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(audit.entries))
+	}
+	for i, entry := range audit.entries {
+		if entry.Action != expected[i].action || *entry.ActorID != registrarID || (entry.TargetUserID != nil) != expected[i].target {
+			t.Errorf("entry %d: expected %s on the user, got %+v", i, expected[i].action, entry)
+		}
+		if expected[i].metadata != "" && string(entry.Metadata) != expected[i].metadata {
+			t.Errorf("entry %d: expected metadata %s, got %s", i, expected[i].metadata, entry.Metadata)
+		}
+	}
+}
+
+func TestManageRejectsUsersWithMoreRights(t *testing.T) {
+	repository := newRepository()
+	s := newService(repository)
+	s.mailer = &mailer{}
+	ctx := context.Background()
+
+	if _, err := s.UpdateUser(ctx, registrarID, adminID, &dto.UpdateUserRequest{Email: "registrar@example.edu"}); err == nil {
+		t.Error("the registrar changed the email of the administrator")
+	}
+	if _, err := s.Suspend(ctx, registrarID, adminID); err == nil {
+		t.Error("the registrar suspended the administrator")
+	}
+	if _, err := s.ForcePasswordReset(ctx, registrarID, adminID); err == nil {
+		t.Error("the registrar reset the password of the administrator")
+	}
+	if err := s.DeleteUser(ctx, registrarID, adminID); err == nil {
+		t.Error("the registrar deleted the administrator")
+	}
+
+	administrator := repository.users[adminID]
+	if administrator == nil || administrator.Email != "" || administrator.SuspendedAt != nil || len(repository.resets) != 0 {
+		t.Errorf("the administrator account changed: %+v", administrator)
+	}
+}
@@ -0,0 +1,300 @@
+package adminservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
+)
+
+const (
+	maxImportRows = 5000
+
+	// invitationLifetime is how long imported users have to choose a password.
+	invitationLifetime = 7 * 24 * time.Hour
+)
+
+// ErrMailDisabled is returned when invitations are requested but no mailer is configured.
+var ErrMailDisabled = errors.New("sending emails is not configured")
+
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+	Link(path string) string
+}
+
+// ImportUsers creates users from a CSV with a header row. The "name" and
+// "email" columns are required; "role" defaults to student and "courses" is
+// a semicolon-separated list of course IDs to enroll the user in.
+//
+// Every row is validated first and nothing is created if any row is invalid.
+// The role of each row cannot have permissions the actor does not have.
+// Imported users have no password: they choose one with the reset token sent
+// in their invitation, or one emailed when an administrator forces a reset.
+func (s *Service) ImportUsers(ctx context.Context, actorID uint64, r io.Reader, options *dto.ImportOptions) (*dto.ImportReport, error) {
+	if options.SendInvitations && !options.DryRun && s.mailer == nil {
+		return nil, ErrMailDisabled
+	}
+	actor, err := s.repository.GetUserByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the importing user: %w", err)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	report := &dto.ImportReport{DryRun: options.DryRun, Rows: make([]*dto.ImportRowResult, 0)}
+	users := make([]*model.UserImport, 0)
+	seen := make(map[string]int)
+	roles := make(map[string]string) // Error of each role, empty if it can be given
+	emails := make([]string, 0)
+	courseIDs := make([]uint64, 0)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		// FieldPos may only be called after a successful Read
+		var line int
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			line = parseErr.Line
+		} else if err != nil {
+			return nil, fmt.Errorf("could not read CSV: %w", err)
+		} else {
+			line, _ = reader.FieldPos(0)
+		}
+
+		result := &dto.ImportRowResult{Line: line}
+		report.Rows = append(report.Rows, result)
+		if len(report.Rows) > maxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", maxImportRows)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, parseErr.Err.Error())
+			users = append(users, nil)
+			continue
+		}
+
+		user := &model.UserImport{User: model.User{
+			Name: field(record, "name"),
+			Role: field(record, "role"),
+		}}
+		users = append(users, user)
+
+		if user.User.Name == "" {
+			result.Errors = append(result.Errors, "name is required")
+		}
+
+		email := field(record, "email")
+		result.Email = email
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			result.Errors = append(result.Errors, "email is invalid")
+		} else if line, ok := seen[strings.ToLower(email)]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("email is duplicated on line %d", line))
+		} else {
+			seen[strings.ToLower(email)] = result.Line
+			emails = append(emails, email)
+		}
+		user.User.Email = email
+
+		if user.User.Role == "" {
+			user.User.Role = "student"
+		}
+		if _, ok := roles[user.User.Role]; !ok {
+			roles[user.User.Role], err = s.roleError(ctx, actor, user.User.Role)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if message := roles[user.User.Role]; message != "" {
+			result.Errors = append(result.Errors, message)
+		}
+
+		for _, id := range strings.Split(field(record, "courses"), ";") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			courseID, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("course ID %q is invalid", id))
+				continue
+			}
+			user.CourseIDs = append(user.CourseIDs, courseID)
+			courseIDs = append(courseIDs, courseID)
+		}
+	}
+
+	existingEmails, err := s.repository.ExistingEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	existingCourses, err := s.repository.ExistingCourses(ctx, courseIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]*model.UserImport, 0, len(users))
+	tokens := make(map[*model.UserImport]string, len(users))
+	for i, user := range users {
+		result := report.Rows[i]
+		if user != nil {
+			if existingEmails[strings.ToLower(user.User.Email)] {
+				result.Errors = append(result.Errors, "a user with this email already exists")
+			}
+			for _, courseID := range user.CourseIDs {
+				if !existingCourses[courseID] {
+					result.Errors = append(result.Errors, fmt.Sprintf("course %d does not exist", courseID))
+				}
+			}
+		}
+
+		if len(result.Errors) > 0 {
+			report.Failed++
+			continue
+		}
+
+		token, hash, err := newResetToken()
+		if err != nil {
+			return nil, err
+		}
+		user.ResetTokenHash = hash
+		user.ResetExpiresAt = time.Now().Add(invitationLifetime)
+		tokens[user] = token
+		valid = append(valid, user)
+	}
+	report.Total = len(report.Rows)
+
+	if report.Failed > 0 {
+		return report, nil
+	}
+
+	if err := s.repository.ImportUsers(ctx, valid, options.DryRun); err != nil {
+		return nil, err
+	}
+	report.Created = len(valid)
+
+	for i, user := range users {
+		report.Rows[i].UserID = user.User.ID
+		if options.DryRun || !options.SendInvitations {
+			continue
+		}
+
+		link := s.mailer.Link("/reset-password?token=" + tokens[user])
+		body := fmt.Sprintf("Hello %s,\n\nAn account has been created for you. Choose your password within %d days using the link below:\n\n%s\n",
+			user.User.Name, int(invitationLifetime.Hours()/24), link)
+		if err := s.mailer.Send(ctx, user.User.Email, "Your account has been created", body); err != nil {
+			report.Rows[i].InvitationError = err.Error()
+		}
+	}
+
+	return report, nil
+}
+
+// roleError tells why the actor cannot give the role to imported users, or
+// returns an empty string if they can.
+func (s *Service) roleError(ctx context.Context, actor *model.User, role string) (string, error) {
+	exists, err := s.roleRepository.Exists(ctx, role)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return fmt.Sprintf("role %q does not exist", role), nil
+	}
+	covered, err := s.roleRepository.Covers(ctx, actor.Role, role)
+	if err != nil {
+		return "", err
+	}
+	if !covered {
+		return fmt.Sprintf("role %q has permissions you do not have", role), nil
+	}
+	return "", nil
+}
+
+// ExportUsers writes every user as CSV, in a format ImportUsers accepts.
+func (s *Service) ExportUsers(ctx context.Context, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "name", "email", "role", "status", "courses", "created_at"}); err != nil {
+		return err
+	}
+
+	err := s.repository.ExportUsers(ctx, func(export *model.UserExport) error {
+		status := "active"
+		if export.User.SuspendedAt != nil {
+			status = "suspended"
+		}
+
+		courses := make([]string, 0, len(export.CourseIDs))
+		for _, id := range export.CourseIDs {
+			courses = append(courses, strconv.FormatUint(id, 10))
+		}
+
+		return writer.Write([]string{
+			strconv.FormatUint(export.User.ID, 10),
+			escapeFormula(export.User.Name),
+			escapeFormula(export.User.Email),
+			export.User.Role,
+			status,
+			strings.Join(courses, ";"),
+			export.User.CreatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// escapeFormula prevents spreadsheet applications from evaluating a cell.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// newResetToken returns a random password reset token and its hash.
+func newResetToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,115 @@
+package adminservice
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
+)
+
+func TestImportUsers(t *testing.T) {
+	repository := newRepository()
+	repository.courses = []uint64{7}
+	s := newService(repository)
+
+	input := "name,email,role,courses\n" +
+		"Ada,ada@example.edu,,7\n" +
+		"Grace,grace@example.edu,instructor,\n"
+	report, err := s.ImportUsers(context.Background(), adminID, strings.NewReader(input), &dto.ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 2 || report.Created != 2 || report.Failed != 0 {
+		t.Fatalf("expected 2 users created, got %+v", report)
+	}
+	if len(repository.imported) != 2 {
+		t.Fatalf("expected 2 users imported, got %d", len(repository.imported))
+	}
+	ada := repository.imported[0]
+	if ada.User.Role != "student" || len(ada.CourseIDs) != 1 || ada.CourseIDs[0] != 7 || ada.ResetTokenHash == "" {
+		t.Errorf("imported %+v", ada)
+	}
+	if report.Rows[0].Line != 2 || report.Rows[1].Line != 3 {
+		t.Errorf("expected lines 2 and 3, got %d and %d", report.Rows[0].Line, report.Rows[1].Line)
+	}
+}
+
+func TestImportUsersRejectsInvalidRows(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Email: "taken@example.edu"})
+	s := newService(repository)
+
+	input := "name,email,role,courses\n" +
+		"Ada,ada@example.edu,,\n" +
+		"Bad \"quote,bad@example.edu,,\n" +
+		",not an email,root,x\n" +
+		"Taken,taken@example.edu,,9\n" +
+		"Again,ADA@example.edu,,\n"
+	report, err := s.ImportUsers(context.Background(), adminID, strings.NewReader(input), &dto.ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.imported) != 0 {
+		t.Error("imported users although some rows are invalid")
+	}
+	if report.Total != 5 || report.Failed != 4 || report.Created != 0 {
+		t.Fatalf("expected 4 failed rows out of 5, got %+v", report)
+	}
+
+	expected := []struct {
+		line   int
+		errors int
+	}{
+		{line: 2},
+		{line: 3, errors: 1}, // Malformed CSV
+		{line: 4, errors: 4}, // Name, email, role and course
+		{line: 5, errors: 2}, // Existing email and unknown course
+		{line: 6, errors: 1}, // Duplicated email
+	}
+	for i, row := range report.Rows {
+		if row.Line != expected[i].line || len(row.Errors) != expected[i].errors {
+			t.Errorf("row %d: expected %d errors on line %d, got %v on line %d", i, expected[i].errors, expected[i].line, row.Errors, row.Line)
+		}
+	}
+}
+
+func TestImportUsersRejectsRolesWithMoreRights(t *testing.T) {
+	repository := newRepository()
+	s := newService(repository)
+
+	input := "name,email,role\n" +
+		"Ada,ada@example.edu,instructor\n" +
+		"Eve,eve@example.edu,admin\n"
+	report, err := s.ImportUsers(context.Background(), registrarID, strings.NewReader(input), &dto.ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.imported) != 0 || report.Failed != 1 {
+		t.Fatalf("expected the admin row to fail, got %+v", report)
+	}
+	if len(report.Rows[0].Errors) != 0 || len(report.Rows[1].Errors) != 1 {
+		t.Errorf("unexpected errors %v and %v", report.Rows[0].Errors, report.Rows[1].Errors)
+	}
+}
+
+func TestImportUsersRequiresColumns(t *testing.T) {
+	s := newService(newRepository())
+	if _, err := s.ImportUsers(context.Background(), adminID, strings.NewReader("name,role\nAda,student\n"), &dto.ImportOptions{}); err == nil {
+		t.Error("imported a CSV without an email column")
+	}
+}
+
+func TestExportUsersEscapesFormulas(t *testing.T) {
+	repository := newRepository(&model.User{ID: 10, Name: "=HYPERLINK(\"http://evil\")", Email: "a@example.edu", Role: "student"})
+	s := newService(repository)
+
+	var out bytes.Buffer
+	if err := s.ExportUsers(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"'=HYPERLINK(""http://evil"")"`) {
+		t.Errorf("formula is not escaped: %s", out.String())
+	}
+}
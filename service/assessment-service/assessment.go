@@ -5,13 +5,14 @@ import (
 
 	"github.com/dapthehuman/learning-management-system/database/models"
 	dto "github.com/dapthehuman/learning-management-system/dto/assesment"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
 type Repository interface {
 	Create(ctx context.Context, assessment *models.Assessment) (*models.Assessment, error)
-	GetAllByCourseID(ctx context.Context, courseID uint64) ([]*models.Assessment, error)
+	ListByCourse(ctx context.Context, courseID uint64, userID uint64, q *listing.Query) (*listing.Page[*models.Assessment], error)
 	GetByID(ctx context.Context, assessmentID uint64) (*models.Assessment, error)
 	SubmitAnswer(ctx context.Context, submission *models.Submission) (*models.Submission, error)
 }
@@ -36,13 +37,15 @@ func (s *Service) CreateAssessment(ctx context.Context, createDTO *dto.CreateAss
 	return typeutil.MustConvert[*dto.Assessment](createdAssessment), nil
 }
 
-func (s *Service) GetAssessmentByCourseID(ctx context.Context, courseID uint64) ([]*dto.Assessment, error) {
-	assessments, err := s.repository.GetAllByCourseID(ctx, courseID)
+// GetAssessmentByCourseID returns a page of the assessments of the course,
+// with the due dates of the cohort of the user.
+func (s *Service) GetAssessmentByCourseID(ctx context.Context, courseID uint64, userID uint64, q *listing.Query) (*listing.Page[*dto.Assessment], error) {
+	assessments, err := s.repository.ListByCourse(ctx, courseID, userID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.Assessment](assessments), nil
+	return typeutil.MustConvert[*listing.Page[*dto.Assessment]](assessments), nil
 }
 
 func (s *Service) GetAssessmentByID(ctx context.Context, assessmentID uint64) (*dto.Assessment, error) {
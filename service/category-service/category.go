@@ -0,0 +1,139 @@
+package categoryservice
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/category"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// slugPattern matches the slugs categories are filtered by in the catalog.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// slugSeparators are replaced by hyphens when a slug is derived from a name.
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]*model.Category, error)
+	GetByID(ctx context.Context, id uint64) (*model.Category, error)
+	Create(ctx context.Context, category *model.Category) (*model.Category, error)
+	Update(ctx context.Context, category *model.Category) (*model.Category, error)
+	Delete(ctx context.Context, id uint64) error
+}
+
+type Service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *Service {
+	return &Service{
+		repository: repository,
+	}
+}
+
+// GetAll returns every category, parents first.
+func (s *Service) GetAll(ctx context.Context) ([]*dto.Category, error) {
+	categories, err := s.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[[]*dto.Category](categories), nil
+}
+
+func (s *Service) Create(ctx context.Context, createDTO *dto.SaveCategoryRequest) (*dto.Category, error) {
+	category, err := s.validate(ctx, 0, createDTO)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repository.Create(ctx, category)
+	if err != nil {
+		return nil, errors.New("A category with this slug already exists")
+	}
+
+	return typeutil.MustConvert[*dto.Category](created), nil
+}
+
+// Update renames the category, or moves it under another parent.
+func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.SaveCategoryRequest) (*dto.Category, error) {
+	existing, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("Category not found")
+	}
+
+	category, err := s.validate(ctx, id, updateDTO)
+	if err != nil {
+		return nil, err
+	}
+	category.ID = id
+
+	updated, err := s.repository.Update(ctx, category)
+	if err != nil {
+		return nil, errors.New("A category with this slug already exists")
+	}
+
+	return typeutil.MustConvert[*dto.Category](updated), nil
+}
+
+// Delete deletes the category along with its subcategories. Their courses
+// are kept, without a category.
+func (s *Service) Delete(ctx context.Context, id uint64) error {
+	return s.repository.Delete(ctx, id)
+}
+
+// validate checks the request and returns the category it describes. id is
+// the category being updated, 0 for a new one: it cannot be moved under
+// itself or one of its subcategories.
+func (s *Service) validate(ctx context.Context, id uint64, saveDTO *dto.SaveCategoryRequest) (*model.Category, error) {
+	name := strings.TrimSpace(saveDTO.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(saveDTO.Slug))
+	if slug == "" {
+		slug = strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	}
+	if !slugPattern.MatchString(slug) || len(slug) > 100 {
+		return nil, errors.New("the slug must be made of lowercase letters, digits and hyphens")
+	}
+
+	if saveDTO.ParentID != nil {
+		categories, err := s.repository.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		parents := make(map[uint64]*uint64, len(categories))
+		for _, category := range categories {
+			parents[category.ID] = category.ParentID
+		}
+
+		if _, ok := parents[*saveDTO.ParentID]; !ok {
+			return nil, errors.New("Parent category not found")
+		}
+		for parentID := saveDTO.ParentID; parentID != nil; parentID = parents[*parentID] {
+			if *parentID == id {
+				return nil, errors.New("A category cannot be moved under itself or one of its subcategories")
+			}
+		}
+	}
+
+	return &model.Category{
+		ParentID: saveDTO.ParentID,
+		Name:     name,
+		Slug:     slug,
+	}, nil
+}
+
+func (s *Service) Name() string {
+	return service.Category
+}
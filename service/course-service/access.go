@@ -0,0 +1,64 @@
+package courseservice
+
+import (
+	"context"
+
+	"github.com/dapthehuman/learning-management-system/dto"
+)
+
+// ContentAccess tells which sections of a course a user can read. Students
+// enrolled in the course, its staff and users allowed to manage any course
+// read all of them, the others only its preview sections.
+type ContentAccess struct {
+	full     bool
+	previews map[uint64]bool
+}
+
+// ContentAccess returns the sections of the course the viewer can read. It
+// does not check that the viewer can see the course.
+func (s *Service) ContentAccess(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*ContentAccess, error) {
+	previews, err := s.repository.PreviewSections(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	access := &ContentAccess{previews: make(map[uint64]bool, len(previews))}
+	for _, id := range previews {
+		access.previews[id] = true
+	}
+
+	if viewer.ManageAny {
+		access.full = true
+		return access, nil
+	}
+
+	role, err := s.repository.GetStaffRole(ctx, courseID, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		access.full = true
+		return access, nil
+	}
+
+	access.full, err = s.repository.IsEnrolled(ctx, courseID, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// Full reports whether the user can read all the content of the course,
+// including its assessments.
+func (a *ContentAccess) Full() bool {
+	return a.full
+}
+
+// Section reports whether the user can read the section and its materials.
+func (a *ContentAccess) Section(id uint64) bool {
+	return a.full || a.previews[id]
+}
+
+// Preview reports whether the section is a preview section.
+func (a *ContentAccess) Preview(id uint64) bool {
+	return a.previews[id]
+}
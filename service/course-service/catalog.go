@@ -0,0 +1,228 @@
+package courseservice
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// Values of the duration facet, from the estimated duration of the courses.
+const (
+	DurationUnderHour   = "under_1h"
+	DurationFewHours    = "1_5h"
+	DurationManyHours   = "5_20h"
+	DurationOver20Hours = "over_20h"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// languagePattern matches the BCP 47 tags courses are given, e.g. "en" or
+// "pt-BR".
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Catalog returns a page of the listed courses matching the filter, along
+// with the number of courses for each category, tag, level, language and
+// duration.
+func (s *Service) Catalog(ctx context.Context, filter *dto.CatalogFilter, q *listing.Query) (*dto.Catalog, error) {
+	courses, err := s.repository.GetPublished(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[uint64]*uint64, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	var inCategory func(course *model.Course) bool
+	if filter.Category != "" {
+		i := slices.IndexFunc(categories, func(category *model.Category) bool {
+			return category.Slug == filter.Category || strconv.FormatUint(category.ID, 10) == filter.Category
+		})
+		if i == -1 {
+			return nil, errors.New("Category not found")
+		}
+		inCategory = func(course *model.Course) bool {
+			return course.CategoryID != nil && slices.Contains(ancestors(parents, *course.CategoryID), categories[i].ID)
+		}
+	}
+
+	tags := normalizeTags(filter.Tags)
+	matches := func(course *model.Course, ignored string) bool {
+		return (ignored == "category" || inCategory == nil || inCategory(course)) &&
+			(ignored == "level" || filter.Level == "" || course.Level == filter.Level) &&
+			(ignored == "language" || filter.Language == "" || strings.EqualFold(course.Language, filter.Language)) &&
+			(ignored == "duration" || filter.Duration == "" || duration(course.EstimatedMinutes) == filter.Duration) &&
+			!slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(course.Tags, tag) })
+	}
+
+	now := time.Now()
+	found := make([]*model.Course, 0)
+	categoryCounts := make(map[uint64]int)
+	tagCounts := make(map[string]int)
+	levelCounts := make(map[string]int)
+	languageCounts := make(map[string]int)
+	durationCounts := make(map[string]int)
+	for _, course := range courses {
+		if !course.Listed(now) {
+			continue
+		}
+		if matches(course, "") {
+			found = append(found, course)
+			for _, tag := range course.Tags {
+				tagCounts[tag]++
+			}
+		}
+		if course.CategoryID != nil && matches(course, "category") {
+			for _, id := range ancestors(parents, *course.CategoryID) {
+				categoryCounts[id]++
+			}
+		}
+		if course.Level != "" && matches(course, "level") {
+			levelCounts[course.Level]++
+		}
+		if course.Language != "" && matches(course, "language") {
+			languageCounts[course.Language]++
+		}
+		if course.EstimatedMinutes > 0 && matches(course, "duration") {
+			durationCounts[duration(course.EstimatedMinutes)]++
+		}
+	}
+
+	categoryFacets := make([]*dto.CategoryFacet, 0, len(categoryCounts))
+	for _, category := range categories {
+		if count := categoryCounts[category.ID]; count > 0 {
+			categoryFacets = append(categoryFacets, &dto.CategoryFacet{
+				ID:       category.ID,
+				ParentID: category.ParentID,
+				Name:     category.Name,
+				Slug:     category.Slug,
+				Count:    count,
+			})
+		}
+	}
+
+	page, err := listing.Slice(typeutil.MustConvert[[]*dto.Course](found), q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.Catalog{
+		Page: *page,
+		Facets: dto.CatalogFacets{
+			Categories: categoryFacets,
+			Tags:       facets(tagCounts),
+			Levels:     facets(levelCounts),
+			Languages:  facets(languageCounts),
+			Durations:  facets(durationCounts),
+		},
+	}, nil
+}
+
+// validateCatalogFields checks and normalizes the catalog fields of the
+// course.
+func (s *Service) validateCatalogFields(ctx context.Context, course *model.Course) error {
+	switch course.Level {
+	case "", model.LevelBeginner, model.LevelIntermediate, model.LevelAdvanced:
+	default:
+		return errors.New("level must be beginner, intermediate or advanced")
+	}
+
+	if course.Language != "" && !languagePattern.MatchString(course.Language) {
+		return errors.New("language must be a language tag such as \"en\" or \"pt-BR\"")
+	}
+	if course.EstimatedMinutes < 0 {
+		return errors.New("estimated_minutes cannot be negative")
+	}
+
+	course.Tags = normalizeTags(course.Tags)
+	if len(course.Tags) > maxTags {
+		return errors.New("A course cannot have more than 20 tags")
+	}
+	for _, tag := range course.Tags {
+		if len(tag) > maxTagLength {
+			return errors.New("Tags cannot be longer than 50 characters")
+		}
+	}
+
+	if course.CategoryID != nil {
+		category, err := s.categoryRepository.GetByID(ctx, *course.CategoryID)
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return errors.New("Category not found")
+		}
+	}
+	return nil
+}
+
+// normalizeTags lowercases the tags and removes the blank and duplicate ones.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// ancestors returns the category and the categories it is nested under.
+func ancestors(parents map[uint64]*uint64, id uint64) []uint64 {
+	ids := []uint64{id}
+	for parentID := parents[id]; parentID != nil && !slices.Contains(ids, *parentID); parentID = parents[*parentID] {
+		ids = append(ids, *parentID)
+	}
+	return ids
+}
+
+// duration returns the value of the duration facet of a course taking the
+// given number of minutes.
+func duration(minutes int) string {
+	switch {
+	case minutes <= 0:
+		return ""
+	case minutes < 60:
+		return DurationUnderHour
+	case minutes <= 5*60:
+		return DurationFewHours
+	case minutes <= 20*60:
+		return DurationManyHours
+	default:
+		return DurationOver20Hours
+	}
+}
+
+// facets returns the counted values, most frequent first.
+func facets(counts map[string]int) []*dto.Facet {
+	result := make([]*dto.Facet, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, &dto.Facet{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
@@ -0,0 +1,96 @@
+package courseservice
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// maxShiftDays is the largest offset, in days, dates can be shifted by when
+// a course is cloned.
+const maxShiftDays = 3650
+
+// Clone copies a course with its content into a new draft owned by the
+// viewer. Templates can be cloned by anyone allowed to create courses, other
+// courses only by their owners and instructors.
+func (s *Service) Clone(ctx context.Context, viewer *dto.CourseViewer, id uint64, cloneDTO *dto.CloneCourseRequest) (*dto.Course, error) {
+	if cloneDTO.ShiftDays > maxShiftDays || cloneDTO.ShiftDays < -maxShiftDays {
+		return nil, errors.New("shift_days must be between -3650 and 3650")
+	}
+
+	source, err := s.repository.First(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || source.ID == 0 {
+		return nil, errors.New("Course not found")
+	}
+
+	if !source.IsTemplate && !viewer.ManageAny {
+		role, err := s.repository.GetStaffRole(ctx, id, viewer.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if role != "owner" && role != "instructor" {
+			return nil, errors.New("Course not found")
+		}
+	}
+
+	course, err := s.repository.Clone(ctx, id, &model.CourseClone{
+		Title:      cloneDTO.Title,
+		IsTemplate: cloneDTO.IsTemplate,
+		Shift:      time.Duration(cloneDTO.ShiftDays) * 24 * time.Hour,
+	}, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil {
+		return nil, errors.New("Course not found")
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"course_id":  course.ID,
+		"source_id":  id,
+		"shift_days": cloneDTO.ShiftDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:  &viewer.UserID,
+		Action:   "course.clone",
+		Metadata: raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Course](course), nil
+}
+
+// SetTemplate makes the course available to clone to everyone allowed to
+// create courses, or stops it.
+func (s *Service) SetTemplate(ctx context.Context, id uint64, templateDTO *dto.TemplateCourseRequest) (*dto.Course, error) {
+	course, err := s.repository.SetTemplate(ctx, id, templateDTO.IsTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Course](course), nil
+}
+
+// Templates returns a page of the templates of the organization.
+func (s *Service) Templates(ctx context.Context, q *listing.Query) (*listing.Page[*dto.Course], error) {
+	courses, err := s.repository.ListTemplates(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.Course]](courses), nil
+}
@@ -0,0 +1,191 @@
+package courseservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// maxCohortMembers bounds the students moved to a cohort at once.
+const maxCohortMembers = 500
+
+func (s *Service) GetCohorts(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.Cohort], error) {
+	cohorts, err := s.repository.ListCohorts(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.Cohort]](cohorts), nil
+}
+
+func (s *Service) GetCohort(ctx context.Context, courseID uint64, cohortID uint64) (*dto.Cohort, error) {
+	cohort, err := s.repository.GetCohort(ctx, courseID, cohortID)
+	if err != nil {
+		return nil, err
+	}
+	if cohort == nil {
+		return nil, errors.New("Cohort not found")
+	}
+
+	return typeutil.MustConvert[*dto.Cohort](cohort), nil
+}
+
+func (s *Service) CreateCohort(ctx context.Context, courseID uint64, saveDTO *dto.SaveCohortRequest) (*dto.Cohort, error) {
+	cohort, err := cohortFromRequest(courseID, saveDTO)
+	if err != nil {
+		return nil, err
+	}
+
+	cohort, err = s.repository.CreateCohort(ctx, cohort)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Cohort](cohort), nil
+}
+
+// UpdateCohort saves the name and schedule of the cohort and replaces its
+// staff and due dates.
+func (s *Service) UpdateCohort(ctx context.Context, courseID uint64, cohortID uint64, saveDTO *dto.SaveCohortRequest) (*dto.Cohort, error) {
+	cohort, err := cohortFromRequest(courseID, saveDTO)
+	if err != nil {
+		return nil, err
+	}
+	cohort.ID = cohortID
+
+	cohort, err = s.repository.UpdateCohort(ctx, cohort)
+	if err != nil {
+		return nil, err
+	}
+	if cohort == nil {
+		return nil, errors.New("Cohort not found")
+	}
+
+	return typeutil.MustConvert[*dto.Cohort](cohort), nil
+}
+
+func (s *Service) DeleteCohort(ctx context.Context, courseID uint64, cohortID uint64) error {
+	deleted, err := s.repository.DeleteCohort(ctx, courseID, cohortID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("Cohort not found")
+	}
+	return nil
+}
+
+// AddCohortMembers moves students enrolled in the course to the cohort, from
+// the cohort they were in if any.
+func (s *Service) AddCohortMembers(ctx context.Context, courseID uint64, cohortID uint64, membersDTO *dto.CohortMembersRequest) error {
+	userIDs := slices.Clone(membersDTO.UserIDs)
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+	if len(userIDs) == 0 {
+		return errors.New("No students to add")
+	}
+	if len(userIDs) > maxCohortMembers {
+		return errors.New(fmt.Sprintf("At most %d students can be added at once", maxCohortMembers))
+	}
+
+	found, err := s.repository.AddCohortMembers(ctx, courseID, cohortID, userIDs)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("Cohort not found")
+	}
+	return nil
+}
+
+func (s *Service) RemoveCohortMember(ctx context.Context, courseID uint64, cohortID uint64, userID uint64) error {
+	removed, err := s.repository.RemoveCohortMember(ctx, courseID, cohortID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("This student is not part of the cohort")
+	}
+	return nil
+}
+
+// cohortFromRequest validates the request and returns the cohort it
+// describes.
+func cohortFromRequest(courseID uint64, saveDTO *dto.SaveCohortRequest) (*model.Cohort, error) {
+	name := strings.TrimSpace(saveDTO.Name)
+	if name == "" {
+		return nil, errors.New("The name of the cohort is required")
+	}
+	if saveDTO.StartsAt != nil && saveDTO.EndsAt != nil && !saveDTO.EndsAt.After(*saveDTO.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+
+	cohort := &model.Cohort{
+		CourseID: courseID,
+		Name:     name,
+		StartsAt: saveDTO.StartsAt,
+		EndsAt:   saveDTO.EndsAt,
+		Staff:    make([]*model.CohortStaff, 0, len(saveDTO.StaffIDs)),
+		DueDates: make([]*model.CohortDueDate, 0, len(saveDTO.DueDates)),
+	}
+	for _, userID := range saveDTO.StaffIDs {
+		if slices.ContainsFunc(cohort.Staff, func(m *model.CohortStaff) bool { return m.UserID == userID }) {
+			return nil, errors.New(fmt.Sprintf("User %d is listed twice", userID))
+		}
+		cohort.Staff = append(cohort.Staff, &model.CohortStaff{UserID: userID})
+	}
+	for _, dueDate := range saveDTO.DueDates {
+		if slices.ContainsFunc(cohort.DueDates, func(d *model.CohortDueDate) bool { return d.AssessmentID == dueDate.AssessmentID }) {
+			return nil, errors.New(fmt.Sprintf("Assessment %d is listed twice", dueDate.AssessmentID))
+		}
+		cohort.DueDates = append(cohort.DueDates, &model.CohortDueDate{AssessmentID: dueDate.AssessmentID, DueAt: dueDate.DueAt})
+	}
+
+	return cohort, nil
+}
+
+// GetGradebook returns a page of the grades of the students enrolled in the
+// course, filterable by cohort.
+func (s *Service) GetGradebook(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.GradebookEntry], error) {
+	entries, err := s.repository.Gradebook(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.GradebookEntry]](entries), nil
+}
+
+// GetProgress returns a page of how far each student enrolled in the course
+// is through its materials, filterable by cohort.
+func (s *Service) GetProgress(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.StudentProgress], error) {
+	progress, err := s.repository.ListProgress(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return listing.Map(progress, func(p *model.StudentProgress) *dto.StudentProgress {
+		result := typeutil.MustConvert[*dto.StudentProgress](p)
+		if p.Total > 0 {
+			result.Percentage = p.Completed * 100 / p.Total
+		}
+		return result
+	}), nil
+}
+
+// GetSubmissions returns a page of the submissions to the assessments of the
+// course, filterable by cohort.
+func (s *Service) GetSubmissions(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseSubmission], error) {
+	submissions, err := s.repository.ListSubmissions(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.CourseSubmission]](submissions), nil
+}
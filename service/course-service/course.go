@@ -2,10 +2,12 @@ package courseservice
 
 import (
 	"context"
+	"time"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
 	"github.com/dapthehuman/learning-management-system/dto"
 	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
 	"goyave.dev/goyave/v5/util/errors"
 	"goyave.dev/goyave/v5/util/typeutil"
@@ -13,25 +15,79 @@ import (
 
 type Repository interface {
 	First(ctx context.Context, id uint64) (*model.Course, error)
-	GetAll(ctx context.Context) ([]*model.Course, error)
-	Create(ctx context.Context, course *model.Course) (*model.Course, error)
+	List(ctx context.Context, userID uint64, all bool, q *listing.Query) (*listing.Page[*model.Course], error)
+	GetPublished(ctx context.Context) ([]*model.Course, error)
+	ListByStatus(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.Course], error)
+	ChangeStatus(ctx context.Context, course *model.Course, from ...string) (*model.Course, error)
+	ReviewRequired(ctx context.Context) (bool, error)
+	IsEnrolled(ctx context.Context, courseID uint64, userID uint64) (bool, error)
+	PreviewSections(ctx context.Context, courseID uint64) ([]uint64, error)
+	Create(ctx context.Context, course *model.Course, ownerID uint64) (*model.Course, error)
 	Update(ctx context.Context, course *model.Course) (*model.Course, error)
 	Delete(ctx context.Context, id uint64) error
+	Clone(ctx context.Context, id uint64, clone *model.CourseClone, ownerID uint64) (*model.Course, error)
+	SetTemplate(ctx context.Context, id uint64, isTemplate bool) (*model.Course, error)
+	ListTemplates(ctx context.Context, q *listing.Query) (*listing.Page[*model.Course], error)
 
 	CreateCurriculum(ctx context.Context, courseID uint64, curriculum *model.Curriculum) (*model.Curriculum, error)
-	GetCurriculum(ctx context.Context, courseID uint64) ([]*model.Curriculum, error)
+	ListCurriculum(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.Curriculum], error)
 	GetCurriculumByID(ctx context.Context, id uint64) (*model.Curriculum, error)
 	UpdateCurriculum(ctx context.Context, curriculum *model.Curriculum) (*model.Curriculum, error)
-	DeleteCurriculum(ctx context.Context, id uint64) error
+	DeleteCurriculum(ctx context.Context, id uint64) ([]uint64, error)
+	MoveCurriculum(ctx context.Context, courseID uint64, id uint64, parentID *uint64, position int) ([]*model.Curriculum, error)
+	SectionParents(ctx context.Context, courseID uint64) (map[uint64]uint64, error)
+	Outline(ctx context.Context, courseID uint64) ([]*model.Curriculum, []*model.Material, error)
+	ReorderOutline(ctx context.Context, courseID uint64, sections []*model.Curriculum, materials []*model.Material) ([]*model.Curriculum, []*model.Material, error)
+
+	ListStaff(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseStaff], error)
+	GetStaffRole(ctx context.Context, courseID uint64, userID uint64) (string, error)
+	SaveStaff(ctx context.Context, member *model.CourseStaff) (*model.CourseStaff, error)
+	DeleteStaff(ctx context.Context, courseID uint64, userID uint64) error
+	CountOwners(ctx context.Context, courseID uint64) (int64, error)
+
+	GetRequirements(ctx context.Context, courseID uint64) (*model.CourseRequirements, error)
+	SaveRequirements(ctx context.Context, requirements *model.CourseRequirements) (*model.CourseRequirements, error)
+
+	ListCohorts(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.Cohort], error)
+	GetCohort(ctx context.Context, courseID uint64, cohortID uint64) (*model.Cohort, error)
+	CreateCohort(ctx context.Context, cohort *model.Cohort) (*model.Cohort, error)
+	UpdateCohort(ctx context.Context, cohort *model.Cohort) (*model.Cohort, error)
+	DeleteCohort(ctx context.Context, courseID uint64, cohortID uint64) (bool, error)
+	AddCohortMembers(ctx context.Context, courseID uint64, cohortID uint64, userIDs []uint64) (bool, error)
+	RemoveCohortMember(ctx context.Context, courseID uint64, cohortID uint64, userID uint64) (bool, error)
+
+	Gradebook(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.GradebookEntry], error)
+	ListProgress(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.StudentProgress], error)
+	ListSubmissions(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseSubmission], error)
+
+	ListReleaseRules(ctx context.Context, courseID uint64) ([]*model.ReleaseRule, error)
+	SaveReleaseRule(ctx context.Context, rule *model.ReleaseRule) (*model.ReleaseRule, error)
+	DeleteReleaseRule(ctx context.Context, courseID uint64, id uint64) (bool, error)
+	ReleaseState(ctx context.Context, courseID uint64, userID uint64) (*time.Time, []uint64, error)
+}
+
+type CategoryRepository interface {
+	GetAll(ctx context.Context) ([]*model.Category, error)
+	GetByID(ctx context.Context, id uint64) (*model.Category, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
 }
 
 type Service struct {
-	repository Repository
+	repository         Repository
+	auditRepository    AuditRepository
+	versionRepository  VersionRepository
+	categoryRepository CategoryRepository
 }
 
-func NewService(repository Repository) *Service {
+func NewService(repository Repository, auditRepository AuditRepository, versionRepository VersionRepository, categoryRepository CategoryRepository) *Service {
 	return &Service{
-		repository: repository,
+		repository:         repository,
+		auditRepository:    auditRepository,
+		versionRepository:  versionRepository,
+		categoryRepository: categoryRepository,
 	}
 }
 
@@ -48,18 +104,79 @@ func (s *Service) GetByID(ctx context.Context, id uint64) (*dto.Course, error) {
 	return typeutil.MustConvert[*dto.Course](course), nil
 }
 
-func (s *Service) GetAll(ctx context.Context) ([]*dto.Course, error) {
-	courses, err := s.repository.GetAll(ctx)
+// Show returns the course if the viewer can see it: listed courses are
+// visible to everyone, the others only to their staff, reviewers for the
+// ones in review, and enrolled students for the archived ones.
+func (s *Service) Show(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+	course, err := s.repository.First(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || course.ID == 0 {
+		return nil, errors.New("Course not found")
+	}
+
+	visible, err := s.visible(ctx, viewer, course)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, errors.New("Course not found")
+	}
+
+	result := typeutil.MustConvert[*dto.Course](course)
+	content, err := s.PublishedContent(ctx, viewer, id)
+	if err != nil {
+		return nil, err
+	}
+	if content != nil {
+		result.Title = content.Course.Title
+		result.Description = content.Course.Description
+	}
+	return result, nil
+}
+
+func (s *Service) visible(ctx context.Context, viewer *dto.CourseViewer, course *model.Course) (bool, error) {
+	if course.Listed(time.Now()) || viewer.ManageAny || (viewer.CanReview && course.Status == model.CourseInReview) {
+		return true, nil
+	}
+
+	role, err := s.repository.GetStaffRole(ctx, course.ID, viewer.UserID)
+	if err != nil || role != "" {
+		return role != "", err
+	}
+
+	if course.Status == model.CourseArchived {
+		return s.repository.IsEnrolled(ctx, course.ID, viewer.UserID)
+	}
+	return false, nil
+}
+
+// GetAll returns a page of the catalog, along with the courses the viewer is
+// part of the staff of whatever their status. Users granted
+// "course.manage_any" get every course.
+func (s *Service) GetAll(ctx context.Context, viewer *dto.CourseViewer, q *listing.Query) (*listing.Page[*dto.Course], error) {
+	courses, err := s.repository.List(ctx, viewer.UserID, viewer.ManageAny, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.Course](courses), nil
+	return typeutil.MustConvert[*listing.Page[*dto.Course]](courses), nil
 }
 
-func (s *Service) Create(ctx context.Context, createDTO *dto.CreateCourseRequest) (*dto.Course, error) {
+// Create creates a course owned by the given user.
+func (s *Service) Create(ctx context.Context, ownerID uint64, createDTO *dto.CreateCourseRequest) (*dto.Course, error) {
 	course := typeutil.MustConvert[*model.Course](createDTO)
-	createdCourse, err := s.repository.Create(ctx, course)
+	if err := s.validateCatalogFields(ctx, course); err != nil {
+		return nil, err
+	}
+
+	createdCourse, err := s.repository.Create(ctx, course, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.record(ctx, ownerID, createdCourse.ID, model.VersionCourse, createdCourse.ID, "create", createdCourse.VersionData())
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +184,7 @@ func (s *Service) Create(ctx context.Context, createDTO *dto.CreateCourseRequest
 	return typeutil.MustConvert[*dto.Course](createdCourse), nil
 }
 
-func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.UpdateCourseRequest) (*dto.Course, error) {
+func (s *Service) Update(ctx context.Context, actorID uint64, id uint64, updateDTO *dto.UpdateCourseRequest) (*dto.Course, error) {
 	course, err := s.repository.First(ctx, id)
 	if err != nil {
 		return nil, err
@@ -79,11 +196,20 @@ func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.UpdateCo
 
 	nCourse := typeutil.MustConvert[*model.Course](updateDTO)
 	nCourse.ID = id
+	if err := s.validateCatalogFields(ctx, nCourse); err != nil {
+		return nil, err
+	}
+
 	updatedCourse, err := s.repository.Update(ctx, nCourse)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.record(ctx, actorID, id, model.VersionCourse, id, "update", updatedCourse.VersionData())
+	if err != nil {
+		return nil, err
+	}
+
 	return typeutil.MustConvert[*dto.Course](updatedCourse), nil
 }
 
@@ -100,23 +226,38 @@ func (s *Service) Delete(ctx context.Context, id uint64) error {
 	return s.repository.Delete(ctx, id)
 }
 
-func (s *Service) CreateCurriculum(ctx context.Context, courseID uint64, createDTO *curriculumDto.CreateCurriculumRequest) (*curriculumDto.Curriculum, error) {
+func (s *Service) CreateCurriculum(ctx context.Context, actorID uint64, courseID uint64, createDTO *curriculumDto.CreateCurriculumRequest) (*curriculumDto.Curriculum, error) {
+	if createDTO.ParentID != nil {
+		parent, err := s.repository.GetCurriculumByID(ctx, *createDTO.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.CourseID != courseID {
+			return nil, errors.New("Parent section not found")
+		}
+	}
+
 	curriculum := typeutil.MustConvert[*model.Curriculum](createDTO)
 	createdCurriculum, err := s.repository.CreateCurriculum(ctx, courseID, curriculum)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.record(ctx, actorID, courseID, model.VersionCurriculum, createdCurriculum.ID, "create", createdCurriculum.VersionData())
+	if err != nil {
+		return nil, err
+	}
+
 	return typeutil.MustConvert[*curriculumDto.Curriculum](createdCurriculum), nil
 }
 
-func (s *Service) GetCurriculumByCourseID(ctx context.Context, courseID uint64) ([]*curriculumDto.Curriculum, error) {
-	curriculum, err := s.repository.GetCurriculum(ctx, courseID)
+func (s *Service) GetCurriculumByCourseID(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*curriculumDto.Curriculum], error) {
+	curriculum, err := s.repository.ListCurriculum(ctx, courseID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*curriculumDto.Curriculum](curriculum), nil
+	return typeutil.MustConvert[*listing.Page[*curriculumDto.Curriculum]](curriculum), nil
 }
 
 func (s *Service) GetCurriculumByID(ctx context.Context, id uint64) (*curriculumDto.Curriculum, error) {
@@ -128,18 +269,70 @@ func (s *Service) GetCurriculumByID(ctx context.Context, id uint64) (*curriculum
 	return typeutil.MustConvert[*curriculumDto.Curriculum](curriculum), nil
 }
 
-func (s *Service) UpdateCurriculum(ctx context.Context, courseID uint64, updateDTO *curriculumDto.UpdateCurriculumRequest) (*curriculumDto.Curriculum, error) {
+func (s *Service) UpdateCurriculum(ctx context.Context, actorID uint64, id uint64, updateDTO *curriculumDto.UpdateCurriculumRequest) (*curriculumDto.Curriculum, error) {
 	curriculum := typeutil.MustConvert[*model.Curriculum](updateDTO)
+	curriculum.ID = id
+	if updateDTO.IsPreview == nil {
+		current, err := s.repository.GetCurriculumByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		curriculum.IsPreview = current.IsPreview
+	}
 	updatedCurriculum, err := s.repository.UpdateCurriculum(ctx, curriculum)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.record(ctx, actorID, updatedCurriculum.CourseID, model.VersionCurriculum, id, "update", updatedCurriculum.VersionData())
+	if err != nil {
+		return nil, err
+	}
+
 	return typeutil.MustConvert[*curriculumDto.Curriculum](updatedCurriculum), nil
 }
 
-func (s *Service) DeleteCurriculum(ctx context.Context, id uint64) error {
-	return s.repository.DeleteCurriculum(ctx, id)
+// MoveCurriculum moves the section, with the sections nested in it, under
+// another section of the course or to its top level, renumbering the
+// sections around it.
+func (s *Service) MoveCurriculum(ctx context.Context, actorID uint64, courseID uint64, id uint64, moveDTO *curriculumDto.MoveCurriculumRequest) ([]*curriculumDto.Curriculum, error) {
+	moved, err := s.repository.MoveCurriculum(ctx, courseID, id, moveDTO.ParentID, moveDTO.Position)
+	if err != nil {
+		return nil, err
+	}
+	if moved == nil {
+		return nil, errors.New("Curriculum not found")
+	}
+
+	for _, curriculum := range moved {
+		err := s.record(ctx, actorID, courseID, model.VersionCurriculum, curriculum.ID, "update", curriculum.VersionData())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return typeutil.MustConvert[[]*curriculumDto.Curriculum](moved), nil
+}
+
+func (s *Service) DeleteCurriculum(ctx context.Context, actorID uint64, id uint64) error {
+	curriculum, err := s.repository.GetCurriculumByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if curriculum == nil || curriculum.ID == 0 {
+		return errors.New("Curriculum not found")
+	}
+
+	deleted, err := s.repository.DeleteCurriculum(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, sectionID := range deleted {
+		if err := s.record(ctx, actorID, curriculum.CourseID, model.VersionCurriculum, sectionID, "delete", nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Service) Name() string {
@@ -0,0 +1,173 @@
+package courseservice
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+	materialDto "github.com/dapthehuman/learning-management-system/dto/material"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// Outline returns the sections of the course as the viewer reads them, nested
+// in each other, with their materials. Students read the published content,
+// and see the sections they cannot read only when one of their sections is
+// a preview, without their materials. The content of locked materials is
+// left out. It does not check that the viewer can see the course.
+func (s *Service) Outline(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) ([]*dto.OutlineSection, error) {
+	content, err := s.PublishedContent(ctx, viewer, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		curriculums, materials, err := s.repository.Outline(ctx, courseID)
+		if err != nil {
+			return nil, err
+		}
+		content = &dto.CourseContent{
+			Curriculums: typeutil.MustConvert[[]*curriculumDto.Curriculum](curriculums),
+			Materials:   typeutil.MustConvert[[]*materialDto.MaterialResponse](materials),
+		}
+	}
+
+	access, err := s.ContentAccess(ctx, viewer, courseID)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := s.Releases(ctx, viewer, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(content.Curriculums, func(i, j int) bool {
+		a, b := content.Curriculums[i], content.Curriculums[j]
+		return a.SectionOrder < b.SectionOrder || a.SectionOrder == b.SectionOrder && a.ID < b.ID
+	})
+	sort.SliceStable(content.Materials, func(i, j int) bool {
+		a, b := content.Materials[i], content.Materials[j]
+		return a.Order < b.Order || a.Order == b.Order && a.ID < b.ID
+	})
+
+	sections := make(map[uint64]*dto.OutlineSection, len(content.Curriculums))
+	for _, curriculum := range content.Curriculums {
+		id := uint64(curriculum.ID)
+		curriculum.IsPreview = access.Preview(id)
+		curriculum.Lock = releases.Curriculum(id)
+		sections[id] = &dto.OutlineSection{
+			Curriculum: curriculum,
+			Materials:  make([]*materialDto.MaterialResponse, 0),
+			Sections:   make([]*dto.OutlineSection, 0),
+		}
+	}
+	for _, material := range content.Materials {
+		curriculumID := uint64(material.CurriculumID)
+		section, ok := sections[curriculumID]
+		if !ok || !access.Section(curriculumID) {
+			continue
+		}
+		material.Lock = releases.Material(uint64(material.ID), curriculumID)
+		if material.Lock != nil {
+			material.Content = ""
+		}
+		section.Materials = append(section.Materials, material)
+	}
+
+	outline := make([]*dto.OutlineSection, 0)
+	for _, curriculum := range content.Curriculums {
+		section := sections[uint64(curriculum.ID)]
+		if parent, ok := sections[ptrValue(curriculum.ParentID)]; ok {
+			parent.Sections = append(parent.Sections, section)
+		} else {
+			outline = append(outline, section)
+		}
+	}
+	return readable(outline, access), nil
+}
+
+// ReorderOutline places all the sections and materials of the course as
+// listed, numbering the sections and the materials of each section from 1,
+// and returns the new outline. Sections and materials can change parents,
+// but not course.
+func (s *Service) ReorderOutline(ctx context.Context, viewer *dto.CourseViewer, courseID uint64, reorderDTO *dto.ReorderOutlineRequest) ([]*dto.OutlineSection, error) {
+	sections := make([]*model.Curriculum, 0)
+	materials := make([]*model.Material, 0)
+	seenSections := make(map[uint64]bool)
+	seenMaterials := make(map[uint64]bool)
+
+	var place func(orders []*dto.OutlineOrder, parentID *uint64) error
+	place = func(orders []*dto.OutlineOrder, parentID *uint64) error {
+		for i, order := range orders {
+			if order == nil {
+				return errors.New("Each section of the outline needs an id")
+			}
+			if seenSections[order.ID] {
+				return errors.New(fmt.Sprintf("Section %d is listed more than once", order.ID))
+			}
+			seenSections[order.ID] = true
+			sections = append(sections, &model.Curriculum{ID: order.ID, ParentID: parentID, SectionOrder: i + 1})
+
+			for j, materialID := range order.Materials {
+				if seenMaterials[materialID] {
+					return errors.New(fmt.Sprintf("Material %d is listed more than once", materialID))
+				}
+				seenMaterials[materialID] = true
+				materials = append(materials, &model.Material{ID: int(materialID), CurriculumID: int(order.ID), Order: j + 1})
+			}
+
+			if err := place(order.Sections, &order.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := place(reorderDTO.Sections, nil); err != nil {
+		return nil, err
+	}
+
+	changedSections, changedMaterials, err := s.repository.ReorderOutline(ctx, courseID, sections, materials)
+	if err != nil {
+		return nil, err
+	}
+	if changedSections == nil {
+		return nil, errors.New("Course not found")
+	}
+
+	for _, curriculum := range changedSections {
+		err := s.record(ctx, viewer.UserID, courseID, model.VersionCurriculum, curriculum.ID, "update", curriculum.VersionData())
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, material := range changedMaterials {
+		err := s.record(ctx, viewer.UserID, courseID, model.VersionMaterial, uint64(material.ID), "update", material.VersionData())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Outline(ctx, viewer, courseID)
+}
+
+// readable returns the sections the user can read, along with the ones
+// containing a section they can read.
+func readable(sections []*dto.OutlineSection, access *ContentAccess) []*dto.OutlineSection {
+	kept := make([]*dto.OutlineSection, 0, len(sections))
+	for _, section := range sections {
+		section.Sections = readable(section.Sections, access)
+		if access.Section(uint64(section.ID)) || len(section.Sections) > 0 {
+			kept = append(kept, section)
+		}
+	}
+	return kept
+}
+
+func ptrValue(value *uint64) uint64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,91 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+)
+
+type repository struct {
+	Repository
+	called    bool
+	sections  []*model.Curriculum
+	materials []*model.Material
+}
+
+func (r *repository) ReorderOutline(ctx context.Context, courseID uint64, sections []*model.Curriculum, materials []*model.Material) ([]*model.Curriculum, []*model.Material, error) {
+	r.called = true
+	r.sections = sections
+	r.materials = materials
+	return nil, nil, nil
+}
+
+func TestReorderOutline(t *testing.T) {
+	repository := &repository{}
+	s := NewService(repository, nil, nil, nil)
+
+	reorderDTO := &dto.ReorderOutlineRequest{Sections: []*dto.OutlineOrder{
+		{ID: 3, Materials: []uint64{12, 10}, Sections: []*dto.OutlineOrder{
+			{ID: 5, Materials: []uint64{11}},
+			{ID: 4},
+		}},
+		{ID: 1},
+	}}
+	if _, err := s.ReorderOutline(context.Background(), &dto.CourseViewer{UserID: 1}, 1, reorderDTO); err == nil {
+		t.Error("expected the course not to be found")
+	}
+
+	sections := map[uint64]struct {
+		parentID uint64
+		order    int
+	}{3: {0, 1}, 5: {3, 1}, 4: {3, 2}, 1: {0, 2}}
+	if len(repository.sections) != len(sections) {
+		t.Fatalf("expected %d sections, got %d", len(sections), len(repository.sections))
+	}
+	for _, section := range repository.sections {
+		expected := sections[section.ID]
+		if ptrValue(section.ParentID) != expected.parentID || section.SectionOrder != expected.order {
+			t.Errorf("section %d: expected parent %d and order %d, got %d and %d",
+				section.ID, expected.parentID, expected.order, ptrValue(section.ParentID), section.SectionOrder)
+		}
+	}
+
+	materials := map[int]struct {
+		curriculumID int
+		order        int
+	}{12: {3, 1}, 10: {3, 2}, 11: {5, 1}}
+	if len(repository.materials) != len(materials) {
+		t.Fatalf("expected %d materials, got %d", len(materials), len(repository.materials))
+	}
+	for _, material := range repository.materials {
+		expected := materials[material.ID]
+		if material.CurriculumID != expected.curriculumID || material.Order != expected.order {
+			t.Errorf("material %d: expected section %d and order %d, got %d and %d",
+				material.ID, expected.curriculumID, expected.order, material.CurriculumID, material.Order)
+		}
+	}
+}
+
+func TestReorderOutlineRejects(t *testing.T) {
+	cases := map[string][]*dto.OutlineOrder{
+		"duplicate section":  {{ID: 1, Sections: []*dto.OutlineOrder{{ID: 1}}}},
+		"duplicate material": {{ID: 1, Materials: []uint64{7}}, {ID: 2, Materials: []uint64{7}}},
+		"missing section":    {{ID: 1}, nil},
+	}
+	for name, orders := range cases {
+		t.Run(name, func(t *testing.T) {
+			repository := &repository{}
+			s := NewService(repository, nil, nil, nil)
+
+			reorderDTO := &dto.ReorderOutlineRequest{Sections: orders}
+			if _, err := s.ReorderOutline(context.Background(), &dto.CourseViewer{UserID: 1}, 1, reorderDTO); err == nil {
+				t.Error("expected the outline to be rejected")
+			}
+			if repository.called {
+				t.Error("the outline was applied")
+			}
+		})
+	}
+}
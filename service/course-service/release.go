@@ -0,0 +1,191 @@
+package courseservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	curriculumDto "github.com/dapthehuman/learning-management-system/dto/curriculum"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// maxReleaseDays bounds how long after enrolling content can be released.
+const maxReleaseDays = 3650
+
+func (s *Service) GetReleaseRules(ctx context.Context, courseID uint64) ([]*dto.ReleaseRule, error) {
+	rules, err := s.repository.ListReleaseRules(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[[]*dto.ReleaseRule](rules), nil
+}
+
+// SaveReleaseRule sets the release rule of a section or a material of the
+// course, replacing its previous one.
+func (s *Service) SaveReleaseRule(ctx context.Context, courseID uint64, saveDTO *dto.SaveReleaseRuleRequest) (*dto.ReleaseRule, error) {
+	if (saveDTO.CurriculumID == nil) == (saveDTO.MaterialID == nil) {
+		return nil, errors.New("A release rule applies to either a curriculum_id or a material_id")
+	}
+
+	conditions := 0
+	for _, set := range []bool{saveDTO.ReleaseAt != nil, saveDTO.DaysAfterEnrollment != nil, saveDTO.AfterCurriculumID != nil} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions != 1 {
+		return nil, errors.New("A release rule needs exactly one of release_at, days_after_enrollment and after_curriculum_id")
+	}
+	if saveDTO.DaysAfterEnrollment != nil && (*saveDTO.DaysAfterEnrollment < 0 || *saveDTO.DaysAfterEnrollment > maxReleaseDays) {
+		return nil, errors.New(fmt.Sprintf("days_after_enrollment must be between 0 and %d", maxReleaseDays))
+	}
+
+	rule, err := s.repository.SaveReleaseRule(ctx, &model.ReleaseRule{
+		CourseID:            courseID,
+		CurriculumID:        saveDTO.CurriculumID,
+		MaterialID:          saveDTO.MaterialID,
+		ReleaseAt:           saveDTO.ReleaseAt,
+		DaysAfterEnrollment: saveDTO.DaysAfterEnrollment,
+		AfterCurriculumID:   saveDTO.AfterCurriculumID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.ReleaseRule](rule), nil
+}
+
+func (s *Service) DeleteReleaseRule(ctx context.Context, courseID uint64, id uint64) error {
+	deleted, err := s.repository.DeleteReleaseRule(ctx, courseID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("Release rule not found")
+	}
+	return nil
+}
+
+// Releases tells which content of a course is released to a student. A
+// section is locked while one of its ancestors is. A nil Releases locks
+// nothing.
+type Releases struct {
+	now         time.Time
+	enrolledAt  *time.Time
+	parents     map[uint64]uint64
+	completed   map[uint64]bool
+	curriculums map[uint64]*model.ReleaseRule
+	materials   map[uint64]*model.ReleaseRule
+}
+
+// Releases returns what the viewer can read of the content of the course.
+// Staff and users allowed to manage any course are never locked out, so it
+// returns nil for them, and for courses without release rules.
+func (s *Service) Releases(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*Releases, error) {
+	if viewer.ManageAny {
+		return nil, nil
+	}
+
+	role, err := s.repository.GetStaffRole(ctx, courseID, viewer.UserID)
+	if err != nil || role != "" {
+		return nil, err
+	}
+
+	rules, err := s.repository.ListReleaseRules(ctx, courseID)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+
+	enrolledAt, completed, err := s.repository.ReleaseState(ctx, courseID, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := s.repository.SectionParents(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := &Releases{
+		now:         time.Now(),
+		enrolledAt:  enrolledAt,
+		parents:     parents,
+		completed:   make(map[uint64]bool, len(completed)),
+		curriculums: make(map[uint64]*model.ReleaseRule),
+		materials:   make(map[uint64]*model.ReleaseRule),
+	}
+
+	// A section is completed once the sections nested in it are too
+	own := make(map[uint64]bool, len(completed))
+	for _, id := range completed {
+		own[id] = true
+		releases.completed[id] = true
+	}
+	for id := range parents {
+		if !own[id] {
+			for ancestor := parents[id]; ancestor != 0; ancestor = parents[ancestor] {
+				delete(releases.completed, ancestor)
+			}
+		}
+	}
+	for _, rule := range rules {
+		if rule.CurriculumID != nil {
+			releases.curriculums[*rule.CurriculumID] = rule
+		} else {
+			releases.materials[*rule.MaterialID] = rule
+		}
+	}
+	return releases, nil
+}
+
+// Curriculum returns why the section is locked, nil if it is released.
+func (r *Releases) Curriculum(id uint64) *curriculumDto.Lock {
+	if r == nil {
+		return nil
+	}
+	for section := id; section != 0; section = r.parents[section] {
+		if lock := r.lock(r.curriculums[section]); lock != nil {
+			return lock
+		}
+	}
+	return nil
+}
+
+// Material returns why the material of the section is locked, nil if both
+// are released.
+func (r *Releases) Material(id uint64, curriculumID uint64) *curriculumDto.Lock {
+	if r == nil {
+		return nil
+	}
+	if lock := r.Curriculum(curriculumID); lock != nil {
+		return lock
+	}
+	return r.lock(r.materials[id])
+}
+
+func (r *Releases) lock(rule *model.ReleaseRule) *curriculumDto.Lock {
+	switch {
+	case rule == nil:
+		return nil
+	case rule.ReleaseAt != nil:
+		if r.now.Before(*rule.ReleaseAt) {
+			return &curriculumDto.Lock{Reason: curriculumDto.LockDate, AvailableAt: rule.ReleaseAt}
+		}
+	case rule.DaysAfterEnrollment != nil:
+		if r.enrolledAt == nil {
+			return &curriculumDto.Lock{Reason: curriculumDto.LockEnrollment}
+		}
+		availableAt := r.enrolledAt.AddDate(0, 0, *rule.DaysAfterEnrollment)
+		if r.now.Before(availableAt) {
+			return &curriculumDto.Lock{Reason: curriculumDto.LockEnrollment, AvailableAt: &availableAt}
+		}
+	case rule.AfterCurriculumID != nil:
+		if !r.completed[*rule.AfterCurriculumID] {
+			return &curriculumDto.Lock{Reason: curriculumDto.LockCompletion, CurriculumID: rule.AfterCurriculumID}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package courseservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// maxPrerequisites bounds the prerequisites of a course.
+const maxPrerequisites = 20
+
+func (s *Service) GetRequirements(ctx context.Context, courseID uint64) (*dto.CourseRequirements, error) {
+	requirements, err := s.repository.GetRequirements(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.CourseRequirements](requirements), nil
+}
+
+// SaveRequirements replaces the prerequisites, eligibility rules and
+// capacity of the course.
+func (s *Service) SaveRequirements(ctx context.Context, courseID uint64, saveDTO *dto.SaveCourseRequirementsRequest) (*dto.CourseRequirements, error) {
+	if len(saveDTO.Prerequisites) > maxPrerequisites {
+		return nil, errors.New(fmt.Sprintf("A course can have at most %d prerequisites", maxPrerequisites))
+	}
+	if saveDTO.OpensAt != nil && saveDTO.ClosesAt != nil && !saveDTO.ClosesAt.After(*saveDTO.OpensAt) {
+		return nil, errors.New("closes_at must be after opens_at")
+	}
+	if saveDTO.Capacity != nil && *saveDTO.Capacity < 1 {
+		return nil, errors.New("capacity must be at least 1, or null for unlimited seats")
+	}
+
+	requirements := &model.CourseRequirements{
+		CourseID:      courseID,
+		Prerequisites: make([]*model.Prerequisite, 0, len(saveDTO.Prerequisites)),
+		Roles:         normalizeList(saveDTO.Roles, strings.TrimSpace),
+		EmailDomains: normalizeList(saveDTO.EmailDomains, func(domain string) string {
+			return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+		}),
+		OpensAt:  saveDTO.OpensAt,
+		ClosesAt: saveDTO.ClosesAt,
+		Capacity: saveDTO.Capacity,
+	}
+
+	for _, prerequisite := range saveDTO.Prerequisites {
+		if prerequisite.CourseID == courseID {
+			return nil, errors.New("A course cannot require itself")
+		}
+		if prerequisite.MinGrade != nil && (*prerequisite.MinGrade < 0 || *prerequisite.MinGrade > 100) {
+			return nil, errors.New("min_grade must be between 0 and 100")
+		}
+		if slices.ContainsFunc(requirements.Prerequisites, func(p *model.Prerequisite) bool { return p.CourseID == prerequisite.CourseID }) {
+			return nil, errors.New(fmt.Sprintf("Course %d is listed twice", prerequisite.CourseID))
+		}
+
+		course, err := s.repository.First(ctx, prerequisite.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if course == nil || course.ID == 0 {
+			return nil, errors.New(fmt.Sprintf("Course %d not found", prerequisite.CourseID))
+		}
+
+		requirements.Prerequisites = append(requirements.Prerequisites, &model.Prerequisite{
+			CourseID: prerequisite.CourseID,
+			MinGrade: prerequisite.MinGrade,
+		})
+	}
+
+	saved, err := s.repository.SaveRequirements(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.CourseRequirements](saved), nil
+}
+
+// normalizeList applies f to each value of the list, dropping the empty and
+// duplicate results.
+func normalizeList(list []string, f func(string) string) []string {
+	result := make([]string, 0, len(list))
+	for _, value := range list {
+		value = f(value)
+		if value != "" && !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package courseservice
+
+import (
+	"context"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+func (s *Service) GetStaff(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseStaff], error) {
+	staff, err := s.repository.ListStaff(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.CourseStaff]](staff), nil
+}
+
+// HasCourseRole reports whether the user is part of the staff of the course
+// with one of the given roles.
+func (s *Service) HasCourseRole(ctx context.Context, courseID uint64, userID uint64, roles ...string) (bool, error) {
+	role, err := s.repository.GetStaffRole(ctx, courseID, userID)
+	if err != nil || role == "" {
+		return false, err
+	}
+
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CourseIDByCurriculum returns the ID of the course a curriculum section belongs to.
+func (s *Service) CourseIDByCurriculum(ctx context.Context, curriculumID uint64) (uint64, error) {
+	curriculum, err := s.repository.GetCurriculumByID(ctx, curriculumID)
+	if err != nil {
+		return 0, err
+	}
+
+	if curriculum == nil || curriculum.ID == 0 {
+		return 0, errors.New("Curriculum not found")
+	}
+	return curriculum.CourseID, nil
+}
+
+func (s *Service) SaveStaff(ctx context.Context, courseID uint64, saveDTO *dto.SaveCourseStaffRequest) (*dto.CourseStaff, error) {
+	if !model.IsStaffRole(saveDTO.Role) {
+		return nil, errors.New("Invalid course role")
+	}
+
+	if saveDTO.Role != model.StaffOwner {
+		if err := s.keepOwner(ctx, courseID, saveDTO.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := s.repository.SaveStaff(ctx, &model.CourseStaff{
+		CourseID: courseID,
+		UserID:   saveDTO.UserID,
+		Role:     saveDTO.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.CourseStaff](member), nil
+}
+
+func (s *Service) RemoveStaff(ctx context.Context, courseID uint64, userID uint64) error {
+	if err := s.keepOwner(ctx, courseID, userID); err != nil {
+		return err
+	}
+
+	return s.repository.DeleteStaff(ctx, courseID, userID)
+}
+
+// keepOwner prevents the last owner of a course from being removed or demoted.
+func (s *Service) keepOwner(ctx context.Context, courseID uint64, userID uint64) error {
+	role, err := s.repository.GetStaffRole(ctx, courseID, userID)
+	if err != nil {
+		return err
+	}
+	if role != model.StaffOwner {
+		return nil
+	}
+
+	owners, err := s.repository.CountOwners(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return errors.New("A course must keep at least one owner")
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package courseservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// ListInReview returns a page of the courses waiting for a reviewer, oldest
+// first by default.
+func (s *Service) ListInReview(ctx context.Context, q *listing.Query) (*listing.Page[*dto.Course], error) {
+	courses, err := s.repository.ListByStatus(ctx, model.CourseInReview, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.Course]](courses), nil
+}
+
+// Submit asks a reviewer to approve a draft.
+func (s *Service) Submit(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+	return s.transition(ctx, viewer.UserID, "course.submit", id, func(course *model.Course) error {
+		course.Status = model.CourseInReview
+		course.ReviewNote = ""
+		return nil
+	}, model.CourseDraft)
+}
+
+// Publish publishes a draft, or an archived course again, freezing its
+// content in a snapshot for students to read. When the organization requires
+// reviews, drafts must be approved by a reviewer instead, unless the viewer
+// is one.
+func (s *Service) Publish(ctx context.Context, viewer *dto.CourseViewer, id uint64, publishDTO *dto.PublishCourseRequest) (*dto.Course, error) {
+	mustReview := false
+	if !viewer.CanReview {
+		required, err := s.repository.ReviewRequired(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mustReview = required
+	}
+
+	course, err := s.transition(ctx, viewer.UserID, "course.publish", id, func(course *model.Course) error {
+		if course.Status == model.CourseDraft && mustReview {
+			return errors.New("Courses must be reviewed before they are published, submit it for review instead")
+		}
+		return publish(course, publishDTO.PublishAt)
+	}, model.CourseDraft, model.CourseArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.versionRepository.CreateSnapshot(ctx, id, viewer.UserID); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Approve publishes a course submitted for review, freezing its content in a
+// snapshot for students to read.
+func (s *Service) Approve(ctx context.Context, viewer *dto.CourseViewer, id uint64, publishDTO *dto.PublishCourseRequest) (*dto.Course, error) {
+	course, err := s.transition(ctx, viewer.UserID, "course.approve", id, func(course *model.Course) error {
+		course.ReviewedBy = &viewer.UserID
+		course.ReviewNote = ""
+		return publish(course, publishDTO.PublishAt)
+	}, model.CourseInReview)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.versionRepository.CreateSnapshot(ctx, id, viewer.UserID); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// Reject sends a course submitted for review back to draft, with the reason
+// for the staff to address.
+func (s *Service) Reject(ctx context.Context, viewer *dto.CourseViewer, id uint64, rejectDTO *dto.RejectCourseRequest) (*dto.Course, error) {
+	note := strings.TrimSpace(rejectDTO.Note)
+	if note == "" {
+		return nil, errors.New("A note explaining the rejection is required")
+	}
+
+	return s.transition(ctx, viewer.UserID, "course.reject", id, func(course *model.Course) error {
+		course.Status = model.CourseDraft
+		course.ReviewedBy = &viewer.UserID
+		course.ReviewNote = note
+		return nil
+	}, model.CourseInReview)
+}
+
+// Withdraw brings a course in review, or a scheduled course not yet listed,
+// back to draft. Listed courses are archived instead, as students may
+// already be enrolled in them.
+func (s *Service) Withdraw(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+	return s.transition(ctx, viewer.UserID, "course.withdraw", id, func(course *model.Course) error {
+		if course.Listed(time.Now()) {
+			return errors.New("The course is already published, archive it instead")
+		}
+		course.Status = model.CourseDraft
+		course.PublishedAt = nil
+		return nil
+	}, model.CourseInReview, model.CoursePublished)
+}
+
+// Archive removes a published course from the catalog. Enrolled students
+// can still read it.
+func (s *Service) Archive(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.Course, error) {
+	return s.transition(ctx, viewer.UserID, "course.archive", id, func(course *model.Course) error {
+		now := time.Now()
+		course.Status = model.CourseArchived
+		course.ArchivedAt = &now
+		return nil
+	}, model.CoursePublished)
+}
+
+// publish marks the course as published at publishAt, or now if it is nil.
+func publish(course *model.Course, publishAt *time.Time) error {
+	now := time.Now()
+	if publishAt == nil {
+		publishAt = &now
+	} else if publishAt.Before(now.Add(-time.Minute)) {
+		return errors.New("publish_at cannot be in the past")
+	}
+
+	course.Status = model.CoursePublished
+	course.PublishedAt = publishAt
+	course.ArchivedAt = nil
+	return nil
+}
+
+// transition applies change to the course if its status is one of from, and
+// records it in the audit log.
+func (s *Service) transition(ctx context.Context, actorID uint64, action string, id uint64, change func(course *model.Course) error, from ...string) (*dto.Course, error) {
+	course, err := s.repository.First(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || course.ID == 0 {
+		return nil, errors.New("Course not found")
+	}
+	if !slices.Contains(from, course.Status) {
+		return nil, errors.New(fmt.Sprintf("A %s course cannot be changed this way", strings.ReplaceAll(course.Status, "_", " ")))
+	}
+
+	previous := course.Status
+	if err := change(course); err != nil {
+		return nil, err
+	}
+
+	// The status is checked again by the update in case it changed meanwhile
+	updated, err := s.repository.ChangeStatus(ctx, course, previous)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, errors.New("The course was changed by someone else, try again")
+	}
+
+	if err := s.audit(ctx, action, actorID, previous, updated); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Course](updated), nil
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, previous string, course *model.Course) error {
+	raw, err := json.Marshal(map[string]any{
+		"course_id":    course.ID,
+		"from":         previous,
+		"to":           course.Status,
+		"published_at": course.PublishedAt,
+		"review_note":  course.ReviewNote,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:  &actorID,
+		Action:   action,
+		Metadata: raw,
+	})
+	return err
+}
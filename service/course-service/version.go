@@ -0,0 +1,176 @@
+package courseservice
+
+import (
+	"context"
+	"encoding/json"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+type VersionRepository interface {
+	Record(ctx context.Context, version *model.ContentVersion) (*model.ContentVersion, error)
+	ListByCourse(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.ContentVersion], error)
+	GetByID(ctx context.Context, id uint64) (*model.ContentVersion, error)
+	Restore(ctx context.Context, version *model.ContentVersion, authorID uint64) (*model.ContentVersion, error)
+	CreateSnapshot(ctx context.Context, courseID uint64, authorID uint64) (*model.CourseSnapshot, error)
+	GetSnapshot(ctx context.Context, id uint64) (*model.CourseSnapshot, error)
+	ListSnapshots(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*model.CourseSnapshot], error)
+}
+
+// History returns a page of the changes to the course, its sections and its
+// materials, newest first by default.
+func (s *Service) History(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.ContentVersion], error) {
+	versions, err := s.versionRepository.ListByCourse(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.ContentVersion]](versions), nil
+}
+
+// Rollback puts the entity of a version of the course back in the state the
+// version describes. Deleted sections and materials are recreated, but a
+// material can only be restored once its section exists.
+func (s *Service) Rollback(ctx context.Context, actorID uint64, courseID uint64, versionID uint64) (*dto.ContentVersion, error) {
+	version, err := s.versionRepository.GetByID(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil || version.CourseID != courseID {
+		return nil, errors.New("Version not found")
+	}
+	if version.Data == nil {
+		return nil, errors.New("This version is a deletion, delete the content instead of rolling back to it")
+	}
+
+	if version.EntityType == model.VersionMaterial {
+		var material model.Material
+		if err := json.Unmarshal(version.Data, &material); err != nil {
+			return nil, err
+		}
+		curriculum, err := s.repository.GetCurriculumByID(ctx, uint64(material.CurriculumID))
+		if err != nil || curriculum == nil || curriculum.CourseID != courseID {
+			return nil, errors.New("The section of this material was deleted, restore it first")
+		}
+	}
+
+	restored, err := s.versionRepository.Restore(ctx, version, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.ContentVersion](restored), nil
+}
+
+// Snapshots returns a page of the snapshots of the course, newest first by
+// default.
+func (s *Service) Snapshots(ctx context.Context, courseID uint64, q *listing.Query) (*listing.Page[*dto.CourseSnapshot], error) {
+	snapshots, err := s.versionRepository.ListSnapshots(ctx, courseID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.CourseSnapshot]](snapshots), nil
+}
+
+// Snapshot returns a snapshot of the course with its content.
+func (s *Service) Snapshot(ctx context.Context, courseID uint64, id uint64) (*dto.CourseSnapshot, error) {
+	snapshot, err := s.versionRepository.GetSnapshot(ctx, id)
+	if err != nil || snapshot.CourseID != courseID {
+		return nil, errors.New("Snapshot not found")
+	}
+
+	return typeutil.MustConvert[*dto.CourseSnapshot](snapshot), nil
+}
+
+// PublishedContent returns the snapshot of the course the viewer reads, or
+// nil if they read the live content: the staff edit it, and courses without
+// a snapshot have nothing else to show.
+func (s *Service) PublishedContent(ctx context.Context, viewer *dto.CourseViewer, courseID uint64) (*dto.CourseContent, error) {
+	if viewer.ManageAny {
+		return nil, nil
+	}
+
+	role, err := s.repository.GetStaffRole(ctx, courseID, viewer.UserID)
+	if err != nil || role != "" {
+		return nil, err
+	}
+
+	course, err := s.repository.First(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || course.PublishedSnapshotID == nil {
+		return nil, nil
+	}
+
+	snapshot, err := s.versionRepository.GetSnapshot(ctx, *course.PublishedSnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.CourseContent](snapshot.Content), nil
+}
+
+// PublishChanges makes the current content of a published or archived course
+// the one students read. When the organization requires reviews, only
+// reviewers can publish changes.
+func (s *Service) PublishChanges(ctx context.Context, viewer *dto.CourseViewer, id uint64) (*dto.CourseSnapshot, error) {
+	course, err := s.repository.First(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || course.ID == 0 {
+		return nil, errors.New("Course not found")
+	}
+	if course.Status != model.CoursePublished && course.Status != model.CourseArchived {
+		return nil, errors.New("Only the changes of a published course can be published")
+	}
+
+	if !viewer.CanReview {
+		required, err := s.repository.ReviewRequired(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, errors.New("Changes must be published by a reviewer")
+		}
+	}
+
+	snapshot, err := s.versionRepository.CreateSnapshot(ctx, id, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "course.publish_changes", viewer.UserID, course.Status, course); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.CourseSnapshot](snapshot), nil
+}
+
+// record adds a version to the history of the course. data holds the
+// versioned fields after the change, nil if the entity was deleted.
+func (s *Service) record(ctx context.Context, actorID uint64, courseID uint64, entityType string, entityID uint64, action string, data map[string]any) error {
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.versionRepository.Record(ctx, &model.ContentVersion{
+		CourseID:   courseID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Data:       raw,
+		AuthorID:   &actorID,
+	})
+	return err
+}
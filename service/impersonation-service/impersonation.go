@@ -0,0 +1,153 @@
+package impersonationservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/admin"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// Lifetime is how long an impersonation token stays valid.
+const Lifetime = 30 * time.Minute
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+}
+
+type RoleRepository interface {
+	GetPermissions(ctx context.Context, name string) ([]string, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
+}
+
+type Service struct {
+	userRepository  UserRepository
+	roleRepository  RoleRepository
+	auditRepository AuditRepository
+	redis           redis.UniversalClient
+}
+
+func NewService(userRepository UserRepository, roleRepository RoleRepository, auditRepository AuditRepository, redis redis.UniversalClient) *Service {
+	return &Service{
+		userRepository:  userRepository,
+		roleRepository:  roleRepository,
+		auditRepository: auditRepository,
+		redis:           redis,
+	}
+}
+
+// Start issues a token letting the actor act as the user. The token carries
+// an "act" claim identifying the actor and is only accepted while its
+// session is active in Redis, so it can be ended before it expires.
+func (s *Service) Start(ctx context.Context, actorID uint64, userID uint64) (*dto.ImpersonationResponse, error) {
+	if actorID == userID {
+		return nil, errors.New("you cannot impersonate yourself")
+	}
+
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Impersonating another administrator would be a privilege escalation path
+	permissions, err := s.roleRepository.GetPermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		if permission == "user.impersonate" {
+			return nil, errors.New("users allowed to impersonate cannot be impersonated")
+		}
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	session := hex.EncodeToString(b)
+	expiresAt := time.Now().Add(Lifetime)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"org_id":  tenant.ID(ctx),
+		"exp":     expiresAt.Unix(),
+		"jti":     session,
+		"act":     map[string]any{"sub": actorID},
+	}).SignedString([]byte(os.Getenv("APP_SECRET")))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.redis.Set(ctx, sessionKey(session), actorID, Lifetime).Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "impersonation.start", actorID, userID, map[string]any{"session": session, "expires_at": expiresAt}); err != nil {
+		return nil, err
+	}
+
+	return &dto.ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      typeutil.MustConvert[*dto.User](user),
+	}, nil
+}
+
+// Stop ends an impersonation session before its token expires.
+func (s *Service) Stop(ctx context.Context, actorID uint64, userID uint64, session string) error {
+	deleted, err := s.redis.Del(ctx, sessionKey(session)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.New("impersonation session is not active")
+	}
+
+	return s.audit(ctx, "impersonation.stop", actorID, userID, map[string]any{"session": session})
+}
+
+// IsActive reports whether the impersonation session has neither expired
+// nor been stopped.
+func (s *Service) IsActive(ctx context.Context, session string) (bool, error) {
+	n, err := s.redis.Exists(ctx, sessionKey(session)).Result()
+	return n > 0, err
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, userID uint64, metadata map[string]any) error {
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:      &actorID,
+		Action:       action,
+		TargetUserID: &userID,
+		Metadata:     raw,
+	})
+	return err
+}
+
+func sessionKey(session string) string {
+	return fmt.Sprintf("impersonation:%s", session)
+}
+
+func (s *Service) Name() string {
+	return service.Impersonation
+}
@@ -0,0 +1,137 @@
+package impersonationservice
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/dapthehuman/learning-management-system/cache/cachetest"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/golang-jwt/jwt"
+)
+
+const secret = "impersonation test secret"
+
+type userRepository map[uint64]*model.User
+
+func (r userRepository) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+type roleRepository map[string][]string
+
+func (r roleRepository) GetPermissions(ctx context.Context, name string) ([]string, error) {
+	return r[name], nil
+}
+
+type auditRepository struct {
+	entries []*model.AuditLog
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func newService(t *testing.T) (*Service, *auditRepository) {
+	t.Helper()
+	t.Setenv("APP_SECRET", secret)
+
+	users := userRepository{
+		1: {ID: 1, Email: "admin@example.edu", Role: "admin"},
+		2: {ID: 2, Email: "student@example.edu", Role: "student"},
+		3: {ID: 3, Email: "support@example.edu", Role: "support"},
+	}
+	roles := roleRepository{
+		"admin":   {"user.impersonate", "user.read"},
+		"support": {"user.impersonate"},
+		"student": {"course.read"},
+	}
+	audit := &auditRepository{}
+	return NewService(users, roles, audit, cachetest.NewRedis()), audit
+}
+
+func TestStart(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	s, audit := newService(t)
+
+	impersonation, err := s.Start(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(impersonation.Token, claims, func(*jwt.Token) (any, error) { return []byte(secret), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, ok := claims["act"].(map[string]any)
+	if !ok || act["sub"] != float64(1) || claims["user_id"] != float64(2) {
+		t.Errorf("expected user 2 acted on by user 1, got %v", claims)
+	}
+
+	session, _ := claims["jti"].(string)
+	if active, err := s.IsActive(ctx, session); err != nil || !active {
+		t.Errorf("session is not active: %v", err)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != "impersonation.start" || *audit.entries[0].ActorID != 1 || *audit.entries[0].TargetUserID != 2 {
+		t.Errorf("expected the start to be audited, got %v", audit.entries)
+	}
+}
+
+func TestStartRefuses(t *testing.T) {
+	cases := []struct {
+		name   string
+		userID uint64
+	}{
+		{name: "self", userID: 1},
+		{name: "impersonator", userID: 3},
+		{name: "unknown user", userID: 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := tenant.WithOrganization(context.Background(), 1)
+			s, audit := newService(t)
+
+			if _, err := s.Start(ctx, 1, c.userID); err == nil {
+				t.Errorf("impersonated user %d", c.userID)
+			}
+			if len(audit.entries) != 0 {
+				t.Errorf("audited a refused impersonation: %v", audit.entries)
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	s, audit := newService(t)
+
+	impersonation, err := s.Start(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(impersonation.Token, claims, func(*jwt.Token) (any, error) { return []byte(secret), nil }); err != nil {
+		t.Fatal(err)
+	}
+	session := claims["jti"].(string)
+
+	if err := s.Stop(ctx, 1, 2, session); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := s.IsActive(ctx, session); err != nil || active {
+		t.Errorf("session is still active: %v", err)
+	}
+	if len(audit.entries) != 2 || audit.entries[1].Action != "impersonation.stop" {
+		t.Errorf("expected the stop to be audited, got %v", audit.entries)
+	}
+
+	if err := s.Stop(ctx, 1, 2, session); err == nil {
+		t.Error("stopped the same session twice")
+	}
+}
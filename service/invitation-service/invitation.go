@@ -0,0 +1,326 @@
+package invitationservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
+	authDto "github.com/dapthehuman/learning-management-system/dto/auth"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/service"
+	"golang.org/x/crypto/bcrypt"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+const (
+	// Lifetime is how long an invitation link stays valid. Resending an
+	// invitation starts a new period.
+	Lifetime = 7 * 24 * time.Hour
+
+	defaultRole       = "instructor"
+	defaultCourseRole = model.StaffInstructor
+)
+
+type Repository interface {
+	Create(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error)
+	GetByID(ctx context.Context, id uint64) (*model.Invitation, error)
+	GetOpenByEmail(ctx context.Context, email string) (*model.Invitation, error)
+	GetPendingByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	List(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.Invitation], error)
+	Renew(ctx context.Context, id uint64, tokenHash string, expiresAt time.Time) (*model.Invitation, error)
+	Revoke(ctx context.Context, id uint64) (*model.Invitation, error)
+	Accept(ctx context.Context, tokenHash string, user *model.User) (*model.Invitation, error)
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+}
+
+type RoleRepository interface {
+	Exists(ctx context.Context, name string) (bool, error)
+	Covers(ctx context.Context, holder string, role string) (bool, error)
+}
+
+type CourseRepository interface {
+	First(ctx context.Context, id uint64) (*model.Course, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
+}
+
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+	Link(path string) string
+}
+
+type Service struct {
+	repository       Repository
+	userRepository   UserRepository
+	roleRepository   RoleRepository
+	courseRepository CourseRepository
+	auditRepository  AuditRepository
+	mailer           Mailer
+}
+
+// NewService creates the invitation service. mailer may be nil when sending
+// emails is not configured, in which case the invitation tokens are returned
+// to the inviter instead.
+func NewService(repository Repository, userRepository UserRepository, roleRepository RoleRepository, courseRepository CourseRepository, auditRepository AuditRepository, mailer Mailer) *Service {
+	return &Service{
+		repository:       repository,
+		userRepository:   userRepository,
+		roleRepository:   roleRepository,
+		courseRepository: courseRepository,
+		auditRepository:  auditRepository,
+		mailer:           mailer,
+	}
+}
+
+// Create invites someone to create an account with a pre-assigned role and,
+// optionally, a role in the staff of a course. The role cannot have
+// permissions the inviter does not have.
+func (s *Service) Create(ctx context.Context, actorID uint64, createDTO *adminDto.CreateInvitationRequest) (*adminDto.InvitationResponse, error) {
+	email := strings.TrimSpace(createDTO.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, errors.New("invalid email")
+	}
+
+	if createDTO.Role == "" {
+		createDTO.Role = defaultRole
+	}
+	exists, err := s.roleRepository.Exists(ctx, createDTO.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("unknown role")
+	}
+	if err := s.checkGrant(ctx, actorID, createDTO.Role); err != nil {
+		return nil, err
+	}
+
+	var courseRole *string
+	if createDTO.CourseID != nil {
+		if createDTO.CourseRole == "" {
+			createDTO.CourseRole = defaultCourseRole
+		}
+		if !model.IsStaffRole(createDTO.CourseRole) {
+			return nil, errors.New("invalid course role")
+		}
+		course, err := s.courseRepository.First(ctx, *createDTO.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if course == nil || course.ID == 0 {
+			return nil, errors.New("course not found")
+		}
+		courseRole = &createDTO.CourseRole
+	} else if createDTO.CourseRole != "" {
+		return nil, errors.New("course_role requires course_id")
+	}
+
+	if _, err := s.userRepository.GetByEmail(ctx, email); err == nil {
+		return nil, errors.New("user with email already exists")
+	}
+	open, err := s.repository.GetOpenByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, errors.New("this email already has an open invitation, resend or revoke it instead")
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repository.Create(ctx, &model.Invitation{
+		Email:      email,
+		Name:       createDTO.Name,
+		Role:       createDTO.Role,
+		CourseID:   createDTO.CourseID,
+		CourseRole: courseRole,
+		TokenHash:  hash,
+		InvitedBy:  &actorID,
+		ExpiresAt:  time.Now().Add(Lifetime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "invitation.create", actorID, invitation); err != nil {
+		return nil, err
+	}
+
+	return s.deliver(ctx, invitation, token), nil
+}
+
+func (s *Service) List(ctx context.Context, status string, q *listing.Query) (*listing.Page[*adminDto.Invitation], error) {
+	invitations, err := s.repository.List(ctx, status, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return listing.Map(invitations, toDTO), nil
+}
+
+// Resend issues a new link for an open invitation, expired or not. Links
+// sent before stop working.
+func (s *Service) Resend(ctx context.Context, actorID uint64, id uint64) (*adminDto.InvitationResponse, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repository.Renew(ctx, id, hash, time.Now().Add(Lifetime))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "invitation.resend", actorID, invitation); err != nil {
+		return nil, err
+	}
+
+	return s.deliver(ctx, invitation, token), nil
+}
+
+func (s *Service) Revoke(ctx context.Context, actorID uint64, id uint64) (*adminDto.Invitation, error) {
+	invitation, err := s.repository.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "invitation.revoke", actorID, invitation); err != nil {
+		return nil, err
+	}
+
+	return toDTO(invitation), nil
+}
+
+// Details returns the invitation holding the token, if it can still be accepted.
+func (s *Service) Details(ctx context.Context, token string) (*authDto.InvitationDetails, error) {
+	invitation, err := s.repository.GetPendingByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	return typeutil.MustConvert[*authDto.InvitationDetails](invitation), nil
+}
+
+// Accept creates the account of the invitee with the password they chose.
+func (s *Service) Accept(ctx context.Context, acceptDTO *authDto.AcceptInvitationRequest) (*dto.User, error) {
+	if _, err := s.repository.GetPendingByTokenHash(ctx, hashToken(acceptDTO.Token)); err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(acceptDTO.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Name:         strings.TrimSpace(acceptDTO.Name),
+		PasswordHash: string(hashedPassword),
+	}
+	if _, err := s.repository.Accept(ctx, hashToken(acceptDTO.Token), user); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.User](user), nil
+}
+
+// deliver emails the invitation link when possible, and otherwise hands the
+// token back to the inviter.
+func (s *Service) deliver(ctx context.Context, invitation *model.Invitation, token string) *adminDto.InvitationResponse {
+	response := &adminDto.InvitationResponse{Invitation: toDTO(invitation)}
+	if s.mailer == nil {
+		response.Token = token
+		return response
+	}
+
+	link := s.mailer.Link("/accept-invitation?token=" + token)
+	body := fmt.Sprintf("Hello %s,\n\nYou have been invited to join as %s. Choose your password within %d days using the link below:\n\n%s\n",
+		invitation.Name, invitation.Role, int(Lifetime.Hours()/24), link)
+	if err := s.mailer.Send(ctx, invitation.Email, "You have been invited", body); err != nil {
+		response.Token = token
+		return response
+	}
+
+	response.EmailSent = true
+	return response
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, invitation *model.Invitation) error {
+	raw, err := json.Marshal(map[string]any{
+		"invitation_id": invitation.ID,
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+		"course_id":     invitation.CourseID,
+		"course_role":   invitation.CourseRole,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:  &actorID,
+		Action:   action,
+		Metadata: raw,
+	})
+	return err
+}
+
+// checkGrant prevents the actor from inviting someone with a role having
+// permissions they do not have, such as an administrator role.
+func (s *Service) checkGrant(ctx context.Context, actorID uint64, role string) error {
+	actor, err := s.userRepository.GetByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	covered, err := s.roleRepository.Covers(ctx, actor.Role, role)
+	if err != nil {
+		return err
+	}
+	if !covered {
+		return errors.New(fmt.Sprintf("you cannot invite with the %s role, which has permissions you do not have", role))
+	}
+	return nil
+}
+
+func (s *Service) Name() string {
+	return service.Invitation
+}
+
+func toDTO(invitation *model.Invitation) *adminDto.Invitation {
+	result := typeutil.MustConvert[*adminDto.Invitation](invitation)
+	result.Status = invitation.Status()
+	return result
+}
+
+// newToken returns a random invitation token and its hash.
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
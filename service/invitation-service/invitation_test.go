@@ -0,0 +1,150 @@
+package invitationservice
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
+	"github.com/dapthehuman/learning-management-system/listing"
+)
+
+type repository struct {
+	invitations []*model.Invitation
+}
+
+func (r *repository) Create(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error) {
+	invitation.ID = uint64(len(r.invitations) + 1)
+	r.invitations = append(r.invitations, invitation)
+	return invitation, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uint64) (*model.Invitation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) GetOpenByEmail(ctx context.Context, email string) (*model.Invitation, error) {
+	return nil, nil
+}
+
+func (r *repository) GetPendingByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) List(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.Invitation], error) {
+	return &listing.Page[*model.Invitation]{Data: r.invitations}, nil
+}
+
+func (r *repository) Renew(ctx context.Context, id uint64, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) Revoke(ctx context.Context, id uint64) (*model.Invitation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) Accept(ctx context.Context, tokenHash string, user *model.User) (*model.Invitation, error) {
+	return nil, sql.ErrNoRows
+}
+
+type userRepository map[uint64]*model.User
+
+func (r userRepository) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+type roleRepository map[string][]string
+
+func (r roleRepository) Exists(ctx context.Context, name string) (bool, error) {
+	_, ok := r[name]
+	return ok, nil
+}
+
+func (r roleRepository) Covers(ctx context.Context, holder string, role string) (bool, error) {
+	for _, permission := range r[role] {
+		if !slices.Contains(r[holder], permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type courseRepository struct{}
+
+func (courseRepository) First(ctx context.Context, id uint64) (*model.Course, error) {
+	return &model.Course{ID: id}, nil
+}
+
+type auditRepository struct{}
+
+func (auditRepository) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	return entry, nil
+}
+
+func TestCreateRoles(t *testing.T) {
+	users := userRepository{
+		1: {ID: 1, Email: "admin@example.edu", Role: "admin"},
+		2: {ID: 2, Email: "registrar@example.edu", Role: "registrar"},
+	}
+	roles := roleRepository{
+		"admin":      {"course.write", "user.invite", "user.update"},
+		"registrar":  {"course.write", "user.invite"},
+		"instructor": {"course.write"},
+	}
+	courseID := uint64(7)
+
+	cases := []struct {
+		name    string
+		actorID uint64
+		request adminDto.CreateInvitationRequest
+		allowed bool
+	}{
+		{name: "role within the inviter permissions", actorID: 2, request: adminDto.CreateInvitationRequest{Role: "instructor"}, allowed: true},
+		{name: "inviter role", actorID: 2, request: adminDto.CreateInvitationRequest{Role: "registrar"}, allowed: true},
+		{name: "default role", actorID: 2, allowed: true},
+		{name: "role with more permissions", actorID: 2, request: adminDto.CreateInvitationRequest{Role: "admin"}},
+		{name: "administrator", actorID: 1, request: adminDto.CreateInvitationRequest{Role: "admin"}, allowed: true},
+		{name: "unknown role", actorID: 1, request: adminDto.CreateInvitationRequest{Role: "root"}},
+		{name: "course role", actorID: 2, request: adminDto.CreateInvitationRequest{CourseID: &courseID, CourseRole: model.StaffGrader}, allowed: true},
+		{name: "unknown course role", actorID: 2, request: adminDto.CreateInvitationRequest{CourseID: &courseID, CourseRole: "dean"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			invitations := &repository{}
+			s := NewService(invitations, users, roles, courseRepository{}, auditRepository{}, nil)
+
+			c.request.Email = "new@example.edu"
+			response, err := s.Create(context.Background(), c.actorID, &c.request)
+			if !c.allowed {
+				if err == nil {
+					t.Errorf("invited with role %s", response.Invitation.Role)
+				}
+				if len(invitations.invitations) != 0 {
+					t.Error("stored a refused invitation")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(invitations.invitations) != 1 || response.Token == "" {
+				t.Errorf("expected an invitation and its token, got %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package mailservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dapthehuman/learning-management-system/service"
+)
+
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// AppURL is the public URL of the frontend, used to build links in emails.
+	AppURL string
+}
+
+// ConfigFromEnv reads the SMTP configuration from MAIL_* variables.
+func ConfigFromEnv() Config {
+	return Config{
+		Host:     os.Getenv("MAIL_HOST"),
+		Port:     os.Getenv("MAIL_PORT"),
+		Username: os.Getenv("MAIL_USERNAME"),
+		Password: os.Getenv("MAIL_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+		AppURL:   strings.TrimSuffix(os.Getenv("APP_URL"), "/"),
+	}
+}
+
+// Enabled reports whether enough configuration is present to send emails.
+func (c Config) Enabled() bool {
+	return c.Host != "" && c.Port != "" && c.From != ""
+}
+
+type Service struct {
+	config Config
+}
+
+func NewService(config Config) *Service {
+	return &Service{
+		config: config,
+	}
+}
+
+// Link returns an absolute link to the given path of the frontend.
+func (s *Service) Link(path string) string {
+	return s.config.AppURL + path
+}
+
+// Send delivers a plain text email.
+func (s *Service) Send(ctx context.Context, to string, subject string, body string) error {
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.config.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	if err := smtp.SendMail(addr, auth, s.config.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("could not send email to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (s *Service) Name() string {
+	return service.Mail
+}
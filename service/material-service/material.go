@@ -2,32 +2,49 @@ package studentservice
 
 import (
 	"context"
+	"encoding/json"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
 	dto "github.com/dapthehuman/learning-management-system/dto/material"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5/util/errors"
 	"goyave.dev/goyave/v5/util/typeutil"
 )
 
 type Repository interface {
 	Create(ctx context.Context, material *model.Material) (*model.Material, error)
 	GetByID(ctx context.Context, id uint64) (*model.Material, error)
-	GetByCurriculumID(ctx context.Context, curriculumID uint64) ([]*model.Material, error)
+	ListByCurriculum(ctx context.Context, curriculumID uint64, q *listing.Query) (*listing.Page[*model.Material], error)
 	Update(ctx context.Context, material *model.Material) (*model.Material, error)
 	Delete(ctx context.Context, id uint64) error
+	Move(ctx context.Context, id uint64, curriculumID uint64, position int) ([]*model.Material, error)
+}
+
+// CurriculumRepository finds the course a material belongs to.
+type CurriculumRepository interface {
+	GetCurriculumByID(ctx context.Context, id uint64) (*model.Curriculum, error)
+}
+
+type VersionRepository interface {
+	Record(ctx context.Context, version *model.ContentVersion) (*model.ContentVersion, error)
 }
 
 type Service struct {
-	repository Repository
+	repository           Repository
+	curriculumRepository CurriculumRepository
+	versionRepository    VersionRepository
 }
 
-func NewService(repository Repository) *Service {
+func NewService(repository Repository, curriculumRepository CurriculumRepository, versionRepository VersionRepository) *Service {
 	return &Service{
-		repository: repository,
+		repository:           repository,
+		curriculumRepository: curriculumRepository,
+		versionRepository:    versionRepository,
 	}
 }
 
-func (s *Service) Create(ctx context.Context, materialDTO *dto.CreateMaterialRequest) (*dto.CreateMaterialResponse, error) {
+func (s *Service) Create(ctx context.Context, actorID uint64, materialDTO *dto.CreateMaterialRequest) (*dto.CreateMaterialResponse, error) {
 	material := typeutil.MustConvert[*model.Material](materialDTO)
 
 	createdMaterial, err := s.repository.Create(ctx, material)
@@ -35,6 +52,10 @@ func (s *Service) Create(ctx context.Context, materialDTO *dto.CreateMaterialReq
 		return nil, err
 	}
 
+	if err := s.record(ctx, actorID, createdMaterial, "create", createdMaterial.VersionData()); err != nil {
+		return nil, err
+	}
+
 	return typeutil.MustConvert[*dto.CreateMaterialResponse](createdMaterial), nil
 }
 
@@ -47,32 +68,112 @@ func (s *Service) GetByID(ctx context.Context, id uint64) (*dto.MaterialResponse
 	return typeutil.MustConvert[*dto.MaterialResponse](material), nil
 }
 
-func (s *Service) GetByCurriculumID(ctx context.Context, curriculumID uint64) ([]*dto.MaterialResponse, error) {
-	materials, err := s.repository.GetByCurriculumID(ctx, curriculumID)
+func (s *Service) GetByCurriculumID(ctx context.Context, curriculumID uint64, q *listing.Query) (*listing.Page[*dto.MaterialResponse], error) {
+	materials, err := s.repository.ListByCurriculum(ctx, curriculumID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.MaterialResponse](materials), nil
+	return typeutil.MustConvert[*listing.Page[*dto.MaterialResponse]](materials), nil
 }
 
-func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.UpdateMaterialRequest) (*dto.MaterialResponse, error) {
-	_, err := s.repository.GetByID(ctx, id)
+func (s *Service) Update(ctx context.Context, actorID uint64, id uint64, updateDTO *dto.UpdateMaterialRequest) (*dto.MaterialResponse, error) {
+	material, err := s.repository.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	updatedMaterial := typeutil.MustConvert[*model.Material](updateDTO)
+	updatedMaterial.ID = material.ID
+	updatedMaterial.CurriculumID = material.CurriculumID
 	updatedMaterial, err = s.repository.Update(ctx, updatedMaterial)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.record(ctx, actorID, updatedMaterial, "update", updatedMaterial.VersionData()); err != nil {
+		return nil, err
+	}
+
 	return typeutil.MustConvert[*dto.MaterialResponse](updatedMaterial), nil
 }
 
-func (s *Service) Delete(ctx context.Context, id uint64) error {
-	return s.repository.Delete(ctx, id)
+func (s *Service) Delete(ctx context.Context, actorID uint64, id uint64) error {
+	material, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.record(ctx, actorID, material, "delete", nil)
+}
+
+// Move moves the material to a section of the same course, renumbering the
+// materials around it, and returns the materials whose section or order
+// changed.
+func (s *Service) Move(ctx context.Context, actorID uint64, id uint64, moveDTO *dto.MoveMaterialRequest) ([]*dto.MaterialResponse, error) {
+	material, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.curriculumRepository.GetCurriculumByID(ctx, uint64(material.CurriculumID))
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.curriculumRepository.GetCurriculumByID(ctx, moveDTO.CurriculumID)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil || to.ID == 0 || from.CourseID != to.CourseID {
+		return nil, errors.New("A material can only be moved to a section of the same course")
+	}
+
+	moved, err := s.repository.Move(ctx, id, moveDTO.CurriculumID, moveDTO.Position)
+	if err != nil {
+		return nil, err
+	}
+	if moved == nil {
+		return nil, errors.New("Material not found")
+	}
+
+	for _, material := range moved {
+		if err := s.record(ctx, actorID, material, "update", material.VersionData()); err != nil {
+			return nil, err
+		}
+	}
+
+	return typeutil.MustConvert[[]*dto.MaterialResponse](moved), nil
+}
+
+// record adds a version of the material to the history of its course. data
+// holds the versioned fields after the change, nil if it was deleted.
+func (s *Service) record(ctx context.Context, actorID uint64, material *model.Material, action string, data map[string]any) error {
+	curriculum, err := s.curriculumRepository.GetCurriculumByID(ctx, uint64(material.CurriculumID))
+	if err != nil {
+		return err
+	}
+	if curriculum == nil || curriculum.ID == 0 {
+		return errors.New("Curriculum not found")
+	}
+
+	var raw json.RawMessage
+	if data != nil {
+		if raw, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.versionRepository.Record(ctx, &model.ContentVersion{
+		CourseID:   curriculum.CourseID,
+		EntityType: model.VersionMaterial,
+		EntityID:   uint64(material.ID),
+		Action:     action,
+		Data:       raw,
+		AuthorID:   &actorID,
+	})
+	return err
 }
 
 func (s *Service) Name() string {
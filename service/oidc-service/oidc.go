@@ -0,0 +1,257 @@
+package oidcservice
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// loginTTL is how long a started login can wait for the provider callback.
+const loginTTL = 10 * time.Minute
+
+type Repository interface {
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error)
+}
+
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// DefaultRole is given to users provisioned on their first login.
+	DefaultRole string
+	// AllowedDomains restricts logins to these email domains. Empty allows all.
+	AllowedDomains []string
+}
+
+// ConfigFromEnv reads the OIDC_* environment variables.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		IssuerURL:      os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:       os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:         splitList(os.Getenv("OIDC_SCOPES")),
+		DefaultRole:    os.Getenv("OIDC_DEFAULT_ROLE"),
+		AllowedDomains: splitList(os.Getenv("OIDC_ALLOWED_DOMAINS")),
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = "student"
+	}
+	return cfg
+}
+
+// Enabled reports whether enough settings are present to use OIDC login.
+func (c Config) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != "" && c.RedirectURL != ""
+}
+
+type pendingLogin struct {
+	Verifier       string `json:"verifier"`
+	Nonce          string `json:"nonce"`
+	OrganizationID uint64 `json:"organization_id"`
+}
+
+type Service struct {
+	repository Repository
+	redis      redis.UniversalClient
+	provider   *Provider
+	config     Config
+}
+
+func NewService(repository Repository, redis redis.UniversalClient, config Config) *Service {
+	return &Service{
+		repository: repository,
+		redis:      redis,
+		provider:   NewProvider(config.IssuerURL, nil),
+		config:     config,
+	}
+}
+
+// BeginLogin starts an authorization-code flow and returns the URL the user
+// must be redirected to.
+func (s *Service) BeginLogin(ctx context.Context) (string, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	login := pendingLogin{OrganizationID: tenant.ID(ctx)}
+	if login.Verifier, err = randomString(); err != nil {
+		return "", err
+	}
+	if login.Nonce, err = randomString(); err != nil {
+		return "", err
+	}
+
+	loginJSON, err := json.Marshal(login)
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, stateKey(state), loginJSON, loginTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return s.provider.AuthCodeURL(ctx, s.config.ClientID, s.config.RedirectURL, s.config.Scopes, state, login.Nonce, login.Verifier)
+}
+
+// CompleteLogin handles the provider callback and returns the local user
+// matching the authenticated account, provisioning it if needed, and the
+// organization the login was started in. The callback itself carries no
+// organization, so the user is resolved in that one.
+func (s *Service) CompleteLogin(ctx context.Context, code, state string) (*dto.User, uint64, error) {
+	val, err := s.redis.GetDel(ctx, stateKey(state)).Result()
+	if err == redis.Nil {
+		return nil, 0, errors.New("unknown or expired login state")
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	var login pendingLogin
+	if err := json.Unmarshal([]byte(val), &login); err != nil {
+		return nil, 0, err
+	}
+	if login.OrganizationID == 0 {
+		return nil, 0, errors.New("the login was not started in an organization")
+	}
+	ctx = tenant.WithOrganization(ctx, login.OrganizationID)
+
+	rawIDToken, err := s.provider.Exchange(ctx, s.config.ClientID, s.config.ClientSecret, s.config.RedirectURL, code, login.Verifier)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	claims, err := s.provider.VerifyIDToken(ctx, rawIDToken, s.config.ClientID, login.Nonce)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	user, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return typeutil.MustConvert[*dto.User](user), login.OrganizationID, nil
+}
+
+func (s *Service) resolveUser(ctx context.Context, claims *Claims) (*model.User, error) {
+	identity, err := s.repository.GetIdentity(ctx, s.provider.Issuer(), claims.Subject)
+	if err == nil {
+		return s.repository.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// The email decides which account the provider account is linked to, so
+	// it must be one the provider vouches for: a missing email_verified claim
+	// is not enough.
+	if claims.Email == "" {
+		return nil, errors.New("identity provider did not return an email address")
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		return nil, errors.New("email address is not verified by the identity provider")
+	}
+	if !s.domainAllowed(claims.Email) {
+		return nil, fmt.Errorf("email domain of %s is not allowed", claims.Email)
+	}
+
+	// Link to an existing account with the same email, or provision a new one.
+	user, err := s.repository.GetByEmail(ctx, claims.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		name := claims.Name
+		if name == "" {
+			name = claims.Email
+		}
+		user, err = s.repository.Create(ctx, &model.User{
+			Name:  name,
+			Email: claims.Email,
+			Role:  s.config.DefaultRole,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.repository.CreateIdentity(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: s.provider.Issuer(),
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) domainAllowed(email string) bool {
+	if len(s.config.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range s.config.AllowedDomains {
+		if domain == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) Name() string {
+	return service.OIDC
+}
+
+func stateKey(state string) string {
+	return fmt.Sprintf("oidc:state:%s", state)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func splitList(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
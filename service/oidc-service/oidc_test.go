@@ -0,0 +1,295 @@
+package oidcservice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/dapthehuman/learning-management-system/cache/cachetest"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/service/oidc-service/oidctest"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+const clientID = "lms"
+
+type repository struct {
+	users       map[uint64]*model.User
+	identities  []*model.UserIdentity
+	identityErr error
+}
+
+func newRepository(users ...*model.User) *repository {
+	r := &repository{users: make(map[uint64]*model.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *repository) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	user.ID = uint64(len(r.users) + 100)
+	user.OrganizationID = tenant.ID(ctx)
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *repository) GetIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	if r.identityErr != nil {
+		return nil, r.identityErr
+	}
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	r.identities = append(r.identities, identity)
+	return identity, nil
+}
+
+func newService(t *testing.T, repository *repository) (*Service, *oidctest.Provider, *cachetest.Redis) {
+	t.Helper()
+	provider, err := oidctest.NewProvider(clientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	redis := cachetest.NewRedis()
+	service := NewService(repository, redis, Config{
+		IssuerURL:   provider.Issuer(),
+		ClientID:    clientID,
+		RedirectURL: "http://lms.test/auth/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+		DefaultRole: "student",
+	})
+	return service, provider, redis
+}
+
+// authorize starts a login and follows it to the provider, returning the
+// code and state the provider redirects back with.
+func authorize(t *testing.T, ctx context.Context, s *Service) (string, string) {
+	t.Helper()
+	authURL, err := s.BeginLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+// editLogin changes the login stored for the state.
+func editLogin(t *testing.T, redis *cachetest.Redis, state string, edit func(login *pendingLogin)) {
+	t.Helper()
+	value, ok := redis.Value(stateKey(state))
+	if !ok {
+		t.Fatal("no pending login for the state")
+	}
+	var login pendingLogin
+	if err := json.Unmarshal([]byte(value), &login); err != nil {
+		t.Fatal(err)
+	}
+	edit(&login)
+	loginJSON, err := json.Marshal(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis.Put(stateKey(state), string(loginJSON))
+}
+
+func TestCompleteLoginProvisionsUser(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	repository := newRepository()
+	s, provider, _ := newService(t, repository)
+
+	code, state := authorize(t, ctx, s)
+	user, _, err := s.CompleteLogin(ctx, code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "student@example.edu" || user.Role != "student" {
+		t.Errorf("provisioned %s with role %s", user.Email, user.Role)
+	}
+	if len(repository.identities) != 1 || repository.identities[0].Provider != provider.Issuer() {
+		t.Errorf("expected one identity of the provider, got %v", repository.identities)
+	}
+
+	// The state is only good for one callback
+	if _, _, err := s.CompleteLogin(ctx, code, state); err == nil {
+		t.Error("completed the same login twice")
+	}
+}
+
+func TestCompleteLoginRejectsUnknownState(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	s, _, _ := newService(t, newRepository())
+
+	code, _ := authorize(t, ctx, s)
+	if _, _, err := s.CompleteLogin(ctx, code, "forged"); err == nil {
+		t.Error("completed a login with an unknown state")
+	}
+}
+
+func TestCompleteLoginInStartingOrganization(t *testing.T) {
+	repository := newRepository()
+	s, _, _ := newService(t, repository)
+
+	// The callback is not sent to the organization the login was started in
+	code, state := authorize(t, tenant.WithOrganization(context.Background(), 2), s)
+	user, organizationID, err := s.CompleteLogin(tenant.WithOrganization(context.Background(), 1), code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if organizationID != 2 {
+		t.Errorf("expected the login to complete in organization 2, got %d", organizationID)
+	}
+	if stored := repository.users[uint64(user.ID)]; stored.OrganizationID != 2 {
+		t.Errorf("provisioned the user in organization %d", stored.OrganizationID)
+	}
+}
+
+func TestCompleteLoginRejectsUnscopedLogin(t *testing.T) {
+	s, _, _ := newService(t, newRepository())
+
+	code, state := authorize(t, context.Background(), s)
+	if _, _, err := s.CompleteLogin(tenant.WithOrganization(context.Background(), 1), code, state); err == nil {
+		t.Error("completed a login started outside of any organization")
+	}
+}
+
+func TestCompleteLoginRejectsPKCEMismatch(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	repository := newRepository()
+	s, _, redis := newService(t, repository)
+
+	code, state := authorize(t, ctx, s)
+	editLogin(t, redis, state, func(login *pendingLogin) { login.Verifier = "another verifier" })
+	if _, _, err := s.CompleteLogin(ctx, code, state); err == nil {
+		t.Error("exchanged a code with the wrong PKCE verifier")
+	}
+	if len(repository.users) != 0 {
+		t.Error("provisioned a user for a rejected login")
+	}
+}
+
+func TestCompleteLoginRejectsBadNonce(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	repository := newRepository()
+	s, _, redis := newService(t, repository)
+
+	code, state := authorize(t, ctx, s)
+	editLogin(t, redis, state, func(login *pendingLogin) { login.Nonce = "another nonce" })
+	if _, _, err := s.CompleteLogin(ctx, code, state); err == nil {
+		t.Error("accepted an ID token with the wrong nonce")
+	}
+	if len(repository.users) != 0 {
+		t.Error("provisioned a user for a rejected login")
+	}
+}
+
+func TestCompleteLoginLinking(t *testing.T) {
+	existing := func() *model.User {
+		return &model.User{ID: 1, Name: "Local", Email: "student@example.edu", Role: "admin"}
+	}
+
+	cases := []struct {
+		name          string
+		emailVerified any // Left out of the ID token when nil
+		identityErr   error
+		linked        bool
+	}{
+		{name: "verified email", emailVerified: true, linked: true},
+		{name: "unverified email", emailVerified: false},
+		{name: "missing email_verified", emailVerified: nil},
+		{name: "identity lookup failure", emailVerified: true, identityErr: errors.New("connection refused")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := tenant.WithOrganization(context.Background(), 1)
+			repository := newRepository(existing())
+			repository.identityErr = c.identityErr
+			s, provider, _ := newService(t, repository)
+			if c.emailVerified == nil {
+				delete(provider.Claims, "email_verified")
+			} else {
+				provider.Claims["email_verified"] = c.emailVerified
+			}
+
+			code, state := authorize(t, ctx, s)
+			user, _, err := s.CompleteLogin(ctx, code, state)
+			if !c.linked {
+				if err == nil {
+					t.Fatalf("logged in as user %d", user.ID)
+				}
+				if len(repository.identities) != 0 || len(repository.users) != 1 {
+					t.Error("linked or provisioned an account")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != 1 || len(repository.identities) != 1 || repository.identities[0].UserID != 1 {
+				t.Errorf("expected a link to user 1, got user %d and identities %v", user.ID, repository.identities)
+			}
+		})
+	}
+}
+
+func TestCompleteLoginUsesLinkedIdentity(t *testing.T) {
+	ctx := tenant.WithOrganization(context.Background(), 1)
+	repository := newRepository(&model.User{ID: 7, Email: "someone@example.edu"})
+	s, provider, _ := newService(t, repository)
+	repository.identities = append(repository.identities, &model.UserIdentity{UserID: 7, Provider: provider.Issuer(), Subject: "oidctest-user"})
+
+	// Once linked, the email of the provider account no longer matters
+	delete(provider.Claims, "email_verified")
+	code, state := authorize(t, ctx, s)
+	user, _, err := s.CompleteLogin(ctx, code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 7 || len(repository.identities) != 1 {
+		t.Errorf("expected user 7 through its identity, got user %d", user.ID)
+	}
+}
@@ -0,0 +1,150 @@
+package organizationservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	adminDto "github.com/dapthehuman/learning-management-system/dto/admin"
+	dto "github.com/dapthehuman/learning-management-system/dto/organization"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+// slugPattern matches a valid DNS label, as slugs are used as subdomains.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// reservedSlugs cannot be used by organizations because they are commonly
+// used for other hosts of the platform.
+var reservedSlugs = map[string]bool{"www": true, "api": true, "app": true, "admin": true, "mail": true}
+
+type Repository interface {
+	GetBySlug(ctx context.Context, slug string) (*model.Organization, error)
+	GetByID(ctx context.Context, id uint64) (*model.Organization, error)
+	Create(ctx context.Context, organization *model.Organization) (*model.Organization, error)
+	Update(ctx context.Context, organization *model.Organization) (*model.Organization, error)
+}
+
+// Inviter invites users to the organization of the context.
+type Inviter interface {
+	Create(ctx context.Context, actorID uint64, createDTO *adminDto.CreateInvitationRequest) (*adminDto.InvitationResponse, error)
+}
+
+type Service struct {
+	repository Repository
+	inviter    Inviter
+}
+
+func NewService(repository Repository, inviter Inviter) *Service {
+	return &Service{
+		repository: repository,
+		inviter:    inviter,
+	}
+}
+
+// Resolve returns the organization having the given slug.
+func (s *Service) Resolve(ctx context.Context, slug string) (*dto.Organization, error) {
+	organization, err := s.repository.GetBySlug(ctx, strings.ToLower(slug))
+	if err != nil {
+		return nil, errors.New("organization not found")
+	}
+
+	return typeutil.MustConvert[*dto.Organization](organization), nil
+}
+
+// Current returns the organization the context is scoped to.
+func (s *Service) Current(ctx context.Context) (*dto.Organization, error) {
+	organization, err := s.repository.GetByID(ctx, tenant.ID(ctx))
+	if err != nil {
+		return nil, errors.New("organization not found")
+	}
+
+	return typeutil.MustConvert[*dto.Organization](organization), nil
+}
+
+// Create creates an organization and invites its first administrator into
+// it on behalf of actorID.
+func (s *Service) Create(ctx context.Context, actorID uint64, createDTO *dto.CreateOrganizationRequest) (*dto.CreateOrganizationResponse, error) {
+	slug := strings.ToLower(strings.TrimSpace(createDTO.Slug))
+	if !slugPattern.MatchString(slug) || reservedSlugs[slug] {
+		return nil, errors.New("the slug must be a valid subdomain of lowercase letters, digits and hyphens")
+	}
+	if strings.TrimSpace(createDTO.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	if address, err := mail.ParseAddress(createDTO.AdminEmail); err != nil || address.Address != createDTO.AdminEmail {
+		return nil, errors.New("admin_email is invalid")
+	}
+	if _, err := s.repository.GetBySlug(ctx, slug); err == nil {
+		return nil, errors.New("an organization with this slug already exists")
+	}
+
+	settings, err := marshalSettings(createDTO.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	organization, err := s.repository.Create(ctx, &model.Organization{
+		Slug:     slug,
+		Name:     strings.TrimSpace(createDTO.Name),
+		Settings: settings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.inviter.Create(tenant.WithOrganization(ctx, organization.ID), actorID, &adminDto.CreateInvitationRequest{
+		Email: createDTO.AdminEmail,
+		Name:  createDTO.AdminName,
+		Role:  "admin",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateOrganizationResponse{
+		Organization:    typeutil.MustConvert[*dto.Organization](organization),
+		AdminInvitation: invitation,
+	}, nil
+}
+
+// UpdateCurrent changes the name or the settings of the organization the
+// context is scoped to.
+func (s *Service) UpdateCurrent(ctx context.Context, updateDTO *dto.UpdateOrganizationRequest) (*dto.Organization, error) {
+	organization, err := s.repository.GetByID(ctx, tenant.ID(ctx))
+	if err != nil {
+		return nil, errors.New("organization not found")
+	}
+
+	if name := strings.TrimSpace(updateDTO.Name); name != "" {
+		organization.Name = name
+	}
+	if updateDTO.Settings != nil {
+		if organization.Settings, err = marshalSettings(updateDTO.Settings); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.repository.Update(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Organization](updated), nil
+}
+
+func (s *Service) Name() string {
+	return service.Organization
+}
+
+func marshalSettings(settings map[string]any) (json.RawMessage, error) {
+	if settings == nil {
+		settings = map[string]any{}
+	}
+	return json.Marshal(settings)
+}
@@ -0,0 +1,282 @@
+package privacyservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+const (
+	// ExportLifetime is how long a completed data export can be downloaded.
+	ExportLifetime = 7 * 24 * time.Hour
+
+	// exportTimeout bounds the generation of an archive. A pending export
+	// older than this is considered abandoned and a new one can be requested.
+	exportTimeout = 10 * time.Minute
+)
+
+var (
+	ErrExportNotReady = errors.New("the data export is not ready")
+	ErrExportExpired  = errors.New("the data export has expired, request a new one")
+)
+
+type Repository interface {
+	CreateExport(ctx context.Context, userID uint64) (*model.DataExport, error)
+	GetLatestExport(ctx context.Context, userID uint64) (*model.DataExport, error)
+	GetExportArchive(ctx context.Context, userID uint64, id uint64) (*model.DataExport, error)
+	CompleteExport(ctx context.Context, id uint64, archive []byte, expiresAt time.Time) error
+	FailExport(ctx context.Context, id uint64, message string) error
+	GetUserData(ctx context.Context, userID uint64) (model.UserData, error)
+
+	CreateErasureRequest(ctx context.Context, userID uint64, reason string) (*model.ErasureRequest, error)
+	GetLatestErasureRequest(ctx context.Context, userID uint64) (*model.ErasureRequest, error)
+	GetErasureRequest(ctx context.Context, id uint64) (*model.ErasureRequest, error)
+	ListErasureRequests(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.ErasureRequest], error)
+	RejectErasureRequest(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error)
+	Erase(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
+}
+
+type Service struct {
+	repository      Repository
+	auditRepository AuditRepository
+}
+
+func NewService(repository Repository, auditRepository AuditRepository) *Service {
+	return &Service{
+		repository:      repository,
+		auditRepository: auditRepository,
+	}
+}
+
+// RequestExport starts generating an archive of the personal data of the
+// user in the background. If an export is already being generated, it is
+// returned instead of starting a new one.
+func (s *Service) RequestExport(ctx context.Context, userID uint64) (*dto.DataExport, error) {
+	latest, err := s.repository.GetLatestExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == "pending" && time.Since(latest.CreatedAt) < exportTimeout {
+		return typeutil.MustConvert[*dto.DataExport](latest), nil
+	}
+
+	export, err := s.repository.CreateExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.generateExport(tenant.ID(ctx), export.ID, userID)
+
+	return typeutil.MustConvert[*dto.DataExport](export), nil
+}
+
+func (s *Service) LatestExport(ctx context.Context, userID uint64) (*dto.DataExport, error) {
+	export, err := s.repository.GetLatestExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, errors.New("no data export requested")
+	}
+
+	return typeutil.MustConvert[*dto.DataExport](export), nil
+}
+
+// DownloadExport returns the ZIP archive of a completed export of the user.
+func (s *Service) DownloadExport(ctx context.Context, userID uint64, id uint64) ([]byte, error) {
+	export, err := s.repository.GetExportArchive(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Status != "completed" {
+		return nil, ErrExportNotReady
+	}
+	if export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now()) {
+		return nil, ErrExportExpired
+	}
+
+	return export.Archive, nil
+}
+
+// generateExport builds the archive, outside of the request that asked for it.
+func (s *Service) generateExport(organizationID uint64, id uint64, userID uint64) {
+	ctx, cancel := context.WithTimeout(tenant.WithOrganization(context.Background(), organizationID), exportTimeout)
+	defer cancel()
+
+	// Nothing recovers the panics of this goroutine, they would stop the server
+	defer func() {
+		if recover() != nil {
+			_ = s.repository.FailExport(ctx, id, "The archive could not be generated, please request a new export.")
+		}
+	}()
+
+	archive, err := s.buildArchive(ctx, userID)
+	if err != nil {
+		_ = s.repository.FailExport(ctx, id, "The archive could not be generated, please request a new export.")
+		return
+	}
+
+	if err := s.repository.CompleteExport(ctx, id, archive, time.Now().Add(ExportLifetime)); err != nil {
+		_ = s.repository.FailExport(ctx, id, "The archive could not be saved, please request a new export.")
+	}
+}
+
+// buildArchive zips every section of the personal data of the user as an
+// indented JSON file.
+func (s *Service) buildArchive(ctx context.Context, userID uint64) ([]byte, error) {
+	data, err := s.repository.GetUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]string, 0, len(data))
+	for section := range data {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for _, section := range sections {
+		file, err := archive.Create(section + ".json")
+		if err != nil {
+			return nil, err
+		}
+
+		indented := new(bytes.Buffer)
+		if err := json.Indent(indented, data[section], "", "  "); err != nil {
+			return nil, err
+		}
+		if _, err := indented.WriteTo(file); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RequestErasure asks for the personal data of the user to be erased. An
+// administrator has to approve the request.
+func (s *Service) RequestErasure(ctx context.Context, userID uint64, createDTO *dto.CreateErasureRequest) (*dto.ErasureRequest, error) {
+	latest, err := s.repository.GetLatestErasureRequest(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == "pending" {
+		return nil, errors.New("an erasure request is already awaiting review")
+	}
+
+	request, err := s.repository.CreateErasureRequest(ctx, userID, createDTO.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.ErasureRequest](request), nil
+}
+
+func (s *Service) LatestErasureRequest(ctx context.Context, userID uint64) (*dto.ErasureRequest, error) {
+	request, err := s.repository.GetLatestErasureRequest(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, errors.New("no erasure requested")
+	}
+
+	return typeutil.MustConvert[*dto.ErasureRequest](request), nil
+}
+
+func (s *Service) ListErasureRequests(ctx context.Context, status string, q *listing.Query) (*listing.Page[*dto.ErasureRequest], error) {
+	requests, err := s.repository.ListErasureRequests(ctx, status, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*listing.Page[*dto.ErasureRequest]](requests), nil
+}
+
+// ApproveErasure anonymizes the personal data of the user who made the
+// request. This cannot be undone.
+func (s *Service) ApproveErasure(ctx context.Context, reviewerID uint64, id uint64, reviewDTO *dto.ReviewErasureRequest) (*dto.ErasureRequest, error) {
+	if err := s.checkReviewer(ctx, reviewerID, id); err != nil {
+		return nil, err
+	}
+
+	request, err := s.repository.Erase(ctx, id, reviewerID, reviewDTO.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "erasure.approve", reviewerID, request); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.ErasureRequest](request), nil
+}
+
+func (s *Service) RejectErasure(ctx context.Context, reviewerID uint64, id uint64, reviewDTO *dto.ReviewErasureRequest) (*dto.ErasureRequest, error) {
+	if err := s.checkReviewer(ctx, reviewerID, id); err != nil {
+		return nil, err
+	}
+
+	request, err := s.repository.RejectErasureRequest(ctx, id, reviewerID, reviewDTO.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, "erasure.reject", reviewerID, request); err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.ErasureRequest](request), nil
+}
+
+// checkReviewer prevents administrators from reviewing their own request.
+func (s *Service) checkReviewer(ctx context.Context, reviewerID uint64, id uint64) error {
+	request, err := s.repository.GetErasureRequest(ctx, id)
+	if err != nil {
+		return errors.New("erasure request not found")
+	}
+	if request.UserID == reviewerID {
+		return errors.New("you cannot review your own erasure request")
+	}
+	return nil
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, request *model.ErasureRequest) error {
+	raw, err := json.Marshal(map[string]any{"erasure_request_id": request.ID})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:      &actorID,
+		Action:       action,
+		TargetUserID: &request.UserID,
+		Metadata:     raw,
+	})
+	return err
+}
+
+func (s *Service) Name() string {
+	return service.Privacy
+}
@@ -0,0 +1,150 @@
+package privacyservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/listing"
+)
+
+type repository struct {
+	mu       sync.Mutex
+	exports  map[uint64]*model.DataExport
+	data     func() model.UserData
+	requests map[uint64]*model.ErasureRequest
+}
+
+func newRepository(data func() model.UserData) *repository {
+	return &repository{exports: make(map[uint64]*model.DataExport), data: data, requests: make(map[uint64]*model.ErasureRequest)}
+}
+
+func (r *repository) export(id uint64) model.DataExport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.exports[id]
+}
+
+func (r *repository) CreateExport(ctx context.Context, userID uint64) (*model.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	export := &model.DataExport{ID: uint64(len(r.exports) + 1), UserID: userID, Status: "pending", CreatedAt: time.Now()}
+	r.exports[export.ID] = export
+	return export, nil
+}
+
+func (r *repository) GetLatestExport(ctx context.Context, userID uint64) (*model.DataExport, error) {
+	return nil, nil
+}
+
+func (r *repository) GetExportArchive(ctx context.Context, userID uint64, id uint64) (*model.DataExport, error) {
+	export := r.export(id)
+	return &export, nil
+}
+
+func (r *repository) CompleteExport(ctx context.Context, id uint64, archive []byte, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exports[id].Status = "completed"
+	r.exports[id].Archive = archive
+	r.exports[id].ExpiresAt = &expiresAt
+	return nil
+}
+
+func (r *repository) FailExport(ctx context.Context, id uint64, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exports[id].Status = "failed"
+	r.exports[id].Error = &message
+	return nil
+}
+
+func (r *repository) GetUserData(ctx context.Context, userID uint64) (model.UserData, error) {
+	return r.data(), nil
+}
+
+func (r *repository) CreateErasureRequest(ctx context.Context, userID uint64, reason string) (*model.ErasureRequest, error) {
+	request := &model.ErasureRequest{ID: uint64(len(r.requests) + 1), UserID: userID, Status: "pending", Reason: reason}
+	r.requests[request.ID] = request
+	return request, nil
+}
+
+func (r *repository) GetLatestErasureRequest(ctx context.Context, userID uint64) (*model.ErasureRequest, error) {
+	return nil, nil
+}
+
+func (r *repository) GetErasureRequest(ctx context.Context, id uint64) (*model.ErasureRequest, error) {
+	return r.requests[id], nil
+}
+
+func (r *repository) ListErasureRequests(ctx context.Context, status string, q *listing.Query) (*listing.Page[*model.ErasureRequest], error) {
+	return &listing.Page[*model.ErasureRequest]{}, nil
+}
+
+func (r *repository) RejectErasureRequest(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error) {
+	r.requests[id].Status = "rejected"
+	return r.requests[id], nil
+}
+
+func (r *repository) Erase(ctx context.Context, id uint64, reviewerID uint64, note string) (*model.ErasureRequest, error) {
+	r.requests[id].Status = "approved"
+	return r.requests[id], nil
+}
+
+type auditRepository struct{}
+
+func (auditRepository) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	return entry, nil
+}
+
+func TestGenerateExport(t *testing.T) {
+	repository := newRepository(func() model.UserData {
+		return model.UserData{"profile": json.RawMessage(`{"name":"Ada"}`), "enrollments": json.RawMessage(`[]`)}
+	})
+	s := NewService(repository, auditRepository{})
+
+	export, _ := repository.CreateExport(context.Background(), 1)
+	s.generateExport(1, export.ID, 1)
+
+	completed := repository.export(export.ID)
+	if completed.Status != "completed" || completed.ExpiresAt == nil {
+		t.Fatalf("expected a completed export, got %+v", completed)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(completed.Archive), int64(len(completed.Archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.File) != 2 || archive.File[0].Name != "enrollments.json" || archive.File[1].Name != "profile.json" {
+		t.Errorf("unexpected archive content %v", archive.File)
+	}
+}
+
+func TestGenerateExportRecoversPanic(t *testing.T) {
+	repository := newRepository(func() model.UserData { panic("unexpected") })
+	s := NewService(repository, auditRepository{})
+
+	export, _ := repository.CreateExport(context.Background(), 1)
+	s.generateExport(1, export.ID, 1)
+
+	if failed := repository.export(export.ID); failed.Status != "failed" || failed.Error == nil {
+		t.Errorf("expected a failed export, got %+v", failed)
+	}
+}
+
+func TestReviewOwnErasureRequest(t *testing.T) {
+	repository := newRepository(nil)
+	s := NewService(repository, auditRepository{})
+
+	request, _ := repository.CreateErasureRequest(context.Background(), 1, "")
+	if _, err := s.ApproveErasure(context.Background(), 1, request.ID, nil); err == nil {
+		t.Error("a user approved their own erasure request")
+	}
+	if request.Status != "pending" {
+		t.Errorf("request is %s", request.Status)
+	}
+}
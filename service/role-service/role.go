@@ -0,0 +1,162 @@
+package roleservice
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/role"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]*model.Role, error)
+	GetByName(ctx context.Context, name string) (*model.Role, error)
+	GetPermissions(ctx context.Context, name string) ([]string, error)
+	ListPermissions(ctx context.Context) ([]*model.Permission, error)
+	Create(ctx context.Context, role *model.Role) (*model.Role, error)
+	Update(ctx context.Context, role *model.Role) (*model.Role, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type Service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *Service {
+	return &Service{
+		repository: repository,
+	}
+}
+
+// HasPermissions reports whether the role is granted all the given permissions.
+func (s *Service) HasPermissions(ctx context.Context, role string, permissions ...string) (bool, error) {
+	granted, err := s.repository.GetPermissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+
+	for _, permission := range permissions {
+		found := false
+		for _, g := range granted {
+			if g == permission {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]*dto.Role, error) {
+	roles, err := s.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[[]*dto.Role](roles), nil
+}
+
+func (s *Service) GetByName(ctx context.Context, name string) (*dto.Role, error) {
+	role, err := s.repository.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Role](role), nil
+}
+
+func (s *Service) ListPermissions(ctx context.Context) ([]*dto.Permission, error) {
+	permissions, err := s.repository.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[[]*dto.Permission](permissions), nil
+}
+
+func (s *Service) Create(ctx context.Context, createDTO *dto.CreateRoleRequest) (*dto.Role, error) {
+	if !roleName.MatchString(createDTO.Name) {
+		return nil, fmt.Errorf("invalid role name %q, use lowercase letters, digits and underscores", createDTO.Name)
+	}
+
+	if err := s.checkPermissions(ctx, createDTO.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := typeutil.MustConvert[*model.Role](createDTO)
+	createdRole, err := s.repository.Create(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Role](createdRole), nil
+}
+
+func (s *Service) Update(ctx context.Context, name string, updateDTO *dto.UpdateRoleRequest) (*dto.Role, error) {
+	role, err := s.repository.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPermissions(ctx, updateDTO.Permissions); err != nil {
+		return nil, err
+	}
+
+	role.Description = updateDTO.Description
+	role.Permissions = updateDTO.Permissions
+	updatedRole, err := s.repository.Update(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.Role](updatedRole), nil
+}
+
+func (s *Service) Delete(ctx context.Context, name string) error {
+	role, err := s.repository.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return errors.New("System roles cannot be deleted")
+	}
+
+	return s.repository.Delete(ctx, name)
+}
+
+func (s *Service) checkPermissions(ctx context.Context, permissions []string) error {
+	known, err := s.repository.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		found := false
+		for _, k := range known {
+			if k.Name == permission {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) Name() string {
+	return service.Role
+}
@@ -0,0 +1,172 @@
+package samlservice
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	metadataNS  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	protocolNS  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	assertionNS = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	nameIDFormatEmail   = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	statusSuccess       = "urn:oasis:names:tc:SAML:2.0:status:Success"
+)
+
+type spEntityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormat               string `xml:"NameIDFormat"`
+		AssertionConsumerService   struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		} `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+type idpEntityDescriptor struct {
+	XMLName          xml.Name `xml:"EntityDescriptor"`
+	EntityID         string   `xml:"entityID,attr"`
+	IDPSSODescriptor struct {
+		KeyDescriptors []struct {
+			Use         string `xml:"use,attr"`
+			Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		SingleSignOnServices []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"SingleSignOnService"`
+	} `xml:"IDPSSODescriptor"`
+}
+
+type authnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+		Value   string   `xml:",chardata"`
+	}
+	NameIDPolicy struct {
+		Format      string `xml:"Format,attr"`
+		AllowCreate bool   `xml:"AllowCreate,attr"`
+	} `xml:"NameIDPolicy"`
+}
+
+// spMetadata renders the service provider metadata for an organization.
+func spMetadata(entityID, acsURL string) ([]byte, error) {
+	descriptor := spEntityDescriptor{EntityID: entityID}
+	descriptor.SPSSODescriptor.WantAssertionsSigned = true
+	descriptor.SPSSODescriptor.ProtocolSupportEnumeration = protocolNS
+	descriptor.SPSSODescriptor.NameIDFormat = nameIDFormatEmail
+	descriptor.SPSSODescriptor.AssertionConsumerService.Binding = bindingHTTPPost
+	descriptor.SPSSODescriptor.AssertionConsumerService.Location = acsURL
+	descriptor.SPSSODescriptor.AssertionConsumerService.Index = 1
+
+	out, err := xml.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// parseIdPMetadata extracts the entity ID, redirect SSO location and signing
+// certificate (as PEM) from an IdP EntityDescriptor.
+func parseIdPMetadata(metadata string) (entityID, ssoURL, certificate string, err error) {
+	var descriptor idpEntityDescriptor
+	if err := xml.Unmarshal([]byte(metadata), &descriptor); err != nil {
+		return "", "", "", err
+	}
+
+	if descriptor.EntityID == "" {
+		return "", "", "", errors.New("metadata has no entityID")
+	}
+
+	for _, sso := range descriptor.IDPSSODescriptor.SingleSignOnServices {
+		if sso.Binding == bindingHTTPRedirect {
+			ssoURL = sso.Location
+			break
+		}
+	}
+	if ssoURL == "" {
+		return "", "", "", errors.New("metadata has no HTTP-Redirect SingleSignOnService")
+	}
+
+	for _, key := range descriptor.IDPSSODescriptor.KeyDescriptors {
+		if key.Use == "" || key.Use == "signing" {
+			der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(key.Certificate), ""))
+			if err != nil {
+				return "", "", "", err
+			}
+			certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+			break
+		}
+	}
+	if certificate == "" {
+		return "", "", "", errors.New("metadata has no signing certificate")
+	}
+
+	return descriptor.EntityID, ssoURL, certificate, nil
+}
+
+// authnRequestURL builds an HTTP-Redirect binding URL carrying an AuthnRequest.
+func authnRequestURL(id, spEntityID, acsURL, ssoURL, relayState string) (string, error) {
+	request := authnRequest{
+		ID:                          id,
+		Version:                     "2.0",
+		IssueInstant:                time.Now().UTC().Format(time.RFC3339),
+		Destination:                 ssoURL,
+		AssertionConsumerServiceURL: acsURL,
+		ProtocolBinding:             bindingHTTPPost,
+	}
+	request.Issuer.Value = spEntityID
+	request.NameIDPolicy.Format = nameIDFormatEmail
+	request.NameIDPolicy.AllowCreate = true
+
+	out, err := xml.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(out); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	params := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString(buf.Bytes())}}
+	if relayState != "" {
+		params.Set("RelayState", relayState)
+	}
+
+	separator := "?"
+	if strings.Contains(ssoURL, "?") {
+		separator = "&"
+	}
+	return ssoURL + separator + params.Encode(), nil
+}
@@ -0,0 +1,441 @@
+package samlservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	authDto "github.com/dapthehuman/learning-management-system/dto/auth"
+	"github.com/dapthehuman/learning-management-system/service"
+	"github.com/dapthehuman/learning-management-system/service/saml-service/xmldsig"
+	"github.com/dapthehuman/learning-management-system/tenant"
+	"github.com/redis/go-redis/v9"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+const (
+	// clockSkew is the tolerance applied to the assertion validity window.
+	clockSkew = 2 * time.Minute
+	// requestTTL is how long a started login can wait for the IdP response.
+	requestTTL = 10 * time.Minute
+)
+
+type Repository interface {
+	Get(ctx context.Context) (*model.SAMLProvider, error)
+	Save(ctx context.Context, provider *model.SAMLProvider) (*model.SAMLProvider, error)
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error)
+}
+
+type RoleRepository interface {
+	Exists(ctx context.Context, name string) (bool, error)
+}
+
+type assertion struct {
+	ID      string `xml:"ID,attr"`
+	Issuer  string `xml:"Issuer"`
+	Subject struct {
+		NameID              string `xml:"NameID"`
+		SubjectConfirmation struct {
+			Data struct {
+				NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
+				Recipient    string `xml:"Recipient,attr"`
+				InResponseTo string `xml:"InResponseTo,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore    string   `xml:"NotBefore,attr"`
+		NotOnOrAfter string   `xml:"NotOnOrAfter,attr"`
+		Audiences    []string `xml:"AudienceRestriction>Audience"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name         string   `xml:"Name,attr"`
+		FriendlyName string   `xml:"FriendlyName,attr"`
+		Values       []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+func (a *assertion) attribute(name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, attr := range a.Attributes {
+		if (attr.Name == name || attr.FriendlyName == name) && len(attr.Values) > 0 {
+			return strings.TrimSpace(attr.Values[0])
+		}
+	}
+	return ""
+}
+
+type Service struct {
+	repository     Repository
+	userRepository UserRepository
+	roleRepository RoleRepository
+	redis          redis.UniversalClient
+	baseURL        string
+}
+
+// NewService creates the SAML service provider. baseURL is the public URL of
+// the application, used to build the SP entity ID and ACS endpoint.
+func NewService(repository Repository, userRepository UserRepository, roleRepository RoleRepository, redis redis.UniversalClient, baseURL string) *Service {
+	return &Service{
+		repository:     repository,
+		userRepository: userRepository,
+		roleRepository: roleRepository,
+		redis:          redis,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// BaseURLFromEnv returns the SP base URL from SAML_SP_BASE_URL.
+func BaseURLFromEnv() string {
+	return os.Getenv("SAML_SP_BASE_URL")
+}
+
+func (s *Service) EntityID(organization string) string {
+	return fmt.Sprintf("%s/auth/saml/%s/metadata", s.baseURL, organization)
+}
+
+func (s *Service) ACSURL(organization string) string {
+	return fmt.Sprintf("%s/auth/saml/%s/acs", s.baseURL, organization)
+}
+
+func (s *Service) Metadata(ctx context.Context, organization string) ([]byte, error) {
+	if _, err := s.repository.Get(ctx); err != nil {
+		return nil, err
+	}
+
+	return spMetadata(s.EntityID(organization), s.ACSURL(organization))
+}
+
+// LoginURL returns the IdP URL starting an SP-initiated login.
+func (s *Service) LoginURL(ctx context.Context, organization string, relayState string) (string, error) {
+	provider, err := s.repository.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	// Responses are only accepted for the requests sent from here, once, and
+	// in the organization that sent them
+	id := "_" + hex.EncodeToString(random)
+	if err := s.redis.Set(ctx, requestKey(id), strconv.FormatUint(tenant.ID(ctx), 10), requestTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return authnRequestURL(id, s.EntityID(organization), s.ACSURL(organization), provider.SSOURL, relayState)
+}
+
+func (s *Service) SaveProvider(ctx context.Context, providerDTO *authDto.SAMLProviderRequest) (*authDto.SAMLProvider, error) {
+	entityID, ssoURL, certificate, err := parseIdPMetadata(providerDTO.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &model.SAMLProvider{
+		EntityID:       entityID,
+		SSOURL:         ssoURL,
+		Certificate:    certificate,
+		DefaultRole:    providerDTO.DefaultRole,
+		EmailAttribute: providerDTO.EmailAttribute,
+		NameAttribute:  providerDTO.NameAttribute,
+		RoleAttribute:  providerDTO.RoleAttribute,
+	}
+	if provider.DefaultRole == "" {
+		provider.DefaultRole = "student"
+	}
+	if ok, err := s.roleRepository.Exists(ctx, provider.DefaultRole); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("invalid default role %q", provider.DefaultRole)
+	}
+	provider.AllowedRoles = make([]string, 0, len(providerDTO.AllowedRoles))
+	for _, role := range providerDTO.AllowedRoles {
+		if ok, err := s.roleRepository.Exists(ctx, role); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("invalid allowed role %q", role)
+		}
+		provider.AllowedRoles = append(provider.AllowedRoles, role)
+	}
+	if provider.EmailAttribute == "" {
+		provider.EmailAttribute = "email"
+	}
+	if provider.NameAttribute == "" {
+		provider.NameAttribute = "name"
+	}
+
+	provider, err = s.repository.Save(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*authDto.SAMLProvider](provider), nil
+}
+
+// ConsumeResponse validates a base64 encoded SAML Response posted to the ACS
+// endpoint of an organization and returns the authenticated user. ctx must
+// be scoped to the organization whose slug is given.
+func (s *Service) ConsumeResponse(ctx context.Context, organization string, samlResponse string) (*dto.User, error) {
+	provider, err := s.repository.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(provider.Certificate))
+	if block == nil {
+		return nil, errors.New("invalid identity provider certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := xmldsig.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	assertionNode, err := verifiedAssertion(root, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the verified element is read from now on, so content outside the
+	// signature cannot be injected.
+	canonical, err := xmldsig.Canonicalize(assertionNode, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var a assertion
+	if err := xml.Unmarshal(canonical, &a); err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := s.validate(&a, provider, organization, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The assertion must answer a login started here for this organization.
+	// IdP-initiated logins are not accepted.
+	if a.Subject.SubjectConfirmation.Data.InResponseTo == "" {
+		return nil, errors.New("assertion does not answer a login request")
+	}
+	if inResponseTo := root.Attr("InResponseTo"); inResponseTo != "" && inResponseTo != a.Subject.SubjectConfirmation.Data.InResponseTo {
+		return nil, errors.New("response and assertion answer different requests")
+	}
+	requested, err := s.redis.GetDel(ctx, requestKey(a.Subject.SubjectConfirmation.Data.InResponseTo)).Result()
+	if err == redis.Nil || (err == nil && requested != strconv.FormatUint(tenant.ID(ctx), 10)) {
+		return nil, errors.New("unknown or expired login request")
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Reject replayed assertions until they expire.
+	ok, err := s.redis.SetNX(ctx, fmt.Sprintf("saml:assertion:%s", a.ID), 1, time.Until(expiresAt)+clockSkew).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("assertion has already been used")
+	}
+
+	user, err := s.resolveUser(ctx, &a, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return typeutil.MustConvert[*dto.User](user), nil
+}
+
+func verifiedAssertion(root *xmldsig.Node, cert *x509.Certificate) (*xmldsig.Node, error) {
+	if root.Local != "Response" || root.Namespace() != protocolNS {
+		return nil, errors.New("document is not a SAML response")
+	}
+
+	status := root.Child(protocolNS, "Status")
+	if status == nil || status.Child(protocolNS, "StatusCode") == nil || status.Child(protocolNS, "StatusCode").Attr("Value") != statusSuccess {
+		return nil, errors.New("identity provider returned an unsuccessful status")
+	}
+
+	if root.Child(assertionNS, "EncryptedAssertion") != nil {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+
+	assertions := make([]*xmldsig.Node, 0, 1)
+	for _, c := range root.ChildElements() {
+		if c.Local == "Assertion" && c.Namespace() == assertionNS {
+			assertions = append(assertions, c)
+		}
+	}
+	if len(assertions) != 1 {
+		return nil, errors.New("response must contain exactly one assertion")
+	}
+
+	err := xmldsig.Verify(root, cert)
+	if err == nil {
+		return assertions[0], nil
+	}
+	if err != xmldsig.ErrNotSigned {
+		return nil, err
+	}
+
+	if err := xmldsig.Verify(assertions[0], cert); err != nil {
+		return nil, err
+	}
+	return assertions[0], nil
+}
+
+// validate checks the issuer, audience, recipient and validity window of the
+// assertion and returns the time it expires. The audience and the recipient
+// are required.
+func (s *Service) validate(a *assertion, provider *model.SAMLProvider, organization string, now time.Time) (time.Time, error) {
+	if a.ID == "" {
+		return time.Time{}, errors.New("assertion has no ID")
+	}
+
+	if strings.TrimSpace(a.Issuer) != provider.EntityID {
+		return time.Time{}, errors.New("assertion issuer mismatch")
+	}
+
+	// Without an audience and a recipient, an assertion issued for another
+	// service provider of the same IdP could be replayed here
+	audienceOK := false
+	for _, audience := range a.Conditions.Audiences {
+		if strings.TrimSpace(audience) == s.EntityID(organization) {
+			audienceOK = true
+		}
+	}
+	if !audienceOK {
+		return time.Time{}, errors.New("assertion audience mismatch")
+	}
+
+	if a.Subject.SubjectConfirmation.Data.Recipient != s.ACSURL(organization) {
+		return time.Time{}, errors.New("assertion recipient mismatch")
+	}
+
+	if a.Conditions.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339Nano, a.Conditions.NotBefore)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if now.Add(clockSkew).Before(notBefore) {
+			return time.Time{}, errors.New("assertion is not yet valid")
+		}
+	}
+
+	expiresAt := now.Add(time.Hour)
+	for _, value := range []string{a.Conditions.NotOnOrAfter, a.Subject.SubjectConfirmation.Data.NotOnOrAfter} {
+		if value == "" {
+			continue
+		}
+		notOnOrAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !now.Add(-clockSkew).Before(notOnOrAfter) {
+			return time.Time{}, errors.New("assertion has expired")
+		}
+		if notOnOrAfter.Before(expiresAt) {
+			expiresAt = notOnOrAfter
+		}
+	}
+
+	return expiresAt, nil
+}
+
+func (s *Service) resolveUser(ctx context.Context, a *assertion, provider *model.SAMLProvider) (*model.User, error) {
+	nameID := strings.TrimSpace(a.Subject.NameID)
+	if nameID == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+
+	identity, err := s.userRepository.GetIdentity(ctx, provider.EntityID, nameID)
+	if err == nil {
+		return s.userRepository.GetByID(ctx, identity.UserID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	email := a.attribute(provider.EmailAttribute)
+	if email == "" && strings.Contains(nameID, "@") {
+		email = nameID
+	}
+	if email == "" {
+		return nil, errors.New("assertion has no email attribute")
+	}
+
+	user, err := s.userRepository.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	} else if err != nil {
+		name := a.attribute(provider.NameAttribute)
+		if name == "" {
+			name = email
+		}
+		role := provider.DefaultRole
+		if mapped := a.attribute(provider.RoleAttribute); slices.Contains(provider.AllowedRoles, mapped) {
+			role = mapped
+		}
+
+		user, err = s.userRepository.Create(ctx, &model.User{
+			Name:  name,
+			Email: email,
+			Role:  role,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.userRepository.CreateIdentity(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider.EntityID,
+		Subject:  nameID,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) Name() string {
+	return service.SAML
+}
+
+func requestKey(id string) string {
+	return fmt.Sprintf("saml:request:%s", id)
+}
@@ -0,0 +1,397 @@
+package samlservice
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dapthehuman/learning-management-system/cache/cachetest"
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	authDto "github.com/dapthehuman/learning-management-system/dto/auth"
+	"github.com/dapthehuman/learning-management-system/service/saml-service/samltest"
+	"github.com/dapthehuman/learning-management-system/service/saml-service/xmldsig"
+	"github.com/dapthehuman/learning-management-system/tenant"
+)
+
+// The organization the identity provider is configured for
+const (
+	organization   = "acme"
+	organizationID = 2
+)
+
+var ctx = tenant.WithOrganization(context.Background(), organizationID)
+
+type repository struct {
+	providers map[uint64]*model.SAMLProvider
+}
+
+func (r *repository) Get(ctx context.Context) (*model.SAMLProvider, error) {
+	if provider, ok := r.providers[tenant.ID(ctx)]; ok {
+		return provider, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) Save(ctx context.Context, provider *model.SAMLProvider) (*model.SAMLProvider, error) {
+	provider.OrganizationID = tenant.ID(ctx)
+	r.providers[provider.OrganizationID] = provider
+	return provider, nil
+}
+
+type userRepository struct {
+	users      map[uint64]*model.User
+	identities []*model.UserIdentity
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	user.ID = uint64(len(r.users) + 100)
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *userRepository) GetIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *userRepository) CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	r.identities = append(r.identities, identity)
+	return identity, nil
+}
+
+type roleRepository []string
+
+func (r roleRepository) Exists(ctx context.Context, name string) (bool, error) {
+	return slices.Contains(r, name), nil
+}
+
+// newService returns a service with the provider of the identity provider
+// configured for organization.
+func newService(t *testing.T, allowedRoles ...string) (*Service, *samltest.IdentityProvider, *userRepository) {
+	t.Helper()
+	idp, err := samltest.NewIdentityProvider("https://idp.example.edu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &userRepository{users: make(map[uint64]*model.User)}
+	s := NewService(
+		&repository{providers: make(map[uint64]*model.SAMLProvider)},
+		users,
+		roleRepository{"admin", "teacher", "student"},
+		cachetest.NewRedis(),
+		"https://lms.example.edu",
+	)
+	_, err = s.SaveProvider(ctx, &authDto.SAMLProviderRequest{
+		Metadata:      idp.Metadata(),
+		RoleAttribute: "role",
+		AllowedRoles:  allowedRoles,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, idp, users
+}
+
+// login starts a login and returns the assertion answering it.
+func login(t *testing.T, s *Service, nameID string, attributes map[string]string) samltest.Assertion {
+	t.Helper()
+	loginURL, err := s.LoginURL(ctx, organization, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deflated, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLRequest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request authnRequest
+	if err := xml.Unmarshal(raw, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	return samltest.Assertion{
+		Audience:     s.EntityID(organization),
+		Recipient:    s.ACSURL(organization),
+		InResponseTo: request.ID,
+		NameID:       nameID,
+		Attributes:   attributes,
+	}
+}
+
+func setAttr(n *xmldsig.Node, local, value string) {
+	for i, a := range n.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			n.Attrs[i].Value = value
+			return
+		}
+	}
+	n.Attrs = append(n.Attrs, xmldsig.Attr{Local: local, Value: value})
+}
+
+func TestConsumeResponse(t *testing.T) {
+	s, idp, users := newService(t)
+
+	response, err := idp.Response(login(t, s, "student@example.edu", map[string]string{"name": "Student"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.ConsumeResponse(ctx, organization, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "student@example.edu" || user.Name != "Student" || user.Role != "student" {
+		t.Errorf("provisioned %s (%s) with role %s", user.Email, user.Name, user.Role)
+	}
+	if len(users.identities) != 1 || users.identities[0].Provider != idp.EntityID {
+		t.Errorf("expected one identity of the provider, got %v", users.identities)
+	}
+
+	// The response is only good once
+	if _, err := s.ConsumeResponse(ctx, organization, response); err == nil {
+		t.Error("consumed the same response twice")
+	}
+}
+
+func TestConsumeResponseRejects(t *testing.T) {
+	cases := []struct {
+		name string
+		// response returns the response posted to the ACS endpoint for a
+		// login answered with a.
+		response func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error)
+	}{
+		{name: "tampered value", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			root, _ := idp.Document(a)
+			if err := idp.SignAssertion(root); err != nil {
+				return "", err
+			}
+			nameID := root.Child(assertionNS, "Assertion").Child(assertionNS, "Subject").Child(assertionNS, "NameID")
+			nameID.SetText("admin@example.edu")
+			return samltest.Encode(root)
+		}},
+		{name: "signature wrapping", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			// The signed assertion is moved out of the way and an assertion
+			// with the same ID takes its place
+			root, _ := idp.Document(a)
+			if err := idp.SignAssertion(root); err != nil {
+				return "", err
+			}
+			original := root.Child(assertionNS, "Assertion")
+			root.Remove(original)
+			wrapper, _ := xmldsig.NewNode(`<samlp:Extensions></samlp:Extensions>`)
+			wrapper.Insert(0, original)
+			root.Insert(len(root.Children), wrapper)
+
+			a.NameID = "admin@example.edu"
+			forged, _ := idp.Document(a)
+			evil := forged.Child(assertionNS, "Assertion")
+			setAttr(evil, "ID", original.Attr("ID"))
+			root.Insert(len(root.Children), evil)
+			return samltest.Encode(root)
+		}},
+		{name: "signature moved to another assertion", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			root, _ := idp.Document(a)
+			if err := idp.SignAssertion(root); err != nil {
+				return "", err
+			}
+			original := root.Child(assertionNS, "Assertion")
+			signature := original.Child(xmldsig.Namespace, "Signature")
+			root.Remove(original)
+
+			a.NameID = "admin@example.edu"
+			forged, _ := idp.Document(a)
+			evil := forged.Child(assertionNS, "Assertion")
+			setAttr(evil, "ID", original.Attr("ID"))
+			evil.Insert(1, signature)
+			root.Insert(len(root.Children), evil)
+			return samltest.Encode(root)
+		}},
+		{name: "unsigned", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			root, err := idp.Document(a)
+			if err != nil {
+				return "", err
+			}
+			return samltest.Encode(root)
+		}},
+		{name: "wrong certificate", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			other, err := samltest.NewIdentityProvider(idp.EntityID)
+			if err != nil {
+				return "", err
+			}
+			return other.Response(a)
+		}},
+		{name: "expired", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.IssueInstant = time.Now().Add(-time.Hour)
+			return idp.Response(a)
+		}},
+		{name: "missing audience", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			root, _ := idp.Document(a)
+			conditions := root.Child(assertionNS, "Assertion").Child(assertionNS, "Conditions")
+			conditions.Remove(conditions.Child(assertionNS, "AudienceRestriction"))
+			if err := idp.SignAssertion(root); err != nil {
+				return "", err
+			}
+			return samltest.Encode(root)
+		}},
+		{name: "other audience", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.Audience = "https://other.example.edu/metadata"
+			return idp.Response(a)
+		}},
+		{name: "missing recipient", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.Recipient = ""
+			return idp.Response(a)
+		}},
+		{name: "other recipient", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.Recipient = "https://other.example.edu/acs"
+			return idp.Response(a)
+		}},
+		{name: "unsolicited", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.InResponseTo = ""
+			return idp.Response(a)
+		}},
+		{name: "unknown request", response: func(t *testing.T, idp *samltest.IdentityProvider, a samltest.Assertion) (string, error) {
+			a.InResponseTo = "_forged"
+			return idp.Response(a)
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, idp, users := newService(t)
+			response, err := c.response(t, idp, login(t, s, "student@example.edu", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user, err := s.ConsumeResponse(ctx, organization, response); err == nil {
+				t.Fatalf("logged in as %s", user.Email)
+			}
+			if len(users.users) != 0 || len(users.identities) != 0 {
+				t.Error("provisioned a user for a rejected response")
+			}
+		})
+	}
+}
+
+func TestConsumeResponseAnswersRequestOnce(t *testing.T) {
+	s, idp, _ := newService(t)
+	a := login(t, s, "student@example.edu", nil)
+
+	first, err := idp.Response(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ConsumeResponse(ctx, organization, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new assertion for the same request
+	second, err := idp.Response(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ConsumeResponse(ctx, organization, second); err == nil {
+		t.Error("answered the same login request twice")
+	}
+}
+
+func TestConsumeResponseRejectsRequestOfAnotherOrganization(t *testing.T) {
+	s, idp, users := newService(t)
+	other := tenant.WithOrganization(context.Background(), organizationID+1)
+	if _, err := s.SaveProvider(other, &authDto.SAMLProviderRequest{Metadata: idp.Metadata()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An assertion for the other organization, answering a login started here
+	a := login(t, s, "student@example.edu", nil)
+	a.Audience = s.EntityID("other")
+	a.Recipient = s.ACSURL("other")
+	response, err := idp.Response(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.ConsumeResponse(other, "other", response); err == nil {
+		t.Errorf("logged in as %s in another organization", user.Email)
+	}
+	if len(users.users) != 0 {
+		t.Error("provisioned a user for a rejected response")
+	}
+}
+
+func TestConsumeResponseRole(t *testing.T) {
+	cases := []struct {
+		name         string
+		allowedRoles []string
+		role         string
+		expected     string
+	}{
+		{name: "allowed", allowedRoles: []string{"teacher"}, role: "teacher", expected: "teacher"},
+		{name: "not allowed", allowedRoles: []string{"teacher"}, role: "admin", expected: "student"},
+		{name: "nothing allowed", role: "admin", expected: "student"},
+		{name: "missing", allowedRoles: []string{"teacher"}, expected: "student"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, idp, _ := newService(t, c.allowedRoles...)
+			attributes := map[string]string{}
+			if c.role != "" {
+				attributes["role"] = c.role
+			}
+			response, err := idp.Response(login(t, s, "someone@example.edu", attributes))
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := s.ConsumeResponse(ctx, organization, response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Role != c.expected {
+				t.Errorf("expected role %s, got %s", c.expected, user.Role)
+			}
+		})
+	}
+}
+
+func TestSaveProviderRejectsUnknownAllowedRole(t *testing.T) {
+	s, idp, _ := newService(t)
+	_, err := s.SaveProvider(ctx, &authDto.SAMLProviderRequest{
+		Metadata:     idp.Metadata(),
+		AllowedRoles: []string{"teacher", "superuser"},
+	})
+	if err == nil {
+		t.Error("saved a provider allowing an unknown role")
+	}
+}
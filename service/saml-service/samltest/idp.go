@@ -0,0 +1,181 @@
+// Package samltest provides a local SAML identity provider with a freshly
+// generated signing certificate, to exercise the service provider without a
+// real IdP.
+package samltest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dapthehuman/learning-management-system/service/saml-service/xmldsig"
+)
+
+const assertionNS = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+type IdentityProvider struct {
+	EntityID    string
+	SSOURL      string
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// NewIdentityProvider generates a key pair and a self-signed certificate.
+func NewIdentityProvider(entityID string) (*IdentityProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: entityID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityProvider{
+		EntityID:    entityID,
+		SSOURL:      strings.TrimSuffix(entityID, "/") + "/sso",
+		Key:         key,
+		Certificate: cert,
+	}, nil
+}
+
+func (idp *IdentityProvider) CertificatePEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.Certificate.Raw}))
+}
+
+// Metadata returns the IdP EntityDescriptor to register with the SP.
+func (idp *IdentityProvider) Metadata() string {
+	return `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + escape(idp.EntityID) + `">` +
+		`<md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">` +
+		`<md:KeyDescriptor use="signing"><ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:X509Data><ds:X509Certificate>` +
+		base64.StdEncoding.EncodeToString(idp.Certificate.Raw) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>` +
+		`<md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + escape(idp.SSOURL) + `"/>` +
+		`</md:IDPSSODescriptor></md:EntityDescriptor>`
+}
+
+// Assertion describes the assertion of a Response.
+type Assertion struct {
+	Audience     string
+	Recipient    string
+	InResponseTo string // ID of the AuthnRequest, left out when empty
+	NameID       string
+	Attributes   map[string]string
+
+	// IssueInstant defaults to now. The assertion is valid from a minute
+	// before it to five minutes after it.
+	IssueInstant time.Time
+}
+
+// Response returns a base64 encoded SAML Response, as posted to the ACS
+// endpoint, holding the assertion signed by the IdP.
+func (idp *IdentityProvider) Response(a Assertion) (string, error) {
+	root, err := idp.Document(a)
+	if err != nil {
+		return "", err
+	}
+	if err := idp.SignAssertion(root); err != nil {
+		return "", err
+	}
+	return Encode(root)
+}
+
+// Document returns the unsigned Response document holding the assertion, to
+// be signed with SignAssertion, altered and encoded with Encode.
+func (idp *IdentityProvider) Document(a Assertion) (*xmldsig.Node, error) {
+	now := a.IssueInstant
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.UTC()
+	issueInstant := now.Format(time.RFC3339)
+	notBefore := now.Add(-time.Minute).Format(time.RFC3339)
+	notOnOrAfter := now.Add(5 * time.Minute).Format(time.RFC3339)
+
+	inResponseTo := ""
+	if a.InResponseTo != "" {
+		inResponseTo = ` InResponseTo="` + escape(a.InResponseTo) + `"`
+	}
+
+	names := make([]string, 0, len(a.Attributes))
+	for name := range a.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var attributeStatement strings.Builder
+	for _, name := range names {
+		attributeStatement.WriteString(`<saml:Attribute Name="` + escape(name) + `"><saml:AttributeValue>` + escape(a.Attributes[name]) + `</saml:AttributeValue></saml:Attribute>`)
+	}
+
+	return xmldsig.Parse([]byte(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"` +
+		` ID="` + newID() + `" Version="2.0" IssueInstant="` + issueInstant + `" Destination="` + escape(a.Recipient) + `"` + inResponseTo + `>` +
+		`<saml:Issuer>` + escape(idp.EntityID) + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		`<saml:Assertion ID="` + newID() + `" Version="2.0" IssueInstant="` + issueInstant + `">` +
+		`<saml:Issuer>` + escape(idp.EntityID) + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">` + escape(a.NameID) + `</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">` +
+		`<saml:SubjectConfirmationData NotOnOrAfter="` + notOnOrAfter + `" Recipient="` + escape(a.Recipient) + `"` + inResponseTo + `/>` +
+		`</saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + notBefore + `" NotOnOrAfter="` + notOnOrAfter + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + escape(a.Audience) + `</saml:Audience></saml:AudienceRestriction>` +
+		`</saml:Conditions>` +
+		`<saml:AttributeStatement>` + attributeStatement.String() + `</saml:AttributeStatement>` +
+		`</saml:Assertion>` +
+		`</samlp:Response>`))
+}
+
+// SignAssertion signs the assertion of a Response document.
+func (idp *IdentityProvider) SignAssertion(root *xmldsig.Node) error {
+	assertion := root.Child(assertionNS, "Assertion")
+	if assertion == nil {
+		return errors.New("samltest: assertion not found")
+	}
+
+	// The signature goes right after the assertion Issuer.
+	return xmldsig.Sign(assertion, 1, idp.Key, idp.Certificate)
+}
+
+// Encode serializes a Response document as posted to the ACS endpoint.
+func Encode(root *xmldsig.Node) (string, error) {
+	document, err := xmldsig.Canonicalize(root, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(document), nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "_" + hex.EncodeToString(b)
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -0,0 +1,305 @@
+package xmldsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Node is an XML element that keeps the namespace prefixes as written in the
+// document, which is required to canonicalize it.
+type Node struct {
+	Prefix   string
+	Local    string
+	Attrs    []Attr
+	NSDecls  map[string]string // Prefix to URI, "" for the default namespace
+	Children []any             // *Node or Text
+	Parent   *Node
+}
+
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+type Text string
+
+// Parse reads a document and returns its root element. Comments and
+// processing instructions are dropped, DTDs are rejected.
+func Parse(data []byte) (*Node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *Node
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &Node{Prefix: t.Name.Space, Local: t.Name.Local, NSDecls: map[string]string{}, Parent: current}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					node.NSDecls[""] = a.Value
+				case a.Name.Space == "xmlns":
+					node.NSDecls[a.Name.Local] = a.Value
+				default:
+					node.Attrs = append(node.Attrs, Attr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value})
+				}
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("xmldsig: multiple root elements")
+				}
+				root = node
+			} else {
+				current.Children = append(current.Children, node)
+			}
+			current = node
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, errors.New("xmldsig: mismatched end element")
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, Text(t))
+			}
+		case xml.Directive:
+			return nil, errors.New("xmldsig: DTDs are not allowed")
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, errors.New("xmldsig: incomplete document")
+	}
+	return root, nil
+}
+
+// NewNode builds a detached element from a document fragment. Namespace
+// prefixes that are not declared in the fragment are resolved once the node
+// is attached with Insert.
+func NewNode(fragment string) (*Node, error) {
+	return Parse([]byte(fragment))
+}
+
+// Insert attaches child to n at the given index of its children.
+func (n *Node) Insert(index int, child *Node) {
+	child.Parent = n
+	if index > len(n.Children) {
+		index = len(n.Children)
+	}
+	n.Children = append(n.Children[:index], append([]any{child}, n.Children[index:]...)...)
+}
+
+// Remove detaches child from n.
+func (n *Node) Remove(child *Node) {
+	for i, c := range n.Children {
+		if c == child {
+			n.Children = append(n.Children[:i], n.Children[i+1:]...)
+			child.Parent = nil
+			return
+		}
+	}
+}
+
+// Namespace returns the URI bound to the element's prefix.
+func (n *Node) Namespace() string {
+	uri, _ := n.lookupNS(n.Prefix)
+	return uri
+}
+
+func (n *Node) lookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+	for e := n; e != nil; e = e.Parent {
+		if uri, ok := e.NSDecls[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", prefix == ""
+}
+
+// Attr returns the value of the unprefixed attribute with the given name.
+func (n *Node) Attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Child returns the first child element with the given namespace and name.
+func (n *Node) Child(namespace, local string) *Node {
+	for _, c := range n.ChildElements() {
+		if c.Local == local && c.Namespace() == namespace {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildElements returns the element children of n.
+func (n *Node) ChildElements() []*Node {
+	elements := make([]*Node, 0, len(n.Children))
+	for _, c := range n.Children {
+		if el, ok := c.(*Node); ok {
+			elements = append(elements, el)
+		}
+	}
+	return elements
+}
+
+// Text returns the concatenated character data of n's direct children.
+func (n *Node) Text() string {
+	var sb strings.Builder
+	for _, c := range n.Children {
+		if t, ok := c.(Text); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	return sb.String()
+}
+
+// SetText replaces the children of n with the given character data.
+func (n *Node) SetText(text string) {
+	n.Children = []any{Text(text)}
+}
+
+// Canonicalize serializes n with Exclusive XML Canonicalization (without
+// comments). Prefixes listed in inclusive are treated as visibly utilized
+// and exclude, if not nil, is omitted from the output.
+func Canonicalize(n *Node, inclusive []string, exclude *Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := canonicalize(&buf, n, map[string]string{}, inclusive, exclude); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func canonicalize(buf *bytes.Buffer, n *Node, rendered map[string]string, inclusive []string, exclude *Node) error {
+	used := []string{n.Prefix}
+	for _, a := range n.Attrs {
+		if a.Prefix != "" && a.Prefix != "xml" {
+			used = append(used, a.Prefix)
+		}
+	}
+	for _, p := range inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		if _, ok := n.lookupNS(p); ok {
+			used = append(used, p)
+		}
+	}
+
+	scope := make(map[string]string, len(rendered))
+	for k, v := range rendered {
+		scope[k] = v
+	}
+
+	decls := make([]string, 0)
+	for _, p := range used {
+		uri, ok := n.lookupNS(p)
+		if !ok {
+			return fmt.Errorf("xmldsig: undeclared namespace prefix %q", p)
+		}
+		prev, has := scope[p]
+		if p == "" && uri == "" && (!has || prev == "") {
+			continue
+		}
+		if has && prev == uri {
+			continue
+		}
+		scope[p] = uri
+		decls = append(decls, p)
+	}
+	sort.Strings(decls)
+
+	type qualifiedAttr struct {
+		uri  string
+		attr Attr
+	}
+	attrs := make([]qualifiedAttr, 0, len(n.Attrs))
+	for _, a := range n.Attrs {
+		uri := ""
+		if a.Prefix != "" {
+			var ok bool
+			if uri, ok = n.lookupNS(a.Prefix); !ok {
+				return fmt.Errorf("xmldsig: undeclared namespace prefix %q", a.Prefix)
+			}
+		}
+		attrs = append(attrs, qualifiedAttr{uri: uri, attr: a})
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].attr.Local < attrs[j].attr.Local
+	})
+
+	name := qualifiedName(n.Prefix, n.Local)
+	buf.WriteString("<" + name)
+	for _, p := range decls {
+		if p == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(` xmlns:` + p + `="`)
+		}
+		buf.WriteString(escapeAttr(scope[p]) + `"`)
+	}
+	for _, a := range attrs {
+		buf.WriteString(" " + qualifiedName(a.attr.Prefix, a.attr.Local) + `="` + escapeAttr(a.attr.Value) + `"`)
+	}
+	buf.WriteString(">")
+
+	for _, c := range n.Children {
+		switch child := c.(type) {
+		case *Node:
+			if child == exclude {
+				continue
+			}
+			if err := canonicalize(buf, child, scope, inclusive, exclude); err != nil {
+				return err
+			}
+		case Text:
+			buf.WriteString(escapeText(string(child)))
+		}
+	}
+
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
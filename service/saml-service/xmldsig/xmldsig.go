@@ -0,0 +1,221 @@
+// Package xmldsig verifies and creates enveloped XML signatures as used by
+// SAML 2.0. Only the profile used in practice by SAML identity providers is
+// supported: exclusive canonicalization, the enveloped-signature transform
+// and RSA signatures.
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	// Register the hash functions referenced by the algorithms below.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+const (
+	Namespace = "http://www.w3.org/2000/09/xmldsig#"
+
+	algExcC14N      = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped    = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA1      = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	algRSASHA256    = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512    = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algDigestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	algDigestSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algDigestSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var signatureHashes = map[string]crypto.Hash{
+	algRSASHA1:   crypto.SHA1,
+	algRSASHA256: crypto.SHA256,
+	algRSASHA512: crypto.SHA512,
+}
+
+var digestHashes = map[string]crypto.Hash{
+	algDigestSHA1:   crypto.SHA1,
+	algDigestSHA256: crypto.SHA256,
+	algDigestSHA512: crypto.SHA512,
+}
+
+// ErrNotSigned is returned by Verify when the element has no signature.
+var ErrNotSigned = errors.New("xmldsig: element is not signed")
+
+// Verify checks the enveloped signature of el against the given certificate.
+// The signature must be a direct child of el and reference el by its ID
+// attribute, so that the verified content is exactly el.
+func Verify(el *Node, cert *x509.Certificate) error {
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("xmldsig: only RSA certificates are supported")
+	}
+
+	signature := el.Child(Namespace, "Signature")
+	if signature == nil {
+		return ErrNotSigned
+	}
+
+	signedInfo := signature.Child(Namespace, "SignedInfo")
+	if signedInfo == nil {
+		return errors.New("xmldsig: missing SignedInfo")
+	}
+
+	c14nMethod := signedInfo.Child(Namespace, "CanonicalizationMethod")
+	if c14nMethod == nil || c14nMethod.Attr("Algorithm") != algExcC14N {
+		return errors.New("xmldsig: unsupported canonicalization method")
+	}
+
+	signatureMethod := signedInfo.Child(Namespace, "SignatureMethod")
+	if signatureMethod == nil {
+		return errors.New("xmldsig: missing SignatureMethod")
+	}
+	signatureHash, ok := signatureHashes[signatureMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("xmldsig: unsupported signature method %q", signatureMethod.Attr("Algorithm"))
+	}
+
+	references := make([]*Node, 0, 1)
+	for _, c := range signedInfo.ChildElements() {
+		if c.Local == "Reference" && c.Namespace() == Namespace {
+			references = append(references, c)
+		}
+	}
+	if len(references) != 1 {
+		return errors.New("xmldsig: exactly one reference is required")
+	}
+	reference := references[0]
+
+	id := el.Attr("ID")
+	if id == "" || reference.Attr("URI") != "#"+id {
+		return errors.New("xmldsig: signature does not reference the signed element")
+	}
+
+	var inclusive []string
+	if transforms := reference.Child(Namespace, "Transforms"); transforms != nil {
+		for _, t := range transforms.ChildElements() {
+			switch t.Attr("Algorithm") {
+			case algEnveloped:
+			case algExcC14N:
+				inclusive = inclusivePrefixes(t)
+			default:
+				return fmt.Errorf("xmldsig: unsupported transform %q", t.Attr("Algorithm"))
+			}
+		}
+	}
+
+	digestMethod := reference.Child(Namespace, "DigestMethod")
+	digestValue := reference.Child(Namespace, "DigestValue")
+	if digestMethod == nil || digestValue == nil {
+		return errors.New("xmldsig: missing digest")
+	}
+	digestHash, ok := digestHashes[digestMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("xmldsig: unsupported digest method %q", digestMethod.Attr("Algorithm"))
+	}
+
+	canonical, err := Canonicalize(el, inclusive, signature)
+	if err != nil {
+		return err
+	}
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digestValue.Text()))
+	if err != nil {
+		return err
+	}
+	h := digestHash.New()
+	h.Write(canonical)
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return errors.New("xmldsig: digest mismatch")
+	}
+
+	signatureValue := signature.Child(Namespace, "SignatureValue")
+	if signatureValue == nil {
+		return errors.New("xmldsig: missing SignatureValue")
+	}
+	sig, err := base64.StdEncoding.DecodeString(removeWhitespace(signatureValue.Text()))
+	if err != nil {
+		return err
+	}
+
+	canonicalSignedInfo, err := Canonicalize(signedInfo, inclusivePrefixes(c14nMethod), nil)
+	if err != nil {
+		return err
+	}
+	h = signatureHash.New()
+	h.Write(canonicalSignedInfo)
+	if err := rsa.VerifyPKCS1v15(publicKey, signatureHash, h.Sum(nil), sig); err != nil {
+		return errors.New("xmldsig: invalid signature")
+	}
+
+	return nil
+}
+
+// Sign adds an enveloped RSA-SHA256 signature to el, inserted as its child at
+// the given index. el must have an ID attribute.
+func Sign(el *Node, index int, key *rsa.PrivateKey, cert *x509.Certificate) error {
+	id := el.Attr("ID")
+	if id == "" {
+		return errors.New("xmldsig: element has no ID attribute")
+	}
+
+	canonical, err := Canonicalize(el, nil, nil)
+	if err != nil {
+		return err
+	}
+	digest := crypto.SHA256.New()
+	digest.Write(canonical)
+
+	signature, err := NewNode(`<ds:Signature xmlns:ds="` + Namespace + `">` +
+		`<ds:SignedInfo>` +
+		`<ds:CanonicalizationMethod Algorithm="` + algExcC14N + `"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="` + algRSASHA256 + `"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + escapeAttr(id) + `">` +
+		`<ds:Transforms>` +
+		`<ds:Transform Algorithm="` + algEnveloped + `"></ds:Transform>` +
+		`<ds:Transform Algorithm="` + algExcC14N + `"></ds:Transform>` +
+		`</ds:Transforms>` +
+		`<ds:DigestMethod Algorithm="` + algDigestSHA256 + `"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest.Sum(nil)) + `</ds:DigestValue>` +
+		`</ds:Reference>` +
+		`</ds:SignedInfo>` +
+		`<ds:SignatureValue></ds:SignatureValue>` +
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(cert.Raw) + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo>` +
+		`</ds:Signature>`)
+	if err != nil {
+		return err
+	}
+	el.Insert(index, signature)
+
+	canonicalSignedInfo, err := Canonicalize(signature.Child(Namespace, "SignedInfo"), nil, nil)
+	if err != nil {
+		return err
+	}
+	h := crypto.SHA256.New()
+	h.Write(canonicalSignedInfo)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	signature.Child(Namespace, "SignatureValue").SetText(base64.StdEncoding.EncodeToString(sig))
+
+	return nil
+}
+
+func inclusivePrefixes(method *Node) []string {
+	list := method.Child(algExcC14N, "InclusiveNamespaces")
+	if list == nil {
+		return nil
+	}
+	return strings.Fields(list.Attr("PrefixList"))
+}
+
+func removeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
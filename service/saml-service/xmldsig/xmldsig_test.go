@@ -0,0 +1,258 @@
+package xmldsig
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const document = `<r:Root xmlns:r="urn:r" xmlns:unused="urn:unused" ID="_root">` +
+	`<r:Issuer>idp</r:Issuer>` +
+	`<r:Value Name="email">student@example.edu</r:Value>` +
+	`<r:Value Name="role">student</r:Value>` +
+	`</r:Root>`
+
+func newCertificate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "xmldsig test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+// signed returns the document signed with key, serialized and parsed again
+// as a receiver would.
+func signed(t *testing.T, key *rsa.PrivateKey, cert *x509.Certificate) *Node {
+	t.Helper()
+	root, err := Parse([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Sign(root, 1, key, cert); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Canonicalize(root, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err = Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func child(n *Node, local string, index int) *Node {
+	for _, c := range n.ChildElements() {
+		if c.Local == local {
+			if index == 0 {
+				return c
+			}
+			index--
+		}
+	}
+	return nil
+}
+
+func setAttr(n *Node, local, value string) {
+	for i, a := range n.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			n.Attrs[i].Value = value
+			return
+		}
+	}
+	n.Attrs = append(n.Attrs, Attr{Local: local, Value: value})
+}
+
+func TestVerify(t *testing.T) {
+	key, cert := newCertificate(t)
+	if err := Verify(signed(t, key, cert), cert); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	key, cert := newCertificate(t)
+
+	cases := []struct {
+		name   string
+		tamper func(root *Node)
+	}{
+		{name: "text", tamper: func(root *Node) { child(root, "Value", 1).SetText("admin") }},
+		{name: "attribute", tamper: func(root *Node) { setAttr(child(root, "Value", 0), "Name", "role") }},
+		{name: "added attribute", tamper: func(root *Node) { setAttr(child(root, "Issuer", 0), "Format", "any") }},
+		{name: "removed element", tamper: func(root *Node) { root.Remove(child(root, "Value", 1)) }},
+		{name: "added element", tamper: func(root *Node) {
+			value, _ := NewNode(`<r:Value Name="role">admin</r:Value>`)
+			root.Insert(len(root.Children), value)
+		}},
+		{name: "signed info", tamper: func(root *Node) {
+			// The digest still matches without the redundant transform, the
+			// signature over SignedInfo does not
+			transforms := child(child(child(child(root, "Signature", 0), "SignedInfo", 0), "Reference", 0), "Transforms", 0)
+			transforms.Remove(child(transforms, "Transform", 1))
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root := signed(t, key, cert)
+			c.tamper(root)
+			if err := Verify(root, cert); err == nil {
+				t.Error("verified a tampered document")
+			}
+		})
+	}
+}
+
+func TestVerifyWrongCertificate(t *testing.T) {
+	key, cert := newCertificate(t)
+	_, other := newCertificate(t)
+	if err := Verify(signed(t, key, cert), other); err == nil {
+		t.Error("verified a signature with another certificate")
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	_, cert := newCertificate(t)
+	root, err := Parse([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(root, cert); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("expected ErrNotSigned, got %v", err)
+	}
+}
+
+func TestVerifyReference(t *testing.T) {
+	key, cert := newCertificate(t)
+
+	t.Run("other element", func(t *testing.T) {
+		// A valid signature moved into an element it does not reference
+		root := signed(t, key, cert)
+		signature := child(root, "Signature", 0)
+		root.Remove(signature)
+		value := child(root, "Value", 0)
+		setAttr(value, "ID", "_value")
+		value.Insert(0, signature)
+		if err := Verify(value, cert); err == nil {
+			t.Error("verified an element the signature does not reference")
+		}
+	})
+
+	t.Run("copied ID", func(t *testing.T) {
+		root := signed(t, key, cert)
+		signature := child(root, "Signature", 0)
+		root.Remove(signature)
+		value := child(root, "Value", 0)
+		setAttr(value, "ID", "_root")
+		value.Insert(0, signature)
+		if err := Verify(value, cert); err == nil {
+			t.Error("verified an element carrying the ID of the signed element")
+		}
+	})
+
+	t.Run("nested signature", func(t *testing.T) {
+		// The signature must be a direct child of the verified element
+		root := signed(t, key, cert)
+		signature := child(root, "Signature", 0)
+		root.Remove(signature)
+		child(root, "Issuer", 0).Insert(0, signature)
+		if err := Verify(root, cert); !errors.Is(err, ErrNotSigned) {
+			t.Errorf("expected ErrNotSigned, got %v", err)
+		}
+	})
+
+	t.Run("two references", func(t *testing.T) {
+		root := signed(t, key, cert)
+		signedInfo := child(child(root, "Signature", 0), "SignedInfo", 0)
+		reference, _ := NewNode(`<ds:Reference xmlns:ds="` + Namespace + `" URI="#_other"></ds:Reference>`)
+		signedInfo.Insert(len(signedInfo.Children), reference)
+		if err := Verify(root, cert); err == nil {
+			t.Error("verified a signature with two references")
+		}
+	})
+}
+
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		inclusive []string
+		expected  string
+	}{
+		{
+			name:     "attribute order",
+			input:    `<a xmlns:b="urn:b" xmlns:z="urn:z" z:attr="1" b:attr="2" c="3"><b:x/></a>`,
+			expected: `<a xmlns:b="urn:b" xmlns:z="urn:z" c="3" b:attr="2" z:attr="1"><b:x></b:x></a>`,
+		},
+		{
+			name:     "unused namespace",
+			input:    `<a xmlns="urn:a" xmlns:b="urn:b"><c/></a>`,
+			expected: `<a xmlns="urn:a"><c></c></a>`,
+		},
+		{
+			name:     "namespace declared where used",
+			input:    `<a xmlns:b="urn:b"><c><b:d/><b:e/></c></a>`,
+			expected: `<a><c><b:d xmlns:b="urn:b"></b:d><b:e xmlns:b="urn:b"></b:e></c></a>`,
+		},
+		{
+			name:      "inclusive prefix",
+			input:     `<a xmlns:b="urn:b" xmlns:d="urn:d"><c/></a>`,
+			inclusive: []string{"b"},
+			expected:  `<a xmlns:b="urn:b"><c></c></a>`,
+		},
+		{
+			name:     "escaping",
+			input:    `<a b="&quot;&lt;&#9;&#10;">&amp;&lt;&gt;&quot;</a>`,
+			expected: `<a b="&quot;&lt;&#x9;&#xA;">&amp;&lt;&gt;"</a>`,
+		},
+		{
+			name:     "comments",
+			input:    `<a><!-- comment -->text<?pi?></a>`,
+			expected: `<a>text</a>`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, err := Parse([]byte(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			canonical, err := Canonicalize(root, c.inclusive, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(canonical) != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, canonical)
+			}
+		})
+	}
+}
+
+func TestParseRejectsDTD(t *testing.T) {
+	_, err := Parse([]byte(`<!DOCTYPE a [<!ENTITY e "entity">]><a>&e;</a>`))
+	if err == nil || !strings.Contains(err.Error(), "DTD") {
+		t.Errorf("expected a DTD error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package scimservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+)
+
+// Filterable attributes, keyed by their lowercase name.
+var (
+	userAttributes = map[string]string{
+		"id":                "id",
+		"username":          "userName",
+		"emails":            "emails.value",
+		"emails.value":      "emails.value",
+		"displayname":       "displayName",
+		"name.formatted":    "name.formatted",
+		"externalid":        "externalId",
+		"active":            "active",
+		"meta.created":      "meta.created",
+		"meta.lastmodified": "meta.lastModified",
+	}
+	groupAttributes = map[string]string{
+		"id":                "id",
+		"displayname":       "displayName",
+		"externalid":        "externalId",
+		"meta.created":      "meta.created",
+		"meta.lastmodified": "meta.lastModified",
+	}
+)
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+// parseFilter parses the subset of the SCIM filter syntax identity providers
+// rely on: attribute comparisons joined by "and", without grouping, e.g.
+// `userName eq "jane@example.com" and active eq true`.
+func parseFilter(filter string, attributes map[string]string) ([]model.FilterCondition, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := make([]model.FilterCondition, 0, 1)
+	for i := 0; i < len(tokens); {
+		if len(conditions) > 0 {
+			if !strings.EqualFold(tokens[i], "and") {
+				return nil, invalidFilter(fmt.Sprintf("unsupported logical operator %q, only \"and\" is supported", tokens[i]))
+			}
+			i++
+		}
+		if i+1 >= len(tokens) {
+			return nil, invalidFilter("incomplete filter")
+		}
+
+		attribute := strings.ToLower(stripSchema(tokens[i]))
+		field, ok := attributes[attribute]
+		if !ok {
+			return nil, invalidFilter(fmt.Sprintf("unsupported filter attribute %q", tokens[i]))
+		}
+
+		operator := strings.ToLower(tokens[i+1])
+		if !operators[operator] {
+			return nil, invalidFilter(fmt.Sprintf("unsupported filter operator %q", tokens[i+1]))
+		}
+		i += 2
+
+		condition := model.FilterCondition{Field: field, Operator: operator}
+		if operator != "pr" {
+			if i >= len(tokens) {
+				return nil, invalidFilter("missing comparison value")
+			}
+			if err := json.Unmarshal([]byte(tokens[i]), &condition.Value); err != nil {
+				return nil, invalidFilter(fmt.Sprintf("invalid comparison value %s", tokens[i]))
+			}
+			i++
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// tokenize splits the filter on spaces, keeping quoted strings whole.
+func tokenize(filter string) ([]string, error) {
+	tokens := make([]string, 0, 3)
+	for i := 0; i < len(filter); {
+		switch c := filter[i]; {
+		case c == ' ':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			return nil, invalidFilter("grouping and complex attribute filters are not supported")
+		case c == '"':
+			end := i + 1
+			for ; end < len(filter) && filter[end] != '"'; end++ {
+				if filter[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(filter) {
+				return nil, invalidFilter("unterminated string")
+			}
+			tokens = append(tokens, filter[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && filter[end] != ' ' {
+				end++
+			}
+			tokens = append(tokens, filter[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// stripSchema removes the schema URN prefix of a fully qualified attribute.
+func stripSchema(attribute string) string {
+	for _, schema := range []string{userSchemaPrefix, groupSchemaPrefix} {
+		if len(attribute) > len(schema) && strings.EqualFold(attribute[:len(schema)], schema) {
+			return attribute[len(schema):]
+		}
+	}
+	return attribute
+}
+
+func invalidFilter(detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: "invalidFilter", Detail: detail}
+}
@@ -0,0 +1,60 @@
+package scimservice
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+)
+
+func TestParseFilter(t *testing.T) {
+	conditions, err := parseFilter(`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "jane@example.edu" AND active eq true and externalId pr`, userAttributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []model.FilterCondition{
+		{Field: "userName", Operator: "eq", Value: "jane@example.edu"},
+		{Field: "active", Operator: "eq", Value: true},
+		{Field: "externalId", Operator: "pr"},
+	}
+	if len(conditions) != len(expected) {
+		t.Fatalf("expected %d conditions, got %v", len(expected), conditions)
+	}
+	for i, condition := range conditions {
+		if condition != expected[i] {
+			t.Errorf("condition %d: expected %v, got %v", i, expected[i], condition)
+		}
+	}
+
+	if conditions, err := parseFilter("", userAttributes); err != nil || len(conditions) != 0 {
+		t.Errorf("expected no condition for an empty filter, got %v, %v", conditions, err)
+	}
+}
+
+func TestParseFilterRejects(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter string
+	}{
+		{name: "or", filter: `userName eq "a@example.edu" or userName eq "b@example.edu"`},
+		{name: "grouping", filter: `(userName eq "a@example.edu")`},
+		{name: "complex attribute", filter: `emails[type eq "work"]`},
+		{name: "unknown attribute", filter: `password eq "secret"`},
+		{name: "group attribute", filter: `members eq "1"`},
+		{name: "unknown operator", filter: `userName like "a%"`},
+		{name: "missing value", filter: `userName eq`},
+		{name: "unterminated string", filter: `userName eq "a@example.edu`},
+		{name: "invalid value", filter: `userName eq jane`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conditions, err := parseFilter(c.filter, userAttributes)
+			var scimErr *Error
+			if !errors.As(err, &scimErr) || scimErr.Status != http.StatusBadRequest || scimErr.Type != "invalidFilter" {
+				t.Errorf("expected an invalidFilter error, got %v, %v", conditions, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package scimservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dto "github.com/dapthehuman/learning-management-system/dto/scim"
+)
+
+// memberPath matches the value filter identity providers use to remove a
+// single member, e.g. `members[value eq "42"]`.
+var memberPath = regexp.MustCompile(`(?i)^members\[value eq "([^"]*)"\]$`)
+
+// patchUser applies a PATCH operation to the SCIM representation of a user.
+func patchUser(user *dto.User, operation dto.PatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	path := strings.ToLower(stripSchema(operation.Path))
+
+	switch op {
+	case "add", "replace":
+		if path == "" {
+			// The value holds the attributes to set
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+				return invalidValue("the value of an operation without path must be an object")
+			}
+			for attribute, value := range attributes {
+				if err := setUserAttribute(user, strings.ToLower(stripSchema(attribute)), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return setUserAttribute(user, path, operation.Value)
+
+	case "remove":
+		switch path {
+		case "externalid":
+			user.ExternalID = ""
+		case "name", "name.formatted", "name.givenname", "name.familyname", "displayname":
+			user.Name = nil
+			user.DisplayName = ""
+		default:
+			return noTarget(operation.Path)
+		}
+		return nil
+	}
+
+	return invalidValue(fmt.Sprintf("unsupported operation %q", operation.Op))
+}
+
+func setUserAttribute(user *dto.User, path string, value json.RawMessage) error {
+	switch path {
+	case "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		user.Active = &active
+		return nil
+	case "emails", `emails[type eq "work"]`, `emails[type eq "work"].value`, "emails.value":
+		// The email of the user is its userName
+		return nil
+	case "name":
+		var name dto.Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return invalidValue("name must be an object")
+		}
+		user.Name = &name
+		user.DisplayName = ""
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return invalidValue(fmt.Sprintf("%s must be a string", path))
+	}
+
+	switch path {
+	case "username":
+		user.UserName = s
+	case "displayname":
+		user.DisplayName = s
+	case "externalid":
+		user.ExternalID = s
+	case "name.formatted":
+		user.Name = &dto.Name{Formatted: s}
+		user.DisplayName = ""
+	case "name.givenname", "name.familyname":
+		name := &dto.Name{}
+		if user.Name != nil {
+			name.GivenName, name.FamilyName = user.Name.GivenName, user.Name.FamilyName
+		}
+		if path == "name.givenname" {
+			name.GivenName = s
+		} else {
+			name.FamilyName = s
+		}
+		user.Name = name
+		user.DisplayName = ""
+	default:
+		return noTarget(path)
+	}
+	return nil
+}
+
+// patchGroup applies a PATCH operation to the SCIM representation of a group.
+func patchGroup(group *dto.Group, operation dto.PatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	path := strings.ToLower(stripSchema(operation.Path))
+
+	switch op {
+	case "add", "replace":
+		if path == "" {
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+				return invalidValue("the value of an operation without path must be an object")
+			}
+			for attribute, value := range attributes {
+				if err := setGroupAttribute(group, op, strings.ToLower(stripSchema(attribute)), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return setGroupAttribute(group, op, path, operation.Value)
+
+	case "remove":
+		if matches := memberPath.FindStringSubmatch(operation.Path); matches != nil {
+			group.Members = withoutMembers(group.Members, []dto.Member{{Value: matches[1]}})
+			return nil
+		}
+
+		switch path {
+		case "members":
+			if len(operation.Value) == 0 || string(operation.Value) == "null" {
+				group.Members = nil
+				return nil
+			}
+			var members []dto.Member
+			if err := json.Unmarshal(operation.Value, &members); err != nil {
+				return invalidValue("members must be a list")
+			}
+			group.Members = withoutMembers(group.Members, members)
+		case "externalid":
+			group.ExternalID = ""
+		default:
+			return noTarget(operation.Path)
+		}
+		return nil
+	}
+
+	return invalidValue(fmt.Sprintf("unsupported operation %q", operation.Op))
+}
+
+func setGroupAttribute(group *dto.Group, op string, path string, value json.RawMessage) error {
+	if path == "members" {
+		var members []dto.Member
+		if err := json.Unmarshal(value, &members); err != nil {
+			return invalidValue("members must be a list")
+		}
+		if op == "replace" {
+			group.Members = members
+			return nil
+		}
+		group.Members = append(withoutMembers(group.Members, members), members...)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return invalidValue(fmt.Sprintf("%s must be a string", path))
+	}
+
+	switch path {
+	case "displayname":
+		group.DisplayName = s
+	case "externalid":
+		group.ExternalID = s
+	default:
+		return noTarget(path)
+	}
+	return nil
+}
+
+func withoutMembers(members []dto.Member, removed []dto.Member) []dto.Member {
+	excluded := make(map[string]bool, len(removed))
+	for _, member := range removed {
+		excluded[member.Value] = true
+	}
+
+	kept := make([]dto.Member, 0, len(members))
+	for _, member := range members {
+		if !excluded[member.Value] {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+// parseBool accepts JSON booleans and the "True"/"False" strings some
+// identity providers send.
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, invalidValue("active must be a boolean")
+}
+
+func noTarget(path string) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: "noTarget", Detail: fmt.Sprintf("unsupported path %q", path)}
+}
@@ -0,0 +1,606 @@
+package scimservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/scim"
+	"github.com/dapthehuman/learning-management-system/service"
+)
+
+const (
+	defaultCount = 100
+	maxCount     = 500
+
+	// defaultRole is given to provisioned users.
+	defaultRole = "student"
+
+	// provisionerRole is the role of the service accounts SCIM is used with.
+	// Only users whose role it covers can be changed through SCIM.
+	provisionerRole = "scim_provisioner"
+
+	userSchemaPrefix  = dto.UserSchema + ":"
+	groupSchemaPrefix = dto.GroupSchema + ":"
+)
+
+// Error is a SCIM protocol error. Its status and type are part of the error
+// response.
+type Error struct {
+	Status int
+	Type   string // scimType, e.g. "invalidFilter", "uniqueness"
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+var errNotFound = &Error{Status: http.StatusNotFound, Detail: "Resource not found"}
+
+func invalidValue(detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: "invalidValue", Detail: detail}
+}
+
+type Repository interface {
+	ListUsers(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.User, int64, error)
+	GetUser(ctx context.Context, id uint64) (*model.User, error)
+	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
+	UpdateUser(ctx context.Context, user *model.User) (*model.User, error)
+	ExistingUsers(ctx context.Context, ids []uint64) (map[uint64]bool, error)
+
+	ListGroups(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.Cohort, int64, error)
+	GetGroup(ctx context.Context, id uint64) (*model.Cohort, error)
+	CreateGroup(ctx context.Context, cohort *model.Cohort, memberIDs []uint64) (*model.Cohort, error)
+	UpdateGroup(ctx context.Context, cohort *model.Cohort, add []uint64, remove []uint64) (*model.Cohort, error)
+	DeleteGroup(ctx context.Context, id uint64) error
+}
+
+type CourseRepository interface {
+	First(ctx context.Context, id uint64) (*model.Course, error)
+}
+
+type RoleRepository interface {
+	Covers(ctx context.Context, holder string, role string) (bool, error)
+}
+
+// Service implements SCIM 2.0 provisioning (RFC 7644). Users map onto user
+// accounts, identified by their email as userName; deprovisioned users are
+// suspended rather than deleted. Groups map onto course cohorts.
+type Service struct {
+	repository       Repository
+	courseRepository CourseRepository
+	roleRepository   RoleRepository
+	baseURL          string
+}
+
+// NewService creates the SCIM service. baseURL is the public URL of the
+// application, used in resource locations.
+func NewService(repository Repository, courseRepository CourseRepository, roleRepository RoleRepository, baseURL string) *Service {
+	return &Service{
+		repository:       repository,
+		courseRepository: courseRepository,
+		roleRepository:   roleRepository,
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *Service) ListUsers(ctx context.Context, listDTO *dto.ListRequest) (*dto.ListResponse, error) {
+	filters, err := parseFilter(listDTO.Filter, userAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count := pagination(listDTO)
+	users, total, err := s.repository.ListUsers(ctx, filters, count, startIndex-1)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*dto.User, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, s.toUser(user))
+	}
+	return listResponse(resources, len(resources), total, startIndex), nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (*dto.User, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(user), nil
+}
+
+func (s *Service) CreateUser(ctx context.Context, userDTO *dto.User) (*dto.User, error) {
+	user := &model.User{Role: defaultRole}
+	if err := applyUser(user, userDTO); err != nil {
+		return nil, err
+	}
+	if err := s.checkUniqueUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repository.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(created), nil
+}
+
+// ReplaceUser overwrites the attributes of the user with the given ones.
+func (s *Service) ReplaceUser(ctx context.Context, id string, userDTO *dto.User) (*dto.User, error) {
+	user, err := s.getProvisionableUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ExternalID = nil
+	if err := applyUser(user, userDTO); err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, user)
+}
+
+func (s *Service) PatchUser(ctx context.Context, id string, patchDTO *dto.PatchRequest) (*dto.User, error) {
+	user, err := s.getProvisionableUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	userDTO := s.toUser(user)
+	for _, operation := range patchDTO.Operations {
+		if err := patchUser(userDTO, operation); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyUser(user, userDTO); err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, user)
+}
+
+// DeprovisionUser suspends the user. Their account and learning history are
+// kept so they can be reprovisioned later.
+func (s *Service) DeprovisionUser(ctx context.Context, id string) error {
+	user, err := s.getProvisionableUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if user.SuspendedAt == nil {
+		now := time.Now()
+		user.SuspendedAt = &now
+	}
+	_, err = s.repository.UpdateUser(ctx, user)
+	return err
+}
+
+func (s *Service) ListGroups(ctx context.Context, listDTO *dto.ListRequest) (*dto.ListResponse, error) {
+	filters, err := parseFilter(listDTO.Filter, groupAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count := pagination(listDTO)
+	cohorts, total, err := s.repository.ListGroups(ctx, filters, count, startIndex-1)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*dto.Group, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		resources = append(resources, s.toGroup(cohort))
+	}
+	return listResponse(resources, len(resources), total, startIndex), nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id string) (*dto.Group, error) {
+	cohort, err := s.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(cohort), nil
+}
+
+// CreateGroup creates a cohort in the course given by the cohort extension
+// and enrolls its members in the course.
+func (s *Service) CreateGroup(ctx context.Context, groupDTO *dto.Group) (*dto.Group, error) {
+	if groupDTO.Cohort == nil || groupDTO.Cohort.CourseID == "" {
+		return nil, invalidValue(fmt.Sprintf("courseId of the %s extension is required", dto.CohortSchema))
+	}
+	courseID, err := strconv.ParseUint(groupDTO.Cohort.CourseID.String(), 10, 64)
+	if err != nil {
+		return nil, invalidValue("invalid courseId")
+	}
+	course, err := s.courseRepository.First(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || course.ID == 0 {
+		return nil, invalidValue("course not found")
+	}
+
+	cohort := &model.Cohort{CourseID: courseID}
+	if err := applyGroup(cohort, groupDTO); err != nil {
+		return nil, err
+	}
+	memberIDs, err := s.memberIDs(ctx, groupDTO.Members)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkUniqueGroup(ctx, cohort); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repository.CreateGroup(ctx, cohort, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(created), nil
+}
+
+// ReplaceGroup overwrites the attributes and members of the cohort. Its
+// course cannot be changed.
+func (s *Service) ReplaceGroup(ctx context.Context, id string, groupDTO *dto.Group) (*dto.Group, error) {
+	cohort, err := s.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupDTO.Cohort != nil && groupDTO.Cohort.CourseID != "" && groupDTO.Cohort.CourseID.String() != strconv.FormatUint(cohort.CourseID, 10) {
+		return nil, &Error{Status: http.StatusBadRequest, Type: "mutability", Detail: "the course of a cohort cannot be changed"}
+	}
+
+	cohort.ExternalID = nil
+	if err := applyGroup(cohort, groupDTO); err != nil {
+		return nil, err
+	}
+	members, err := s.memberIDs(ctx, groupDTO.Members)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, cohort, members)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, id string, patchDTO *dto.PatchRequest) (*dto.Group, error) {
+	cohort, err := s.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	groupDTO := s.toGroup(cohort)
+	for _, operation := range patchDTO.Operations {
+		if err := patchGroup(groupDTO, operation); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyGroup(cohort, groupDTO); err != nil {
+		return nil, err
+	}
+	members, err := s.memberIDs(ctx, groupDTO.Members)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, cohort, members)
+}
+
+// DeleteGroup deletes the cohort. Its members stay enrolled in the course.
+func (s *Service) DeleteGroup(ctx context.Context, id string) error {
+	cohort, err := s.getGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repository.DeleteGroup(ctx, cohort.ID)
+}
+
+func (s *Service) ServiceProviderConfig() *dto.ServiceProviderConfig {
+	return &dto.ServiceProviderConfig{
+		Schemas:        []string{dto.ServiceProviderSchema},
+		Patch:          dto.Supported{Supported: true},
+		Bulk:           dto.BulkSupport{Supported: false},
+		Filter:         dto.FilterSupport{Supported: true, MaxResults: maxCount},
+		ChangePassword: dto.Supported{Supported: false},
+		Sort:           dto.Supported{Supported: false},
+		ETag:           dto.Supported{Supported: false},
+		AuthenticationSchemes: []dto.AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Service-account API key",
+			Description: "An API key with the write scope, of a user granted the scim.provision permission",
+			Primary:     true,
+		}},
+	}
+}
+
+func (s *Service) getUser(ctx context.Context, id string) (*model.User, error) {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound
+	}
+	user, err := s.repository.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errNotFound
+	}
+	return user, nil
+}
+
+// getProvisionableUser returns the user if SCIM may change them. Users whose
+// role has permissions the provisioner does not have, such as administrators,
+// are managed in the application only: rewriting their email would let the
+// provisioner take over their account.
+func (s *Service) getProvisionableUser(ctx context.Context, id string) (*model.User, error) {
+	user, err := s.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	covered, err := s.roleRepository.Covers(ctx, provisionerRole, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, &Error{Status: http.StatusForbidden, Detail: fmt.Sprintf("users of the %s role cannot be changed through SCIM", user.Role)}
+	}
+	return user, nil
+}
+
+func (s *Service) saveUser(ctx context.Context, user *model.User) (*dto.User, error) {
+	if err := s.checkUniqueUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repository.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(updated), nil
+}
+
+// checkUniqueUser makes sure no other user has the same email or external ID.
+func (s *Service) checkUniqueUser(ctx context.Context, user *model.User) error {
+	filters := [][]model.FilterCondition{{{Field: "userName", Operator: "eq", Value: user.Email}}}
+	if user.ExternalID != nil {
+		filters = append(filters, []model.FilterCondition{{Field: "externalId", Operator: "eq", Value: *user.ExternalID}})
+	}
+
+	for _, filter := range filters {
+		users, _, err := s.repository.ListUsers(ctx, filter, 2, 0)
+		if err != nil {
+			return err
+		}
+		for _, other := range users {
+			if other.ID != user.ID {
+				return &Error{Status: http.StatusConflict, Type: "uniqueness", Detail: fmt.Sprintf("%s is already used by another user", filter[0].Field)}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) getGroup(ctx context.Context, id string) (*model.Cohort, error) {
+	cohortID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound
+	}
+	cohort, err := s.repository.GetGroup(ctx, cohortID)
+	if err != nil {
+		return nil, errNotFound
+	}
+	return cohort, nil
+}
+
+// saveGroup saves the cohort and applies the difference between its current
+// members and the given ones.
+func (s *Service) saveGroup(ctx context.Context, cohort *model.Cohort, members []uint64) (*dto.Group, error) {
+	if err := s.checkUniqueGroup(ctx, cohort); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uint64]bool, len(members))
+	for _, id := range members {
+		wanted[id] = true
+	}
+	remove := make([]uint64, 0)
+	for _, member := range cohort.Members {
+		if !wanted[member.UserID] {
+			remove = append(remove, member.UserID)
+		}
+		delete(wanted, member.UserID)
+	}
+	add := make([]uint64, 0, len(wanted))
+	for id := range wanted {
+		add = append(add, id)
+	}
+	sort.Slice(add, func(i, j int) bool { return add[i] < add[j] })
+
+	updated, err := s.repository.UpdateGroup(ctx, cohort, add, remove)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(updated), nil
+}
+
+// checkUniqueGroup makes sure no other cohort of the course has the same
+// name, and no other cohort has the same external ID.
+func (s *Service) checkUniqueGroup(ctx context.Context, cohort *model.Cohort) error {
+	filters := [][]model.FilterCondition{{{Field: "displayName", Operator: "eq", Value: cohort.Name}}}
+	if cohort.ExternalID != nil {
+		filters = append(filters, []model.FilterCondition{{Field: "externalId", Operator: "eq", Value: *cohort.ExternalID}})
+	}
+
+	for i, filter := range filters {
+		cohorts, _, err := s.repository.ListGroups(ctx, filter, maxCount, 0)
+		if err != nil {
+			return err
+		}
+		for _, other := range cohorts {
+			if other.ID != cohort.ID && (i > 0 || other.CourseID == cohort.CourseID) {
+				return &Error{Status: http.StatusConflict, Type: "uniqueness", Detail: fmt.Sprintf("%s is already used by another group", filter[0].Field)}
+			}
+		}
+	}
+	return nil
+}
+
+// memberIDs parses the members of a group and checks that they exist.
+func (s *Service) memberIDs(ctx context.Context, members []dto.Member) ([]uint64, error) {
+	ids := make([]uint64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member.Value, 10, 64)
+		if err != nil {
+			return nil, invalidValue(fmt.Sprintf("unknown member %q", member.Value))
+		}
+		ids = append(ids, id)
+	}
+
+	existing, err := s.repository.ExistingUsers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			return nil, invalidValue(fmt.Sprintf("unknown member %q", strconv.FormatUint(id, 10)))
+		}
+	}
+	return ids, nil
+}
+
+func (s *Service) toUser(user *model.User) *dto.User {
+	id := strconv.FormatUint(user.ID, 10)
+	active := user.SuspendedAt == nil
+	result := &dto.User{
+		Schemas:     []string{dto.UserSchema},
+		ID:          id,
+		UserName:    user.Email,
+		Name:        &dto.Name{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []dto.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        s.meta("User", "/Users/"+id, user.CreatedAt, user.UpdatedAt),
+	}
+	if user.ExternalID != nil {
+		result.ExternalID = *user.ExternalID
+	}
+	return result
+}
+
+func (s *Service) toGroup(cohort *model.Cohort) *dto.Group {
+	id := strconv.FormatUint(cohort.ID, 10)
+	result := &dto.Group{
+		Schemas:     []string{dto.GroupSchema, dto.CohortSchema},
+		ID:          id,
+		DisplayName: cohort.Name,
+		Members:     make([]dto.Member, 0, len(cohort.Members)),
+		Cohort:      &dto.CohortExtension{CourseID: json.Number(strconv.FormatUint(cohort.CourseID, 10))},
+		Meta:        s.meta("Group", "/Groups/"+id, cohort.CreatedAt, cohort.UpdatedAt),
+	}
+	if cohort.ExternalID != nil {
+		result.ExternalID = *cohort.ExternalID
+	}
+	for _, member := range cohort.Members {
+		userID := strconv.FormatUint(member.UserID, 10)
+		result.Members = append(result.Members, dto.Member{
+			Value:   userID,
+			Display: member.Name,
+			Ref:     s.baseURL + "/scim/v2/Users/" + userID,
+		})
+	}
+	return result
+}
+
+func (s *Service) meta(resourceType string, path string, created time.Time, lastModified time.Time) *dto.Meta {
+	return &dto.Meta{
+		ResourceType: resourceType,
+		Created:      created.UTC().Format(time.RFC3339),
+		LastModified: lastModified.UTC().Format(time.RFC3339),
+		Location:     s.baseURL + "/scim/v2" + path,
+	}
+}
+
+// applyUser copies the SCIM attributes to the user. The userName must be an
+// email address, which becomes the email of the user.
+func applyUser(user *model.User, userDTO *dto.User) error {
+	email := strings.TrimSpace(userDTO.UserName)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return invalidValue("userName must be an email address")
+	}
+	user.Email = email
+
+	switch {
+	case userDTO.DisplayName != "":
+		user.Name = userDTO.DisplayName
+	case userDTO.Name != nil && userDTO.Name.Formatted != "":
+		user.Name = userDTO.Name.Formatted
+	case userDTO.Name != nil && (userDTO.Name.GivenName != "" || userDTO.Name.FamilyName != ""):
+		user.Name = strings.TrimSpace(userDTO.Name.GivenName + " " + userDTO.Name.FamilyName)
+	default:
+		user.Name = email
+	}
+
+	user.ExternalID = nil
+	if userDTO.ExternalID != "" {
+		externalID := userDTO.ExternalID
+		user.ExternalID = &externalID
+	}
+
+	switch {
+	case userDTO.Active == nil || *userDTO.Active:
+		user.SuspendedAt = nil
+	case user.SuspendedAt == nil:
+		now := time.Now()
+		user.SuspendedAt = &now
+	}
+	return nil
+}
+
+func applyGroup(cohort *model.Cohort, groupDTO *dto.Group) error {
+	name := strings.TrimSpace(groupDTO.DisplayName)
+	if name == "" {
+		return invalidValue("displayName is required")
+	}
+	cohort.Name = name
+
+	cohort.ExternalID = nil
+	if groupDTO.ExternalID != "" {
+		externalID := groupDTO.ExternalID
+		cohort.ExternalID = &externalID
+	}
+	return nil
+}
+
+func pagination(listDTO *dto.ListRequest) (int, int) {
+	startIndex, count := listDTO.StartIndex, listDTO.Count
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 1 {
+		count = defaultCount
+	}
+	if count > maxCount {
+		count = maxCount
+	}
+	return startIndex, count
+}
+
+func listResponse(resources any, itemsPerPage int, total int64, startIndex int) *dto.ListResponse {
+	return &dto.ListResponse{
+		Schemas:      []string{dto.ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+func (s *Service) Name() string {
+	return service.SCIM
+}
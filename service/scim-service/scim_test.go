@@ -0,0 +1,371 @@
+package scimservice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	dto "github.com/dapthehuman/learning-management-system/dto/scim"
+)
+
+type repository struct {
+	users   map[uint64]*model.User
+	cohorts map[uint64]*model.Cohort
+
+	// Last membership change applied by UpdateGroup
+	added   []uint64
+	removed []uint64
+}
+
+func newRepository(users ...*model.User) *repository {
+	r := &repository{users: make(map[uint64]*model.User), cohorts: make(map[uint64]*model.Cohort)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+// matches supports the "eq" conditions the service uses for its uniqueness
+// checks.
+func matches(filters []model.FilterCondition, values map[string]string) bool {
+	for _, filter := range filters {
+		if filter.Operator != "eq" || values[filter.Field] != filter.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *repository) ListUsers(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.User, int64, error) {
+	users := make([]*model.User, 0)
+	for _, user := range r.users {
+		values := map[string]string{"userName": user.Email}
+		if user.ExternalID != nil {
+			values["externalId"] = *user.ExternalID
+		}
+		if matches(filters, values) {
+			users = append(users, user)
+		}
+	}
+	return users, int64(len(users)), nil
+}
+
+func (r *repository) GetUser(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	user.ID = uint64(len(r.users) + 100)
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *repository) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *repository) ExistingUsers(ctx context.Context, ids []uint64) (map[uint64]bool, error) {
+	existing := make(map[uint64]bool)
+	for _, id := range ids {
+		_, existing[id] = r.users[id]
+	}
+	return existing, nil
+}
+
+func (r *repository) ListGroups(ctx context.Context, filters []model.FilterCondition, limit int, offset int) ([]*model.Cohort, int64, error) {
+	cohorts := make([]*model.Cohort, 0)
+	for _, cohort := range r.cohorts {
+		values := map[string]string{"displayName": cohort.Name}
+		if cohort.ExternalID != nil {
+			values["externalId"] = *cohort.ExternalID
+		}
+		if matches(filters, values) {
+			cohorts = append(cohorts, cohort)
+		}
+	}
+	return cohorts, int64(len(cohorts)), nil
+}
+
+func (r *repository) GetGroup(ctx context.Context, id uint64) (*model.Cohort, error) {
+	if cohort, ok := r.cohorts[id]; ok {
+		return cohort, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) CreateGroup(ctx context.Context, cohort *model.Cohort, memberIDs []uint64) (*model.Cohort, error) {
+	cohort.ID = uint64(len(r.cohorts) + 1)
+	for _, id := range memberIDs {
+		cohort.Members = append(cohort.Members, &model.CohortMember{UserID: id, Name: r.users[id].Name})
+	}
+	r.cohorts[cohort.ID] = cohort
+	return cohort, nil
+}
+
+func (r *repository) UpdateGroup(ctx context.Context, cohort *model.Cohort, add []uint64, remove []uint64) (*model.Cohort, error) {
+	r.added, r.removed = add, remove
+	members := make([]*model.CohortMember, 0, len(cohort.Members)+len(add))
+	for _, member := range cohort.Members {
+		if !slices.Contains(remove, member.UserID) {
+			members = append(members, member)
+		}
+	}
+	for _, id := range add {
+		members = append(members, &model.CohortMember{UserID: id, Name: r.users[id].Name})
+	}
+	cohort.Members = members
+	r.cohorts[cohort.ID] = cohort
+	return cohort, nil
+}
+
+func (r *repository) DeleteGroup(ctx context.Context, id uint64) error {
+	delete(r.cohorts, id)
+	return nil
+}
+
+type courseRepository []uint64
+
+func (r courseRepository) First(ctx context.Context, id uint64) (*model.Course, error) {
+	if slices.Contains(r, id) {
+		return &model.Course{ID: id}, nil
+	}
+	return nil, nil
+}
+
+type roleRepository map[string][]string
+
+func (r roleRepository) Covers(ctx context.Context, holder string, role string) (bool, error) {
+	for _, permission := range r[role] {
+		if !slices.Contains(r[holder], permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+var roles = roleRepository{
+	"admin":            {"scim.provision", "user.update"},
+	"scim_provisioner": {"scim.provision"},
+	"student":          {},
+}
+
+func newService(repository *repository) *Service {
+	return NewService(repository, courseRepository{7, 8}, roles, "https://lms.example.edu/")
+}
+
+func expectError(t *testing.T, err error, status int, scimType string) {
+	t.Helper()
+	var scimErr *Error
+	if !errors.As(err, &scimErr) || scimErr.Status != status || scimErr.Type != scimType {
+		t.Errorf("expected a %d %q error, got %v", status, scimType, err)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	repository := newRepository()
+	s := newService(repository)
+
+	user, err := s.CreateUser(context.Background(), &dto.User{UserName: "jane@example.edu", ExternalID: "00u1", Name: &dto.Name{GivenName: "Jane", FamilyName: "Doe"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := repository.users[100]
+	if stored.Role != defaultRole || stored.Email != "jane@example.edu" || stored.Name != "Jane Doe" || *stored.ExternalID != "00u1" {
+		t.Errorf("stored %+v", stored)
+	}
+	if user.ID != "100" || !*user.Active || user.Meta.Location != "https://lms.example.edu/scim/v2/Users/100" {
+		t.Errorf("returned %+v", user)
+	}
+}
+
+func TestCreateUserRejects(t *testing.T) {
+	externalID := "00u1"
+	cases := []struct {
+		name     string
+		user     dto.User
+		status   int
+		scimType string
+	}{
+		{name: "userName is not an email", user: dto.User{UserName: "jane"}, status: http.StatusBadRequest, scimType: "invalidValue"},
+		{name: "existing userName", user: dto.User{UserName: "taken@example.edu"}, status: http.StatusConflict, scimType: "uniqueness"},
+		{name: "existing externalId", user: dto.User{UserName: "jane@example.edu", ExternalID: externalID}, status: http.StatusConflict, scimType: "uniqueness"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repository := newRepository(&model.User{ID: 1, Email: "taken@example.edu", ExternalID: &externalID})
+			s := newService(repository)
+
+			_, err := s.CreateUser(context.Background(), &c.user)
+			expectError(t, err, c.status, c.scimType)
+			if len(repository.users) != 1 {
+				t.Error("stored a refused user")
+			}
+		})
+	}
+}
+
+func TestPatchUserActive(t *testing.T) {
+	repository := newRepository(&model.User{ID: 1, Email: "jane@example.edu", Name: "Jane"})
+	s := newService(repository)
+
+	for _, value := range []string{`false`, `"False"`} {
+		repository.users[1].SuspendedAt = nil
+		patch := &dto.PatchRequest{Operations: []dto.PatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(value)}}}
+		user, err := s.PatchUser(context.Background(), "1", patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *user.Active || repository.users[1].SuspendedAt == nil {
+			t.Errorf("active %s: the user is not suspended", value)
+		}
+	}
+
+	patch := &dto.PatchRequest{Operations: []dto.PatchOperation{{Op: "replace", Value: json.RawMessage(`{"active":true}`)}}}
+	user, err := s.PatchUser(context.Background(), "1", patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*user.Active || repository.users[1].SuspendedAt != nil {
+		t.Error("the user is still suspended")
+	}
+}
+
+func TestDeprovisionUser(t *testing.T) {
+	repository := newRepository(&model.User{ID: 1, Email: "jane@example.edu"})
+	s := newService(repository)
+
+	if err := s.DeprovisionUser(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if repository.users[1] == nil || repository.users[1].SuspendedAt == nil {
+		t.Error("expected the user to be kept and suspended")
+	}
+
+	expectError(t, s.DeprovisionUser(context.Background(), "2"), http.StatusNotFound, "")
+}
+
+func TestChangeUserRejectsPrivilegedUsers(t *testing.T) {
+	repository := newRepository(&model.User{ID: 1, Email: "admin@example.edu", Role: "admin"})
+	s := newService(repository)
+
+	_, err := s.ReplaceUser(context.Background(), "1", &dto.User{UserName: "attacker@example.edu"})
+	expectError(t, err, http.StatusForbidden, "")
+
+	patch := &dto.PatchRequest{Operations: []dto.PatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}}}
+	_, err = s.PatchUser(context.Background(), "1", patch)
+	expectError(t, err, http.StatusForbidden, "")
+
+	expectError(t, s.DeprovisionUser(context.Background(), "1"), http.StatusForbidden, "")
+
+	if admin := repository.users[1]; admin.Email != "admin@example.edu" || admin.SuspendedAt != nil {
+		t.Errorf("the administrator changed: %+v", admin)
+	}
+}
+
+func TestCreateGroup(t *testing.T) {
+	repository := newRepository(&model.User{ID: 1, Name: "Ada"}, &model.User{ID: 2, Name: "Grace"})
+	s := newService(repository)
+
+	group, err := s.CreateGroup(context.Background(), &dto.Group{
+		DisplayName: "Morning",
+		Members:     []dto.Member{{Value: "1"}, {Value: "2"}},
+		Cohort:      &dto.CohortExtension{CourseID: "7"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Cohort.CourseID != "7" || len(group.Members) != 2 || group.Members[1].Display != "Grace" {
+		t.Errorf("returned %+v", group)
+	}
+}
+
+func TestCreateGroupRejects(t *testing.T) {
+	cases := []struct {
+		name     string
+		group    dto.Group
+		status   int
+		scimType string
+	}{
+		{name: "no course", group: dto.Group{DisplayName: "Evening"}, status: http.StatusBadRequest, scimType: "invalidValue"},
+		{name: "unknown course", group: dto.Group{DisplayName: "Evening", Cohort: &dto.CohortExtension{CourseID: "9"}}, status: http.StatusBadRequest, scimType: "invalidValue"},
+		{name: "unknown member", group: dto.Group{DisplayName: "Evening", Members: []dto.Member{{Value: "3"}}, Cohort: &dto.CohortExtension{CourseID: "7"}}, status: http.StatusBadRequest, scimType: "invalidValue"},
+		{name: "existing name in the course", group: dto.Group{DisplayName: "Morning", Cohort: &dto.CohortExtension{CourseID: "7"}}, status: http.StatusConflict, scimType: "uniqueness"},
+		{name: "existing externalId", group: dto.Group{DisplayName: "Evening", ExternalID: "g1", Cohort: &dto.CohortExtension{CourseID: "8"}}, status: http.StatusConflict, scimType: "uniqueness"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repository := newRepository(&model.User{ID: 1})
+			externalID := "g1"
+			repository.cohorts[1] = &model.Cohort{ID: 1, CourseID: 7, Name: "Morning", ExternalID: &externalID}
+			s := newService(repository)
+
+			_, err := s.CreateGroup(context.Background(), &c.group)
+			expectError(t, err, c.status, c.scimType)
+			if len(repository.cohorts) != 1 {
+				t.Error("stored a refused group")
+			}
+		})
+	}
+
+	// The same name is allowed in another course
+	repository := newRepository()
+	repository.cohorts[1] = &model.Cohort{ID: 1, CourseID: 7, Name: "Morning"}
+	s := newService(repository)
+	if _, err := s.CreateGroup(context.Background(), &dto.Group{DisplayName: "Morning", Cohort: &dto.CohortExtension{CourseID: "8"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPatchGroupMembers(t *testing.T) {
+	repository := newRepository(&model.User{ID: 1}, &model.User{ID: 2}, &model.User{ID: 3})
+	repository.cohorts[1] = &model.Cohort{ID: 1, CourseID: 7, Name: "Morning", Members: []*model.CohortMember{{UserID: 1}, {UserID: 2}}}
+	s := newService(repository)
+
+	patch := &dto.PatchRequest{Operations: []dto.PatchOperation{
+		{Op: "remove", Path: `members[value eq "1"]`},
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"3"},{"value":"2"}]`)},
+	}}
+	group, err := s.PatchGroup(context.Background(), "1", patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repository.added, []uint64{3}) || !slices.Equal(repository.removed, []uint64{1}) {
+		t.Errorf("expected to add 3 and remove 1, added %v and removed %v", repository.added, repository.removed)
+	}
+	if len(group.Members) != 2 || group.Members[0].Value != "2" || group.Members[1].Value != "3" {
+		t.Errorf("returned members %v", group.Members)
+	}
+
+	patch = &dto.PatchRequest{Operations: []dto.PatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"4"}]`)}}}
+	_, err = s.PatchGroup(context.Background(), "1", patch)
+	expectError(t, err, http.StatusBadRequest, "invalidValue")
+
+	patch = &dto.PatchRequest{Operations: []dto.PatchOperation{{Op: "remove", Path: "members"}}}
+	if _, err := s.PatchGroup(context.Background(), "1", patch); err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.added) != 0 || !slices.Equal(repository.removed, []uint64{2, 3}) {
+		t.Errorf("expected to remove every member, added %v and removed %v", repository.added, repository.removed)
+	}
+}
+
+func TestReplaceGroupKeepsCourse(t *testing.T) {
+	repository := newRepository()
+	repository.cohorts[1] = &model.Cohort{ID: 1, CourseID: 7, Name: "Morning"}
+	s := newService(repository)
+
+	_, err := s.ReplaceGroup(context.Background(), "1", &dto.Group{DisplayName: "Evening", Cohort: &dto.CohortExtension{CourseID: "8"}})
+	expectError(t, err, http.StatusBadRequest, "mutability")
+	if cohort := repository.cohorts[1]; cohort.CourseID != 7 || cohort.Name != "Morning" {
+		t.Errorf("cohort changed to %+v", cohort)
+	}
+}
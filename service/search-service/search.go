@@ -0,0 +1,82 @@
+package searchservice
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+const (
+	defaultLimit   = 20
+	maxLimit       = 100
+	maxQueryLength = 200
+)
+
+// highlighter turns the delimiters of the matches in escaped snippets into
+// HTML.
+var highlighter = strings.NewReplacer(model.SnippetStart, "<mark>", model.SnippetStop, "</mark>")
+
+type Repository interface {
+	Search(ctx context.Context, query *model.SearchQuery) ([]*model.SearchResult, error)
+}
+
+type Service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *Service {
+	return &Service{
+		repository: repository,
+	}
+}
+
+// Search returns the courses, sections and text materials matching the
+// query that the viewer can see, best first.
+func (s *Service) Search(ctx context.Context, viewer *dto.CourseViewer, request *dto.SearchRequest) ([]*dto.SearchResult, error) {
+	text := strings.TrimSpace(request.Query)
+	if text == "" {
+		return nil, errors.New("q is required")
+	}
+	if len(text) > maxQueryLength {
+		return nil, errors.New("q cannot be longer than 200 characters")
+	}
+
+	switch request.Type {
+	case "", model.SearchCourse, model.SearchCurriculum, model.SearchMaterial:
+	default:
+		return nil, errors.New("type must be course, curriculum or material")
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	results, err := s.repository.Search(ctx, &model.SearchQuery{
+		Text:      text,
+		Type:      request.Type,
+		Limit:     min(limit, maxLimit),
+		UserID:    viewer.UserID,
+		ManageAny: viewer.ManageAny,
+		CanReview: viewer.CanReview,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		result.Snippet = highlighter.Replace(html.EscapeString(result.Snippet))
+	}
+
+	return typeutil.MustConvert[[]*dto.SearchResult](results), nil
+}
+
+func (s *Service) Name() string {
+	return service.Search
+}
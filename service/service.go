@@ -11,11 +11,23 @@ package service
 // Name of the implemented services.
 const (
 	// TODO add service names
-	User       = "user"
-	Admin       = "admin"
-	Course     = "course"
-	Student    = "student"
-	Curriculum = "curriculum"
-	Assessment = "assessment"
-	Material   = "material"
+	User          = "user"
+	Admin         = "admin"
+	Course        = "course"
+	Student       = "student"
+	Curriculum    = "curriculum"
+	Assessment    = "assessment"
+	Material      = "material"
+	OIDC          = "oidc"
+	SAML          = "saml"
+	Token         = "token"
+	Role          = "role"
+	Impersonation = "impersonation"
+	Mail          = "mail"
+	Invitation    = "invitation"
+	Privacy       = "privacy"
+	SCIM          = "scim"
+	Organization  = "organization"
+	Category      = "category"
+	Search        = "search"
 )
@@ -0,0 +1,132 @@
+package studentservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"goyave.dev/goyave/v5/util/errors"
+)
+
+// IneligibleError is returned when enrolling a student who does not meet the
+// requirements of the course.
+type IneligibleError struct {
+	Unmet []*dto.UnmetRequirement
+}
+
+func (e *IneligibleError) Error() string {
+	return "You do not meet the requirements of this course"
+}
+
+// Eligibility evaluates the requirements of the course for the student and
+// explains the ones they do not meet. Only the courses of the catalog can be
+// enrolled in.
+func (s *Service) Eligibility(ctx context.Context, studentID uint64, courseID uint64) (*dto.Eligibility, error) {
+	student, err := s.repository.GetByID(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return nil, errors.New("Student not found")
+	}
+
+	course, err := s.courseRepository.First(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if course == nil || !course.Listed(time.Now()) {
+		return nil, errors.New("This course is not open for enrollment")
+	}
+
+	requirements, err := s.courseRepository.GetRequirements(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	unmet, err := s.unmetRequirements(ctx, student, requirements, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.Eligibility{
+		CourseID: courseID,
+		Eligible: len(unmet) == 0,
+		Unmet:    unmet,
+	}, nil
+}
+
+func (s *Service) unmetRequirements(ctx context.Context, student *model.User, requirements *model.CourseRequirements, now time.Time) ([]*dto.UnmetRequirement, error) {
+	unmet := make([]*dto.UnmetRequirement, 0)
+
+	if requirements.OpensAt != nil && now.Before(*requirements.OpensAt) {
+		unmet = append(unmet, &dto.UnmetRequirement{
+			Type:    dto.RequirementNotOpen,
+			Message: fmt.Sprintf("Enrollment opens on %s", requirements.OpensAt.Format(time.RFC1123)),
+			At:      requirements.OpensAt,
+		})
+	}
+	if requirements.ClosesAt != nil && !now.Before(*requirements.ClosesAt) {
+		unmet = append(unmet, &dto.UnmetRequirement{
+			Type:    dto.RequirementClosed,
+			Message: fmt.Sprintf("Enrollment closed on %s", requirements.ClosesAt.Format(time.RFC1123)),
+			At:      requirements.ClosesAt,
+		})
+	}
+
+	if len(requirements.Roles) > 0 && !slices.Contains(requirements.Roles, student.Role) {
+		unmet = append(unmet, &dto.UnmetRequirement{
+			Type:    dto.RequirementRole,
+			Message: "This course is reserved to the following roles: " + strings.Join(requirements.Roles, ", "),
+			Allowed: requirements.Roles,
+		})
+	}
+
+	_, domain, _ := strings.Cut(strings.ToLower(student.Email), "@")
+	if len(requirements.EmailDomains) > 0 && !slices.Contains(requirements.EmailDomains, domain) {
+		unmet = append(unmet, &dto.UnmetRequirement{
+			Type:    dto.RequirementEmailDomain,
+			Message: "This course is reserved to the members of the organizations using the following email domains: " + strings.Join(requirements.EmailDomains, ", "),
+			Allowed: requirements.EmailDomains,
+		})
+	}
+
+	if len(requirements.Prerequisites) == 0 {
+		return unmet, nil
+	}
+
+	ids := make([]uint64, 0, len(requirements.Prerequisites))
+	for _, prerequisite := range requirements.Prerequisites {
+		ids = append(ids, prerequisite.CourseID)
+	}
+	results, err := s.repository.CourseResults(ctx, student.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prerequisite := range requirements.Prerequisites {
+		result := results[prerequisite.CourseID]
+		switch {
+		case result == nil || !result.Completed:
+			unmet = append(unmet, &dto.UnmetRequirement{
+				Type:     dto.RequirementPrerequisite,
+				Message:  fmt.Sprintf("Complete %q first", prerequisite.Title),
+				CourseID: &prerequisite.CourseID,
+				MinGrade: prerequisite.MinGrade,
+			})
+		case prerequisite.MinGrade != nil && (result.Grade == nil || *result.Grade < float64(*prerequisite.MinGrade)):
+			unmet = append(unmet, &dto.UnmetRequirement{
+				Type:     dto.RequirementGrade,
+				Message:  fmt.Sprintf("A grade of at least %d in %q is required", *prerequisite.MinGrade, prerequisite.Title),
+				CourseID: &prerequisite.CourseID,
+				MinGrade: prerequisite.MinGrade,
+				Grade:    result.Grade,
+			})
+		}
+	}
+
+	return unmet, nil
+}
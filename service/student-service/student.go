@@ -6,6 +6,7 @@ import (
 	"github.com/dapthehuman/learning-management-system/database/models"
 	model "github.com/dapthehuman/learning-management-system/database/models"
 	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
 	"github.com/dapthehuman/learning-management-system/service"
 	"goyave.dev/goyave/v5/util/errors"
 	"goyave.dev/goyave/v5/util/typeutil"
@@ -13,28 +14,39 @@ import (
 
 type Repository interface {
 	GetByID(ctx context.Context, id uint64) (*model.User, error)
-	GetAll(ctx context.Context) ([]*model.User, error)
+	List(ctx context.Context, q *listing.Query) (*listing.Page[*model.User], error)
 	Update(ctx context.Context, student *model.User) (*model.User, error)
 
-	EnrollCourse(ctx context.Context, studentID uint64, courseID uint64) (*models.Enrollment, error)
-	GetEnrollmentsByUserID(ctx context.Context, studentID uint64) ([]*models.Enrollment, error)
+	EnrollCourse(ctx context.Context, studentID uint64, courseID uint64) (*models.Enrollment, *models.WaitlistEntry, error)
+	Unenroll(ctx context.Context, studentID uint64, courseID uint64) (bool, error)
+	ListWaitlist(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*models.WaitlistEntry], error)
+	ListEnrollmentsByUserID(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*models.Enrollment], error)
 	TrackProgress(ctx context.Context, progress *models.ProgressTracking) (*models.ProgressTracking, error)
-	GetProgressByStudentAndCurriculum(ctx context.Context, studentID uint64, curriculumID uint64) ([]*models.ProgressTracking, error)
+	ListProgress(ctx context.Context, studentID uint64, curriculumID uint64, q *listing.Query) (*listing.Page[*models.ProgressTracking], error)
 
-	ListAchievementByUserID(ctx context.Context, userID uint64) ([]*models.Achievement, error)
+	ListAchievementByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*models.Achievement], error)
 	GetAchievementByID(ctx context.Context, id uint64) (*models.Achievement, error)
 	CreateAchievement(ctx context.Context, achievement *models.Achievement) (*models.Achievement, error)
 	UpdateAchievement(ctx context.Context, achievement *models.Achievement) (*models.Achievement, error)
 	DeleteAchievement(ctx context.Context, id uint64) error
+
+	CourseResults(ctx context.Context, studentID uint64, courseIDs []uint64) (map[uint64]*models.CourseResult, error)
+}
+
+type CourseRepository interface {
+	First(ctx context.Context, id uint64) (*model.Course, error)
+	GetRequirements(ctx context.Context, courseID uint64) (*model.CourseRequirements, error)
 }
 
 type Service struct {
-	repository Repository
+	repository       Repository
+	courseRepository CourseRepository
 }
 
-func NewService(repository Repository) *Service {
+func NewService(repository Repository, courseRepository CourseRepository) *Service {
 	return &Service{
-		repository: repository,
+		repository:       repository,
+		courseRepository: courseRepository,
 	}
 }
 
@@ -50,13 +62,13 @@ func (s *Service) GetByID(ctx context.Context, id uint64) (*dto.User, error) {
 	return typeutil.MustConvert[*dto.User](student), nil
 }
 
-func (s *Service) GetAll(ctx context.Context) ([]*dto.User, error) {
-	students, err := s.repository.GetAll(ctx)
+func (s *Service) GetAll(ctx context.Context, q *listing.Query) (*listing.Page[*dto.User], error) {
+	students, err := s.repository.List(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.User](students), nil
+	return typeutil.MustConvert[*listing.Page[*dto.User]](students), nil
 }
 
 func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.UpdateStudentRequest) (*dto.User, error) {
@@ -79,23 +91,64 @@ func (s *Service) Update(ctx context.Context, id uint64, updateDTO *dto.UpdateSt
 	return typeutil.MustConvert[*dto.User](updatedStudent), nil
 }
 
-func (s *Service) EnrollCourse(ctx context.Context, enrollmentDTO *dto.EnrollStudentRequest) (*dto.Enrollment, error) {
+// EnrollCourse enrolls the student in the course, or puts them on its
+// waitlist if it is full. It returns an *IneligibleError explaining the
+// requirements of the course they do not meet.
+func (s *Service) EnrollCourse(ctx context.Context, enrollmentDTO *dto.EnrollStudentRequest) (*dto.Enrollment, *dto.WaitlistEntry, error) {
 	enrollment := typeutil.MustConvert[*models.Enrollment](enrollmentDTO)
-	enrollment, err := s.repository.EnrollCourse(ctx, uint64(enrollment.UserID), uint64(enrollment.CourseID))
+
+	eligibility, err := s.Eligibility(ctx, enrollment.UserID, enrollment.CourseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !eligibility.Eligible {
+		return nil, nil, &IneligibleError{Unmet: eligibility.Unmet}
+	}
+
+	enrollment, entry, err := s.repository.EnrollCourse(ctx, enrollment.UserID, enrollment.CourseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry != nil {
+		return nil, typeutil.MustConvert[*dto.WaitlistEntry](entry), nil
+	}
+	if enrollment == nil {
+		return nil, nil, errors.New("This course is not open for enrollment")
+	}
+
+	return typeutil.MustConvert[*dto.Enrollment](enrollment), nil, nil
+}
+
+// Unenroll removes the student from the course or its waitlist. Their seat
+// goes to the first student waiting.
+func (s *Service) Unenroll(ctx context.Context, studentID uint64, courseID uint64) error {
+	removed, err := s.repository.Unenroll(ctx, studentID, courseID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("You are not enrolled in this course")
+	}
+
+	return nil
+}
+
+func (s *Service) GetWaitlist(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.WaitlistEntry], error) {
+	entries, err := s.repository.ListWaitlist(ctx, studentID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[*dto.Enrollment](enrollment), nil
+	return typeutil.MustConvert[*listing.Page[*dto.WaitlistEntry]](entries), nil
 }
 
-func (s *Service) GetEnrollmentsByStudentID(ctx context.Context, studentID uint64) ([]*dto.Enrollment, error) {
-	enrollments, err := s.repository.GetEnrollmentsByUserID(ctx, studentID)
+func (s *Service) GetEnrollmentsByStudentID(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.Enrollment], error) {
+	enrollments, err := s.repository.ListEnrollmentsByUserID(ctx, studentID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.Enrollment](enrollments), nil
+	return typeutil.MustConvert[*listing.Page[*dto.Enrollment]](enrollments), nil
 }
 
 func (s *Service) TrackProgress(ctx context.Context, progressDTO *dto.TrackProgressRequest) (*dto.ProgressTracking, error) {
@@ -108,22 +161,22 @@ func (s *Service) TrackProgress(ctx context.Context, progressDTO *dto.TrackProgr
 	return typeutil.MustConvert[*dto.ProgressTracking](progress), nil
 }
 
-func (s *Service) GetProgressByStudentAndCurriculum(ctx context.Context, studentID, curriculumID uint64) ([]*dto.ProgressTracking, error) {
-	progress, err := s.repository.GetProgressByStudentAndCurriculum(ctx, studentID, curriculumID)
+func (s *Service) GetProgressByStudentAndCurriculum(ctx context.Context, studentID, curriculumID uint64, q *listing.Query) (*listing.Page[*dto.ProgressTracking], error) {
+	progress, err := s.repository.ListProgress(ctx, studentID, curriculumID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.ProgressTracking](progress), nil
+	return typeutil.MustConvert[*listing.Page[*dto.ProgressTracking]](progress), nil
 }
 
-func (s *Service) GetListAchievements(ctx context.Context, studentID uint64) ([]*dto.Achievement, error) {
-	achievements, err := s.repository.ListAchievementByUserID(ctx, studentID)
+func (s *Service) GetListAchievements(ctx context.Context, studentID uint64, q *listing.Query) (*listing.Page[*dto.Achievement], error) {
+	achievements, err := s.repository.ListAchievementByUserID(ctx, studentID, q)
 	if err != nil {
 		return nil, err
 	}
 
-	return typeutil.MustConvert[[]*dto.Achievement](achievements), nil
+	return typeutil.MustConvert[*listing.Page[*dto.Achievement]](achievements), nil
 }
 
 func (s *Service) CreateAchievement(ctx context.Context, achievementDTO *dto.CreateAchievementRequest) (*dto.Achievement, error) {
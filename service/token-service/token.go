@@ -0,0 +1,256 @@
+package tokenservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+	"github.com/dapthehuman/learning-management-system/listing"
+	"github.com/dapthehuman/learning-management-system/service"
+	"goyave.dev/goyave/v5/util/errors"
+	"goyave.dev/goyave/v5/util/typeutil"
+)
+
+const (
+	// Prefix makes tokens recognizable, e.g. by secret scanners.
+	Prefix = "lms_"
+
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+
+	defaultLifetimeDays = 30
+	maxLifetimeDays     = 365
+)
+
+type Repository interface {
+	Create(ctx context.Context, token *model.APIToken) (*model.APIToken, error)
+	GetByID(ctx context.Context, id uint64) (*model.APIToken, error)
+	GetActiveByHash(ctx context.Context, hash string) (*model.APIToken, *model.User, error)
+	ListByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*model.APIToken], error)
+	ListAll(ctx context.Context, q *listing.Query) (*listing.Page[*model.APIToken], error)
+	TouchLastUsed(ctx context.Context, id uint64) error
+	Revoke(ctx context.Context, id uint64) error
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+}
+
+type RoleRepository interface {
+	Covers(ctx context.Context, holder string, role string) (bool, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error)
+}
+
+type Service struct {
+	repository      Repository
+	userRepository  UserRepository
+	roleRepository  RoleRepository
+	auditRepository AuditRepository
+}
+
+func NewService(repository Repository, userRepository UserRepository, roleRepository RoleRepository, auditRepository AuditRepository) *Service {
+	return &Service{
+		repository:      repository,
+		userRepository:  userRepository,
+		roleRepository:  roleRepository,
+		auditRepository: auditRepository,
+	}
+}
+
+// Create mints a token for userID. The plain token is only part of the
+// returned response and cannot be retrieved afterwards. Administrators
+// minting a key for someone else can only do so for users whose role has no
+// permissions they do not have, since the key lets them act as that user.
+func (s *Service) Create(ctx context.Context, userID uint64, createdBy uint64, createDTO *dto.CreateAPITokenRequest) (*dto.CreateAPITokenResponse, error) {
+	if userID != createdBy {
+		if err := s.checkGrant(ctx, createdBy, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	scopes := createDTO.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{ScopeRead}
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeWrite {
+			return nil, fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	days := createDTO.ExpiresInDays
+	if days == 0 {
+		days = defaultLifetimeDays
+	}
+	if days < 0 || days > maxLifetimeDays {
+		return nil, fmt.Errorf("expires_in_days must be between 1 and %d", maxLifetimeDays)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	plain := Prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := &model.APIToken{
+		UserID:      userID,
+		Name:        createDTO.Name,
+		TokenPrefix: plain[:len(Prefix)+6],
+		TokenHash:   hash(plain),
+		Scopes:      strings.Join(scopes, ","),
+		CreatedBy:   &createdBy,
+		ExpiresAt:   time.Now().AddDate(0, 0, days),
+	}
+
+	token, err := s.repository.Create(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, "api_token.create", createdBy, token); err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateAPITokenResponse{APIToken: *toDTO(token), Token: plain}, nil
+}
+
+func (s *Service) ListByUserID(ctx context.Context, userID uint64, q *listing.Query) (*listing.Page[*dto.APIToken], error) {
+	tokens, err := s.repository.ListByUserID(ctx, userID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return listing.Map(tokens, toDTO), nil
+}
+
+func (s *Service) ListAll(ctx context.Context, q *listing.Query) (*listing.Page[*dto.APIToken], error) {
+	tokens, err := s.repository.ListAll(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return listing.Map(tokens, toDTO), nil
+}
+
+// Revoke revokes a token owned by userID.
+func (s *Service) Revoke(ctx context.Context, userID uint64, id uint64) error {
+	token, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if token.UserID != userID {
+		return errors.New("Token not found")
+	}
+
+	if err := s.repository.Revoke(ctx, id); err != nil {
+		return err
+	}
+	return s.audit(ctx, "api_token.revoke", userID, token)
+}
+
+// RevokeAny revokes any token, for administrators.
+func (s *Service) RevokeAny(ctx context.Context, actorID uint64, id uint64) error {
+	token, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.Revoke(ctx, id); err != nil {
+		return err
+	}
+	return s.audit(ctx, "api_token.revoke", actorID, token)
+}
+
+// Authenticate resolves a plain token to its owner and records its use.
+func (s *Service) Authenticate(ctx context.Context, plain string) (*dto.User, *dto.APIToken, error) {
+	if !strings.HasPrefix(plain, Prefix) {
+		return nil, nil, errors.New("not an API token")
+	}
+
+	token, user, err := s.repository.GetActiveByHash(ctx, hash(plain))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repository.TouchLastUsed(ctx, token.ID); err != nil {
+		return nil, nil, err
+	}
+
+	return typeutil.MustConvert[*dto.User](user), toDTO(token), nil
+}
+
+// checkGrant prevents the actor from minting keys for a user whose role has
+// permissions they do not have, such as another administrator.
+func (s *Service) checkGrant(ctx context.Context, actorID uint64, userID uint64) error {
+	actor, err := s.userRepository.GetByID(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepository.GetByID(ctx, userID)
+	if err != nil {
+		return errors.New("User not found")
+	}
+
+	covered, err := s.roleRepository.Covers(ctx, actor.Role, user.Role)
+	if err != nil {
+		return err
+	}
+	if !covered {
+		return errors.New(fmt.Sprintf("you cannot create keys for users of the %s role, which has permissions you do not have", user.Role))
+	}
+	return nil
+}
+
+func (s *Service) audit(ctx context.Context, action string, actorID uint64, token *model.APIToken) error {
+	raw, err := json.Marshal(map[string]any{
+		"token_id":   token.ID,
+		"name":       token.Name,
+		"scopes":     strings.Split(token.Scopes, ","),
+		"expires_at": token.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.auditRepository.Create(ctx, &model.AuditLog{
+		ActorID:      &actorID,
+		Action:       action,
+		TargetUserID: &token.UserID,
+		Metadata:     raw,
+	})
+	return err
+}
+
+func (s *Service) Name() string {
+	return service.Token
+}
+
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func toDTO(token *model.APIToken) *dto.APIToken {
+	return &dto.APIToken{
+		ID:          int(token.ID),
+		UserID:      int(token.UserID),
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      strings.Split(token.Scopes, ","),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		RevokedAt:   token.RevokedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
@@ -0,0 +1,124 @@
+package tokenservice
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	model "github.com/dapthehuman/learning-management-system/database/models"
+	"github.com/dapthehuman/learning-management-system/dto"
+)
+
+type repository struct {
+	Repository
+	tokens []*model.APIToken
+}
+
+func (r *repository) Create(ctx context.Context, token *model.APIToken) (*model.APIToken, error) {
+	token.ID = uint64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return token, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uint64) (*model.APIToken, error) {
+	for _, token := range r.tokens {
+		if token.ID == id {
+			return token, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *repository) Revoke(ctx context.Context, id uint64) error {
+	return nil
+}
+
+type userRepository map[uint64]*model.User
+
+func (r userRepository) GetByID(ctx context.Context, id uint64) (*model.User, error) {
+	if user, ok := r[id]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+type roleRepository map[string][]string
+
+func (r roleRepository) Covers(ctx context.Context, holder string, role string) (bool, error) {
+	for _, permission := range r[role] {
+		if !slices.Contains(r[holder], permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type auditRepository struct {
+	entries []*model.AuditLog
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *model.AuditLog) (*model.AuditLog, error) {
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func newService() (*Service, *repository, *auditRepository) {
+	users := userRepository{
+		1: {ID: 1, Role: "admin"},
+		2: {ID: 2, Role: "admin"},
+		3: {ID: 3, Role: "scim_provisioner"},
+		4: {ID: 4, Role: "token_manager"},
+	}
+	roles := roleRepository{
+		"admin":            {"scim.provision", "token.manage", "user.impersonate"},
+		"scim_provisioner": {"scim.provision"},
+		"token_manager":    {"token.manage"},
+	}
+	repository := &repository{}
+	audit := &auditRepository{}
+	return NewService(repository, users, roles, audit), repository, audit
+}
+
+func TestCreateForAnotherUser(t *testing.T) {
+	s, repository, audit := newService()
+
+	if _, err := s.Create(context.Background(), 3, 4, &dto.CreateAPITokenRequest{Name: "SCIM"}); err == nil {
+		t.Error("a key was minted for a role with permissions the administrator lacks")
+	}
+
+	token, err := s.Create(context.Background(), 3, 1, &dto.CreateAPITokenRequest{Name: "SCIM", Scopes: []string{ScopeWrite}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.tokens) != 1 || *repository.tokens[0].CreatedBy != 1 || token.Token == "" {
+		t.Fatalf("unexpected tokens %v", repository.tokens)
+	}
+
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(audit.entries))
+	}
+	entry := audit.entries[0]
+	if entry.Action != "api_token.create" || *entry.ActorID != 1 || *entry.TargetUserID != 3 {
+		t.Errorf("unexpected audit entry %+v", entry)
+	}
+
+	if err := s.RevokeAny(context.Background(), 2, repository.tokens[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.entries) != 2 || audit.entries[1].Action != "api_token.revoke" || *audit.entries[1].ActorID != 2 {
+		t.Errorf("the revocation was not recorded: %+v", audit.entries)
+	}
+}
+
+func TestCreateOwnToken(t *testing.T) {
+	s, repository, _ := newService()
+
+	// Users can always create tokens for themselves
+	if _, err := s.Create(context.Background(), 4, 4, &dto.CreateAPITokenRequest{Name: "CLI"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repository.tokens) != 1 {
+		t.Errorf("expected 1 token, got %d", len(repository.tokens))
+	}
+}
@@ -2,6 +2,8 @@ package userservice
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 
 	model "github.com/dapthehuman/learning-management-system/database/models"
@@ -17,6 +19,10 @@ type Repository interface {
 	Create(ctx context.Context, student *model.User) (*model.User, error)
 	Update(ctx context.Context, student *model.User) (*model.User, error)
 	Delete(ctx context.Context, id uint64) error
+	GetByID(ctx context.Context, id uint64) (*model.User, error)
+	UpdatePassword(ctx context.Context, id uint64, passwordHash string) error
+	GetStatus(ctx context.Context, id uint64) (*model.User, error)
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (*model.User, error)
 }
 
 type Service struct {
@@ -67,9 +73,62 @@ func (s *Service) Login(ctx context.Context, credsDTO *authDto.LoginRequest) (*d
 		return nil, errors.New("invalid credentials")
 	}
 
+	if user.SuspendedAt != nil {
+		return nil, errors.New("account suspended")
+	}
+	if user.PasswordResetRequired {
+		return nil, errors.New("password reset required")
+	}
+
 	return typeutil.MustConvert[*dto.User](user), nil
 }
 
+func (s *Service) ChangePassword(ctx context.Context, userID uint64, changeDTO *authDto.ChangePasswordRequest) error {
+	user, err := s.repository.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(changeDTO.CurrentPassword))
+	if err != nil {
+		return errors.New("invalid credentials")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(changeDTO.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.UpdatePassword(ctx, userID, string(hashedPassword))
+}
+
+// ResetPassword sets a new password using a reset token issued by an administrator.
+func (s *Service) ResetPassword(ctx context.Context, resetDTO *authDto.ResetPasswordRequest) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetDTO.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256([]byte(resetDTO.Token))
+	if _, err := s.repository.ResetPassword(ctx, hex.EncodeToString(sum[:]), string(hashedPassword)); err != nil {
+		return errors.New("invalid or expired reset token")
+	}
+	return nil
+}
+
+func (s *Service) AccountStatus(ctx context.Context, id uint64) (*dto.AccountStatus, error) {
+	user, err := s.repository.GetStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.AccountStatus{
+		Role:                  user.Role,
+		Suspended:             user.SuspendedAt != nil,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}, nil
+}
+
 func (s *Service) Name() string {
 	return service.User
 }
@@ -0,0 +1,28 @@
+// Package tenant carries the organization a request is scoped to.
+//
+// Every repository reads the organization from the context and restricts
+// its queries to it. A context without an organization is scoped to the
+// organization 0, which does not exist: reads find nothing and writes are
+// rejected by the foreign keys, so code that forgot to resolve the tenant
+// can never reach the data of a school.
+package tenant
+
+import "context"
+
+// DefaultOrganizationID is the organization owning the data that existed
+// before multi-tenancy. It is also the platform organization, the only one
+// allowed to manage platform-wide settings such as roles.
+const DefaultOrganizationID uint64 = 1
+
+type contextKey struct{}
+
+// WithOrganization returns a copy of ctx scoped to the given organization.
+func WithOrganization(ctx context.Context, organizationID uint64) context.Context {
+	return context.WithValue(ctx, contextKey{}, organizationID)
+}
+
+// ID returns the organization ctx is scoped to, or 0 if it is not scoped.
+func ID(ctx context.Context) uint64 {
+	id, _ := ctx.Value(contextKey{}).(uint64)
+	return id
+}